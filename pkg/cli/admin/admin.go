@@ -13,6 +13,7 @@ import (
 	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
 	ktemplates "k8s.io/kubectl/pkg/util/templates"
 
+	"github.com/openshift/oc/pkg/cli/admin/alerts"
 	"github.com/openshift/oc/pkg/cli/admin/buildchain"
 	"github.com/openshift/oc/pkg/cli/admin/catalog"
 	"github.com/openshift/oc/pkg/cli/admin/createbootstrapprojecttemplate"
@@ -24,6 +25,7 @@ import (
 	"github.com/openshift/oc/pkg/cli/admin/inspect"
 	"github.com/openshift/oc/pkg/cli/admin/migrate"
 	migrateetcd "github.com/openshift/oc/pkg/cli/admin/migrate/etcd"
+	migrateicsp "github.com/openshift/oc/pkg/cli/admin/migrate/icsp"
 	migrateimages "github.com/openshift/oc/pkg/cli/admin/migrate/images"
 	migratehpa "github.com/openshift/oc/pkg/cli/admin/migrate/legacyhpa"
 	migratestorage "github.com/openshift/oc/pkg/cli/admin/migrate/storage"
@@ -63,6 +65,7 @@ func NewCommandAdmin(f kcmdutil.Factory, streams genericclioptions.IOStreams) *c
 				top.NewCommandTop(f, streams),
 				mustgather.NewMustGatherCommand(f, streams),
 				inspect.NewCmdInspect(streams),
+				alerts.New(f, streams),
 			},
 		},
 		{
@@ -96,6 +99,7 @@ func NewCommandAdmin(f kcmdutil.Factory, streams genericclioptions.IOStreams) *c
 					migrateetcd.NewCmdMigrateTTLs(f, streams),
 					migratehpa.NewCmdMigrateLegacyHPA(f, streams),
 					migratetemplateinstances.NewCmdMigrateTemplateInstances(f, streams),
+					migrateicsp.NewCmdMigrateICSP(f, streams),
 				),
 			},
 		},
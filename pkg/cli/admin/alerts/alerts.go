@@ -0,0 +1,163 @@
+// Package alerts contains a command for listing the alerts firing in a cluster.
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/kubernetes"
+	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	alertsLong = templates.LongDesc(`
+		List the alerts firing in the cluster.
+
+		The alerts are read from the cluster's Prometheus through the API server service proxy,
+		and are listed by severity and namespace, with how long each has been firing. Alerts
+		that are not about a namespace are listed with a namespace of "-".
+
+		By default the alerts of every namespace are listed. Pass --namespace to only list the
+		alerts about one namespace.
+	`)
+
+	alertsExample = templates.Examples(`
+		# List the alerts firing in the cluster
+		  oc adm alerts
+
+		# List the critical alerts about the openshift-etcd namespace
+		  oc adm alerts --severity=critical -n openshift-etcd
+
+		# Print the firing alerts as JSON
+		  oc adm alerts -o json
+	`)
+)
+
+func NewOptions(streams genericclioptions.IOStreams) *Options {
+	return &Options{
+		IOStreams: streams,
+		now:       time.Now,
+	}
+}
+
+func New(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "alerts",
+		Short:   "List the alerts firing in the cluster",
+		Long:    alertsLong,
+		Example: alertsExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			kcmdutil.CheckErr(o.Complete(f, cmd, args))
+			kcmdutil.CheckErr(o.Validate())
+			kcmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringSliceVar(&o.Severities, "severity", o.Severities, "Only list alerts of these severities, like critical or warning.")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format. One of: json.")
+	return cmd
+}
+
+type Options struct {
+	genericclioptions.IOStreams
+
+	Severities []string
+	Output     string
+	// Namespace is empty to list the alerts of every namespace.
+	Namespace string
+
+	Client kubernetes.Interface
+
+	now func() time.Time
+}
+
+func (o *Options) Complete(f kcmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return kcmdutil.UsageErrorf(cmd, "no positional arguments are accepted")
+	}
+	namespace, explicit, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	if explicit {
+		o.Namespace = namespace
+	}
+	cfg, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	if o.Client, err = kubernetes.NewForConfig(cfg); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o *Options) Validate() error {
+	switch o.Output {
+	case "", "json":
+	default:
+		return fmt.Errorf("--output must be json, or empty for a table")
+	}
+	return nil
+}
+
+func (o *Options) Run() error {
+	alerts, _, err := Fetch(context.TODO(), o.Client)
+	if err != nil {
+		return err
+	}
+	firing := o.filter(Firing(alerts))
+
+	if o.Output == "json" {
+		if firing == nil {
+			firing = []Alert{}
+		}
+		data, err := json.MarshalIndent(firing, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(o.Out, string(data))
+		return nil
+	}
+
+	if len(firing) == 0 {
+		fmt.Fprintln(o.ErrOut, "No alerts are firing.")
+		return nil
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	defer w.Flush()
+	fmt.Fprintln(w, "SEVERITY\tNAMESPACE\tALERT\tFIRING FOR\tMESSAGE")
+	now := o.now()
+	for _, alert := range firing {
+		namespace := alert.Namespace()
+		if len(namespace) == 0 {
+			namespace = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", alert.Severity(), namespace, alert.Name(), alert.Duration(now), alert.Message())
+	}
+	return nil
+}
+
+// filter returns the alerts matching the --severity and --namespace flags.
+func (o *Options) filter(alerts []Alert) []Alert {
+	severities := sets.NewString(o.Severities...)
+	var filtered []Alert
+	for _, alert := range alerts {
+		if severities.Len() > 0 && !severities.Has(alert.Severity()) {
+			continue
+		}
+		if len(o.Namespace) > 0 && alert.Namespace() != o.Namespace {
+			continue
+		}
+		filtered = append(filtered, alert)
+	}
+	return filtered
+}
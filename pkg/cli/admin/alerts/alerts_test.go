@@ -0,0 +1,124 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+	restclient "k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+const testResponse = `{"status": "success", "data": {"alerts": [
+  {"labels": {"alertname": "Watchdog", "severity": "none"}, "state": "firing", "activeAt": "2022-01-01T00:00:00Z"},
+  {"labels": {"alertname": "KubePodCrashLooping", "namespace": "openshift-etcd", "severity": "warning"},
+   "annotations": {"description": "Pod openshift-etcd/etcd-0\n is crash looping."}, "state": "firing", "activeAt": "2022-01-01T11:55:00Z"},
+  {"labels": {"alertname": "KubeAPIErrorBudgetBurn", "severity": "critical"},
+   "annotations": {"summary": "The API server is burning too much error budget."}, "state": "firing", "activeAt": "2022-01-01T10:00:00Z"},
+  {"labels": {"alertname": "ClusterNotUpgradeable", "severity": "info"}, "state": "firing", "activeAt": "2022-01-01T00:00:00Z"},
+  {"labels": {"alertname": "KubeletDown", "severity": "critical"}, "state": "pending", "activeAt": "2022-01-01T11:59:00Z"}
+]}}`
+
+var testNow = time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+
+type fakeResponse struct {
+	data []byte
+}
+
+func (r fakeResponse) DoRaw(context.Context) ([]byte, error) { return r.data, nil }
+func (r fakeResponse) Stream(context.Context) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(r.data)), nil
+}
+
+func newFakeClient(response string) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.Fake.AddProxyReactor("services", func(action clienttesting.Action) (bool, restclient.ResponseWrapper, error) {
+		proxy := action.(clienttesting.ProxyGetAction)
+		if proxy.GetNamespace() != monitoringNamespace || proxy.GetName() != prometheusService || proxy.GetPath() != alertsPath {
+			return false, nil, nil
+		}
+		return true, fakeResponse{data: []byte(response)}, nil
+	})
+	return client
+}
+
+func TestFetch(t *testing.T) {
+	alerts, data, err := Fetch(context.TODO(), newFakeClient(testResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 5 || string(data) != testResponse {
+		t.Errorf("unexpected alerts: %#v", alerts)
+	}
+
+	if _, _, err := Fetch(context.TODO(), newFakeClient(`{"status": "error", "error": "query timed out"}`)); err == nil {
+		t.Error("expected an error for a failed query")
+	}
+}
+
+func TestFiring(t *testing.T) {
+	alerts, err := Parse([]byte(testResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, alert := range Firing(alerts) {
+		names = append(names, alert.Name())
+	}
+	want := "KubeAPIErrorBudgetBurn KubePodCrashLooping ClusterNotUpgradeable Watchdog"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestHumanSummary(t *testing.T) {
+	alerts, err := Parse([]byte(testResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\t4 firing: 1 critical, 1 warning, 1 info, 1 none\n" +
+		"\tcritical alert KubeAPIErrorBudgetBurn firing for 2 hours: The API server is burning too much error budget.\n" +
+		"\twarning alert KubePodCrashLooping in namespace openshift-etcd firing for 5 minutes: Pod openshift-etcd/etcd-0 is crash looping."
+	if got := HumanSummary(alerts, testNow); got != want {
+		t.Errorf("unexpected summary:\n%s", got)
+	}
+	if got := HumanSummary(nil, testNow); got != "\tNo alerts are firing" {
+		t.Errorf("unexpected summary: %s", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewOptions(streams)
+	o.Client = newFakeClient(testResponse)
+	o.now = func() time.Time { return testNow }
+	o.Severities = []string{"critical", "warning"}
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := `SEVERITY   NAMESPACE        ALERT                    FIRING FOR   MESSAGE
+critical   -                KubeAPIErrorBudgetBurn   2 hours      The API server is burning too much error budget.
+warning    openshift-etcd   KubePodCrashLooping      5 minutes    Pod openshift-etcd/etcd-0 is crash looping.
+`
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o = NewOptions(streams)
+	o.Client = newFakeClient(testResponse)
+	o.Namespace = "openshift-monitoring"
+	o.Output = "json"
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "[]\n" {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+}
@@ -0,0 +1,190 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	monitoringNamespace = "openshift-monitoring"
+	prometheusService   = "prometheus-k8s"
+	prometheusPort      = "web"
+	alertsPath          = "/api/v1/alerts"
+
+	// StateFiring is the state of alerts whose condition has held for long enough to notify.
+	StateFiring = "firing"
+)
+
+// Alert is an alert as returned by the Prometheus alerts API.
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	State       string            `json:"state"`
+	ActiveAt    *time.Time        `json:"activeAt,omitempty"`
+	Value       string            `json:"value,omitempty"`
+}
+
+// Name returns the name of the alerting rule.
+func (a Alert) Name() string {
+	return a.Labels["alertname"]
+}
+
+// Severity returns the severity label, or "none" if the alert has none.
+func (a Alert) Severity() string {
+	if severity := a.Labels["severity"]; len(severity) > 0 {
+		return severity
+	}
+	return "none"
+}
+
+// Namespace returns the namespace the alert is about, which is empty for cluster-wide alerts.
+func (a Alert) Namespace() string {
+	return a.Labels["namespace"]
+}
+
+// Message returns the most descriptive annotation of the alert.
+func (a Alert) Message() string {
+	for _, key := range []string{"summary", "message", "description"} {
+		if message := a.Annotations[key]; len(message) > 0 {
+			return strings.Join(strings.Fields(message), " ")
+		}
+	}
+	return ""
+}
+
+// Duration returns how long the alert has been active, or "<unknown>".
+func (a Alert) Duration(now time.Time) string {
+	if a.ActiveAt == nil || a.ActiveAt.IsZero() {
+		return "<unknown>"
+	}
+	return units.HumanDuration(now.Sub(*a.ActiveAt))
+}
+
+type alertsResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Data   struct {
+		Alerts []Alert `json:"alerts"`
+	} `json:"data"`
+}
+
+// Fetch returns the alerts of the in-cluster Prometheus, which is queried through the API server
+// service proxy, along with the response as it was received.
+func Fetch(ctx context.Context, client kubernetes.Interface) ([]Alert, []byte, error) {
+	data, err := client.CoreV1().Services(monitoringNamespace).ProxyGet("https", prometheusService, prometheusPort, alertsPath, nil).DoRaw(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to query alerts from service %s/%s: %v", monitoringNamespace, prometheusService, err)
+	}
+	alerts, err := Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return alerts, data, nil
+}
+
+// Parse reads a response of the Prometheus alerts API.
+func Parse(data []byte) ([]Alert, error) {
+	response := &alertsResponse{}
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, fmt.Errorf("unable to read alerts: %v", err)
+	}
+	if response.Status != "success" {
+		return nil, fmt.Errorf("unable to query alerts: %s", response.Error)
+	}
+	return response.Data.Alerts, nil
+}
+
+// severityOrder sorts the well known severities first, most severe first.
+var severityOrder = map[string]int{
+	"critical": 0,
+	"warning":  1,
+	"info":     2,
+	"none":     3,
+}
+
+func severityRank(severity string) int {
+	if rank, ok := severityOrder[severity]; ok {
+		return rank
+	}
+	return len(severityOrder)
+}
+
+// Firing returns the firing alerts, ordered by severity, namespace, name and how long they have
+// been firing.
+func Firing(alerts []Alert) []Alert {
+	var firing []Alert
+	for _, alert := range alerts {
+		if alert.State == StateFiring {
+			firing = append(firing, alert)
+		}
+	}
+	sort.SliceStable(firing, func(i, j int) bool {
+		a, b := firing[i], firing[j]
+		if rankA, rankB := severityRank(a.Severity()), severityRank(b.Severity()); rankA != rankB {
+			return rankA < rankB
+		}
+		if a.Severity() != b.Severity() {
+			return a.Severity() < b.Severity()
+		}
+		if a.Namespace() != b.Namespace() {
+			return a.Namespace() < b.Namespace()
+		}
+		if a.Name() != b.Name() {
+			return a.Name() < b.Name()
+		}
+		if a.ActiveAt != nil && b.ActiveAt != nil {
+			return a.ActiveAt.Before(*b.ActiveAt)
+		}
+		return false
+	})
+	return firing
+}
+
+// HumanSummary describes the firing critical and warning alerts, one per line, and counts the rest.
+func HumanSummary(alerts []Alert, now time.Time) string {
+	firing := Firing(alerts)
+	counts := map[string]int{}
+	var lines []string
+	for _, alert := range firing {
+		counts[alert.Severity()]++
+		if severityRank(alert.Severity()) > severityOrder["warning"] {
+			continue
+		}
+		line := fmt.Sprintf("%s alert %s", alert.Severity(), alert.Name())
+		if namespace := alert.Namespace(); len(namespace) > 0 {
+			line += fmt.Sprintf(" in namespace %s", namespace)
+		}
+		line += fmt.Sprintf(" firing for %s", alert.Duration(now))
+		if message := alert.Message(); len(message) > 0 {
+			line += ": " + message
+		}
+		lines = append(lines, line)
+	}
+	if len(firing) == 0 {
+		return "\tNo alerts are firing"
+	}
+
+	var severities []string
+	for severity := range counts {
+		severities = append(severities, severity)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		if rankI, rankJ := severityRank(severities[i]), severityRank(severities[j]); rankI != rankJ {
+			return rankI < rankJ
+		}
+		return severities[i] < severities[j]
+	})
+	var countStrings []string
+	for _, severity := range severities {
+		countStrings = append(countStrings, fmt.Sprintf("%d %s", counts[severity], severity))
+	}
+	lines = append([]string{fmt.Sprintf("%d firing: %s", len(firing), strings.Join(countStrings, ", "))}, lines...)
+	return "\t" + strings.Join(lines, "\n\t")
+}
@@ -0,0 +1,413 @@
+package catalog
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/joelanford/ignore"
+	"github.com/spf13/pflag"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// PackageFilter selects the operator bundles of a catalog that are mirrored. An empty filter
+// selects every bundle.
+type PackageFilter struct {
+	// Packages limits the bundles to those in the named packages.
+	Packages []string
+	// Channels limits the bundles to those in channels with one of these names.
+	Channels []string
+	// MinVersion and MaxVersion limit the bundles to those with a version in the inclusive range.
+	MinVersion string
+	MaxVersion string
+	// HeadsOnly limits the bundles to the head of each selected channel.
+	HeadsOnly bool
+
+	minVersion, maxVersion *semver.Version
+}
+
+// Bind adds the filter flags to flags.
+func (f *PackageFilter) Bind(flags *pflag.FlagSet) {
+	flags.StringSliceVar(&f.Packages, "packages", f.Packages, "Only mirror the bundles of these operator packages. Comma separated or individual arguments.")
+	flags.StringSliceVar(&f.Channels, "channels", f.Channels, "Only mirror the bundles in channels with these names. Comma separated or individual arguments.")
+	flags.StringVar(&f.MinVersion, "min-version", f.MinVersion, "Only mirror bundles with a version greater than or equal to this semantic version.")
+	flags.StringVar(&f.MaxVersion, "max-version", f.MaxVersion, "Only mirror bundles with a version less than or equal to this semantic version.")
+	flags.BoolVar(&f.HeadsOnly, "heads-only", f.HeadsOnly, "Only mirror the bundle at the head of each selected channel.")
+}
+
+// Complete parses the version bounds of the filter.
+func (f *PackageFilter) Complete() error {
+	f.minVersion, f.maxVersion = nil, nil
+	if len(f.MinVersion) > 0 {
+		v, err := semver.ParseTolerant(f.MinVersion)
+		if err != nil {
+			return fmt.Errorf("--min-version must be a semantic version: %v", err)
+		}
+		f.minVersion = &v
+	}
+	if len(f.MaxVersion) > 0 {
+		v, err := semver.ParseTolerant(f.MaxVersion)
+		if err != nil {
+			return fmt.Errorf("--max-version must be a semantic version: %v", err)
+		}
+		f.maxVersion = &v
+	}
+	if f.minVersion != nil && f.maxVersion != nil && f.minVersion.GT(*f.maxVersion) {
+		return fmt.Errorf("--min-version may not be greater than --max-version")
+	}
+	return nil
+}
+
+// IsEmpty returns true if the filter selects every bundle.
+func (f *PackageFilter) IsEmpty() bool {
+	return f == nil || (len(f.Packages) == 0 && len(f.Channels) == 0 && len(f.MinVersion) == 0 && len(f.MaxVersion) == 0 && !f.HeadsOnly)
+}
+
+// catalogBundle is the information about an operator bundle that a filter is applied to.
+type catalogBundle struct {
+	Name    string
+	Package string
+	Version string
+	// Channels are the names of the channels the bundle is in.
+	Channels sets.String
+	// Heads are the names of the channels the bundle is the head of.
+	Heads sets.String
+}
+
+// Include returns true if the bundle is selected by the filter.
+func (f *PackageFilter) Include(b *catalogBundle) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	if len(f.Packages) > 0 && !sets.NewString(f.Packages...).Has(b.Package) {
+		return false
+	}
+	channels := b.Channels
+	if len(f.Channels) > 0 {
+		channels = channels.Intersection(sets.NewString(f.Channels...))
+		if channels.Len() == 0 {
+			return false
+		}
+	}
+	if f.HeadsOnly && channels.Intersection(b.Heads).Len() == 0 {
+		return false
+	}
+	if f.minVersion != nil || f.maxVersion != nil {
+		v, err := semver.ParseTolerant(b.Version)
+		if err != nil {
+			return false
+		}
+		if f.minVersion != nil && v.LT(*f.minVersion) {
+			return false
+		}
+		if f.maxVersion != nil && v.GT(*f.maxVersion) {
+			return false
+		}
+	}
+	return true
+}
+
+// channelEntry is an entry in an update channel of a package.
+type channelEntry struct {
+	Name     string
+	Replaces string
+	Skips    []string
+}
+
+// channelHeads returns the names of the entries in a channel that are not replaced or skipped by
+// any other entry.
+func channelHeads(entries []channelEntry) sets.String {
+	replaced := sets.NewString()
+	for _, e := range entries {
+		if len(e.Replaces) > 0 {
+			replaced.Insert(e.Replaces)
+		}
+		replaced.Insert(e.Skips...)
+	}
+	heads := sets.NewString()
+	for _, e := range entries {
+		if !replaced.Has(e.Name) {
+			heads.Insert(e.Name)
+		}
+	}
+	return heads
+}
+
+// bundleKey identifies a bundle within a catalog.
+func bundleKey(pkg, name string) string {
+	return strings.Join([]string{pkg, name}, "/")
+}
+
+const (
+	declcfgSchemaPackage = "olm.package"
+	declcfgSchemaChannel = "olm.channel"
+	declcfgSchemaBundle  = "olm.bundle"
+
+	declcfgPropertyPackage = "olm.package"
+	declcfgPropertyChannel = "olm.channel"
+	declcfgPropertySkips   = "olm.skips"
+)
+
+// declcfgBlob is a single object of a declarative config catalog.
+type declcfgBlob struct {
+	declcfgMeta
+	raw json.RawMessage
+}
+
+// declcfgIndex is the content of a declarative config catalog.
+type declcfgIndex struct {
+	blobs []declcfgBlob
+	// bundles is keyed by bundleKey
+	bundles map[string]*catalogBundle
+}
+
+// loadDeclcfgIndex reads all of the declarative configs under root, honoring any .indexignore
+// files, and resolves the channels and channel heads of every bundle.
+func loadDeclcfgIndex(root string) (*declcfgIndex, error) {
+	rootFS := os.DirFS(root)
+
+	matcher, err := ignore.NewMatcher(rootFS, indexIgnoreFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &declcfgIndex{bundles: map[string]*catalogBundle{}}
+	if err := fs.WalkDir(rootFS, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || entry.Name() == indexIgnoreFilename || matcher.Match(path, false) {
+			return nil
+		}
+		f, err := rootFS.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dec := utilyaml.NewYAMLOrJSONDecoder(f, 4096)
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return err
+			}
+			blob := declcfgBlob{raw: raw}
+			if err := json.Unmarshal(raw, &blob.declcfgMeta); err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+			index.blobs = append(index.blobs, blob)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// channels are keyed by package and then by channel name
+	channels := map[string]map[string][]channelEntry{}
+	addEntry := func(pkg, channel string, entry channelEntry) {
+		if channels[pkg] == nil {
+			channels[pkg] = map[string][]channelEntry{}
+		}
+		channels[pkg][channel] = append(channels[pkg][channel], entry)
+	}
+	for _, blob := range index.blobs {
+		switch blob.Schema {
+		case declcfgSchemaChannel:
+			for _, e := range blob.Entries {
+				addEntry(blob.Package, blob.Name, channelEntry{Name: e.Name, Replaces: e.Replaces, Skips: e.Skips})
+			}
+		case declcfgSchemaBundle:
+			b := &catalogBundle{Name: blob.Name, Package: blob.Package, Channels: sets.NewString(), Heads: sets.NewString()}
+			index.bundles[bundleKey(blob.Package, blob.Name)] = b
+
+			// older catalogs record channel membership as properties of the bundle
+			var bundleChannels []channelEntry
+			var skips []string
+			for _, p := range blob.Properties {
+				switch p.Type {
+				case declcfgPropertyPackage:
+					var v struct {
+						Version string `json:"version"`
+					}
+					if err := json.Unmarshal(p.Value, &v); err != nil {
+						return nil, fmt.Errorf("bundle %s has an invalid %s property: %v", blob.Name, p.Type, err)
+					}
+					b.Version = v.Version
+				case declcfgPropertyChannel:
+					var v struct {
+						Name     string `json:"name"`
+						Replaces string `json:"replaces"`
+					}
+					if err := json.Unmarshal(p.Value, &v); err != nil {
+						return nil, fmt.Errorf("bundle %s has an invalid %s property: %v", blob.Name, p.Type, err)
+					}
+					bundleChannels = append(bundleChannels, channelEntry{Name: v.Name, Replaces: v.Replaces})
+				case declcfgPropertySkips:
+					var v string
+					if err := json.Unmarshal(p.Value, &v); err != nil {
+						return nil, fmt.Errorf("bundle %s has an invalid %s property: %v", blob.Name, p.Type, err)
+					}
+					skips = append(skips, v)
+				}
+			}
+			for _, c := range bundleChannels {
+				addEntry(blob.Package, c.Name, channelEntry{Name: blob.Name, Replaces: c.Replaces, Skips: skips})
+			}
+		}
+	}
+
+	for pkg, byName := range channels {
+		for channel, entries := range byName {
+			heads := channelHeads(entries)
+			for _, e := range entries {
+				b, ok := index.bundles[bundleKey(pkg, e.Name)]
+				if !ok {
+					continue
+				}
+				b.Channels.Insert(channel)
+				if heads.Has(e.Name) {
+					b.Heads.Insert(channel)
+				}
+			}
+		}
+	}
+	return index, nil
+}
+
+// writePruned writes the objects of the index that are selected by the filter to w as a stream of
+// JSON documents. Channels only keep the entries of selected bundles, and packages whose default
+// channel was removed default to the first remaining channel.
+func (index *declcfgIndex) writePruned(w io.Writer, filter *PackageFilter) error {
+	selected := sets.NewString()
+	// selectedChannels are keyed by package
+	selectedChannels := map[string]sets.String{}
+	for key, b := range index.bundles {
+		if !filter.Include(b) {
+			continue
+		}
+		selected.Insert(key)
+		channels := b.Channels
+		if len(filter.Channels) > 0 {
+			channels = channels.Intersection(sets.NewString(filter.Channels...))
+		}
+		if selectedChannels[b.Package] == nil {
+			selectedChannels[b.Package] = sets.NewString()
+		}
+		selectedChannels[b.Package] = selectedChannels[b.Package].Union(channels)
+	}
+
+	for _, blob := range index.blobs {
+		raw := blob.raw
+		switch blob.Schema {
+		case declcfgSchemaBundle:
+			if !selected.Has(bundleKey(blob.Package, blob.Name)) {
+				continue
+			}
+		case declcfgSchemaChannel:
+			if !selectedChannels[blob.Package].Has(blob.Name) {
+				continue
+			}
+			var err error
+			if raw, err = pruneChannelEntries(raw, blob.Package, selected); err != nil {
+				return fmt.Errorf("channel %s of package %s: %v", blob.Name, blob.Package, err)
+			}
+		case declcfgSchemaPackage:
+			channels, ok := selectedChannels[blob.Name]
+			if !ok {
+				continue
+			}
+			if !channels.Has(blob.DefaultChannel) && channels.Len() > 0 {
+				var err error
+				if raw, err = setField(raw, "defaultChannel", channels.List()[0]); err != nil {
+					return fmt.Errorf("package %s: %v", blob.Name, err)
+				}
+			}
+		default:
+			if _, ok := selectedChannels[blob.Package]; len(blob.Package) > 0 && !ok {
+				continue
+			}
+		}
+		if _, err := w.Write(append(raw, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneChannelEntries removes the entries of a channel object that do not refer to a selected bundle,
+// as well as replaces fields that refer to a removed entry.
+func pruneChannelEntries(raw json.RawMessage, pkg string, selected sets.String) (json.RawMessage, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	entries, _ := obj["entries"].([]interface{})
+	kept := []interface{}{}
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, _ := entry["name"].(string); !selected.Has(bundleKey(pkg, name)) {
+			continue
+		}
+		if replaces, _ := entry["replaces"].(string); len(replaces) > 0 && !selected.Has(bundleKey(pkg, replaces)) {
+			delete(entry, "replaces")
+		}
+		kept = append(kept, entry)
+	}
+	obj["entries"] = kept
+	return json.Marshal(obj)
+}
+
+// setField sets a top level field of a JSON object.
+func setField(raw json.RawMessage, field string, value interface{}) (json.RawMessage, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	obj[field] = value
+	return json.Marshal(obj)
+}
+
+// writeDeclcfgLayer writes a gzipped image layer to w that replaces the contents of dir with a single
+// index.json file containing index.
+func writeDeclcfgLayer(w io.Writer, dir string, index []byte) error {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	if len(dir) == 0 {
+		return fmt.Errorf("declarative configs stored at the root of the image cannot be pruned")
+	}
+	now := time.Now()
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0755, ModTime: now}); err != nil {
+		return err
+	}
+	// an opaque whiteout hides the configs of the underlying index image
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: path.Join(dir, ".wh..wh..opq"), Mode: 0644, ModTime: now}); err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: path.Join(dir, "index.json"), Mode: 0644, Size: int64(len(index)), ModTime: now}); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, bytes.NewReader(index)); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
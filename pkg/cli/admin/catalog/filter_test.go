@@ -0,0 +1,193 @@
+package catalog
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestPackageFilterComplete(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  PackageFilter
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "tolerant versions", filter: PackageFilter{MinVersion: "v0.9", MaxVersion: "1"}},
+		{name: "invalid min version", filter: PackageFilter{MinVersion: "latest"}, wantErr: true},
+		{name: "invalid max version", filter: PackageFilter{MaxVersion: "1.x"}, wantErr: true},
+		{name: "inverted range", filter: PackageFilter{MinVersion: "0.10.0", MaxVersion: "0.9.0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Complete(); (err != nil) != tt.wantErr {
+				t.Errorf("Complete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChannelHeads(t *testing.T) {
+	entries := []channelEntry{
+		{Name: "a.v1"},
+		{Name: "a.v2", Replaces: "a.v1"},
+		{Name: "a.v3", Replaces: "a.v2", Skips: []string{"a.v2.1"}},
+		{Name: "a.v2.1"},
+		{Name: "a.other"},
+	}
+	if got, want := channelHeads(entries), sets.NewString("a.v3", "a.other"); !got.Equal(want) {
+		t.Errorf("channelHeads() = %v, want %v", got.List(), want.List())
+	}
+}
+
+func TestFilteredRelatedImages(t *testing.T) {
+	etcdHead := map[string]struct{}{
+		"quay.io/test/etcd.0.9.2": {},
+		"quay.io/coreos/etcd-operator@sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2": {},
+	}
+	prometheusStableHead := map[string]struct{}{
+		"quay.io/test/prometheus.0.15.0": {},
+		"quay.io/coreos/prometheus-operator@sha256:0e92dd9b5789c4b13d53e1319d0a6375bcca4caaf0d698af61198061222a576d": {},
+	}
+	prometheusRecent := map[string]struct{}{
+		"quay.io/test/prometheus.0.15.0": {},
+		"quay.io/coreos/prometheus-operator@sha256:0e92dd9b5789c4b13d53e1319d0a6375bcca4caaf0d698af61198061222a576d": {},
+		"quay.io/test/prometheus.0.22.2": {},
+		"quay.io/coreos/prometheus-operator@sha256:3daa69a8c6c2f1d35dcf1fe48a7cd8b230e55f5229a1ded438f687debade5bcf": {},
+	}
+	tests := []struct {
+		name   string
+		filter PackageFilter
+		want   map[string]struct{}
+	}{
+		{
+			name:   "package heads",
+			filter: PackageFilter{Packages: []string{"etcd"}, HeadsOnly: true},
+			want:   etcdHead,
+		},
+		{
+			name:   "channel heads",
+			filter: PackageFilter{Packages: []string{"prometheus"}, Channels: []string{"stable"}, HeadsOnly: true},
+			want:   prometheusStableHead,
+		},
+		{
+			name:   "version range",
+			filter: PackageFilter{Packages: []string{"prometheus", "etcd"}, MinVersion: "0.15.0", MaxVersion: "0.22.2"},
+			want:   prometheusRecent,
+		},
+		{
+			name:   "no matches",
+			filter: PackageFilter{Packages: []string{"missing"}},
+			want:   map[string]struct{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Complete(); err != nil {
+				t.Fatal(err)
+			}
+			parsers := map[string]struct {
+				parser RelatedImagesParser
+				path   string
+			}{
+				"sqlite":  {parser: &sqliteRelatedImagesParser{filter: &tt.filter}, path: "testdata/test.db"},
+				"declcfg": {parser: &declcfgRelatedImagesParser{filter: &tt.filter}, path: "testdata/test-declcfg"},
+			}
+			for name, p := range parsers {
+				got, err := p.parser.Parse(p.path)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if !reflect.DeepEqual(tt.want, got) {
+					t.Errorf("%s: unexpected images: %s", name, diff.ObjectReflectDiff(tt.want, got))
+				}
+			}
+		})
+	}
+}
+
+func TestWritePruned(t *testing.T) {
+	index, err := loadDeclcfgIndex("testdata/test-declcfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := &PackageFilter{Packages: []string{"prometheus"}, Channels: []string{"stable"}}
+	if err := filter.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := index.writePruned(buf, filter); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "pruned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "index.json"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pruned, err := loadDeclcfgIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, blob := range pruned.blobs {
+		switch blob.Schema {
+		case declcfgSchemaPackage:
+			got = append(got, blob.Schema+"/"+blob.Name+"/"+blob.DefaultChannel)
+		default:
+			got = append(got, blob.Schema+"/"+blob.Name)
+		}
+	}
+	want := []string{
+		"olm.package/prometheus/stable",
+		"olm.bundle/prometheusoperator.0.14.0",
+		"olm.bundle/prometheusoperator.0.15.0",
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected pruned index: %s", diff.ObjectReflectDiff(want, got))
+	}
+}
+
+func TestWriteDeclcfgLayer(t *testing.T) {
+	if err := writeDeclcfgLayer(ioutil.Discard, "/", []byte("{}")); err == nil {
+		t.Errorf("expected an error pruning configs at the root of the image")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := writeDeclcfgLayer(buf, "/configs/", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	gr, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	var got []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, h.Name)
+	}
+	want := []string{"configs/", "configs/.wh..wh..opq", "configs/index.json"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected layer contents: %s", diff.ObjectReflectDiff(want, got))
+	}
+}
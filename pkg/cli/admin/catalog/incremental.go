@@ -0,0 +1,190 @@
+package catalog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alicebob/sqlittle"
+	"github.com/blang/semver"
+)
+
+const (
+	// bundleListFilename records the bundles mirrored by a run so that a later run can report
+	// the bundles that were added and removed.
+	bundleListFilename = "bundles.txt"
+	changelogFilename  = "changelog.txt"
+)
+
+// readMapping reads a mapping.txt file written by a previous run and returns its entries keyed by
+// source.
+func readMapping(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mapping := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.SplitN(text, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("%s:%d: expected SOURCE=DESTINATION", path, line)
+		}
+		mapping[parts[0]] = parts[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// listDeclcfgBundles returns the bundles of a declarative config catalog that are selected by filter.
+func listDeclcfgBundles(root string, filter *PackageFilter) ([]*catalogBundle, error) {
+	index, err := loadDeclcfgIndex(root)
+	if err != nil {
+		return nil, err
+	}
+	var bundles []*catalogBundle
+	for _, b := range index.bundles {
+		if filter.Include(b) {
+			bundles = append(bundles, b)
+		}
+	}
+	return bundles, nil
+}
+
+// listSqliteBundles returns the bundles of a sqlite catalog that are selected by filter.
+func listSqliteBundles(file string, filter *PackageFilter) ([]*catalogBundle, error) {
+	db, err := sqlittle.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	all, err := sqliteBundles(db, true)
+	if err != nil {
+		// older databases do not record bundle versions
+		if all, err = sqliteBundles(db, false); err != nil {
+			return nil, err
+		}
+	}
+	var bundles []*catalogBundle
+	for _, b := range all {
+		if filter.Include(b) {
+			bundles = append(bundles, b)
+		}
+	}
+	return bundles, nil
+}
+
+// sortBundles orders bundles by package, then by version, then by name.
+func sortBundles(bundles []*catalogBundle) {
+	sort.Slice(bundles, func(i, j int) bool {
+		a, b := bundles[i], bundles[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Version != b.Version {
+			va, errA := semver.ParseTolerant(a.Version)
+			vb, errB := semver.ParseTolerant(b.Version)
+			if errA == nil && errB == nil && !va.EQ(vb) {
+				return va.LT(vb)
+			}
+			return a.Version < b.Version
+		}
+		return a.Name < b.Name
+	})
+}
+
+// writeBundleList writes one line per bundle with its package, name and version separated by tabs.
+func writeBundleList(w io.Writer, bundles []*catalogBundle) error {
+	sortBundles(bundles)
+	for _, b := range bundles {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", b.Package, b.Name, b.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBundleList reads a file written by writeBundleList.
+func readBundleList(path string) ([]*catalogBundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var bundles []*catalogBundle
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		parts := strings.Split(scanner.Text(), "\t")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected PACKAGE, NAME and VERSION separated by tabs", path, line)
+		}
+		bundles = append(bundles, &catalogBundle{Package: parts[0], Name: parts[1], Version: parts[2]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return bundles, nil
+}
+
+// diffBundles returns the bundles of current that are not in previous, and the bundles of previous
+// that are not in current, in sorted order.
+func diffBundles(previous, current []*catalogBundle) (added, removed []*catalogBundle) {
+	keys := func(bundles []*catalogBundle) map[string]*catalogBundle {
+		m := make(map[string]*catalogBundle, len(bundles))
+		for _, b := range bundles {
+			m[bundleKey(b.Package, b.Name)] = b
+		}
+		return m
+	}
+	previousKeys, currentKeys := keys(previous), keys(current)
+	for key, b := range currentKeys {
+		if _, ok := previousKeys[key]; !ok {
+			added = append(added, b)
+		}
+	}
+	for key, b := range previousKeys {
+		if _, ok := currentKeys[key]; !ok {
+			removed = append(removed, b)
+		}
+	}
+	sortBundles(added)
+	sortBundles(removed)
+	return added, removed
+}
+
+// writeChangelog describes the operator versions that were added and removed since a previous run,
+// and how many images were copied.
+func writeChangelog(w io.Writer, previousDir string, added, removed []*catalogBundle, mirrored, skipped int) {
+	fmt.Fprintf(w, "Changes since %s:\n", previousDir)
+	writeSection := func(title string, bundles []*catalogBundle) {
+		fmt.Fprintf(w, "\n%s:\n", title)
+		if len(bundles) == 0 {
+			fmt.Fprintf(w, "  none\n")
+			return
+		}
+		for _, b := range bundles {
+			version := b.Version
+			if len(version) == 0 {
+				version = "unknown version"
+			}
+			fmt.Fprintf(w, "  %s %s (%s)\n", b.Package, version, b.Name)
+		}
+	}
+	writeSection("Added operator versions", added)
+	writeSection("Removed operator versions", removed)
+	fmt.Fprintf(w, "\nMirrored %d images, skipped %d images that were mirrored previously.\n", mirrored, skipped)
+}
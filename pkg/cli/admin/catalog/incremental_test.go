@@ -0,0 +1,255 @@
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/diff"
+
+	"github.com/openshift/oc/pkg/cli/image/imagesource"
+)
+
+func TestMappingDelta(t *testing.T) {
+	const (
+		digest = "sha256:d134a9865524c29fcf75bbc4469013bc38d8a15cb5f41acfddb6b9e492f556e4"
+		other  = "sha256:0e92dd9b5789c4b13d53e1319d0a6375bcca4caaf0d698af61198061222a576d"
+	)
+	mapping := map[imagesource.TypedImageReference]imagesource.TypedImageReference{
+		mustParseRef(t, "quay.io/my/index:v1"):         mustParseRef(t, "mirror.local/my/index:v1"),
+		mustParseRef(t, "quay.io/my/operator@"+digest): mustParseRef(t, "mirror.local/my/operator:2b13d275@"+digest),
+		mustParseRef(t, "quay.io/my/operand@"+other):   mustParseRef(t, "mirror.local/my/operand:4f5a6b7c@"+other),
+		mustParseRef(t, "quay.io/my/moved@"+other):     mustParseRef(t, "mirror.local/new/moved:4f5a6b7c@"+other),
+	}
+	previous := map[string]string{
+		"quay.io/my/index:v1":           "mirror.local/my/index:v1",
+		"quay.io/my/operator@" + digest: "mirror.local/my/operator:2b13d275",
+		"quay.io/my/moved@" + other:     "mirror.local/old/moved:4f5a6b7c",
+	}
+	want := map[imagesource.TypedImageReference]imagesource.TypedImageReference{
+		mustParseRef(t, "quay.io/my/index:v1"):       mustParseRef(t, "mirror.local/my/index:v1"),
+		mustParseRef(t, "quay.io/my/operand@"+other): mustParseRef(t, "mirror.local/my/operand:4f5a6b7c@"+other),
+		mustParseRef(t, "quay.io/my/moved@"+other):   mustParseRef(t, "mirror.local/new/moved:4f5a6b7c@"+other),
+	}
+	if got := mappingDelta(mapping, previous); !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected delta: %s", diff.ObjectReflectDiff(want, got))
+	}
+}
+
+func TestReadMapping(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mapping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "mapping.txt")
+	if err := ioutil.WriteFile(path, []byte("quay.io/my/index:v1=mirror.local/my/index:v1\n\nquay.io/my/operator@sha256:d134=mirror.local/my/operator:2b13d275\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readMapping(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"quay.io/my/index:v1":             "mirror.local/my/index:v1",
+		"quay.io/my/operator@sha256:d134": "mirror.local/my/operator:2b13d275",
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected mapping: %s", diff.ObjectReflectDiff(want, got))
+	}
+
+	if err := ioutil.WriteFile(path, []byte("quay.io/my/index:v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readMapping(path); err == nil {
+		t.Errorf("expected an error for a line without a destination")
+	}
+}
+
+func TestListBundles(t *testing.T) {
+	declcfg, err := listDeclcfgBundles("testdata/test-declcfg", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := listSqliteBundles("testdata/test.db", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, bundles := range map[string][]*catalogBundle{"declcfg": declcfg, "sqlite": sqlite} {
+		buf := &bytes.Buffer{}
+		if err := writeBundleList(buf, bundles); err != nil {
+			t.Fatal(err)
+		}
+		want := "etcd\tetcdoperator.v0.9.0\t0.9.0\n" +
+			"etcd\tetcdoperator.v0.9.2\t0.9.2\n" +
+			"prometheus\tprometheusoperator.0.14.0\t0.14.0\n" +
+			"prometheus\tprometheusoperator.0.15.0\t0.15.0\n" +
+			"prometheus\tprometheusoperator.0.22.2\t0.22.2\n"
+		if diff := cmp.Diff(want, buf.String()); len(diff) > 0 {
+			t.Errorf("%s: unexpected bundle list:\n%s", name, diff)
+		}
+	}
+}
+
+func TestChangelog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, bundleListFilename)
+	if err := ioutil.WriteFile(path, []byte("etcd\tetcdoperator.v0.9.0\t0.9.0\nprometheus\tprometheusoperator.0.14.0\t0.14.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	previous, err := readBundleList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := []*catalogBundle{
+		{Package: "etcd", Name: "etcdoperator.v0.9.10", Version: "0.9.10"},
+		{Package: "etcd", Name: "etcdoperator.v0.9.2", Version: "0.9.2"},
+		{Package: "etcd", Name: "etcdoperator.v0.9.0", Version: "0.9.0"},
+		{Package: "noversion", Name: "noversion.v1"},
+	}
+	added, removed := diffBundles(previous, current)
+
+	buf := &bytes.Buffer{}
+	writeChangelog(buf, "manifests-old", added, removed, 3, 7)
+	want := `Changes since manifests-old:
+
+Added operator versions:
+  etcd 0.9.2 (etcdoperator.v0.9.2)
+  etcd 0.9.10 (etcdoperator.v0.9.10)
+  noversion unknown version (noversion.v1)
+
+Removed operator versions:
+  prometheus 0.14.0 (prometheusoperator.0.14.0)
+
+Mirrored 3 images, skipped 7 images that were mirrored previously.
+`
+	if diff := cmp.Diff(want, buf.String()); len(diff) > 0 {
+		t.Errorf("unexpected changelog:\n%s", diff)
+	}
+}
+
+func TestMirrorPreviousMapping(t *testing.T) {
+	var mirrored map[imagesource.TypedImageReference]imagesource.TypedImageReference
+	b := &IndexImageMirrorer{
+		ImageMirrorer: ImageMirrorerFunc(func(mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) error {
+			mirrored = mapping
+			return nil
+		}),
+		IndexExtractor:      existingExtractor("testdata/test-declcfg"),
+		RelatedImagesParser: &declcfgRelatedImagesParser{},
+		Source:              mustParse(t, "quay.io/example/image:tag"),
+		Dest:                mustParse(t, "localhost:5000"),
+		MaxPathComponents:   2,
+	}
+	all, err := b.Mirror()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a previous run that mirrored every image except one operator image, images referenced by
+	// tag are mirrored again
+	b.PreviousMapping = map[string]string{}
+	for from, to := range all {
+		if from.Ref.Name == "etcd-operator" && from.Ref.ID == "sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2" {
+			continue
+		}
+		to.Ref.ID = ""
+		b.PreviousMapping[from.String()] = to.String()
+	}
+	got, err := b.Mirror()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(all, got) {
+		t.Errorf("expected the full mapping to be returned: %s", diff.ObjectReflectDiff(all, got))
+	}
+	var names []string
+	for from := range mirrored {
+		names = append(names, from.Ref.Name)
+	}
+	sort.Strings(names)
+	want := []string{"etcd-operator", "etcd.0.9.0", "etcd.0.9.2", "image", "prometheus.0.14.0", "prometheus.0.15.0", "prometheus.0.22.2"}
+	if !reflect.DeepEqual(want, names) {
+		t.Errorf("expected only %v to be mirrored, got %v", want, names)
+	}
+}
+
+func TestMirrorFailedImages(t *testing.T) {
+	failing := "sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2"
+	var mirrored []imagesource.TypedImageReference
+	b := &IndexImageMirrorer{
+		ImageMirrorer: ImageMirrorerFunc(func(mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) error {
+			mirrored = nil
+			for from := range mapping {
+				mirrored = append(mirrored, from)
+			}
+			failed := map[imagesource.TypedImageReference]error{}
+			for from := range mapping {
+				if from.Ref.ID == failing {
+					failed[from] = fmt.Errorf("unauthorized")
+				}
+			}
+			if len(failed) > 0 {
+				return &MirrorError{Failed: failed}
+			}
+			return nil
+		}),
+		IndexExtractor:      existingExtractor("testdata/test-declcfg"),
+		RelatedImagesParser: &declcfgRelatedImagesParser{},
+		Source:              mustParse(t, "quay.io/example/image:tag"),
+		Dest:                mustParse(t, "localhost:5000"),
+		MaxPathComponents:   2,
+	}
+	mapping, err := b.Mirror()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(mapping) != len(mirrored)-1 {
+		t.Errorf("expected only the failed image to be left out of the mapping, got %d of %d images", len(mapping), len(mirrored))
+	}
+	for from := range mapping {
+		if from.Ref.ID == failing {
+			t.Errorf("expected %s to be left out of the mapping", from)
+		}
+	}
+
+	// the next run copies the failed image again
+	buf := &bytes.Buffer{}
+	if err := writeToMapping(buf, mapping); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "mapping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mapping.txt")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b.PreviousMapping, err = readMapping(path); err != nil {
+		t.Fatal(err)
+	}
+	b.Mirror()
+	retried := false
+	for _, from := range mirrored {
+		if from.Ref.ID == failing {
+			retried = true
+		}
+	}
+	if !retried {
+		t.Errorf("expected the failed image to be mirrored again")
+	}
+}
@@ -3,22 +3,19 @@ package catalog
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/errors"
-	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/klog/v2"
 	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
@@ -28,11 +25,10 @@ import (
 	"github.com/alicebob/sqlittle"
 	"github.com/docker/distribution"
 	"github.com/docker/distribution/manifest/manifestlist"
-	"github.com/joelanford/ignore"
 	"github.com/opencontainers/go-digest"
 	"github.com/spf13/cobra"
 
-	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	imgappend "github.com/openshift/oc/pkg/cli/image/append"
 	imgextract "github.com/openshift/oc/pkg/cli/image/extract"
 	"github.com/openshift/oc/pkg/cli/image/imagesource"
 	"github.com/openshift/oc/pkg/cli/image/info"
@@ -61,11 +57,25 @@ var (
 
 		An image content source policy is written to a file that can be added to a cluster with access to the target
 		registry. This will configure the cluster to pull from the mirrors instead of the locations referenced in
-		the operator manifests.
+		the operator manifests. Newer clusters use ImageDigestMirrorSet instead, which is written when
+		--output-mirror-kind=ImageDigestMirrorSet is set along with an ImageTagMirrorSet for the images that are
+		referenced by tag, such as the index image. Existing policies can be converted with "oc adm migrate icsp".
 
 		A mapping.txt file is also created that is compatible with "oc image mirror". This may be used to further
 		customize the mirroring configuration, but should not be needed in normal circumstances.
 
+		The --packages, --channels, --min-version, --max-version and --heads-only flags limit the operator bundles
+		that are mirrored. For file-based catalogs the index image is pushed with a layer that replaces its
+		declarative configs with only the selected packages, channels and bundles, and the mapping refers to the
+		pruned index. Sqlite-based catalogs cannot be pruned, so the full index image is mirrored along with the
+		images of the selected bundles.
+
+		Pass the manifests directory of a previous run with --previous-manifests to only copy the images that
+		run did not mirror to the same location. Every run records the bundles of the catalog in bundles.txt, and
+		the differences from the previous run are written to changelog.txt. The other manifests describe the
+		whole catalog, except for the images that failed to be mirrored, which are left out so that the next run
+		copies them again.
+
 	` + prefixLines(sqliteDeprecationNotice, "\t\t\t"))
 	mirrorExample = templates.Examples(`
 		# Mirror an operator-registry image and its contents to a registry
@@ -78,9 +88,23 @@ var (
 		oc adm catalog mirror quay.io/my/image:latest file:///local/index
 		oc adm catalog mirror file:///local/index/my/image:latest my-airgapped-registry.com
 
+		# Mirror only the latest bundle of the stable channel of two packages
+		oc adm catalog mirror quay.io/my/image:latest myregistry.com --packages=etcd,prometheus --channels=stable --heads-only
+
+		# Mirror the bundles of a package within a range of versions
+		oc adm catalog mirror quay.io/my/image:latest myregistry.com --packages=etcd --min-version=0.9.0 --max-version=0.9.2
+
+		# Mirror only the images that were added to a catalog since a previous run and list the changes
+		oc adm catalog mirror quay.io/my/image:latest myregistry.com --previous-manifests=manifests-image-1612345678
+		cat manifests-image-*/changelog.txt
+
 		# Configure a cluster to use a mirrored registry
 		oc apply -f manifests/imageContentSourcePolicy.yaml
 
+		# Write ImageDigestMirrorSet and ImageTagMirrorSet manifests instead of an ImageContentSourcePolicy
+		oc adm catalog mirror quay.io/my/image:latest myregistry.com --output-mirror-kind=ImageDigestMirrorSet
+		oc apply -f manifests/imageDigestMirrorSet.yaml -f manifests/imageTagMirrorSet.yaml
+
 		# Edit the mirroring mappings and mirror with "oc image mirror" manually
 		oc adm catalog mirror --manifests-only quay.io/my/image:latest myregistry.com
 		oc image mirror -f manifests/mapping.txt
@@ -115,7 +139,13 @@ type MirrorCatalogOptions struct {
 	FileDir     string
 	MaxICSPSize int
 
-	IcspScope string
+	IcspScope        string
+	OutputMirrorKind string
+
+	PackageFilter PackageFilter
+
+	// PreviousManifests is the manifests directory or mapping.txt file of a previous run
+	PreviousManifests string
 
 	SecurityOptions imagemanifest.SecurityOptions
 	FilterOptions   imagemanifest.FilterOptions
@@ -123,6 +153,12 @@ type MirrorCatalogOptions struct {
 
 	SourceRef imagesource.TypedImageReference
 	DestRef   imagesource.TypedImageReference
+
+	// previousDir holds the manifests of the previous run
+	previousDir string
+	// catalogPath is set to the location the catalog was extracted to
+	catalogPath string
+	listBundles func(catalogPath string) ([]*catalogBundle, error)
 }
 
 func NewMirrorCatalogOptions(streams genericclioptions.IOStreams) *MirrorCatalogOptions {
@@ -131,6 +167,8 @@ func NewMirrorCatalogOptions(streams genericclioptions.IOStreams) *MirrorCatalog
 		IndexImageMirrorerOptions: DefaultImageIndexMirrorerOptions(),
 		ParallelOptions:           imagemanifest.ParallelOptions{MaxPerRegistry: 4},
 		IcspScope:                 "repository",
+		OutputMirrorKind:          MirrorKindICSP,
+		MaxICSPSize:               maxICSPSize,
 	}
 }
 
@@ -152,6 +190,7 @@ func NewMirrorCatalog(f kcmdutil.Factory, streams genericclioptions.IOStreams) *
 
 	o.SecurityOptions.Bind(flags)
 	o.ParallelOptions.Bind(flags)
+	o.PackageFilter.Bind(flags)
 
 	// Images referenced by catalogs must have all variants mirrored. FilterByOs will only apply to the initial index
 	// image, to indicate which arch should be used to extract the catalog index (the index inside should be the same
@@ -172,7 +211,9 @@ func NewMirrorCatalog(f kcmdutil.Factory, streams genericclioptions.IOStreams) *
 	flags.StringVar(&o.FromFileDir, "from-dir", o.FromFileDir, "The directory on disk that file:// images will be read from. Overrides --dir")
 	flags.IntVar(&o.MaxPathComponents, "max-components", 2, "The maximum number of path components allowed in a destination mapping. Example: `quay.io/org/repo` has two path components.")
 	flags.StringVar(&o.IcspScope, "icsp-scope", o.IcspScope, "Scope of registry mirrors in imagecontentsourcepolicy file. Allowed values: repository, registry. Defaults to: repository")
-	flags.IntVar(&o.MaxICSPSize, "max-icsp-size", maxICSPSize, "The maximum number of bytes for the generated ICSP yaml(s). Defaults to 250000")
+	flags.StringVar(&o.OutputMirrorKind, "output-mirror-kind", o.OutputMirrorKind, "The kind of mirror configuration written to the manifests directory. Allowed values: ImageContentSourcePolicy, ImageDigestMirrorSet. ImageDigestMirrorSet also writes an ImageTagMirrorSet for images referenced by tag.")
+	flags.StringVar(&o.PreviousManifests, "previous-manifests", o.PreviousManifests, "The manifests directory, or mapping.txt file, of a previous run. Images that run already mirrored to the same location are not copied again, and a changelog of the added and removed operator versions is written.")
+	flags.IntVar(&o.MaxICSPSize, "max-icsp-size", o.MaxICSPSize, "The maximum number of bytes for the generated ICSP yaml(s). Defaults to 250000")
 	return cmd
 }
 
@@ -191,6 +232,9 @@ func (o *MirrorCatalogOptions) Complete(cmd *cobra.Command, args []string) error
 	if err := o.FilterOptions.Validate(); err != nil {
 		return err
 	}
+	if err := o.PackageFilter.Complete(); err != nil {
+		return err
+	}
 
 	srcRef, err := imagesource.ParseReference(src)
 	if err != nil {
@@ -226,6 +270,21 @@ func (o *MirrorCatalogOptions) Complete(cmd *cobra.Command, args []string) error
 		return err
 	}
 
+	if len(o.PreviousManifests) > 0 {
+		mappingFile := o.PreviousManifests
+		info, err := os.Stat(mappingFile)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			mappingFile = filepath.Join(mappingFile, "mapping.txt")
+		}
+		if o.PreviousMapping, err = readMapping(mappingFile); err != nil {
+			return fmt.Errorf("unable to read the mapping of the previous run: %v", err)
+		}
+		o.previousDir = filepath.Dir(mappingFile)
+	}
+
 	// try to get the catalog file location label from src, from pkg/image/info
 	var image *info.Image
 	retriever := &info.ImageRetriever{
@@ -298,20 +357,14 @@ func (o *MirrorCatalogOptions) Complete(cmd *cobra.Command, args []string) error
 	}
 	fmt.Fprintf(o.IOStreams.Out, "using index path mapping: %s\n", o.IndexPath)
 
-	var mirrorer ImageMirrorerFunc
-	mirrorer = func(mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) error {
-		mappings := []imgmirror.Mapping{}
-		for from, to := range mapping {
-			mappings = append(mappings, imgmirror.Mapping{
-				Source:      from,
-				Destination: to,
-			})
-		}
+	mirrorImages := func(mappings []imgmirror.Mapping) error {
 		a := imgmirror.NewMirrorImageOptions(o.IOStreams)
 		a.SkipMissing = true
 		a.ContinueOnError = true
 		a.DryRun = o.DryRun
 		a.SecurityOptions = o.SecurityOptions
+		a.FileDir = o.FileDir
+		a.FromFileDir = o.FromFileDir
 		// because images in the catalog are statically referenced by digest,
 		// we do not allow filtering for mirroring. this may change if sparse manifestlists are allowed
 		// by registries, or if multi-arch management moves into images that can be rewritten on mirror (i.e. the bundle
@@ -324,8 +377,35 @@ func (o *MirrorCatalogOptions) Complete(cmd *cobra.Command, args []string) error
 		if err := a.Validate(); err != nil {
 			fmt.Fprintf(o.IOStreams.ErrOut, "error configuring image mirroring: %v\n", err)
 		}
-		if err := a.Run(); err != nil {
+		return a.Run()
+	}
+
+	var mirrorer ImageMirrorerFunc
+	mirrorer = func(mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) error {
+		mappings := []imgmirror.Mapping{}
+		for from, to := range mapping {
+			mappings = append(mappings, imgmirror.Mapping{
+				Source:      from,
+				Destination: to,
+			})
+		}
+		if err := mirrorImages(mappings); err != nil {
 			fmt.Fprintf(o.IOStreams.ErrOut, "error mirroring image: %v\n", err)
+			if len(mappings) == 1 {
+				return &MirrorError{Failed: map[imagesource.TypedImageReference]error{mappings[0].Source: err}}
+			}
+			// the errors of the image mirror do not say which images failed, so each image is
+			// mirrored again on its own, which only copies what is still missing
+			fmt.Fprintf(o.IOStreams.ErrOut, "info: mirroring the images again one at a time to find the ones that failed\n")
+			failed := map[imagesource.TypedImageReference]error{}
+			for _, m := range mappings {
+				if err := mirrorImages([]imgmirror.Mapping{m}); err != nil {
+					failed[m.Source] = err
+				}
+			}
+			if len(failed) > 0 {
+				return &MirrorError{Failed: failed}
+			}
 		}
 		return nil
 	}
@@ -338,11 +418,34 @@ func (o *MirrorCatalogOptions) Complete(cmd *cobra.Command, args []string) error
 	o.ImageMirrorer = mirrorer
 	if _, ok := image.Config.Config.Labels[ConfigsLocationLabelKey]; ok {
 		o.IndexExtractor = o.newDeclcfgExtractor(cmd)
-		o.RelatedImagesParser = &declcfgRelatedImagesParser{}
+		o.RelatedImagesParser = &declcfgRelatedImagesParser{filter: &o.PackageFilter}
+		o.listBundles = func(catalogPath string) ([]*catalogBundle, error) {
+			return listDeclcfgBundles(catalogPath, &o.PackageFilter)
+		}
+		if !o.PackageFilter.IsEmpty() {
+			if o.ManifestOnly {
+				fmt.Fprintf(o.IOStreams.ErrOut, "warning: the index image is not pruned when --manifests-only is set, the mapping refers to the full index image\n")
+			} else {
+				o.IndexPruner = o.newDeclcfgPruner(indexLocation)
+			}
+		}
 	} else {
 		o.IndexExtractor = o.newSqliteExtractor(cmd)
-		o.RelatedImagesParser = &sqliteRelatedImagesParser{}
+		o.RelatedImagesParser = &sqliteRelatedImagesParser{filter: &o.PackageFilter}
+		o.listBundles = func(catalogPath string) ([]*catalogBundle, error) {
+			return listSqliteBundles(catalogPath, &o.PackageFilter)
+		}
+		if !o.PackageFilter.IsEmpty() {
+			fmt.Fprintf(o.IOStreams.ErrOut, "warning: sqlite-based index images cannot be pruned, the full index image will be mirrored with only the images of the selected bundles\n")
+		}
 	}
+	// remember where the catalog is extracted to so that its bundles can be listed after mirroring
+	extractor := o.IndexExtractor
+	o.IndexExtractor = IndexExtractorFunc(func(from imagesource.TypedImageReference) (string, error) {
+		catalogPath, err := extractor.Extract(from)
+		o.catalogPath = catalogPath
+		return catalogPath, err
+	})
 
 	return nil
 }
@@ -430,44 +533,180 @@ func (o *MirrorCatalogOptions) newDeclcfgExtractor(cmd *cobra.Command) IndexExtr
 	})
 }
 
-type sqliteRelatedImagesParser struct{}
+// newDeclcfgPruner returns a pruner that pushes the source index image with an additional layer
+// that replaces the declarative configs in configsDir with the content selected by the package filter.
+func (o *MirrorCatalogOptions) newDeclcfgPruner(configsDir string) IndexPruner {
+	return IndexPrunerFunc(func(catalogPath string, from, to imagesource.TypedImageReference) (imagesource.TypedImageReference, error) {
+		index, err := loadDeclcfgIndex(catalogPath)
+		if err != nil {
+			return to, err
+		}
+		configs := &bytes.Buffer{}
+		if err := index.writePruned(configs, &o.PackageFilter); err != nil {
+			return to, err
+		}
+		layer := &bytes.Buffer{}
+		if err := writeDeclcfgLayer(layer, configsDir, configs.Bytes()); err != nil {
+			return to, err
+		}
 
-func (_ sqliteRelatedImagesParser) Parse(file string) (map[string]struct{}, error) {
+		to.Ref.ID = ""
+		a := imgappend.NewAppendImageOptions(o.IOStreams)
+		a.SecurityOptions = o.SecurityOptions
+		a.FilterOptions = o.FilterOptions
+		a.ParallelOptions = o.ParallelOptions
+		a.FileDir = o.FileDir
+		a.FromFileDir = o.FromFileDir
+		a.DryRun = o.DryRun
+		a.From = from.String()
+		a.To = to.String()
+		a.LayerStream = layer
+		if err := a.Validate(); err != nil {
+			return to, err
+		}
+		if err := a.Run(); err != nil {
+			return to, err
+		}
+		if len(a.ToDigest) > 0 {
+			to.Ref.ID = a.ToDigest.String()
+		}
+		fmt.Fprintf(o.IOStreams.Out, "pushed pruned index image to %s\n", to)
+		return to, nil
+	})
+}
+
+type sqliteRelatedImagesParser struct {
+	filter *PackageFilter
+}
+
+func (p sqliteRelatedImagesParser) Parse(file string) (map[string]struct{}, error) {
 	db, err := sqlittle.Open(file)
 	if err != nil {
 		return nil, err
 	}
 
+	var selected sets.String
+	if !p.filter.IsEmpty() {
+		if selected, err = sqliteSelectedBundles(db, p.filter); err != nil {
+			return nil, err
+		}
+	}
+
 	// get all images
 	var images = make(map[string]struct{}, 0)
 	var errs = make([]error, 0)
 	reader := func(r sqlittle.Row) {
-		var image string
-		if err := r.Scan(&image); err != nil {
+		var image, bundle string
+		if err := r.Scan(&image, &bundle); err != nil {
 			errs = append(errs, err)
 			return
 		}
+		if selected != nil && !selected.Has(bundle) {
+			return
+		}
 		if image != "" {
 			images[image] = struct{}{}
 		}
 	}
-	if err := db.Select("related_image", reader, "image"); err != nil {
+	if err := db.Select("related_image", reader, "image", "operatorbundle_name"); err != nil {
 		errs = append(errs, err)
 		return nil, errors.NewAggregate(errs)
 	}
 
 	// get all bundlepaths
-	if err := db.Select("operatorbundle", reader, "bundlepath"); err != nil {
+	if err := db.Select("operatorbundle", reader, "bundlepath", "name"); err != nil {
 		errs = append(errs, err)
 		return nil, errors.NewAggregate(errs)
 	}
 	return images, nil
 }
 
+// sqliteSelectedBundles returns the names of the bundles in the database that are selected by the filter.
+func sqliteSelectedBundles(db *sqlittle.DB, filter *PackageFilter) (sets.String, error) {
+	// older databases do not record bundle versions, only read them when they are needed
+	bundles, err := sqliteBundles(db, len(filter.MinVersion) > 0 || len(filter.MaxVersion) > 0)
+	if err != nil {
+		return nil, err
+	}
+	selected := sets.NewString()
+	for name, b := range bundles {
+		if filter.Include(b) {
+			selected.Insert(name)
+		}
+	}
+	return selected, nil
+}
+
+// sqliteBundles returns the bundles in the channels of the database keyed by name, including their
+// versions if withVersions is set.
+func sqliteBundles(db *sqlittle.DB, withVersions bool) (map[string]*catalogBundle, error) {
+	bundles := map[string]*catalogBundle{}
+	var errs []error
+	if err := db.Select("channel_entry", func(r sqlittle.Row) {
+		var channel, pkg, name string
+		if err := r.Scan(&channel, &pkg, &name); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		b, ok := bundles[name]
+		if !ok {
+			b = &catalogBundle{Name: name, Package: pkg, Channels: sets.NewString(), Heads: sets.NewString()}
+			bundles[name] = b
+		}
+		b.Channels.Insert(channel)
+	}, "channel_name", "package_name", "operatorbundle_name"); err != nil {
+		return nil, err
+	}
+	if err := db.Select("channel", func(r sqlittle.Row) {
+		var channel, head string
+		if err := r.Scan(&channel, &head); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		if b, ok := bundles[head]; ok {
+			b.Heads.Insert(channel)
+		}
+	}, "name", "head_operatorbundle_name"); err != nil {
+		return nil, err
+	}
+	// channel entries may refer to skipped bundles that are not in the database
+	columns := []string{"name"}
+	if withVersions {
+		columns = append(columns, "version")
+	}
+	existing := map[string]*catalogBundle{}
+	if err := db.Select("operatorbundle", func(r sqlittle.Row) {
+		var name, version string
+		targets := []interface{}{&name}
+		if withVersions {
+			targets = append(targets, &version)
+		}
+		if err := r.Scan(targets...); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		if b, ok := bundles[name]; ok {
+			b.Version = version
+			existing[name] = b
+		}
+	}, columns...); err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return nil, errors.NewAggregate(errs)
+	}
+	return existing, nil
+}
+
 type declcfgMeta struct {
-	Schema        string                `json:"schema"`
-	Image         string                `json:"image"`
-	RelatedImages []declcfgRelatedImage `json:"relatedImages,omitempty"`
+	Schema         string                `json:"schema"`
+	Name           string                `json:"name,omitempty"`
+	Package        string                `json:"package,omitempty"`
+	DefaultChannel string                `json:"defaultChannel,omitempty"`
+	Image          string                `json:"image"`
+	RelatedImages  []declcfgRelatedImage `json:"relatedImages,omitempty"`
+	Properties     []declcfgProperty     `json:"properties,omitempty"`
+	Entries        []declcfgChannelEntry `json:"entries,omitempty"`
 }
 
 type declcfgRelatedImage struct {
@@ -475,50 +714,40 @@ type declcfgRelatedImage struct {
 	Image string `json:"image"`
 }
 
-type declcfgRelatedImagesParser struct{}
+type declcfgProperty struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type declcfgChannelEntry struct {
+	Name     string   `json:"name"`
+	Replaces string   `json:"replaces,omitempty"`
+	Skips    []string `json:"skips,omitempty"`
+}
+
+type declcfgRelatedImagesParser struct {
+	filter *PackageFilter
+}
 
 const (
 	indexIgnoreFilename = ".indexignore"
 )
 
-func (_ declcfgRelatedImagesParser) Parse(root string) (map[string]struct{}, error) {
-	rootFS := os.DirFS(root)
-
-	matcher, err := ignore.NewMatcher(rootFS, indexIgnoreFilename)
+func (p declcfgRelatedImagesParser) Parse(root string) (map[string]struct{}, error) {
+	index, err := loadDeclcfgIndex(root)
 	if err != nil {
 		return nil, err
 	}
 
 	relatedImages := map[string]struct{}{}
-	if err := fs.WalkDir(rootFS, ".", func(path string, entry fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if entry.IsDir() || entry.Name() == indexIgnoreFilename || matcher.Match(path, false) {
-			return nil
-		}
-		f, err := rootFS.Open(path)
-		if err != nil {
-			return err
+	for _, blob := range index.blobs {
+		if blob.Schema == declcfgSchemaBundle && !p.filter.Include(index.bundles[bundleKey(blob.Package, blob.Name)]) {
+			continue
 		}
-		defer f.Close()
-		dec := utilyaml.NewYAMLOrJSONDecoder(f, 4096)
-		for {
-			var blob declcfgMeta
-			if err := dec.Decode(&blob); err != nil {
-				if err == io.EOF {
-					break
-				}
-				return err
-			}
-			relatedImages[blob.Image] = struct{}{}
-			for _, ri := range blob.RelatedImages {
-				relatedImages[ri.Image] = struct{}{}
-			}
+		relatedImages[blob.Image] = struct{}{}
+		for _, ri := range blob.RelatedImages {
+			relatedImages[ri.Image] = struct{}{}
 		}
-		return nil
-	}); err != nil {
-		return nil, err
 	}
 	delete(relatedImages, "")
 	return relatedImages, nil
@@ -536,6 +765,9 @@ func (o *MirrorCatalogOptions) Validate() error {
 	default:
 		return fmt.Errorf("invalid icsp-scope %s", o.IcspScope)
 	}
+	if err := ValidateOutputMirrorKind(o.OutputMirrorKind); err != nil {
+		return err
+	}
 	if o.MaxICSPSize <= minICSPSize || o.MaxICSPSize > maxICSPSize {
 		return fmt.Errorf("provided max-icsp-size of %d must be greater than %d and less than or equal to %d", o.MaxICSPSize, minICSPSize, maxICSPSize)
 	}
@@ -562,46 +794,85 @@ func (o *MirrorCatalogOptions) Run() error {
 		fmt.Fprintf(o.IOStreams.ErrOut, "errors during mirroring. the full contents of the catalog may not have been mirrored: %s\n", err.Error())
 	}
 
-	return WriteManifests(o.IOStreams.Out, o.SourceRef, o.DestRef, o.ManifestDir, o.IcspScope, o.MaxICSPSize, mapping)
+	if err := WriteManifests(o.IOStreams.Out, o.SourceRef, o.DestRef, o.ManifestDir, o.IcspScope, o.OutputMirrorKind, o.MaxICSPSize, mapping); err != nil {
+		return err
+	}
+	return o.writeBundleChanges(mapping)
+}
+
+// writeBundleChanges records the bundles of the mirrored catalog in the manifests directory and,
+// if a previous run was given, writes a changelog of the bundles that were added and removed since.
+func (o *MirrorCatalogOptions) writeBundleChanges(mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) error {
+	if len(o.catalogPath) == 0 || o.listBundles == nil {
+		return nil
+	}
+	bundles, err := o.listBundles(o.catalogPath)
+	if err != nil {
+		return fmt.Errorf("unable to list the bundles of the catalog: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := writeBundleList(buf, bundles); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(o.ManifestDir, bundleListFilename), buf.Bytes(), os.ModePerm); err != nil {
+		return fmt.Errorf("error writing bundle list")
+	}
+
+	if o.PreviousMapping == nil {
+		return nil
+	}
+	previous, err := readBundleList(filepath.Join(o.previousDir, bundleListFilename))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		fmt.Fprintf(o.IOStreams.ErrOut, "warning: %s does not list the bundles of the previous run, all bundles are reported as added\n", o.previousDir)
+	}
+	added, removed := diffBundles(previous, bundles)
+	mirrored := len(mappingDelta(mapping, o.PreviousMapping))
+
+	changelog := &bytes.Buffer{}
+	writeChangelog(changelog, o.previousDir, added, removed, mirrored, len(mapping)-mirrored)
+	if err := ioutil.WriteFile(filepath.Join(o.ManifestDir, changelogFilename), changelog.Bytes(), os.ModePerm); err != nil {
+		return fmt.Errorf("error writing changelog")
+	}
+	fmt.Fprintf(o.IOStreams.Out, "\n%s", changelog.String())
+	return nil
 }
 
-func getRegistryMapping(out io.Writer, icspScope string, mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) map[string]string {
+// getRegistryMapping returns the source and mirror repositories, or registries when icspScope is
+// "registry", of the images that are mirrored by digest.
+func getRegistryMapping(out io.Writer, kind, icspScope string, mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) map[string]string {
 	registryMapping := map[string]string{}
 	for k, v := range mapping {
 		if len(v.Ref.ID) == 0 {
-			fmt.Fprintf(out, "no digest mapping available for %s, skip writing to ImageContentSourcePolicy\n", k)
+			fmt.Fprintf(out, "no digest mapping available for %s, skip writing to %s\n", k, kind)
 			continue
 		}
-		if icspScope == "registry" {
-			registryMapping[k.Ref.Registry] = v.Ref.Registry
-		} else {
-			registryMapping[k.Ref.AsRepository().String()] = v.Ref.AsRepository().String()
-		}
+		addRegistryMapping(registryMapping, icspScope, k, v)
 	}
 	return registryMapping
 }
 
-func generateICSPs(out io.Writer, source string, icspScope string, maxICSPSize int, mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) ([][]byte, error) {
-	registryMapping := getRegistryMapping(out, icspScope, mapping)
-	icsps := [][]byte{}
-
-	for i := 0; len(registryMapping) != 0; i++ {
-		icsp, err := generateICSP(out, source+"-"+strconv.Itoa(i), maxICSPSize, registryMapping)
-		if err != nil {
-			return nil, err
+// getTagRegistryMapping returns the source and mirror repositories, or registries when icspScope is
+// "registry", of the images that are referenced by tag.
+func getTagRegistryMapping(icspScope string, mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) map[string]string {
+	registryMapping := map[string]string{}
+	for k, v := range mapping {
+		if len(k.Ref.ID) > 0 || len(k.Ref.Tag) == 0 {
+			continue
 		}
-		icsps = append(icsps, icsp)
+		addRegistryMapping(registryMapping, icspScope, k, v)
 	}
-	return icsps, nil
+	return registryMapping
 }
 
-func aggregateICSPs(icsps [][]byte) []byte {
-	aggregation := []byte{}
-	for _, icsp := range icsps {
-		aggregation = append(aggregation, []byte("---\n")...)
-		aggregation = append(aggregation, icsp...)
+func addRegistryMapping(registryMapping map[string]string, icspScope string, from, to imagesource.TypedImageReference) {
+	if icspScope == "registry" {
+		registryMapping[from.Ref.Registry] = to.Ref.Registry
+	} else {
+		registryMapping[from.Ref.AsRepository().String()] = to.Ref.AsRepository().String()
 	}
-	return aggregation
 }
 
 func (o *MirrorCatalogOptions) postRun() error {
@@ -625,7 +896,7 @@ func (o *MirrorCatalogOptions) postRun() error {
 	return nil
 }
 
-func WriteManifests(out io.Writer, source, dest imagesource.TypedImageReference, dir, icspScope string, maxICSPSize int, mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) error {
+func WriteManifests(out io.Writer, source, dest imagesource.TypedImageReference, dir, icspScope, mirrorKind string, maxICSPSize int, mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) error {
 	f, err := os.Create(filepath.Join(dir, "mapping.txt"))
 	if err != nil {
 		return err
@@ -641,13 +912,28 @@ func WriteManifests(out io.Writer, source, dest imagesource.TypedImageReference,
 	}
 
 	if dest.Type != imagesource.DestinationFile {
-		icsps, err := generateICSPs(out, source.Ref.Name, icspScope, maxICSPSize, mapping)
+		if len(mirrorKind) == 0 {
+			mirrorKind = MirrorKindICSP
+		}
+		sets, err := generateMirrorSets(mirrorKind, source.Ref.Name, maxICSPSize, getRegistryMapping(out, mirrorKind, icspScope, mapping))
 		if err != nil {
 			return err
 		}
+		if err := ioutil.WriteFile(filepath.Join(dir, MirrorSetFilename(mirrorKind)), aggregateMirrorSets(sets), os.ModePerm); err != nil {
+			return fmt.Errorf("error writing %s", mirrorKind)
+		}
 
-		if err := ioutil.WriteFile(filepath.Join(dir, "imageContentSourcePolicy.yaml"), aggregateICSPs(icsps), os.ModePerm); err != nil {
-			return fmt.Errorf("error writing ImageContentSourcePolicy")
+		// images referenced by tag can only be mirrored by an ImageTagMirrorSet
+		if mirrorKind == MirrorKindIDMS {
+			if tagMapping := getTagRegistryMapping(icspScope, mapping); len(tagMapping) > 0 {
+				sets, err := generateMirrorSets(MirrorKindITMS, source.Ref.Name, maxICSPSize, tagMapping)
+				if err != nil {
+					return err
+				}
+				if err := ioutil.WriteFile(filepath.Join(dir, MirrorSetFilename(MirrorKindITMS)), aggregateMirrorSets(sets), os.ModePerm); err != nil {
+					return fmt.Errorf("error writing %s", MirrorKindITMS)
+				}
+			}
 		}
 
 		catalogSource, err := generateCatalogSource(source, mapping)
@@ -712,60 +998,6 @@ func generateCatalogSource(source imagesource.TypedImageReference, mapping map[i
 	return csExample, nil
 }
 
-func generateICSP(out io.Writer, name string, byteLimit int, registryMapping map[string]string) ([]byte, error) {
-	icsp := operatorv1alpha1.ImageContentSourcePolicy{
-		TypeMeta: metav1.TypeMeta{
-			APIVersion: operatorv1alpha1.GroupVersion.String(),
-			Kind:       "ImageContentSourcePolicy"},
-		ObjectMeta: metav1.ObjectMeta{
-			Name: strings.Join(strings.Split(name, "/"), "-"),
-			Labels: map[string]string{
-				"operators.openshift.org/catalog": "true",
-			},
-		},
-		Spec: operatorv1alpha1.ImageContentSourcePolicySpec{
-			RepositoryDigestMirrors: []operatorv1alpha1.RepositoryDigestMirrors{},
-		},
-	}
-
-	for key := range registryMapping {
-		repositoryDigestMirror := operatorv1alpha1.RepositoryDigestMirrors{
-			Source:  key,
-			Mirrors: []string{registryMapping[key]},
-		}
-		icsp.Spec.RepositoryDigestMirrors = append(icsp.Spec.RepositoryDigestMirrors, repositoryDigestMirror)
-		y, err := yaml.Marshal(icsp)
-		if err != nil {
-			return nil, fmt.Errorf("unable to marshal ImageContentSourcePolicy yaml: %v", err)
-		}
-
-		if len(y) > byteLimit {
-			if lenMirrors := len(icsp.Spec.RepositoryDigestMirrors); lenMirrors > 1 {
-				icsp.Spec.RepositoryDigestMirrors = icsp.Spec.RepositoryDigestMirrors[:lenMirrors-1]
-				break
-			}
-			return nil, fmt.Errorf("unable to add mirror %v to ICSP with the max-icsp-size set to %d", repositoryDigestMirror, byteLimit)
-		}
-		delete(registryMapping, key)
-	}
-
-	// Create an unstructured object for removing creationTimestamp
-	unstructuredObj := unstructured.Unstructured{}
-	var err error
-	unstructuredObj.Object, err = runtime.DefaultUnstructuredConverter.ToUnstructured(&icsp)
-	if err != nil {
-		return nil, fmt.Errorf("error converting to unstructured: %v", err)
-	}
-	delete(unstructuredObj.Object["metadata"].(map[string]interface{}), "creationTimestamp")
-
-	icspExample, err := yaml.Marshal(unstructuredObj.Object)
-	if err != nil {
-		return nil, fmt.Errorf("unable to marshal ImageContentSourcePolicy yaml: %v", err)
-	}
-
-	return icspExample, nil
-}
-
 func prefixLines(in string, prefix string) string {
 	lines := strings.Split(in, "\n")
 	for i := range lines {
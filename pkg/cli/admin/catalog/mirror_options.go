@@ -11,10 +11,15 @@ type IndexImageMirrorerOptions struct {
 	ImageMirrorer       ImageMirrorer
 	IndexExtractor      IndexExtractor
 	RelatedImagesParser RelatedImagesParser
+	IndexPruner         IndexPruner
 
 	Source, Dest      imagesource.TypedImageReference
 	ManifestDir       string
 	MaxPathComponents int
+
+	// PreviousMapping, if set, is the mapping.txt content of a previous run keyed by source. Images
+	// that the previous run mirrored to the same destination are not mirrored again.
+	PreviousMapping map[string]string
 }
 
 func (o *IndexImageMirrorerOptions) Validate() error {
@@ -71,6 +76,9 @@ func (c *IndexImageMirrorerOptions) ToOption() ImageIndexMirrorOption {
 		if c.RelatedImagesParser != nil {
 			o.RelatedImagesParser = c.RelatedImagesParser
 		}
+		if c.IndexPruner != nil {
+			o.IndexPruner = c.IndexPruner
+		}
 		if len(c.Source.String()) != 0 {
 			o.Source = c.Source
 		}
@@ -83,6 +91,9 @@ func (c *IndexImageMirrorerOptions) ToOption() ImageIndexMirrorOption {
 		if c.MaxPathComponents > 0 {
 			o.MaxPathComponents = c.MaxPathComponents
 		}
+		if c.PreviousMapping != nil {
+			o.PreviousMapping = c.PreviousMapping
+		}
 	}
 }
 
@@ -112,6 +123,18 @@ func WithRelatedImagesParser(e RelatedImagesParser) ImageIndexMirrorOption {
 	}
 }
 
+func WithPreviousMapping(m map[string]string) ImageIndexMirrorOption {
+	return func(o *IndexImageMirrorerOptions) {
+		o.PreviousMapping = m
+	}
+}
+
+func WithIndexPruner(p IndexPruner) ImageIndexMirrorOption {
+	return func(o *IndexImageMirrorerOptions) {
+		o.IndexPruner = p
+	}
+}
+
 func WithSource(s imagesource.TypedImageReference) ImageIndexMirrorOption {
 	return func(o *IndexImageMirrorerOptions) {
 		o.Source = s
@@ -246,7 +246,7 @@ func TestGetRegistryMapping(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getRegistryMapping(os.Stdout, tt.args.scope, tt.args.mapping)
+			got := getRegistryMapping(os.Stdout, MirrorKindICSP, tt.args.scope, tt.args.mapping)
 			if len(got) != len(tt.want) {
 				t.Errorf("Received map length != expected map length")
 			}
@@ -414,7 +414,7 @@ spec:
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := generateICSP(os.Stdout, tt.args.name, tt.args.icspByteLimit, tt.args.mapping)
+			got, err := generateMirrorSet(MirrorKindICSP, tt.args.name, tt.args.icspByteLimit, tt.args.mapping)
 			if tt.wantErr != nil {
 				if err == nil || err.Error() != tt.wantErr.Error() {
 					t.Errorf("generateICSP() error = %v, wantErr %v", err, tt.wantErr)
@@ -543,7 +543,7 @@ func TestGenerateICSPs(t *testing.T) {
 				byteCount += len(key) + len(value)
 			}
 
-			got, err := generateICSPs(os.Stdout, tt.args.name, tt.args.scope, tt.args.limit, mapping)
+			got, err := generateMirrorSets(MirrorKindICSP, tt.args.name, tt.args.limit, getRegistryMapping(os.Stdout, MirrorKindICSP, tt.args.scope, mapping))
 			if err != nil {
 				t.Error(err)
 				return
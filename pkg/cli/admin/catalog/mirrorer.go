@@ -3,6 +3,7 @@ package catalog
 import (
 	"fmt"
 	"hash/fnv"
+	"sort"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/util/errors"
@@ -35,11 +36,38 @@ func (f RelatedImagesParserFunc) Parse(path string) (map[string]struct{}, error)
 	return f(path)
 }
 
+// IndexPruner knows how to build and push an index image that only contains the selected content
+// of the extracted index, returning the location the index was pushed to
+type IndexPruner interface {
+	Prune(catalogPath string, from, to imagesource.TypedImageReference) (imagesource.TypedImageReference, error)
+}
+
+type IndexPrunerFunc func(catalogPath string, from, to imagesource.TypedImageReference) (imagesource.TypedImageReference, error)
+
+func (f IndexPrunerFunc) Prune(catalogPath string, from, to imagesource.TypedImageReference) (imagesource.TypedImageReference, error) {
+	return f(catalogPath, from, to)
+}
+
 // ImageMirrorer knows how to mirror an image from one registry to another
 type ImageMirrorer interface {
 	Mirror(mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) error
 }
 
+// MirrorError is returned by an ImageMirrorer that failed to mirror some of the images of a
+// mapping, keyed by their source.
+type MirrorError struct {
+	Failed map[imagesource.TypedImageReference]error
+}
+
+func (e *MirrorError) Error() string {
+	var failed []string
+	for from, err := range e.Failed {
+		failed = append(failed, fmt.Sprintf("%s: %v", from, err))
+	}
+	sort.Strings(failed)
+	return fmt.Sprintf("unable to mirror %d images: %s", len(failed), strings.Join(failed, "; "))
+}
+
 type ImageMirrorerFunc func(mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) error
 
 func (f ImageMirrorerFunc) Mirror(mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference) error {
@@ -50,10 +78,12 @@ type IndexImageMirrorer struct {
 	ImageMirrorer       ImageMirrorer
 	IndexExtractor      IndexExtractor
 	RelatedImagesParser RelatedImagesParser
+	IndexPruner         IndexPruner
 
 	// options
 	Source, Dest      imagesource.TypedImageReference
 	MaxPathComponents int
+	PreviousMapping   map[string]string
 }
 
 var _ Mirrorer = &IndexImageMirrorer{}
@@ -71,9 +101,11 @@ func NewIndexImageMirror(options ...ImageIndexMirrorOption) (*IndexImageMirrorer
 		ImageMirrorer:       config.ImageMirrorer,
 		IndexExtractor:      config.IndexExtractor,
 		RelatedImagesParser: config.RelatedImagesParser,
+		IndexPruner:         config.IndexPruner,
 		Source:              config.Source,
 		Dest:                config.Dest,
 		MaxPathComponents:   config.MaxPathComponents,
+		PreviousMapping:     config.PreviousMapping,
 	}, nil
 }
 
@@ -98,15 +130,56 @@ func (b *IndexImageMirrorer) Mirror() (map[imagesource.TypedImageReference]image
 	if err != nil {
 		errs = append(errs, fmt.Errorf("unable to map index image to new location in dest"))
 	}
-	mapping[b.Source] = mappedIndex
+	// a pruned index is pushed instead of mirroring the source index
+	if b.IndexPruner == nil {
+		mapping[b.Source] = mappedIndex
+	}
 
-	if err := b.ImageMirrorer.Mirror(mapping); err != nil {
+	toMirror := mapping
+	if b.PreviousMapping != nil {
+		toMirror = mappingDelta(mapping, b.PreviousMapping)
+	}
+	if err := b.ImageMirrorer.Mirror(toMirror); err != nil {
 		errs = append(errs, fmt.Errorf("mirroring failed: %s", err.Error()))
+		// the images that failed are left out of the mapping, so that a run with this mapping as
+		// its previous mapping copies them again
+		if mirrorErr, ok := err.(*MirrorError); ok {
+			for from := range mirrorErr.Failed {
+				delete(mapping, from)
+			}
+		}
+	}
+
+	if b.IndexPruner != nil {
+		prunedIndex, err := b.IndexPruner.Prune(catalogPath, b.Source, mappedIndex)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to prune index image: %v", err))
+			prunedIndex = mappedIndex
+		}
+		mapping[b.Source] = prunedIndex
 	}
 
 	return mapping, errors.NewAggregate(errs)
 }
 
+// mappingDelta returns the entries of mapping that were not mirrored to the same destination by a
+// previous run. Images referenced by tag are always included because the tag may have moved.
+func mappingDelta(mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference, previous map[string]string) map[imagesource.TypedImageReference]imagesource.TypedImageReference {
+	delta := map[imagesource.TypedImageReference]imagesource.TypedImageReference{}
+	for from, to := range mapping {
+		if len(from.Ref.ID) > 0 {
+			// mapping.txt records destinations without their digest
+			dest := to
+			dest.Ref.ID = ""
+			if previous[from.String()] == dest.String() {
+				continue
+			}
+		}
+		delta[from] = to
+	}
+	return delta
+}
+
 func mappingForImages(images map[string]struct{}, src, dest imagesource.TypedImageReference, maxComponents int) (mapping map[imagesource.TypedImageReference]imagesource.TypedImageReference, errs []error) {
 	if dest.Type != imagesource.DestinationRegistry {
 		// don't do any name mangling when not mirroring to a real registry
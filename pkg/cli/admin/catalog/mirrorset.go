@@ -0,0 +1,188 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+const (
+	// MirrorKindICSP is the kind of the mirror configuration understood by all clusters.
+	MirrorKindICSP = "ImageContentSourcePolicy"
+	// MirrorKindIDMS replaces ImageContentSourcePolicy for images pulled by digest on newer clusters.
+	MirrorKindIDMS = "ImageDigestMirrorSet"
+	// MirrorKindITMS configures mirrors for images pulled by tag on newer clusters.
+	MirrorKindITMS = "ImageTagMirrorSet"
+
+	configAPIVersion = "config.openshift.io/v1"
+)
+
+// mirrorSetTypes describes the API version and spec field of each mirror configuration kind.
+var mirrorSetTypes = map[string]struct {
+	apiVersion string
+	field      string
+	short      string
+}{
+	MirrorKindICSP: {apiVersion: operatorv1alpha1.GroupVersion.String(), field: "repositoryDigestMirrors", short: "ICSP"},
+	MirrorKindIDMS: {apiVersion: configAPIVersion, field: "imageDigestMirrors", short: "IDMS"},
+	MirrorKindITMS: {apiVersion: configAPIVersion, field: "imageTagMirrors", short: "ITMS"},
+}
+
+// ImageMirrors is a source repository and the mirrors that serve its content. It is serialized the
+// same way for every mirror configuration kind.
+type ImageMirrors struct {
+	Source  string   `json:"source"`
+	Mirrors []string `json:"mirrors,omitempty"`
+}
+
+// ValidateOutputMirrorKind returns an error if kind may not be requested as the mirror configuration
+// written by a command. An ImageTagMirrorSet is only ever written alongside an ImageDigestMirrorSet.
+func ValidateOutputMirrorKind(kind string) error {
+	switch kind {
+	case MirrorKindICSP, MirrorKindIDMS:
+		return nil
+	default:
+		return fmt.Errorf("invalid output mirror kind %q, must be one of %s or %s", kind, MirrorKindICSP, MirrorKindIDMS)
+	}
+}
+
+// MarshalMirrorSet returns the YAML for a mirror configuration of the given kind.
+func MarshalMirrorSet(kind, name string, labels map[string]string, mirrors []ImageMirrors) ([]byte, error) {
+	t, ok := mirrorSetTypes[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown mirror kind %q", kind)
+	}
+	items := make([]interface{}, 0, len(mirrors))
+	for _, m := range mirrors {
+		item := map[string]interface{}{"source": m.Source}
+		if len(m.Mirrors) > 0 {
+			sources := make([]interface{}, 0, len(m.Mirrors))
+			for _, mirror := range m.Mirrors {
+				sources = append(sources, mirror)
+			}
+			item["mirrors"] = sources
+		}
+		items = append(items, item)
+	}
+	metadata := map[string]interface{}{
+		"name": name,
+	}
+	if len(labels) > 0 {
+		l := map[string]interface{}{}
+		for k, v := range labels {
+			l[k] = v
+		}
+		metadata["labels"] = l
+	}
+	obj := unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": t.apiVersion,
+			"kind":       kind,
+			"metadata":   metadata,
+			"spec": map[string]interface{}{
+				t.field: items,
+			},
+		},
+	}
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal %s yaml: %v", kind, err)
+	}
+	return data, nil
+}
+
+// UnmarshalMirrorSet returns the name, labels and mirrors of a mirror configuration of any kind.
+func UnmarshalMirrorSet(data []byte) (kind, name string, labels map[string]string, mirrors []ImageMirrors, err error) {
+	var obj struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec map[string][]ImageMirrors `json:"spec"`
+	}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return "", "", nil, nil, err
+	}
+	t, ok := mirrorSetTypes[obj.Kind]
+	if !ok {
+		return "", "", nil, nil, fmt.Errorf("unknown mirror kind %q", obj.Kind)
+	}
+	return obj.Kind, obj.Metadata.Name, obj.Metadata.Labels, obj.Spec[t.field], nil
+}
+
+// generateMirrorSets splits the registry mapping into as many mirror configurations of the given kind
+// as are needed to keep each one under maxSize bytes.
+func generateMirrorSets(kind, source string, maxSize int, registryMapping map[string]string) ([][]byte, error) {
+	sets := [][]byte{}
+	for i := 0; len(registryMapping) != 0; i++ {
+		set, err := generateMirrorSet(kind, source+"-"+strconv.Itoa(i), maxSize, registryMapping)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// generateMirrorSet returns a mirror configuration of the given kind containing as many entries of
+// the registry mapping as fit in byteLimit bytes, removing those entries from the mapping.
+func generateMirrorSet(kind, name string, byteLimit int, registryMapping map[string]string) ([]byte, error) {
+	name = strings.Join(strings.Split(name, "/"), "-")
+	labels := map[string]string{
+		"operators.openshift.org/catalog": "true",
+	}
+
+	keys := make([]string, 0, len(registryMapping))
+	for key := range registryMapping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mirrors := []ImageMirrors{}
+	data, err := MarshalMirrorSet(kind, name, labels, mirrors)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		mirror := ImageMirrors{
+			Source:  key,
+			Mirrors: []string{registryMapping[key]},
+		}
+		y, err := MarshalMirrorSet(kind, name, labels, append(mirrors, mirror))
+		if err != nil {
+			return nil, err
+		}
+		if len(y) > byteLimit {
+			if len(mirrors) > 0 {
+				break
+			}
+			return nil, fmt.Errorf("unable to add mirror %v to %s with the max-icsp-size set to %d", mirror, mirrorSetTypes[kind].short, byteLimit)
+		}
+		mirrors = append(mirrors, mirror)
+		data = y
+		delete(registryMapping, key)
+	}
+	return data, nil
+}
+
+// MirrorSetFilename returns the name of the file that mirror configurations of kind are written to.
+func MirrorSetFilename(kind string) string {
+	return strings.ToLower(kind[:1]) + kind[1:] + ".yaml"
+}
+
+// aggregateMirrorSets joins mirror configurations into a single multi-document YAML file.
+func aggregateMirrorSets(sets [][]byte) []byte {
+	aggregation := []byte{}
+	for _, set := range sets {
+		aggregation = append(aggregation, []byte("---\n")...)
+		aggregation = append(aggregation, set...)
+	}
+	return aggregation
+}
@@ -0,0 +1,106 @@
+package catalog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/oc/pkg/cli/image/imagesource"
+)
+
+func TestGenerateMirrorSetKinds(t *testing.T) {
+	mapping := map[string]string{"quay.io/my/index": "mirror.local/my/index"}
+	tests := []struct {
+		kind string
+		want string
+	}{
+		{
+			kind: MirrorKindIDMS,
+			want: `apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  labels:
+    operators.openshift.org/catalog: "true"
+  name: my-index-0
+spec:
+  imageDigestMirrors:
+  - mirrors:
+    - mirror.local/my/index
+    source: quay.io/my/index
+`,
+		},
+		{
+			kind: MirrorKindITMS,
+			want: `apiVersion: config.openshift.io/v1
+kind: ImageTagMirrorSet
+metadata:
+  labels:
+    operators.openshift.org/catalog: "true"
+  name: my-index-0
+spec:
+  imageTagMirrors:
+  - mirrors:
+    - mirror.local/my/index
+    source: quay.io/my/index
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			registryMapping := map[string]string{}
+			for k, v := range mapping {
+				registryMapping[k] = v
+			}
+			got, err := generateMirrorSets(tt.kind, "my/index", maxICSPSize, registryMapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("expected one object, got %d", len(got))
+			}
+			if diff := cmp.Diff(tt.want, string(got[0])); len(diff) > 0 {
+				t.Errorf("unexpected output:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteManifestsMirrorKind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := mustParseRef(t, "quay.io/my/index:v1")
+	mapping := map[imagesource.TypedImageReference]imagesource.TypedImageReference{
+		source: mustParseRef(t, "mirror.local/my/index:v1"),
+		mustParseRef(t, "quay.io/my/operator@sha256:d134a9865524c29fcf75bbc4469013bc38d8a15cb5f41acfddb6b9e492f556e4"): mustParseRef(t, "mirror.local/my/operator:2b13d275@sha256:d134a9865524c29fcf75bbc4469013bc38d8a15cb5f41acfddb6b9e492f556e4"),
+	}
+	if err := WriteManifests(ioutil.Discard, source, mustParseRef(t, "mirror.local"), dir, "repository", MirrorKindIDMS, maxICSPSize, mapping); err != nil {
+		t.Fatal(err)
+	}
+
+	for filename, want := range map[string]string{
+		"imageDigestMirrorSet.yaml": "quay.io/my/operator",
+		"imageTagMirrorSet.yaml":    "quay.io/my/index",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, mirrors, err := UnmarshalMirrorSet(data[len("---\n"):])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mirrors) != 1 || mirrors[0].Source != want {
+			t.Errorf("%s: unexpected mirrors %v", filename, mirrors)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "imageContentSourcePolicy.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected no ImageContentSourcePolicy to be written: %v", err)
+	}
+}
@@ -0,0 +1,371 @@
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/openshift/oc/pkg/helpers/offline"
+)
+
+var (
+	diffLong = templates.LongDesc(`
+		Compare the resources gathered in two directories.
+
+		The directories are the output of oc adm inspect or oc adm must-gather, typically gathered
+		before and after a change. Objects are matched by group, kind, namespace and name, and
+		are reported as added, removed or changed, with the fields that changed.
+
+		Fields that change without anything happening are ignored: the resource version, the
+		managed fields and timestamps in the metadata and status of objects such as
+		creationTimestamp, lastTransitionTime or the startedAt of containers. More fields can be
+		ignored with --ignore-field, given as a path like metadata.annotations or
+		status. Events are not compared, use oc adm inspect timeline to see them.
+	`)
+
+	diffExample = templates.Examples(`
+		# Compare the data gathered before and after an upgrade
+		  oc adm inspect diff must-gather.before must-gather.after
+
+		# Compare two inspections without comparing the status of objects
+		  oc adm inspect diff inspect.local.1 inspect.local.2 --ignore-field=status
+
+		# Print the differences as JSON
+		  oc adm inspect diff inspect.local.1 inspect.local.2 -o json
+	`)
+)
+
+// volatileFields are removed wherever they appear in the metadata and status of an object, as
+// they change even when nothing meaningful did. The rest of an object, like the data of a config
+// map or the spec of a custom resource, is compared in full.
+var volatileFields = sets.NewString(
+	"creationTimestamp",
+	"lastTransitionTime",
+	"lastHeartbeatTime",
+	"lastProbeTime",
+	"lastUpdateTime",
+	"lastTimestamp",
+	"firstTimestamp",
+	"eventTime",
+	"startedAt",
+	"finishedAt",
+)
+
+// volatileLeaseFields are removed from the spec of leases, which their holders renew.
+var volatileLeaseFields = []string{"renewTime", "acquireTime"}
+
+// skippedResources are not compared: events are better seen on a timeline, and projects are
+// derived from namespaces when the directories are loaded.
+var skippedResources = sets.NewString(
+	schema.GroupResource{Resource: "events"}.String(),
+	schema.GroupResource{Group: "events.k8s.io", Resource: "events"}.String(),
+	schema.GroupResource{Group: "project.openshift.io", Resource: "projects"}.String(),
+)
+
+// objectRef identifies an object in both directories.
+type objectRef struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (r objectRef) String() string {
+	s := strings.ToLower(r.Kind)
+	if len(r.Group) > 0 {
+		s += "." + r.Group
+	}
+	s += "/" + r.Name
+	if len(r.Namespace) > 0 {
+		s += " -n " + r.Namespace
+	}
+	return s
+}
+
+func (r objectRef) less(other objectRef) bool {
+	if r.Group != other.Group {
+		return r.Group < other.Group
+	}
+	if r.Kind != other.Kind {
+		return r.Kind < other.Kind
+	}
+	if r.Namespace != other.Namespace {
+		return r.Namespace < other.Namespace
+	}
+	return r.Name < other.Name
+}
+
+// fieldDiff is a field whose value differs. Old or New is nil if the field is only set on one side.
+type fieldDiff struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+type objectDiff struct {
+	Object objectRef   `json:"object"`
+	Fields []fieldDiff `json:"fields"`
+}
+
+type snapshotDiff struct {
+	Added   []objectRef  `json:"added"`
+	Removed []objectRef  `json:"removed"`
+	Changed []objectDiff `json:"changed"`
+}
+
+type DiffOptions struct {
+	genericclioptions.IOStreams
+
+	OldDir       string
+	NewDir       string
+	IgnoreFields []string
+	Output       string
+}
+
+func NewDiffOptions(streams genericclioptions.IOStreams) *DiffOptions {
+	return &DiffOptions{
+		IOStreams: streams,
+	}
+}
+
+func NewCmdDiff(streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewDiffOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "diff DIR1 DIR2",
+		Short:   "Compare the resources gathered in two directories",
+		Long:    diffLong,
+		Example: diffExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			kcmdutil.CheckErr(o.Complete(cmd, args))
+			kcmdutil.CheckErr(o.Validate())
+			kcmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringSliceVar(&o.IgnoreFields, "ignore-field", o.IgnoreFields, "Also ignore these fields of every object, given as paths like metadata.labels or status.")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format. One of: json.")
+	return cmd
+}
+
+func (o *DiffOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return kcmdutil.UsageErrorf(cmd, "two directories are required")
+	}
+	o.OldDir, o.NewDir = args[0], args[1]
+	return nil
+}
+
+func (o *DiffOptions) Validate() error {
+	switch o.Output {
+	case "", "json":
+	default:
+		return fmt.Errorf("--output must be json, or empty for text")
+	}
+	for _, field := range o.IgnoreFields {
+		if len(field) == 0 || strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") {
+			return fmt.Errorf("--ignore-field must be a path like metadata.labels, got %q", field)
+		}
+	}
+	return nil
+}
+
+func (o *DiffOptions) Run() error {
+	oldStore, err := offline.Load(o.OldDir)
+	if err != nil {
+		return err
+	}
+	newStore, err := offline.Load(o.NewDir)
+	if err != nil {
+		return err
+	}
+	diff := o.diff(snapshotObjects(oldStore), snapshotObjects(newStore))
+
+	if o.Output == "json" {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(o.Out, string(data))
+		return nil
+	}
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0 {
+		fmt.Fprintln(o.ErrOut, "No differences found.")
+		return nil
+	}
+	return printDiff(o.Out, diff)
+}
+
+// snapshotObjects returns the objects of a store that are compared, by reference.
+func snapshotObjects(store *offline.Store) map[objectRef]*unstructured.Unstructured {
+	objects := map[objectRef]*unstructured.Unstructured{}
+	for _, gr := range store.Resources() {
+		if skippedResources.Has(gr.String()) {
+			continue
+		}
+		for _, obj := range store.Objects(gr) {
+			gvk := obj.GroupVersionKind()
+			objects[objectRef{Group: gvk.Group, Kind: gvk.Kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}] = obj
+		}
+	}
+	return objects
+}
+
+// diff compares the objects of two directories.
+func (o *DiffOptions) diff(oldObjects, newObjects map[objectRef]*unstructured.Unstructured) snapshotDiff {
+	diff := snapshotDiff{
+		Added:   []objectRef{},
+		Removed: []objectRef{},
+		Changed: []objectDiff{},
+	}
+	for ref, oldObj := range oldObjects {
+		newObj, ok := newObjects[ref]
+		if !ok {
+			diff.Removed = append(diff.Removed, ref)
+			continue
+		}
+		var fields []fieldDiff
+		diffValues("", o.normalize(oldObj), o.normalize(newObj), &fields)
+		if len(fields) > 0 {
+			diff.Changed = append(diff.Changed, objectDiff{Object: ref, Fields: fields})
+		}
+	}
+	for ref := range newObjects {
+		if _, ok := oldObjects[ref]; !ok {
+			diff.Added = append(diff.Added, ref)
+		}
+	}
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].less(diff.Added[j]) })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].less(diff.Removed[j]) })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Object.less(diff.Changed[j].Object) })
+	return diff
+}
+
+// normalize returns the content of an object without its volatile and ignored fields. The
+// apiVersion is dropped too, as the same object may have been gathered at another version.
+func (o *DiffOptions) normalize(obj *unstructured.Unstructured) map[string]interface{} {
+	content := obj.DeepCopy().Object
+	delete(content, "apiVersion")
+	unstructured.RemoveNestedField(content, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(content, "metadata", "managedFields")
+	removeVolatileFields(content["metadata"])
+	removeVolatileFields(content["status"])
+	if obj.GroupVersionKind().GroupKind() == (schema.GroupKind{Group: "coordination.k8s.io", Kind: "Lease"}) {
+		for _, field := range volatileLeaseFields {
+			unstructured.RemoveNestedField(content, "spec", field)
+		}
+	}
+	for _, field := range o.IgnoreFields {
+		unstructured.RemoveNestedField(content, strings.Split(field, ".")...)
+	}
+	return content
+}
+
+func removeVolatileFields(value interface{}) {
+	switch t := value.(type) {
+	case map[string]interface{}:
+		for key, child := range t {
+			if volatileFields.Has(key) {
+				delete(t, key)
+				continue
+			}
+			removeVolatileFields(child)
+		}
+	case []interface{}:
+		for _, child := range t {
+			removeVolatileFields(child)
+		}
+	}
+}
+
+// diffValues appends the fields below path whose values differ to fields. Lists are compared item
+// by item.
+func diffValues(path string, oldValue, newValue interface{}, fields *[]fieldDiff) {
+	switch oldTyped := oldValue.(type) {
+	case map[string]interface{}:
+		newTyped, ok := newValue.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := sets.NewString()
+		for key := range oldTyped {
+			keys.Insert(key)
+		}
+		for key := range newTyped {
+			keys.Insert(key)
+		}
+		for _, key := range keys.List() {
+			diffValues(fieldPath(path, key), oldTyped[key], newTyped[key], fields)
+		}
+		return
+	case []interface{}:
+		newTyped, ok := newValue.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(oldTyped) || i < len(newTyped); i++ {
+			var oldItem, newItem interface{}
+			if i < len(oldTyped) {
+				oldItem = oldTyped[i]
+			}
+			if i < len(newTyped) {
+				newItem = newTyped[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), oldItem, newItem, fields)
+		}
+		return
+	}
+	if !reflect.DeepEqual(oldValue, newValue) {
+		*fields = append(*fields, fieldDiff{Path: path, Old: oldValue, New: newValue})
+	}
+}
+
+// fieldPath appends a key to a path, quoting keys such as label names that contain dots or slashes.
+func fieldPath(path, key string) string {
+	if strings.ContainsAny(key, "./[]") {
+		return fmt.Sprintf("%s[%s]", path, strconv.Quote(key))
+	}
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+func printDiff(out io.Writer, diff snapshotDiff) error {
+	for _, ref := range diff.Removed {
+		fmt.Fprintf(out, "- %s\n", ref)
+	}
+	for _, ref := range diff.Added {
+		fmt.Fprintf(out, "+ %s\n", ref)
+	}
+	for _, changed := range diff.Changed {
+		fmt.Fprintf(out, "~ %s\n", changed.Object)
+		for _, field := range changed.Fields {
+			fmt.Fprintf(out, "    %s: %s -> %s\n", field.Path, formatFieldValue(field.Old), formatFieldValue(field.New))
+		}
+	}
+	return nil
+}
+
+// formatFieldValue prints a value as compact JSON, or <unset> for a missing field.
+func formatFieldValue(value interface{}) string {
+	if value == nil {
+		return "<unset>"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
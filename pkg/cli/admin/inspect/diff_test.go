@@ -0,0 +1,200 @@
+package inspect
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var diffOldFiles = map[string]string{
+	"namespaces/demo/demo.yaml": `
+apiVersion: v1
+kind: Namespace
+metadata: {name: demo, resourceVersion: "1", creationTimestamp: "2022-01-01T00:00:00Z"}
+`,
+	"namespaces/demo/apps/deployments.yaml": `
+apiVersion: apps/v1
+kind: DeploymentList
+items:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+    namespace: demo
+    resourceVersion: "10"
+    labels: {app.kubernetes.io/name: web}
+    managedFields: [{manager: kubectl}]
+  spec:
+    replicas: 1
+    template:
+      spec:
+        containers: [{name: web, image: web:1}]
+  status:
+    conditions: [{type: Available, status: "True", lastTransitionTime: "2022-01-01T00:00:00Z"}]
+`,
+	"namespaces/demo/core/configmaps.yaml": `
+apiVersion: v1
+kind: ConfigMapList
+items:
+- metadata: {name: old, namespace: demo}
+  data: {a: "1"}
+`,
+	"namespaces/demo/core/events.yaml": `
+apiVersion: v1
+kind: EventList
+items:
+- metadata: {name: web.1, namespace: demo}
+  reason: Started
+`,
+}
+
+var diffNewFiles = map[string]string{
+	"namespaces/demo/demo.yaml": `
+apiVersion: v1
+kind: Namespace
+metadata: {name: demo, resourceVersion: "2", creationTimestamp: "2022-01-01T00:00:00Z"}
+`,
+	"namespaces/demo/apps/deployments/web.yaml": `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: demo
+  resourceVersion: "20"
+  labels: {app.kubernetes.io/name: web, tier: front}
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers: [{name: web, image: web:2}, {name: proxy, image: proxy}]
+status:
+  conditions: [{type: Available, status: "True", lastTransitionTime: "2022-01-02T00:00:00Z"}]
+`,
+	"namespaces/demo/core/configmaps.yaml": `
+apiVersion: v1
+kind: ConfigMapList
+items:
+- metadata: {name: new, namespace: demo}
+  data: {a: "1"}
+`,
+}
+
+func writeDiffFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "inspect-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDiff(t *testing.T) {
+	oldDir := writeDiffFiles(t, diffOldFiles)
+	defer os.RemoveAll(oldDir)
+	newDir := writeDiffFiles(t, diffNewFiles)
+	defer os.RemoveAll(newDir)
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewDiffOptions(streams)
+	o.OldDir, o.NewDir = oldDir, newDir
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := `- configmap/old -n demo
++ configmap/new -n demo
+~ deployment.apps/web -n demo
+    metadata.labels.tier: <unset> -> "front"
+    spec.replicas: 1 -> 3
+    spec.template.spec.containers[0].image: "web:1" -> "web:2"
+    spec.template.spec.containers[1]: <unset> -> {"image":"proxy","name":"proxy"}
+`
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o = NewDiffOptions(streams)
+	o.OldDir, o.NewDir = oldDir, newDir
+	o.IgnoreFields = []string{"spec", "metadata.labels"}
+	o.Output = "json"
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var diff snapshotDiff
+	if err := json.Unmarshal(out.Bytes(), &diff); err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || len(diff.Removed) != 1 || len(diff.Changed) != 0 {
+		t.Errorf("unexpected diff:\n%s", out.String())
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	var fields []fieldDiff
+	diffValues("",
+		map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{"example.com/a": "1"}}, "data": []interface{}{"a", "b"}},
+		map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{"example.com/a": "2"}}, "data": []interface{}{"a"}},
+		&fields)
+	if len(fields) != 2 || fields[0].Path != "data[1]" || fields[0].New != nil || fields[1].Path != `metadata.annotations["example.com/a"]` {
+		t.Errorf("unexpected fields: %#v", fields)
+	}
+
+	if err := (&DiffOptions{IgnoreFields: []string{"status."}}).Validate(); err == nil {
+		t.Error("expected an error for an invalid field")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  string
+		want string
+	}{
+		{
+			name: "volatile fields of metadata and status",
+			obj:  `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"web","creationTimestamp":"2022-01-01T00:00:00Z"},"status":{"containerStatuses":[{"name":"web","state":{"terminated":{"exitCode":0,"startedAt":"2022-01-01T00:00:00Z","finishedAt":"2022-01-01T00:01:00Z"}}}]}}`,
+			want: `{"kind":"Pod","metadata":{"name":"web"},"status":{"containerStatuses":[{"name":"web","state":{"terminated":{"exitCode":0}}}]}}`,
+		},
+		{
+			name: "data of a config map",
+			obj:  `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"config"},"data":{"creationTimestamp":"2022-01-01T00:00:00Z"}}`,
+			want: `{"data":{"creationTimestamp":"2022-01-01T00:00:00Z"},"kind":"ConfigMap","metadata":{"name":"config"}}`,
+		},
+		{
+			name: "spec of a lease",
+			obj:  `{"apiVersion":"coordination.k8s.io/v1","kind":"Lease","metadata":{"name":"leader"},"spec":{"holderIdentity":"a","renewTime":"2022-01-01T00:00:00.000000Z","acquireTime":"2022-01-01T00:00:00.000000Z"}}`,
+			want: `{"kind":"Lease","metadata":{"name":"leader"},"spec":{"holderIdentity":"a"}}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{}
+			if err := json.Unmarshal([]byte(tc.obj), &obj.Object); err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal((&DiffOptions{}).normalize(obj))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, data)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	kresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -30,6 +31,9 @@ import (
 	"k8s.io/kubectl/pkg/util/templates"
 
 	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/oc/pkg/helpers/parallel"
+	"github.com/openshift/oc/pkg/helpers/redact"
 )
 
 var (
@@ -39,6 +43,27 @@ var (
 		This command downloads the specified resource and any related
 		resources for the purpose of gathering debugging information.
 
+		Use --redact to remove sensitive data, like IP addresses, hostnames, usernames and tokens,
+		from every file written. It accepts the built-in profiles %[1]s, or files
+		with rules like:
+
+		    include: [tokens]
+		    rules:
+		    - name: ip
+		      regex: '\b10\.(?:[0-9]{1,3}\.){2}[0-9]{1,3}\b'
+		      action: pseudonymize
+		    - path: data["password"]
+		      kinds: [ConfigMap]
+		      action: remove
+
+		Regex rules apply to every line of every file, path rules to the objects of the
+		listed kinds. Pseudonymized values are replaced with the same pseudonym everywhere,
+		and --redaction-map saves the pseudonyms to reuse them or to interpret the output.
+
+		Logs that exceed --max-log-size or --max-total-size are truncated with a marker, and
+		resources that don't fit in --max-total-size are skipped. Both are listed in the
+		manifest.json file of the destination directory.
+
 		Experimental: This command is under active development and may change without notice.
 	`)
 
@@ -54,6 +79,12 @@ var (
 
 		# Collect debugging data for all clusteroperators and clusterversions
 		oc adm inspect clusteroperators,clusterversions
+
+		# Collect debugging data for a namespace, 8 pods at a time, with at most 10Mi of logs per container and 1Gi in total
+		oc adm inspect ns/my-app --concurrency=8 --max-log-size=10Mi --max-total-size=1Gi
+
+		# Collect debugging data for a namespace without IP addresses, hostnames, usernames and tokens
+		oc adm inspect ns/my-app --redact=vendor --redaction-map=redaction-map.json
 	`)
 )
 
@@ -84,6 +115,19 @@ type InspectOptions struct {
 	// whether or not to allow writes to an existing and populated base directory
 	overwrite bool
 
+	// concurrency is the number of resources, and of pods of each namespace, gathered at once
+	concurrency int
+	// maxLogSize and maxTotalSize limit the size of the logs of each container and of all files
+	maxLogSize   string
+	maxTotalSize string
+
+	// RedactProfiles are the built-in redaction profiles or profile files applied to every file written
+	RedactProfiles []string
+	// RedactionMap is the file pseudonyms are read from and saved to
+	RedactionMap string
+	// Redactor is created from RedactProfiles if not set
+	Redactor *redact.Redactor
+
 	genericclioptions.IOStreams
 	eventFile string
 }
@@ -93,6 +137,7 @@ func NewInspectOptions(streams genericclioptions.IOStreams) *InspectOptions {
 		printFlags:  genericclioptions.NewPrintFlags("gathered").WithDefaultOutput("yaml").WithTypeSetter(scheme.Scheme),
 		configFlags: genericclioptions.NewConfigFlags(true),
 		overwrite:   true,
+		concurrency: 4,
 		IOStreams:   streams,
 	}
 }
@@ -102,7 +147,7 @@ func NewCmdInspect(streams genericclioptions.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "inspect (TYPE[.VERSION][.GROUP] [NAME] | TYPE[.VERSION][.GROUP]/NAME ...) [flags]",
 		Short:   "Collect debugging data for a given resource",
-		Long:    inspectLong,
+		Long:    fmt.Sprintf(inspectLong, strings.Join(redact.BuiltinProfiles(), ", ")),
 		Example: inspectExample,
 		Run: func(c *cobra.Command, args []string) {
 			kcmdutil.CheckErr(o.Complete(args))
@@ -116,6 +161,11 @@ func NewCmdInspect(streams genericclioptions.IOStreams) *cobra.Command {
 	cmd.Flags().BoolVarP(&o.allNamespaces, "all-namespaces", "A", o.allNamespaces, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
 	cmd.Flags().StringVar(&o.sinceTime, "since-time", o.sinceTime, "Only return logs after a specific date (RFC3339). Defaults to all logs. Only one of since-time / since may be used.")
 	cmd.Flags().DurationVar(&o.since, "since", o.since, "Only return logs newer than a relative duration like 5s, 2m, or 3h. Defaults to all logs. Only one of since-time / since may be used.")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", o.concurrency, "Number of resources, and of pods in each namespace, to gather at the same time.")
+	cmd.Flags().StringVar(&o.maxLogSize, "max-log-size", o.maxLogSize, "Truncate the logs of each container, current and previous together, to this size, like 10Mi. Defaults to no limit.")
+	cmd.Flags().StringVar(&o.maxTotalSize, "max-total-size", o.maxTotalSize, "Stop writing resources and logs once this much data, like 1Gi, was written. Defaults to no limit.")
+	cmd.Flags().StringSliceVar(&o.RedactProfiles, "redact", o.RedactProfiles, fmt.Sprintf("Redact sensitive data from every file written, using built-in profiles (%s) or profile files.", strings.Join(redact.BuiltinProfiles(), ", ")))
+	cmd.Flags().StringVar(&o.RedactionMap, "redaction-map", o.RedactionMap, "A file to read pseudonyms from and to save them to when redacting. Must not be inside the destination directory.")
 	cmd.Flags().BoolVar(&o.rotatedPodLogs, "rotated-pod-logs", o.rotatedPodLogs, "Experimental: If present, retrieve rotated log files that are available for selected pods. This can significantly increase the collected logs size. since/since-time is ignored for rotated logs.")
 
 	// The rotated-pod-logs option should be removed once support for retrieving rotated logs is added to kubelet
@@ -123,6 +173,9 @@ func NewCmdInspect(streams genericclioptions.IOStreams) *cobra.Command {
 	cmd.Flags().MarkHidden("rotated-pod-logs")
 
 	o.configFlags.AddFlags(cmd.Flags())
+
+	cmd.AddCommand(NewCmdTimeline(streams))
+	cmd.AddCommand(NewCmdDiff(streams))
 	return cmd
 }
 
@@ -172,11 +225,50 @@ func (o *InspectOptions) Complete(args []string) error {
 		}
 	}
 
+	if len(o.DestDir) == 0 {
+		o.DestDir = fmt.Sprintf("inspect.local.%06d", rand.Int63())
+	}
+
 	printer, err := o.printFlags.ToPrinter()
 	if err != nil {
 		return err
 	}
 	o.fileWriter = NewMultiSourceWriter(printer)
+	if o.Redactor == nil && len(o.RedactProfiles) > 0 {
+		if o.Redactor, err = redact.New(o.RedactProfiles...); err != nil {
+			return err
+		}
+		if len(o.RedactionMap) > 0 {
+			if err := o.Redactor.LoadMap(o.RedactionMap); err != nil {
+				return err
+			}
+		}
+	}
+	o.fileWriter.redactor = o.Redactor
+	if len(o.maxLogSize) > 0 || len(o.maxTotalSize) > 0 {
+		limits := &sizeLimits{baseDir: o.DestDir}
+		for _, limit := range []struct {
+			flag, value string
+			bytes       *int64
+		}{
+			{flag: "--max-log-size", value: o.maxLogSize, bytes: &limits.maxLog},
+			{flag: "--max-total-size", value: o.maxTotalSize, bytes: &limits.maxTotal},
+		} {
+			if len(limit.value) == 0 {
+				continue
+			}
+			quantity, err := kresource.ParseQuantity(limit.value)
+			if err != nil {
+				return fmt.Errorf("invalid argument %q for %q flag: %v", limit.value, limit.flag, err)
+			}
+			if *limit.bytes = quantity.Value(); *limit.bytes <= 0 {
+				return fmt.Errorf("%s must be greater than zero", limit.flag)
+			}
+		}
+		limits.manifest.MaxLogSize = o.maxLogSize
+		limits.manifest.MaxTotalSize = o.maxTotalSize
+		o.fileWriter.limits = limits
+	}
 	o.podUrlGetter = &PortForwardURLGetter{
 		Protocol:  "https",
 		Host:      "localhost",
@@ -184,10 +276,6 @@ func (o *InspectOptions) Complete(args []string) error {
 	}
 
 	o.builder = resource.NewBuilder(o.configFlags)
-
-	if len(o.DestDir) == 0 {
-		o.DestDir = fmt.Sprintf("inspect.local.%06d", rand.Int63())
-	}
 	return nil
 }
 
@@ -198,6 +286,17 @@ func (o *InspectOptions) Validate() error {
 	if len(o.sinceTime) > 0 && o.since != 0 {
 		return fmt.Errorf("at most one of `sinceTime` or `since` may be specified")
 	}
+	if o.concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if len(o.RedactionMap) > 0 {
+		if len(o.RedactProfiles) == 0 {
+			return fmt.Errorf("--redaction-map may only be specified with --redact")
+		}
+		if err := redact.ValidateMapPath(o.RedactionMap, o.DestDir); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -235,18 +334,35 @@ func (o *InspectOptions) Run() error {
 	// finally, gather polymorphic resources specified by the user
 	allErrs := []error{}
 	ctx := NewResourceContext()
+	var inspectFuncs []func() error
 	for _, info := range infos {
-		err := InspectResource(info, ctx, o)
-		if err != nil {
-			allErrs = append(allErrs, err)
-		}
+		info := info
+		inspectFuncs = append(inspectFuncs, func() error {
+			return InspectResource(info, ctx, o)
+		})
 	}
+	allErrs = append(allErrs, parallel.RunLimited(o.concurrency, inspectFuncs...)...)
 
 	// now gather all the events into a single file and produce a unified file
 	if err := CreateEventFilterPage(o.DestDir); err != nil {
 		allErrs = append(allErrs, err)
 	}
 
+	if o.Redactor != nil && len(o.RedactionMap) > 0 {
+		if err := o.Redactor.WriteMap(o.RedactionMap); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if o.fileWriter.limits != nil {
+		if err := o.fileWriter.limits.writeManifest(); err != nil {
+			allErrs = append(allErrs, err)
+		}
+		if manifest := o.fileWriter.limits.manifest; len(manifest.Truncated) > 0 || len(manifest.Skipped) > 0 {
+			fmt.Fprintf(o.ErrOut, "warning: %d files were truncated and %d skipped to stay within the size limits, see %s\n", len(manifest.Truncated), len(manifest.Skipped), path.Join(o.DestDir, manifestFilename))
+		}
+	}
+
 	fmt.Fprintf(o.Out, "Wrote inspect data to %s.\n", o.DestDir)
 	if len(allErrs) > 0 {
 		return fmt.Errorf("errors occurred while gathering data:\n    %v", errors.NewAggregate(allErrs))
@@ -258,11 +374,10 @@ func (o *InspectOptions) Run() error {
 // gatherConfigResourceData gathers all config.openshift.io resources
 func (o *InspectOptions) gatherConfigResourceData(destDir string, ctx *resourceContext) error {
 	// determine if we've already collected configResourceData
-	if ctx.visited.Has(configResourceDataKey) {
+	if !ctx.visit(configResourceDataKey) {
 		klog.V(1).Infof("Skipping previously-collected config.openshift.io resource data")
 		return nil
 	}
-	ctx.visited.Insert(configResourceDataKey)
 
 	klog.V(1).Infof("Gathering config.openshift.io resource data...\n")
 
@@ -301,11 +416,10 @@ func (o *InspectOptions) gatherConfigResourceData(destDir string, ctx *resourceC
 // gatherOperatorResourceData gathers all kubeapiserver.operator.openshift.io resources
 func (o *InspectOptions) gatherOperatorResourceData(destDir string, ctx *resourceContext) error {
 	// determine if we've already collected operatorResourceData
-	if ctx.visited.Has(operatorResourceDataKey) {
+	if !ctx.visit(operatorResourceDataKey) {
 		klog.V(1).Infof("Skipping previously-collected operator.openshift.io resource data")
 		return nil
 	}
-	ctx.visited.Insert(operatorResourceDataKey)
 
 	// ensure destination path exists
 	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
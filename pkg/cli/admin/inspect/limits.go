@@ -0,0 +1,160 @@
+package inspect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// manifestFilename lists what was truncated or skipped because of the size limits.
+const manifestFilename = "manifest.json"
+
+// errLimitReached stops copying a source once a size limit is reached.
+var errLimitReached = errors.New("size limit reached")
+
+type manifestEntry struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	// Bytes is how much of a truncated file was written.
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+type gatherManifest struct {
+	MaxLogSize   string          `json:"maxLogSize,omitempty"`
+	MaxTotalSize string          `json:"maxTotalSize,omitempty"`
+	Written      int64           `json:"written"`
+	Truncated    []manifestEntry `json:"truncated,omitempty"`
+	Skipped      []manifestEntry `json:"skipped,omitempty"`
+}
+
+// sizeLimits tracks the bytes written by all writers against --max-total-size, and records what
+// was truncated or skipped.
+type sizeLimits struct {
+	baseDir string
+	// maxTotal is the number of bytes that may be written, 0 for no limit.
+	maxTotal int64
+	// maxLog is the number of bytes of logs that may be written per container, 0 for no limit.
+	maxLog int64
+
+	lock     sync.Mutex
+	manifest gatherManifest
+}
+
+// reserve returns how many of n bytes may still be written.
+func (l *sizeLimits) reserve(n int64) int64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.maxTotal > 0 && l.manifest.Written+n > l.maxTotal {
+		n = l.maxTotal - l.manifest.Written
+	}
+	l.manifest.Written += n
+	return n
+}
+
+// exhausted returns true if nothing more may be written.
+func (l *sizeLimits) exhausted() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.maxTotal > 0 && l.manifest.Written >= l.maxTotal
+}
+
+// release returns bytes that were reserved but not kept.
+func (l *sizeLimits) release(n int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.manifest.Written -= n
+}
+
+func (l *sizeLimits) relativePath(path string) string {
+	if rel, err := filepath.Rel(l.baseDir, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+func (l *sizeLimits) skipped(path, reason string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.manifest.Skipped = append(l.manifest.Skipped, manifestEntry{Path: l.relativePath(path), Reason: reason})
+}
+
+func (l *sizeLimits) truncated(path, reason string, written int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.manifest.Truncated = append(l.manifest.Truncated, manifestEntry{Path: l.relativePath(path), Reason: reason, Bytes: written})
+}
+
+// writeManifest writes the manifest to the base directory.
+func (l *sizeLimits) writeManifest() error {
+	l.lock.Lock()
+	manifest := l.manifest
+	l.lock.Unlock()
+	for _, entries := range [][]manifestEntry{manifest.Truncated, manifest.Skipped} {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(l.baseDir, manifestFilename), append(data, '\n'), 0644)
+}
+
+// logBudget is what remains of the --max-log-size of a container, shared by its current and
+// previous logs. It is not safe for concurrent use.
+type logBudget struct {
+	remaining int64
+}
+
+// newLogBudget returns the budget of a container, or nil if logs are not limited.
+func (l *sizeLimits) newLogBudget() *logBudget {
+	if l == nil || l.maxLog <= 0 {
+		return nil
+	}
+	return &logBudget{remaining: l.maxLog}
+}
+
+// limitedWriter writes until the log budget or the total size is exhausted, and then fails with
+// errLimitReached.
+type limitedWriter struct {
+	w       io.Writer
+	limits  *sizeLimits
+	budget  *logBudget
+	written int64
+	// reason is set once the writer is truncated
+	reason string
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if len(w.reason) > 0 {
+		return 0, errLimitReached
+	}
+	n := int64(len(p))
+	if w.budget != nil && n > w.budget.remaining {
+		n = w.budget.remaining
+		w.reason = fmt.Sprintf("the logs of the container exceed --max-log-size=%s", w.limits.manifest.MaxLogSize)
+	}
+	if granted := w.limits.reserve(n); granted < n {
+		n = granted
+		w.reason = fmt.Sprintf("--max-total-size=%s was reached", w.limits.manifest.MaxTotalSize)
+	}
+	written, err := w.w.Write(p[:n])
+	w.written += int64(written)
+	if w.budget != nil {
+		w.budget.remaining -= int64(written)
+	}
+	if int64(written) < n {
+		w.limits.release(n - int64(written))
+	}
+	if err != nil {
+		return written, err
+	}
+	if len(w.reason) > 0 {
+		return written, errLimitReached
+	}
+	return written, nil
+}
@@ -0,0 +1,92 @@
+package inspect
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestSizeLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inspect-limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	printer, err := genericclioptions.NewPrintFlags("gathered").WithDefaultOutput("yaml").WithTypeSetter(scheme.Scheme).ToPrinter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := NewMultiSourceWriter(printer)
+	writer.limits = &sizeLimits{baseDir: dir, maxLog: 10, maxTotal: 200}
+	writer.limits.manifest.MaxLogSize = "10"
+	writer.limits.manifest.MaxTotalSize = "200"
+
+	// the current log uses up the budget of the container
+	budget := writer.limits.newLogBudget()
+	if err := writer.WriteLogFromSource(filepath.Join(dir, "current.log"), &TextWriterSource{Text: "0123456789abcdef\n"}, budget); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteLogFromSource(filepath.Join(dir, "previous.log"), &TextWriterSource{Text: "old\n"}, budget); err != nil {
+		t.Fatal(err)
+	}
+	// another container has its own budget
+	if err := writer.WriteLogFromSource(filepath.Join(dir, "other.log"), &TextWriterSource{Text: "short\n"}, writer.limits.newLogBudget()); err != nil {
+		t.Fatal(err)
+	}
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"}}
+	if err := writer.WriteFromResource(filepath.Join(dir, "web.yaml"), pod); err != nil {
+		t.Fatal(err)
+	}
+	pod.Annotations = map[string]string{"large": strings.Repeat("x", 200)}
+	if err := writer.WriteFromResource(filepath.Join(dir, "large.yaml"), pod); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "current.log"))
+	if string(data) != "0123456789\n[truncated by oc adm inspect: the logs of the container exceed --max-log-size=10]\n" {
+		t.Errorf("unexpected current log: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "previous.log")); !os.IsNotExist(err) {
+		t.Errorf("expected the previous log to be skipped: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "other.log")); string(data) != "short\n" {
+		t.Errorf("unexpected other log: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "web.yaml")); err != nil {
+		t.Errorf("expected the small resource to be written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "large.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected the large resource to be skipped: %v", err)
+	}
+
+	if err := writer.limits.writeManifest(); err != nil {
+		t.Fatal(err)
+	}
+	data, err = ioutil.ReadFile(filepath.Join(dir, manifestFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := gatherManifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Truncated) != 1 || manifest.Truncated[0].Path != "current.log" || manifest.Truncated[0].Bytes != 10 {
+		t.Errorf("unexpected truncated files: %#v", manifest.Truncated)
+	}
+	if len(manifest.Skipped) != 2 || manifest.Skipped[0].Path != "large.yaml" || !strings.Contains(manifest.Skipped[0].Reason, "--max-total-size=200") || manifest.Skipped[1].Path != "previous.log" {
+		t.Errorf("unexpected skipped files: %#v", manifest.Skipped)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "web.yaml")); err != nil || manifest.Written != 16+info.Size() {
+		t.Errorf("unexpected written bytes %d", manifest.Written)
+	}
+}
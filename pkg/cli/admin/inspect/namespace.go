@@ -13,6 +13,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
+
+	"github.com/openshift/oc/pkg/helpers/parallel"
 )
 
 // TODO someone may later choose to use discovery information to determine what to collect
@@ -72,15 +74,16 @@ func (o *InspectOptions) gatherNamespaceData(baseDir, namespace string) error {
 	klog.V(1).Infof("    Gathering pod data for namespace %q...\n", namespace)
 	// gather specific pod data
 	if pods := resourcesToStore[corev1.SchemeGroupVersion.WithResource("pods")]; pods != nil {
+		var gatherFuncs []func() error
 		for _, pod := range pods.(*unstructured.UnstructuredList).Items {
-			klog.V(1).Infof("        Gathering data for pod %q\n", pod.GetName())
 			structuredPod := &corev1.Pod{}
 			runtime.DefaultUnstructuredConverter.FromUnstructured(pod.Object, structuredPod)
-			if err := o.gatherPodData(path.Join(destDir, "/pods/"+pod.GetName()), namespace, structuredPod); err != nil {
-				errs = append(errs, err)
-				continue
-			}
+			gatherFuncs = append(gatherFuncs, func() error {
+				klog.V(1).Infof("        Gathering data for pod %q\n", structuredPod.Name)
+				return o.gatherPodData(path.Join(destDir, "/pods/"+structuredPod.Name), namespace, structuredPod)
+			})
 		}
+		errs = append(errs, parallel.RunLimited(o.concurrency, gatherFuncs...)...)
 	}
 
 	if len(errs) > 0 {
@@ -8,7 +8,6 @@ import (
 	"path"
 	"regexp"
 	"strings"
-	"sync"
 
 	corev1 "k8s.io/api/core/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -153,73 +152,45 @@ func (o *InspectOptions) gatherContainerLogs(destDir string, pod *corev1.Pod, co
 		return err
 	}
 	errs := []error{}
-	wg := sync.WaitGroup{}
-	errLock := sync.Mutex{}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
-		innerErrs := []error{}
+	// the current log is gathered first, so that it is kept in preference to the previous log when
+	// the logs of the container are limited
+	budget := o.fileWriter.limits.newLogBudget()
+	for _, previous := range []bool{false, true} {
 		logOptions := &corev1.PodLogOptions{
 			Container:  container.Name,
 			Follow:     false,
-			Previous:   false,
+			Previous:   previous,
 			Timestamps: true,
 		}
-		if len(o.sinceTime) > 0 {
-			logOptions.SinceTime = &o.sinceTimestamp
-		}
-		if o.since != 0 {
-			logOptions.SinceSeconds = &o.sinceInt
-		}
-		filename := "current.log"
-		logsReq := o.kubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, logOptions)
-		if err := o.fileWriter.WriteFromSource(path.Join(destDir, "/"+filename), logsReq); err != nil {
-			innerErrs = append(innerErrs, err)
-
-			// if we had an error, we will try again with an insecure backendproxy flag set
-			logOptions.InsecureSkipTLSVerifyBackend = true
-			logsReq = o.kubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, logOptions)
-			filename = "current.insecure.log"
-			if err := o.fileWriter.WriteFromSource(path.Join(destDir, "/"+filename), logsReq); err != nil {
-				innerErrs = append(innerErrs, err)
+		filename := "current"
+		if previous {
+			filename = "previous"
+		} else {
+			if len(o.sinceTime) > 0 {
+				logOptions.SinceTime = &o.sinceTimestamp
+			}
+			if o.since != 0 {
+				logOptions.SinceSeconds = &o.sinceInt
 			}
 		}
-
-		errLock.Lock()
-		defer errLock.Unlock()
-		errs = append(errs, innerErrs...)
-	}()
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
-		innerErrs := []error{}
-		logOptions := &corev1.PodLogOptions{
-			Container:  container.Name,
-			Follow:     false,
-			Previous:   true,
-			Timestamps: true,
+		if budget != nil && o.Redactor == nil {
+			// one more byte than allowed tells a truncated log apart from a log of exactly the allowed size
+			limitBytes := budget.remaining + 1
+			logOptions.LimitBytes = &limitBytes
 		}
+
 		logsReq := o.kubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, logOptions)
-		filename := "previous.log"
-		if err := o.fileWriter.WriteFromSource(path.Join(destDir, "/"+filename), logsReq); err != nil {
-			innerErrs = append(innerErrs, err)
+		if err := o.fileWriter.WriteLogFromSource(path.Join(destDir, "/"+filename+".log"), logsReq, budget); err != nil {
+			errs = append(errs, err)
 
 			// if we had an error, we will try again with an insecure backendproxy flag set
 			logOptions.InsecureSkipTLSVerifyBackend = true
 			logsReq = o.kubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, logOptions)
-			filename = "previous.insecure.log"
-			if err := o.fileWriter.WriteFromSource(path.Join(destDir, "/"+filename), logsReq); err != nil {
-				innerErrs = append(innerErrs, err)
+			if err := o.fileWriter.WriteLogFromSource(path.Join(destDir, "/"+filename+".insecure.log"), logsReq, budget); err != nil {
+				errs = append(errs, err)
 			}
 		}
-
-		errLock.Lock()
-		defer errLock.Unlock()
-		errs = append(errs, innerErrs...)
-	}()
-	wg.Wait()
+	}
 	return utilerrors.NewAggregate(errs)
 }
@@ -27,11 +27,10 @@ const (
 // InspectResource receives an object to gather debugging data for, and a context to keep track of
 // already-seen objects when following related-object reference chains.
 func InspectResource(info *resource.Info, context *resourceContext, o *InspectOptions) error {
-	if context.visited.Has(infoToContextKey(info)) {
+	if !context.visit(infoToContextKey(info)) {
 		klog.V(1).Infof("Skipping previously-inspected resource: %q ...", infoToContextKey(info))
 		return nil
 	}
-	context.visited.Insert(infoToContextKey(info))
 
 	switch info.ResourceMapping().Resource.GroupResource() {
 	case configv1.GroupVersion.WithResource("clusteroperators").GroupResource():
@@ -70,7 +69,7 @@ func InspectResource(info *resource.Info, context *resourceContext, o *InspectOp
 		}
 		resourcesToCollect := namespaceResourcesToCollect()
 		for _, resource := range resourcesToCollect {
-			if context.visited.Has(resourceToContextKey(resource, info.Name)) {
+			if context.hasVisited(resourceToContextKey(resource, info.Name)) {
 				continue
 			}
 			resourceInfos, err := groupResourceToInfos(o.configFlags, resource, info.Name)
@@ -128,7 +127,7 @@ func gatherRelatedObjects(context *resourceContext, unstr *unstructured.Unstruct
 
 	errs := []error{}
 	for _, relatedRef := range relatedObjReferences {
-		if context.visited.Has(objectRefToContextKey(relatedRef)) {
+		if context.hasVisited(objectRefToContextKey(relatedRef)) {
 			continue
 		}
 
@@ -0,0 +1,450 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/klog/v2"
+	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/openshift/oc/pkg/helpers/offline"
+)
+
+var (
+	timelineLong = templates.LongDesc(`
+		Display a timeline of what happened in a cluster, from the output of oc adm inspect or
+		oc adm must-gather.
+
+		The timeline merges the events, the condition changes and container starts and
+		terminations of pods, and the condition changes of cluster operators found in the
+		directory, in chronological order. Entries may be filtered by namespace with --namespace,
+		by reason with --reason and by involved object, given as KIND/NAME or NAME, with --object.
+		Each filter accepts several comma separated values.
+
+		The timeline is printed as a table, or with --output as a HTML page that can be
+		filtered and sorted in a browser, or as CSV or JSON.
+	`)
+
+	timelineExample = templates.Examples(`
+		# Display the timeline of a must-gather directory
+		  oc adm inspect timeline must-gather.local.1234
+
+		# Write the timeline of the openshift-etcd namespace as a HTML page
+		  oc adm inspect timeline inspect.local.1234 -n openshift-etcd -o html > timeline.html
+
+		# Display what happened to the etcd cluster operator and to a pod, as CSV
+		  oc adm inspect timeline must-gather.local.1234 --object=clusteroperator/etcd --object=pod/etcd-master-0 -o csv
+
+		# Display the events and transitions whose reason mentions a failure
+		  oc adm inspect timeline must-gather.local.1234 --reason=fail
+	`)
+)
+
+const (
+	timelineSourceEvent           = "Event"
+	timelineSourcePod             = "Pod"
+	timelineSourceClusterOperator = "ClusterOperator"
+)
+
+// timelineEntry is something that happened at a point in time.
+type timelineEntry struct {
+	Time time.Time `json:"time"`
+	// Type is Normal or Warning, as for events.
+	Type      string `json:"type"`
+	Source    string `json:"source"`
+	Namespace string `json:"namespace,omitempty"`
+	// Object is the involved object, as kind/name.
+	Object  string `json:"object"`
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+	// Count is how many times an event was seen.
+	Count int32 `json:"count,omitempty"`
+}
+
+type TimelineOptions struct {
+	genericclioptions.IOStreams
+
+	Dir        string
+	Namespaces []string
+	Reasons    []string
+	Objects    []string
+	Output     string
+}
+
+func NewTimelineOptions(streams genericclioptions.IOStreams) *TimelineOptions {
+	return &TimelineOptions{
+		IOStreams: streams,
+	}
+}
+
+func NewCmdTimeline(streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewTimelineOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "timeline DIR",
+		Short:   "Display a timeline of events and state changes from gathered data",
+		Long:    timelineLong,
+		Example: timelineExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			kcmdutil.CheckErr(o.Complete(cmd, args))
+			kcmdutil.CheckErr(o.Validate())
+			kcmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringSliceVarP(&o.Namespaces, "namespace", "n", o.Namespaces, "Only include entries about these namespaces.")
+	cmd.Flags().StringSliceVar(&o.Reasons, "reason", o.Reasons, "Only include entries whose reason contains one of these strings, ignoring case.")
+	cmd.Flags().StringSliceVar(&o.Objects, "object", o.Objects, "Only include entries about these objects, as KIND/NAME or NAME.")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format. One of: html, csv, json.")
+	return cmd
+}
+
+func (o *TimelineOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return kcmdutil.UsageErrorf(cmd, "a directory is required")
+	}
+	o.Dir = args[0]
+	return nil
+}
+
+func (o *TimelineOptions) Validate() error {
+	switch o.Output {
+	case "", "html", "csv", "json":
+	default:
+		return fmt.Errorf("--output must be one of html, csv or json, or empty for a table")
+	}
+	for _, object := range o.Objects {
+		if parts := strings.Split(object, "/"); len(parts) > 2 || len(parts[len(parts)-1]) == 0 {
+			return fmt.Errorf("--object must be of the form KIND/NAME or NAME, got %q", object)
+		}
+	}
+	return nil
+}
+
+func (o *TimelineOptions) Run() error {
+	store, err := offline.Load(o.Dir)
+	if err != nil {
+		return err
+	}
+	entries := o.filter(buildTimeline(store))
+	switch o.Output {
+	case "html":
+		return writeTimelineHTML(o.Out, entries)
+	case "csv":
+		return writeTimelineCSV(o.Out, entries)
+	case "json":
+		if entries == nil {
+			entries = []timelineEntry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(o.Out, string(data))
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(o.ErrOut, "No entries found.")
+		return nil
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	defer w.Flush()
+	fmt.Fprintln(w, "TIME\tTYPE\tNAMESPACE\tOBJECT\tREASON\tMESSAGE")
+	for _, entry := range entries {
+		namespace := entry.Namespace
+		if len(namespace) == 0 {
+			namespace = "-"
+		}
+		reason := entry.Reason
+		if entry.Count > 1 {
+			reason += fmt.Sprintf(" (x%d)", entry.Count)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", entry.Time.UTC().Format(time.RFC3339), entry.Type, namespace, entry.Object, reason, entry.Message)
+	}
+	return nil
+}
+
+// filter returns the entries that match the namespace, reason and object filters.
+func (o *TimelineOptions) filter(entries []timelineEntry) []timelineEntry {
+	namespaces := sets.NewString(o.Namespaces...)
+	var filtered []timelineEntry
+	for _, entry := range entries {
+		if namespaces.Len() > 0 && !namespaces.Has(entry.Namespace) {
+			continue
+		}
+		if len(o.Reasons) > 0 && !matchesAnyReason(o.Reasons, entry.Reason) {
+			continue
+		}
+		if len(o.Objects) > 0 && !matchesAnyObject(o.Objects, entry.Object) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+func matchesAnyReason(reasons []string, reason string) bool {
+	for _, r := range reasons {
+		if strings.Contains(strings.ToLower(reason), strings.ToLower(r)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAnyObject compares kinds ignoring case, so that pod/a matches Pod/a.
+func matchesAnyObject(objects []string, object string) bool {
+	kind, name := "", object
+	if i := strings.Index(object, "/"); i != -1 {
+		kind, name = object[:i], object[i+1:]
+	}
+	for _, o := range objects {
+		if i := strings.Index(o, "/"); i != -1 {
+			if strings.EqualFold(o[:i], kind) && o[i+1:] == name {
+				return true
+			}
+			continue
+		}
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+// buildTimeline returns the entries of the events, pods and cluster operators of the store in
+// chronological order.
+func buildTimeline(store *offline.Store) []timelineEntry {
+	var entries []timelineEntry
+	for _, obj := range store.Objects(schema.GroupResource{Resource: "events"}) {
+		event := &corev1.Event{}
+		if !fromUnstructured(obj, event) {
+			continue
+		}
+		entries = append(entries, eventEntry(event))
+	}
+	for _, obj := range store.Objects(schema.GroupResource{Resource: "pods"}) {
+		pod := &corev1.Pod{}
+		if !fromUnstructured(obj, pod) {
+			continue
+		}
+		entries = append(entries, podEntries(pod)...)
+	}
+	for _, obj := range store.Objects(schema.GroupResource{Group: configv1.GroupName, Resource: "clusteroperators"}) {
+		operator := &configv1.ClusterOperator{}
+		if !fromUnstructured(obj, operator) {
+			continue
+		}
+		entries = append(entries, clusterOperatorEntries(operator)...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Time.Before(entries[j].Time)
+		}
+		if entries[i].Namespace != entries[j].Namespace {
+			return entries[i].Namespace < entries[j].Namespace
+		}
+		return entries[i].Object < entries[j].Object
+	})
+	return entries
+}
+
+func fromUnstructured(obj *unstructured.Unstructured, into interface{}) bool {
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, into); err != nil {
+		klog.V(2).Infof("Skipping %s %s/%s: %v", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+		return false
+	}
+	return true
+}
+
+func eventEntry(event *corev1.Event) timelineEntry {
+	var at time.Time
+	switch {
+	case !event.LastTimestamp.IsZero():
+		at = event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		at = event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		at = event.FirstTimestamp.Time
+	default:
+		at = event.CreationTimestamp.Time
+	}
+	namespace := event.InvolvedObject.Namespace
+	if len(namespace) == 0 {
+		namespace = event.Namespace
+	}
+	eventType := event.Type
+	if len(eventType) == 0 {
+		eventType = corev1.EventTypeNormal
+	}
+	count := event.Count
+	if event.Series != nil && event.Series.Count > count {
+		count = event.Series.Count
+	}
+	return timelineEntry{
+		Time:      at,
+		Type:      eventType,
+		Source:    timelineSourceEvent,
+		Namespace: namespace,
+		Object:    objectName(event.InvolvedObject.Kind, event.InvolvedObject.Name),
+		Reason:    event.Reason,
+		Message:   strings.TrimSpace(event.Message),
+		Count:     count,
+	}
+}
+
+// podEntries returns the condition changes of a pod, and the starts and terminations of its
+// containers.
+func podEntries(pod *corev1.Pod) []timelineEntry {
+	object := objectName("Pod", pod.Name)
+	var entries []timelineEntry
+	for _, condition := range pod.Status.Conditions {
+		if condition.LastTransitionTime.IsZero() {
+			continue
+		}
+		entries = append(entries, timelineEntry{
+			Time:      condition.LastTransitionTime.Time,
+			Type:      typeFor(condition.Status == corev1.ConditionFalse),
+			Source:    timelineSourcePod,
+			Namespace: pod.Namespace,
+			Object:    object,
+			Reason:    fmt.Sprintf("%s=%s", condition.Type, condition.Status),
+			Message:   conditionMessage(condition.Reason, condition.Message),
+		})
+	}
+
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		for _, state := range []corev1.ContainerState{status.LastTerminationState, status.State} {
+			if running := state.Running; running != nil && !running.StartedAt.IsZero() {
+				entries = append(entries, containerEntry(pod, running.StartedAt, false, "ContainerStarted", fmt.Sprintf("container %s started", status.Name)))
+			}
+			terminated := state.Terminated
+			if terminated == nil {
+				continue
+			}
+			if !terminated.StartedAt.IsZero() {
+				entries = append(entries, containerEntry(pod, terminated.StartedAt, false, "ContainerStarted", fmt.Sprintf("container %s started", status.Name)))
+			}
+			if !terminated.FinishedAt.IsZero() {
+				message := fmt.Sprintf("container %s exited with %d", status.Name, terminated.ExitCode)
+				if len(terminated.Reason) > 0 {
+					message += ": " + terminated.Reason
+				}
+				entries = append(entries, containerEntry(pod, terminated.FinishedAt, terminated.ExitCode != 0, "ContainerTerminated", message))
+			}
+		}
+	}
+	return entries
+}
+
+func containerEntry(pod *corev1.Pod, at metav1.Time, warning bool, reason, message string) timelineEntry {
+	return timelineEntry{
+		Time:      at.Time,
+		Type:      typeFor(warning),
+		Source:    timelineSourcePod,
+		Namespace: pod.Namespace,
+		Object:    objectName("Pod", pod.Name),
+		Reason:    reason,
+		Message:   message,
+	}
+}
+
+// clusterOperatorEntries returns the condition changes of a cluster operator.
+func clusterOperatorEntries(operator *configv1.ClusterOperator) []timelineEntry {
+	var entries []timelineEntry
+	for _, condition := range operator.Status.Conditions {
+		if condition.LastTransitionTime.IsZero() {
+			continue
+		}
+		unhealthy := false
+		switch condition.Type {
+		case configv1.OperatorAvailable, configv1.OperatorUpgradeable:
+			unhealthy = condition.Status == configv1.ConditionFalse
+		case configv1.OperatorDegraded:
+			unhealthy = condition.Status == configv1.ConditionTrue
+		}
+		entries = append(entries, timelineEntry{
+			Time:    condition.LastTransitionTime.Time,
+			Type:    typeFor(unhealthy),
+			Source:  timelineSourceClusterOperator,
+			Object:  objectName("ClusterOperator", operator.Name),
+			Reason:  fmt.Sprintf("%s=%s", condition.Type, condition.Status),
+			Message: conditionMessage(condition.Reason, condition.Message),
+		})
+	}
+	return entries
+}
+
+func objectName(kind, name string) string {
+	if len(kind) == 0 {
+		return name
+	}
+	return strings.ToLower(kind) + "/" + name
+}
+
+func typeFor(warning bool) string {
+	if warning {
+		return corev1.EventTypeWarning
+	}
+	return corev1.EventTypeNormal
+}
+
+func conditionMessage(reason, message string) string {
+	message = strings.Join(strings.Fields(message), " ")
+	switch {
+	case len(reason) == 0:
+		return message
+	case len(message) == 0:
+		return reason
+	default:
+		return reason + ": " + message
+	}
+}
+
+func writeTimelineCSV(w io.Writer, entries []timelineEntry) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"time", "type", "source", "namespace", "object", "reason", "message", "count"})
+	for _, entry := range entries {
+		count := ""
+		if entry.Count > 0 {
+			count = strconv.Itoa(int(entry.Count))
+		}
+		out.Write([]string{entry.Time.UTC().Format(time.RFC3339), entry.Type, entry.Source, entry.Namespace, entry.Object, entry.Reason, entry.Message, count})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func writeTimelineHTML(w io.Writer, entries []timelineEntry) error {
+	t := template.Must(template.New("timeline").Funcs(template.FuncMap{
+		"formatTime": func(t time.Time) string {
+			return t.UTC().Format(time.RFC3339)
+		},
+	}).Parse(timelineHTMLPage))
+	out := &bytes.Buffer{}
+	if err := t.Execute(out, entries); err != nil {
+		return err
+	}
+	_, err := out.WriteTo(w)
+	return err
+}
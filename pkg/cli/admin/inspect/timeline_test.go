@@ -0,0 +1,202 @@
+package inspect
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var timelineFiles = map[string]string{
+	"namespaces/demo/demo.yaml": `
+apiVersion: v1
+kind: Namespace
+metadata: {name: demo}
+`,
+	"namespaces/demo/core/events.yaml": `
+apiVersion: v1
+kind: EventList
+items:
+- metadata: {name: web-1.1, namespace: demo}
+  involvedObject: {kind: Pod, name: web-1, namespace: demo}
+  reason: BackOff
+  message: Back-off restarting failed container
+  type: Warning
+  count: 3
+  firstTimestamp: "2022-01-01T00:01:00Z"
+  lastTimestamp: "2022-01-01T00:03:00Z"
+- metadata: {name: web-1.2, namespace: demo}
+  involvedObject: {kind: Pod, name: web-1, namespace: demo}
+  reason: Scheduled
+  message: Successfully assigned demo/web-1 to node-1
+  eventTime: "2022-01-01T00:00:00.000000Z"
+`,
+	"namespaces/demo/pods/web-1/web-1.yaml": `
+apiVersion: v1
+kind: Pod
+metadata: {name: web-1, namespace: demo}
+spec:
+  containers: [{name: web, image: web}]
+status:
+  conditions:
+  - {type: Ready, status: "False", reason: ContainersNotReady, lastTransitionTime: "2022-01-01T00:02:00Z"}
+  containerStatuses:
+  - name: web
+    image: web
+    imageID: web
+    ready: false
+    restartCount: 1
+    state:
+      running: {startedAt: "2022-01-01T00:02:30Z"}
+    lastState:
+      terminated: {exitCode: 1, reason: Error, startedAt: "2022-01-01T00:00:10Z", finishedAt: "2022-01-01T00:02:00Z"}
+`,
+	"cluster-scoped-resources/config.openshift.io/clusteroperators.yaml": `
+apiVersion: config.openshift.io/v1
+kind: ClusterOperatorList
+items:
+- apiVersion: config.openshift.io/v1
+  kind: ClusterOperator
+  metadata: {name: etcd}
+  status:
+    conditions:
+    - {type: Degraded, status: "True", reason: Unhealthy, message: "member\n is unhealthy", lastTransitionTime: "2022-01-01T00:02:15Z"}
+    - {type: Available, status: "True", lastTransitionTime: "2021-12-31T00:00:00Z"}
+`,
+}
+
+func writeTimelineFiles(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "inspect-timeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range timelineFiles {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestTimeline(t *testing.T) {
+	dir := writeTimelineFiles(t)
+	defer os.RemoveAll(dir)
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewTimelineOptions(streams)
+	o.Dir = dir
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := `TIME                   TYPE      NAMESPACE   OBJECT                 REASON                MESSAGE
+2021-12-31T00:00:00Z   Normal    -           clusteroperator/etcd   Available=True        
+2022-01-01T00:00:00Z   Normal    demo        pod/web-1              Scheduled             Successfully assigned demo/web-1 to node-1
+2022-01-01T00:00:10Z   Normal    demo        pod/web-1              ContainerStarted      container web started
+2022-01-01T00:02:00Z   Warning   demo        pod/web-1              Ready=False           ContainersNotReady
+2022-01-01T00:02:00Z   Warning   demo        pod/web-1              ContainerTerminated   container web exited with 1: Error
+2022-01-01T00:02:15Z   Warning   -           clusteroperator/etcd   Degraded=True         Unhealthy: member is unhealthy
+2022-01-01T00:02:30Z   Normal    demo        pod/web-1              ContainerStarted      container web started
+2022-01-01T00:03:00Z   Warning   demo        pod/web-1              BackOff (x3)          Back-off restarting failed container
+`
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestTimelineFilters(t *testing.T) {
+	dir := writeTimelineFiles(t)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		options TimelineOptions
+		want    []string
+	}{
+		{
+			name:    "namespace",
+			options: TimelineOptions{Namespaces: []string{"demo"}, Reasons: []string{"container"}},
+			want:    []string{"ContainerStarted", "ContainerTerminated", "ContainerStarted"},
+		},
+		{
+			name:    "reason",
+			options: TimelineOptions{Reasons: []string{"degraded", "backoff"}},
+			want:    []string{"Degraded=True", "BackOff"},
+		},
+		{
+			name:    "object",
+			options: TimelineOptions{Objects: []string{"ClusterOperator/etcd"}},
+			want:    []string{"Available=True", "Degraded=True"},
+		},
+		{
+			name:    "object name",
+			options: TimelineOptions{Objects: []string{"web-1"}, Reasons: []string{"=", "sched"}},
+			want:    []string{"Scheduled", "Ready=False"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streams, _, out, _ := genericclioptions.NewTestIOStreams()
+			o := test.options
+			o.IOStreams = streams
+			o.Dir = dir
+			o.Output = "json"
+			if err := o.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if err := o.Run(); err != nil {
+				t.Fatal(err)
+			}
+			var entries []timelineEntry
+			if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+				t.Fatal(err)
+			}
+			var reasons []string
+			for _, entry := range entries {
+				reasons = append(reasons, entry.Reason)
+			}
+			if strings.Join(reasons, " ") != strings.Join(test.want, " ") {
+				t.Errorf("got %v, want %v", reasons, test.want)
+			}
+		})
+	}
+
+	if err := (&TimelineOptions{Objects: []string{"pod/"}}).Validate(); err == nil {
+		t.Error("expected an error for an object without a name")
+	}
+}
+
+func TestTimelineOutputs(t *testing.T) {
+	dir := writeTimelineFiles(t)
+	defer os.RemoveAll(dir)
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewTimelineOptions(streams)
+	o.Dir = dir
+	o.Reasons = []string{"backoff"}
+	o.Output = "csv"
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := "time,type,source,namespace,object,reason,message,count\n" +
+		"2022-01-01T00:03:00Z,Warning,Event,demo,pod/web-1,BackOff,Back-off restarting failed container,3\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	out.Reset()
+	o.Output = "html"
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `<td>BackOff (3)</td>`) || !strings.Contains(out.String(), `class="table-warning"`) {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	configv1 "github.com/openshift/api/config/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -28,11 +29,30 @@ import (
 	"k8s.io/cli-runtime/pkg/resource"
 )
 
-// resourceContext is used to keep track of previously seen objects
+// resourceContext is used to keep track of previously seen objects. It is safe for concurrent use.
 type resourceContext struct {
+	lock    sync.Mutex
 	visited sets.String
 }
 
+// visit records a key, and returns false if it was already visited.
+func (c *resourceContext) visit(key string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.visited.Has(key) {
+		return false
+	}
+	c.visited.Insert(key)
+	return true
+}
+
+// hasVisited returns true if a key was visited.
+func (c *resourceContext) hasVisited(key string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.visited.Has(key)
+}
+
 func NewResourceContext() *resourceContext {
 	return &resourceContext{
 		visited: sets.NewString(),
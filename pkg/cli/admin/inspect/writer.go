@@ -3,11 +3,14 @@ package inspect
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"os"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/printers"
+
+	"github.com/openshift/oc/pkg/helpers/redact"
 )
 
 type fileWriterSource interface {
@@ -23,8 +26,9 @@ func (t *TextWriterSource) Stream(ctx context.Context) (io.ReadCloser, error) {
 }
 
 type resourceWriterSource struct {
-	obj     runtime.Object
-	printer printers.ResourcePrinter
+	obj      runtime.Object
+	printer  printers.ResourcePrinter
+	redactor *redact.Redactor
 }
 
 func (r *resourceWriterSource) Stream(ctx context.Context) (io.ReadCloser, error) {
@@ -32,6 +36,13 @@ func (r *resourceWriterSource) Stream(ctx context.Context) (io.ReadCloser, error
 	if err := r.printer.PrintObj(r.obj, buf); err != nil {
 		return nil, err
 	}
+	if r.redactor != nil {
+		data, err := r.redactor.Document(buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		buf = bytes.NewBuffer(data)
+	}
 
 	return &resourceWriterReadCloser{buffer: buf}, nil
 }
@@ -48,9 +59,27 @@ func (r *resourceWriterReadCloser) Close() error {
 	return nil
 }
 
-type simpleFileWriter struct{}
+type simpleFileWriter struct {
+	redactor *redact.Redactor
+	limits   *sizeLimits
+	// budget is the remaining log size of a container
+	budget *logBudget
+	// truncate keeps the beginning of a file that exceeds a size limit, instead of skipping it
+	truncate bool
+}
 
 func (f *simpleFileWriter) Write(filepath string, src fileWriterSource) error {
+	if f.limits != nil {
+		if f.limits.exhausted() {
+			f.limits.skipped(filepath, fmt.Sprintf("--max-total-size=%s was reached", f.limits.manifest.MaxTotalSize))
+			return nil
+		}
+		if f.budget != nil && f.budget.remaining <= 0 {
+			f.limits.skipped(filepath, fmt.Sprintf("the logs of the container exceed --max-log-size=%s", f.limits.manifest.MaxLogSize))
+			return nil
+		}
+	}
+
 	dest, err := os.OpenFile(filepath, os.O_RDWR|os.O_CREATE, 0755)
 	if err != nil {
 		return err
@@ -63,26 +92,60 @@ func (f *simpleFileWriter) Write(filepath string, src fileWriterSource) error {
 	}
 	defer readCloser.Close()
 
-	_, err = io.Copy(dest, readCloser)
+	var out io.Writer = dest
+	var limited *limitedWriter
+	if f.limits != nil {
+		limited = &limitedWriter{w: dest, limits: f.limits, budget: f.budget}
+		out = limited
+	}
+	if f.redactor != nil {
+		err = f.redactor.CopyFile(filepath, out, readCloser)
+	} else {
+		_, err = io.Copy(out, readCloser)
+	}
+	if err != errLimitReached {
+		return err
+	}
+
+	if !f.truncate {
+		dest.Close()
+		f.limits.release(limited.written)
+		f.limits.skipped(filepath, limited.reason)
+		return os.Remove(filepath)
+	}
+	f.limits.truncated(filepath, limited.reason, limited.written)
+	_, err = fmt.Fprintf(dest, "\n[truncated by oc adm inspect: %s]\n", limited.reason)
 	return err
 }
 
 type MultiSourceFileWriter struct {
-	printer printers.ResourcePrinter
+	printer  printers.ResourcePrinter
+	redactor *redact.Redactor
+	limits   *sizeLimits
 }
 
+// WriteFromSource writes a file, which is skipped if it would exceed --max-total-size.
 func (f *MultiSourceFileWriter) WriteFromSource(filepath string, source fileWriterSource) error {
-	writer := &simpleFileWriter{}
+	writer := &simpleFileWriter{redactor: f.redactor, limits: f.limits}
+	return writer.Write(filepath, source)
+}
+
+// WriteLogFromSource writes a container log, which is truncated if it exceeds --max-total-size or
+// the remaining log size of the container.
+func (f *MultiSourceFileWriter) WriteLogFromSource(filepath string, source fileWriterSource, budget *logBudget) error {
+	writer := &simpleFileWriter{redactor: f.redactor, limits: f.limits, budget: budget, truncate: true}
 	return writer.Write(filepath, source)
 }
 
 func (f *MultiSourceFileWriter) WriteFromResource(filepath string, obj runtime.Object) error {
+	// resources are redacted as a whole, so that the field rules of the redactor apply
 	source := &resourceWriterSource{
-		obj:     obj,
-		printer: f.printer,
+		obj:      obj,
+		printer:  f.printer,
+		redactor: f.redactor,
 	}
 
-	writer := &simpleFileWriter{}
+	writer := &simpleFileWriter{limits: f.limits}
 	return writer.Write(filepath, source)
 }
 
@@ -0,0 +1,65 @@
+package inspect
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/openshift/oc/pkg/helpers/redact"
+)
+
+func TestRedactedWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inspect-redact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	printer, err := genericclioptions.NewPrintFlags("gathered").WithDefaultOutput("yaml").WithTypeSetter(scheme.Scheme).ToPrinter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	redactor, err := redact.New("vendor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := NewMultiSourceWriter(printer)
+	writer.redactor = redactor
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "demo"},
+		Spec:       corev1.PodSpec{NodeName: "ip-10-0-1-2.ec2.internal"},
+		Status:     corev1.PodStatus{PodIP: "10.128.0.5"},
+	}
+	if err := writer.WriteFromResource(filepath.Join(dir, "web.yaml"), pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteFromSource(filepath.Join(dir, "current.log"), &TextWriterSource{Text: "node ip-10-0-1-2.ec2.internal serving 10.128.0.5\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "web.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"kind: Pod", "nodeName: node-1", "podIP: ipv4-1"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected %q in:\n%s", expected, data)
+		}
+	}
+	data, err = ioutil.ReadFile(filepath.Join(dir, "current.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the node name is only known to be a node name in the pod, but the IP is the same everywhere
+	if string(data) != "node ip-10-0-1-2.ec2.internal serving ipv4-1\n" {
+		t.Errorf("unexpected log: %q", data)
+	}
+}
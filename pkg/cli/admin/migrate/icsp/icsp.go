@@ -0,0 +1,158 @@
+package icsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/openshift/oc/pkg/cli/admin/catalog"
+)
+
+var (
+	migrateICSPLong = templates.LongDesc(`
+		Convert ImageContentSourcePolicy files to ImageDigestMirrorSet files
+
+		Newer clusters configure registry mirrors with ImageDigestMirrorSet instead of the
+		deprecated ImageContentSourcePolicy. This command reads ImageContentSourcePolicy objects
+		from the given files, which may contain several YAML documents, and writes an
+		ImageDigestMirrorSet with the same name, labels and mirrors for each of them.
+
+		The converted objects are printed unless --dest-dir is set, in which case each one is
+		written to a file named after the object. The cluster is not contacted.`)
+
+	migrateICSPExample = templates.Examples(`
+		# Print the ImageDigestMirrorSets equivalent to the policies written by 'oc adm catalog mirror'
+		oc adm migrate icsp manifests-my-index/imageContentSourcePolicy.yaml
+
+		# Convert several files into a directory and apply them
+		oc adm migrate icsp icsp-1.yaml icsp-2.yaml --dest-dir idms
+		oc apply -f idms/
+	`)
+)
+
+type MigrateICSPOptions struct {
+	genericclioptions.IOStreams
+
+	Filenames []string
+	DestDir   string
+}
+
+func NewMigrateICSPOptions(streams genericclioptions.IOStreams) *MigrateICSPOptions {
+	return &MigrateICSPOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdMigrateICSP implements a command converting ImageContentSourcePolicy files.
+func NewCmdMigrateICSP(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewMigrateICSPOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "icsp FILE...",
+		Short:   "Convert ImageContentSourcePolicy files to ImageDigestMirrorSet files",
+		Long:    migrateICSPLong,
+		Example: migrateICSPExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			kcmdutil.CheckErr(o.Complete(args))
+			kcmdutil.CheckErr(o.Validate())
+			kcmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVar(&o.DestDir, "dest-dir", o.DestDir, "A directory to write the converted objects to. Defaults to printing them.")
+	return cmd
+}
+
+func (o *MigrateICSPOptions) Complete(args []string) error {
+	o.Filenames = args
+	return nil
+}
+
+func (o *MigrateICSPOptions) Validate() error {
+	if len(o.Filenames) == 0 {
+		return fmt.Errorf("at least one file containing ImageContentSourcePolicy objects is required")
+	}
+	return nil
+}
+
+func (o *MigrateICSPOptions) Run() error {
+	if len(o.DestDir) > 0 {
+		if err := os.MkdirAll(o.DestDir, 0755); err != nil {
+			return err
+		}
+	}
+	written := map[string]string{}
+	for _, filename := range o.Filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		converted, err := convertICSPs(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+		for _, c := range converted {
+			if len(o.DestDir) == 0 {
+				fmt.Fprintf(o.Out, "---\n%s", c.data)
+				continue
+			}
+			if previous, ok := written[c.name]; ok {
+				return fmt.Errorf("%s: an ImageContentSourcePolicy named %s was already converted from %s", filename, c.name, previous)
+			}
+			written[c.name] = filename
+			path := filepath.Join(o.DestDir, c.name+".yaml")
+			if err := ioutil.WriteFile(path, c.data, 0644); err != nil {
+				return err
+			}
+			fmt.Fprintf(o.Out, "wrote %s %s to %s\n", catalog.MirrorKindIDMS, c.name, path)
+		}
+	}
+	return nil
+}
+
+// convertedICSP is an ImageDigestMirrorSet converted from an ImageContentSourcePolicy.
+type convertedICSP struct {
+	name string
+	data []byte
+}
+
+// convertICSPs reads the ImageContentSourcePolicy documents in r and returns the equivalent
+// ImageDigestMirrorSets. Empty documents are ignored, and any other kind is an error.
+func convertICSPs(r io.Reader) ([]convertedICSP, error) {
+	var converted []convertedICSP
+	dec := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+		kind, name, labels, mirrors, err := catalog.UnmarshalMirrorSet(raw)
+		if err != nil {
+			return nil, err
+		}
+		if kind != catalog.MirrorKindICSP {
+			return nil, fmt.Errorf("%s %s is not an %s", kind, name, catalog.MirrorKindICSP)
+		}
+		data, err := catalog.MarshalMirrorSet(catalog.MirrorKindIDMS, name, labels, mirrors)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, convertedICSP{name: name, data: data})
+	}
+	return converted, nil
+}
@@ -0,0 +1,100 @@
+package icsp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConvertICSPs(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "multiple documents",
+			in: `---
+apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  labels:
+    operators.openshift.org/catalog: "true"
+  name: my-index-0
+spec:
+  repositoryDigestMirrors:
+  - mirrors:
+    - mirror.local/my/operator
+    source: quay.io/my/operator
+---
+apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: example
+spec:
+  repositoryDigestMirrors:
+  - mirrors:
+    - mirror.local/ocp/release
+    - backup.local/ocp/release
+    source: quay.io/openshift-release-dev/ocp-release
+`,
+			want: []string{
+				`apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  labels:
+    operators.openshift.org/catalog: "true"
+  name: my-index-0
+spec:
+  imageDigestMirrors:
+  - mirrors:
+    - mirror.local/my/operator
+    source: quay.io/my/operator
+`,
+				`apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  name: example
+spec:
+  imageDigestMirrors:
+  - mirrors:
+    - mirror.local/ocp/release
+    - backup.local/ocp/release
+    source: quay.io/openshift-release-dev/ocp-release
+`,
+			},
+		},
+		{
+			name: "other kinds are rejected",
+			in: `apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  name: example
+`,
+			wantErr: "ImageDigestMirrorSet example is not an ImageContentSourcePolicy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, err := convertICSPs(strings.NewReader(tt.in))
+			if len(tt.wantErr) > 0 {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range converted {
+				got = append(got, string(c.data))
+			}
+			if diff := cmp.Diff(tt.want, got); len(diff) > 0 {
+				t.Errorf("unexpected output:\n%s", diff)
+			}
+		})
+	}
+}
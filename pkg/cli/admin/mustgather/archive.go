@@ -0,0 +1,261 @@
+package mustgather
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/oc/pkg/cli/image/imagesource"
+)
+
+const (
+	archiveGzip = "gzip"
+	archiveZstd = "zstd"
+
+	// checksumSuffix is appended to the archive name for the file holding its SHA-256 checksum, in
+	// the format read by sha256sum --check.
+	checksumSuffix = ".sha256"
+)
+
+// archiveAndUpload archives the destination directory, and uploads the archive if requested.
+func (o *MustGatherOptions) archiveAndUpload() error {
+	if _, err := os.Stat(o.DestDir); err != nil {
+		return fmt.Errorf("nothing was gathered to archive: %v", err)
+	}
+	checksum, err := writeArchive(o.DestDir, o.Archive, o.ErrOut)
+	if err != nil {
+		return err
+	}
+	o.log("wrote %s with sha256 %s", o.Archive, checksum)
+	if o.uploadTarget == nil {
+		return nil
+	}
+	if err := uploadArchive(context.TODO(), o.uploadTarget, o.Archive, checksum); err != nil {
+		return fmt.Errorf("unable to upload %s, it must be sent manually: %v", o.Archive, err)
+	}
+	o.log("uploaded %s to %s", o.Archive, o.uploadTarget.Redacted())
+	return nil
+}
+
+// archiveFormat returns the compression of an archive from its name.
+func archiveFormat(name string) (string, error) {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return archiveGzip, nil
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return archiveZstd, nil
+	default:
+		return "", fmt.Errorf("--archive must end in .tar.gz, .tgz, .tar.zst or .tzst")
+	}
+}
+
+// validateUploadTarget checks that the target of --upload-to is an HTTP(S) endpoint or an S3 bucket.
+func validateUploadTarget(target string) (*url.URL, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("--upload-to is not a valid URL: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case "s3":
+		if parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 3); len(parts) < 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("--upload-to must be of the form s3://HOST/REGION/BUCKET[/PREFIX]")
+		}
+	default:
+		return nil, fmt.Errorf("--upload-to must be an http://, https:// or s3:// URL")
+	}
+	return u, nil
+}
+
+// isWithin returns true if name is dir or a path below it.
+func isWithin(name, dir string) (bool, error) {
+	absName, err := filepath.Abs(name)
+	if err != nil {
+		return false, err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(absDir, absName)
+	if err != nil {
+		return false, err
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))), nil
+}
+
+// writeArchive streams the contents of dir as a compressed tar into the archive, below a directory
+// with the name of dir, and writes the checksum of the archive next to it. It returns the checksum.
+func writeArchive(dir, archive string, errOut io.Writer) (string, error) {
+	format, err := archiveFormat(archive)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Create(archive)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	out := io.MultiWriter(f, hash)
+
+	var compressed io.WriteCloser
+	var wait func() error
+	switch format {
+	case archiveGzip:
+		compressed = gzip.NewWriter(out)
+		wait = func() error { return nil }
+	case archiveZstd:
+		zstd, err := exec.LookPath("zstd")
+		if err != nil {
+			return "", fmt.Errorf("the zstd command is required to write %s: %v", archive, err)
+		}
+		cmd := exec.Command(zstd, "-q", "-c")
+		cmd.Stdout = out
+		cmd.Stderr = errOut
+		if compressed, err = cmd.StdinPipe(); err != nil {
+			return "", err
+		}
+		if err := cmd.Start(); err != nil {
+			return "", err
+		}
+		wait = cmd.Wait
+	}
+
+	if err := writeTar(tar.NewWriter(compressed), dir); err != nil {
+		compressed.Close()
+		wait()
+		return "", fmt.Errorf("unable to archive %s: %v", dir, err)
+	}
+	if err := compressed.Close(); err != nil {
+		return "", err
+	}
+	if err := wait(); err != nil {
+		return "", fmt.Errorf("unable to compress %s: %v", archive, err)
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	line := fmt.Sprintf("%s  %s\n", checksum, filepath.Base(archive))
+	if err := ioutil.WriteFile(archive+checksumSuffix, []byte(line), 0644); err != nil {
+		return "", err
+	}
+	return checksum, nil
+}
+
+// writeTar writes the files below dir into tw and closes it.
+func writeTar(tw *tar.Writer, dir string) error {
+	dir = filepath.Clean(dir)
+	prefix := filepath.Base(dir)
+	err := filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(name); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// uploadArchive sends the archive and its checksum file to the target. HTTP(S) endpoints receive
+// the archive in a POST request, and S3 buckets receive both files below the key prefix given.
+func uploadArchive(ctx context.Context, target *url.URL, archive, checksum string) error {
+	if target.Scheme == "s3" {
+		for _, name := range []string{archive, archive + checksumSuffix} {
+			if err := uploadToS3(ctx, target, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	contentType := "application/gzip"
+	if format, _ := archiveFormat(archive); format == archiveZstd {
+		contentType = "application/zstd"
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(archive)))
+	req.Header.Set("X-Checksum-Sha256", checksum)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload to %s failed with %s: %s", target.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// uploadToS3 uploads a file below the key prefix of the target.
+func uploadToS3(ctx context.Context, target *url.URL, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	object := *target
+	object.Path = strings.TrimSuffix(target.Path, "/") + "/" + filepath.Base(name)
+	return imagesource.UploadObject(ctx, nil, &object, false, f)
+}
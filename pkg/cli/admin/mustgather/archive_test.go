@@ -0,0 +1,163 @@
+package mustgather
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "must-gather-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	gathered := filepath.Join(dir, "must-gather.local.1")
+	for name, content := range map[string]string{
+		"timestamp": "now\n",
+		"quay-io-must-gather/namespaces/demo/demo.yaml": "kind: Namespace\n",
+	} {
+		path := filepath.Join(gathered, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive := filepath.Join(dir, "must-gather.tar.gz")
+	checksum, err := writeArchive(gathered, archive, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != checksum {
+		t.Errorf("checksum %s does not match the archive", checksum)
+	}
+	line, err := ioutil.ReadFile(archive + checksumSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(line) != checksum+"  must-gather.tar.gz\n" {
+		t.Errorf("unexpected checksum file: %q", line)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	want := []string{
+		"must-gather.local.1/",
+		"must-gather.local.1/quay-io-must-gather/",
+		"must-gather.local.1/quay-io-must-gather/namespaces/",
+		"must-gather.local.1/quay-io-must-gather/namespaces/demo/",
+		"must-gather.local.1/quay-io-must-gather/namespaces/demo/demo.yaml",
+		"must-gather.local.1/timestamp",
+	}
+	if !reflect.DeepEqual(want, names) {
+		t.Errorf("unexpected archive contents: %v", names)
+	}
+
+	if _, err := writeArchive(gathered, filepath.Join(dir, "must-gather.zip"), ioutil.Discard); err == nil {
+		t.Error("expected an error for an unknown archive format")
+	}
+}
+
+func TestUploadArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "must-gather-upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "must-gather.tar.gz")
+	if err := ioutil.WriteFile(archive, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var received http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.URL.Path == "/denied" {
+			http.Error(w, "no case number", http.StatusForbidden)
+			return
+		}
+		received = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	target, err := validateUploadTarget(server.URL + "/upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uploadArchive(context.TODO(), target, archive, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "archive" || received.Get("X-Checksum-Sha256") != "abc" || received.Get("Content-Type") != "application/gzip" {
+		t.Errorf("unexpected upload: %v %q", received, body)
+	}
+
+	target, _ = validateUploadTarget(server.URL + "/denied")
+	if err := uploadArchive(context.TODO(), target, archive, "abc"); err == nil {
+		t.Error("expected an error when the upload is rejected")
+	}
+}
+
+func TestValidateArchiveOptions(t *testing.T) {
+	for _, target := range []string{"s3://s3.amazonaws.com/us-east-1/bucket", "s3://minio.example.com/us-east-1/bucket/prefix/", "https://example.com/upload"} {
+		if _, err := validateUploadTarget(target); err != nil {
+			t.Errorf("%s: %v", target, err)
+		}
+	}
+	for _, target := range []string{"s3://s3.amazonaws.com/us-east-1", "ftp://example.com/", "example.com/upload"} {
+		if _, err := validateUploadTarget(target); err == nil {
+			t.Errorf("%s: expected an error", target)
+		}
+	}
+
+	tests := []struct {
+		name, dir string
+		want      bool
+	}{
+		{name: "out/archive.tar.gz", dir: "out", want: true},
+		{name: "out/../archive.tar.gz", dir: "out"},
+		{name: "out.tar.gz", dir: "out"},
+		{name: "out", dir: "./out/", want: true},
+	}
+	for _, test := range tests {
+		if got, err := isWithin(test.name, test.dir); err != nil || got != test.want {
+			t.Errorf("%s in %s: got %t, %v", test.name, test.dir, got, err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package mustgather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/openshift/oc/pkg/helpers/parallel"
+)
+
+const (
+	// gatherStatusFilename records how each plug-in image fared.
+	gatherStatusFilename = "gather-status.json"
+
+	// copyAttempts is how many times the gathered data is copied before giving up, waiting a
+	// multiple of copyRetryInterval longer after each failed attempt.
+	copyAttempts      = 3
+	copyRetryInterval = 5 * time.Second
+)
+
+// gatherStatus is the outcome of running a plug-in image in one pod.
+type gatherStatus struct {
+	Image string `json:"image"`
+	Pod   string `json:"pod,omitempty"`
+	Node  string `json:"node,omitempty"`
+	// ExitCode is the exit code of the gather command, if it completed.
+	ExitCode *int32 `json:"exitCode,omitempty"`
+	TimedOut bool   `json:"timedOut,omitempty"`
+	Duration string `json:"duration"`
+	// Bytes is the size of the data copied from the pod, which may be partial if the gather failed.
+	Bytes int64  `json:"bytes"`
+	Error string `json:"error,omitempty"`
+
+	order int
+}
+
+// parseImageTimeouts reads the IMAGE=DURATION values of --image-timeout.
+func parseImageTimeouts(values []string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, value := range values {
+		i := strings.LastIndex(value, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("--image-timeout must be of the form IMAGE=DURATION, got %q", value)
+		}
+		timeout, err := time.ParseDuration(value[i+1:])
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("--image-timeout %q must have a duration higher than zero, like 5s, 2m, or 3h", value)
+		}
+		timeouts[value[:i]] = timeout
+	}
+	return timeouts, nil
+}
+
+// imageTimeout returns the length of time an image may gather data for.
+func (o *MustGatherOptions) imageTimeout(image string) time.Duration {
+	if timeout, ok := o.ImageTimeouts[image]; ok {
+		return timeout
+	}
+	return o.Timeout
+}
+
+// gatherImage runs a plug-in image on the requested nodes and copies what it gathered.
+func (o *MustGatherOptions) gatherImage(namespace, image string) ([]*gatherStatus, error) {
+	order := 0
+	for i := range o.Images {
+		if o.Images[i] == image {
+			order = i
+			break
+		}
+	}
+	var nodes []string
+	if o.NodeSelector != "" {
+		nodeList, err := o.Client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
+			LabelSelector: o.NodeSelector,
+		})
+		if err != nil {
+			return []*gatherStatus{{Image: image, Error: err.Error(), order: order}}, err
+		}
+		for _, node := range nodeList.Items {
+			nodes = append(nodes, node.Name)
+		}
+	} else {
+		nodes = []string{o.NodeName}
+	}
+
+	var statuses []*gatherStatus
+	var errs []error
+	var gatherPods []func() error
+	for _, node := range nodes {
+		pod, err := o.Client.CoreV1().Pods(namespace).Create(context.TODO(), o.newPod(node, image), metav1.CreateOptions{})
+		if err != nil {
+			statuses = append(statuses, &gatherStatus{Image: image, Node: node, Error: err.Error(), order: order})
+			errs = append(errs, err)
+			continue
+		}
+		if o.NodeSelector != "" {
+			o.log("pod: %s on node: %s for plug-in image %s created", pod.Name, node, image)
+		} else {
+			o.log("pod for plug-in image %s created", image)
+		}
+		status := &gatherStatus{Image: image, Pod: pod.Name, Node: node, order: order}
+		statuses = append(statuses, status)
+		gatherPods = append(gatherPods, func() error {
+			return o.gatherPod(pod, status)
+		})
+	}
+	errs = append(errs, parallel.Run(gatherPods...)...)
+	return statuses, errors.NewAggregate(errs)
+}
+
+// gatherPod waits for the gather of a pod to complete or to time out, and copies whatever was
+// gathered into the destination directory.
+func (o *MustGatherOptions) gatherPod(pod *corev1.Pod, status *gatherStatus) error {
+	start := time.Now()
+	deadline := start.Add(o.imageTimeout(status.Image))
+	var errs []error
+	defer func() {
+		status.Duration = time.Since(start).Round(time.Second).String()
+		if err := errors.NewAggregate(errs); err != nil {
+			status.Error = err.Error()
+		}
+	}()
+	log := newPodOutLogger(o.Out, pod.Name)
+	podName := pod.Name
+
+	// wait for gather container to be running (gather is running)
+	if err := o.waitForGatherContainerRunning(pod, time.Until(deadline)); err != nil {
+		log("gather did not start: %s", err)
+		status.TimedOut = err == wait.ErrWaitTimeout
+		errs = append(errs, fmt.Errorf("gather did not start for pod %s: %s", podName, err))
+		return errs[0]
+	}
+	// stream gather container logs while waiting for the gather, which is stopped by the timeout
+	logsDone := make(chan struct{})
+	go func() {
+		defer close(logsDone)
+		if err := o.getGatherContainerLogs(pod); err != nil {
+			log("gather logs unavailable: %v", err)
+		}
+	}()
+
+	// wait for pod to be running (gather has completed)
+	log("waiting for gather to complete")
+	if err := o.waitForGatherToComplete(pod, time.Until(deadline)); err != nil {
+		log("gather never finished: %v", err)
+		status.TimedOut = err == wait.ErrWaitTimeout
+		errs = append(errs, fmt.Errorf("gather never finished for pod %s: %s", podName, err))
+	}
+	if !status.TimedOut {
+		<-logsDone
+	}
+
+	// copy the gathered files into the local destination dir, even if the gather failed, to keep
+	// the partial data
+	log("downloading gather output")
+	pod, err := o.Client.CoreV1().Pods(pod.Namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		log("gather output not downloaded: %v\n", err)
+		errs = append(errs, fmt.Errorf("unable to download output from pod %s: %s", podName, err))
+		return errors.NewAggregate(errs)
+	}
+	status.ExitCode = gatherExitCode(pod)
+	if err := exitCodeError(podName, status.ExitCode); err != nil {
+		log("gather failed: %v", err)
+		errs = append(errs, err)
+	}
+	err = o.copyFilesFromPod(pod)
+	status.Bytes = dirSize(o.podDestDir(pod))
+	if err != nil {
+		log("gather output not downloaded: %v\n", err)
+		errs = append(errs, fmt.Errorf("unable to download output from pod %s: %s", podName, err))
+	}
+
+	if status.TimedOut && !o.Keep {
+		// stop the gather, which is still running
+		if err := o.Client.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), podName, metav1.DeleteOptions{}); err != nil {
+			log("unable to stop the gather: %v", err)
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+// gatherExitCode returns the exit code of the gather command, which the gather container writes
+// to its termination message, or nil if it did not complete.
+func gatherExitCode(pod *corev1.Pod) *int32 {
+	state := gatherContainerState(pod)
+	if state == nil || state.Terminated == nil {
+		return nil
+	}
+	exitCode := state.Terminated.ExitCode
+	if code, err := strconv.ParseInt(strings.TrimSpace(state.Terminated.Message), 10, 32); err == nil {
+		exitCode = int32(code)
+	}
+	return &exitCode
+}
+
+// exitCodeError returns an error if the gather command of a pod exited with a non-zero code. The
+// gather container itself always succeeds, so that the gathered data can still be copied.
+func exitCodeError(podName string, exitCode *int32) error {
+	if exitCode == nil || *exitCode == 0 {
+		return nil
+	}
+	return fmt.Errorf("gather failed for pod %s: exit code %d", podName, *exitCode)
+}
+
+// gatherContainerState returns the state of the gather container, if it has one yet.
+func gatherContainerState(pod *corev1.Pod) *corev1.ContainerState {
+	for _, cstate := range pod.Status.ContainerStatuses {
+		if cstate.Name == "gather" {
+			return &cstate.State
+		}
+	}
+	return nil
+}
+
+// podDestDir returns the local directory the data gathered by a pod is copied to.
+func (o *MustGatherOptions) podDestDir(pod *corev1.Pod) string {
+	imageFolder := regexp.MustCompile("[^A-Za-z0-9]+").ReplaceAllString(pod.Status.ContainerStatuses[0].ImageID, "-")
+	if o.NodeSelector != "" {
+		return path.Join(o.DestDir, regexp.MustCompile("[^A-Za-z0-9]+").ReplaceAllString(pod.Spec.NodeName, "-"), imageFolder)
+	}
+	return path.Join(o.DestDir, imageFolder)
+}
+
+// dirSize returns the size of the files below dir.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// writeGatherStatus writes the status of every plug-in image to the destination directory and
+// prints a line for each.
+func (o *MustGatherOptions) writeGatherStatus(statuses []*gatherStatus) error {
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].order != statuses[j].order {
+			return statuses[i].order < statuses[j].order
+		}
+		return statuses[i].Node < statuses[j].Node
+	})
+	for _, status := range statuses {
+		o.log("%s", status)
+	}
+	if statuses == nil {
+		statuses = []*gatherStatus{}
+	}
+	data, err := json.MarshalIndent(statuses, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(o.DestDir, gatherStatusFilename), append(data, '\n'), 0644)
+}
+
+func (s *gatherStatus) String() string {
+	var result string
+	switch {
+	case s.TimedOut:
+		result = "timed out"
+	case s.ExitCode != nil:
+		result = fmt.Sprintf("exited with %d", *s.ExitCode)
+	case len(s.Error) > 0:
+		result = "failed"
+	default:
+		result = "did not complete"
+	}
+	where := s.Image
+	if len(s.Node) > 0 {
+		where += " on node " + s.Node
+	}
+	if len(s.Duration) > 0 {
+		result += " after " + s.Duration
+	}
+	return fmt.Sprintf("plug-in image %s %s, collecting %s", where, result, units.HumanSize(float64(s.Bytes)))
+}
@@ -0,0 +1,130 @@
+package mustgather
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestParseImageTimeouts(t *testing.T) {
+	timeouts, err := parseImageTimeouts([]string{"quay.io/org/plugin:latest=30m", "registry:5000/plugin@sha256:abc=90s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{
+		"quay.io/org/plugin:latest":       30 * time.Minute,
+		"registry:5000/plugin@sha256:abc": 90 * time.Second,
+	}
+	if !reflect.DeepEqual(want, timeouts) {
+		t.Errorf("unexpected timeouts: %v", timeouts)
+	}
+	for _, value := range []string{"quay.io/org/plugin", "=5m", "quay.io/org/plugin=5", "quay.io/org/plugin=-5m"} {
+		if _, err := parseImageTimeouts([]string{value}); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+
+	o := &MustGatherOptions{Timeout: time.Minute, ImageTimeouts: want}
+	if o.imageTimeout("quay.io/org/plugin:latest") != 30*time.Minute || o.imageTimeout("other") != time.Minute {
+		t.Error("unexpected image timeouts")
+	}
+}
+
+func TestGatherExitCode(t *testing.T) {
+	podWithState := func(state corev1.ContainerState) *corev1.Pod {
+		return &corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "copy"},
+			{Name: "gather", State: state},
+		}}}
+	}
+	tests := []struct {
+		name  string
+		state corev1.ContainerState
+		want  *int32
+	}{
+		{name: "running", state: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+		{name: "from the termination message", state: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "3\n"}}, want: int32Ptr(3)},
+		{name: "killed", state: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}}, want: int32Ptr(137)},
+	}
+	for _, test := range tests {
+		if got := gatherExitCode(podWithState(test.state)); !reflect.DeepEqual(test.want, got) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if err := exitCodeError("must-gather-abcde", int32Ptr(0)); err != nil {
+		t.Errorf("unexpected error for a successful gather: %v", err)
+	}
+	if err := exitCodeError("must-gather-abcde", nil); err != nil {
+		t.Errorf("unexpected error for a gather that did not complete: %v", err)
+	}
+	if err := exitCodeError("must-gather-abcde", int32Ptr(3)); err == nil || !strings.Contains(err.Error(), "exit code 3") {
+		t.Errorf("expected an error for a failed gather, got %v", err)
+	}
+
+	if got := gatherCommand("/usr/bin/gather"); got != "/usr/bin/gather; status=$?; sync; echo $status > /dev/termination-log" {
+		t.Errorf("unexpected command: %s", got)
+	}
+}
+
+func TestWriteGatherStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "must-gather-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "plugin", "logs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "plugin", "logs", "a.log"), make([]byte, 1500), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "plugin", "b.yaml"), make([]byte, 500), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &MustGatherOptions{IOStreams: streams, LogOut: streams.Out, DestDir: dir}
+	statuses := []*gatherStatus{
+		{Image: "second", Pod: "must-gather-b", TimedOut: true, Duration: "1m0s", Bytes: dirSize(filepath.Join(dir, "plugin")), order: 1},
+		{Image: "first", Pod: "must-gather-a", ExitCode: int32Ptr(0), Duration: "10s", order: 0},
+		{Image: "third", Error: "pods is forbidden", order: 2},
+	}
+	if err := o.writeGatherStatus(statuses); err != nil {
+		t.Fatal(err)
+	}
+	wantOut := `plug-in image first exited with 0 after 10s, collecting 0B
+plug-in image second timed out after 1m0s, collecting 2kB
+plug-in image third failed, collecting 0B
+`
+	if out.String() != wantOut {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, gatherStatusFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written []map[string]interface{}
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 3 || written[0]["exitCode"] != float64(0) || written[1]["bytes"] != float64(2000) || written[1]["timedOut"] != true {
+		t.Errorf("unexpected status file:\n%s", data)
+	}
+	if strings.Contains(string(data), "order") {
+		t.Errorf("unexpected internal field in status file:\n%s", data)
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
@@ -5,10 +5,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/rand"
+	"net/url"
 	"os"
 	"path"
-	"regexp"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,13 +24,17 @@ import (
 	"github.com/openshift/library-go/pkg/image/imageutil"
 	imagereference "github.com/openshift/library-go/pkg/image/reference"
 	"github.com/openshift/library-go/pkg/operator/resource/retry"
+	"github.com/openshift/oc/pkg/cli/admin/alerts"
 	"github.com/openshift/oc/pkg/cli/admin/inspect"
 	"github.com/openshift/oc/pkg/cli/rsync"
+	"github.com/openshift/oc/pkg/helpers/parallel"
+	"github.com/openshift/oc/pkg/helpers/redact"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
@@ -48,6 +54,25 @@ var (
 		This command will launch a pod in a temporary namespace on your cluster that gathers
 		debugging information and then downloads the gathered information.
 
+		The plug-in images run at the same time unless --run-serially or --parallel=false is
+		passed, and each may gather data for as long as --timeout, or --image-timeout for that
+		image. Whatever an image gathered is downloaded even if it fails or times out, and the
+		exit code, duration and size of the data of each image are saved to gather-status.json
+		in the destination directory.
+
+		The firing alerts are included in the summary printed before and after gathering, and
+		all alerts are saved to alerts.json in the destination directory.
+
+		The plug-in images decide what is gathered. Use --redact to remove sensitive data
+		from every file downloaded, as with 'oc adm inspect --redact'.
+
+		Use --archive to compress the gathered data into a .tar.gz or .tar.zst file, with its
+		SHA-256 checksum written next to it in a .sha256 file, and --upload-to to send the
+		archive to an HTTP endpoint in a POST request, or to an S3 bucket given as
+		s3://HOST/REGION/BUCKET[/PREFIX]. S3 credentials are read from the AWS environment
+		variables or the shared AWS credentials file. Writing .tar.zst files requires the zstd
+		command.
+
 		Experimental: This command is under active development and may change without notice.
 	`)
 
@@ -64,11 +89,20 @@ var (
 		# Gather information using multiple plug-in images
 		  oc adm must-gather --image=quay.io/kubevirt/must-gather --image=quay.io/openshift/origin-must-gather
 
+		# Gather information with two plug-in images one after another, giving the second one an hour
+		  oc adm must-gather --run-serially --image=quay.io/openshift/origin-must-gather --image=quay.io/kubevirt/must-gather --image-timeout=quay.io/kubevirt/must-gather=1h
+
 		# Gather information using a specific image stream plug-in
 		  oc adm must-gather --image-stream=openshift/must-gather:latest
 
 		# Gather information using a specific image, command, and pod-dir
 		  oc adm must-gather --image=my/image:tag --source-dir=/pod/directory -- myspecial-command.sh
+
+		# Gather information without IP addresses, hostnames, usernames and tokens, keeping the pseudonyms used
+		  oc adm must-gather --redact=vendor --redaction-map=redaction-map.json
+
+		# Gather information into an archive and upload it to an S3 bucket
+		  oc adm must-gather --archive=must-gather.tar.gz --upload-to=s3://s3.amazonaws.com/us-east-1/support-bucket/case-1234/
 	`)
 )
 
@@ -93,7 +127,14 @@ func NewMustGatherCommand(f kcmdutil.Factory, streams genericclioptions.IOStream
 	cmd.Flags().StringSliceVar(&o.ImageStreams, "image-stream", o.ImageStreams, "Specify an image stream (namespace/name:tag) containing a must-gather plugin image to run.")
 	cmd.Flags().StringVar(&o.DestDir, "dest-dir", o.DestDir, "Set a specific directory on the local machine to write gathered data to.")
 	cmd.Flags().StringVar(&o.SourceDir, "source-dir", o.SourceDir, "Set the specific directory on the pod copy the gathered data from.")
-	cmd.Flags().StringVar(&o.timeoutStr, "timeout", "10m", "The length of time to gather data, like 5s, 2m, or 3h, higher than zero. Defaults to 10 minutes.")
+	cmd.Flags().StringVar(&o.timeoutStr, "timeout", "10m", "The length of time to gather data with each plug-in image, like 5s, 2m, or 3h, higher than zero. Defaults to 10 minutes.")
+	cmd.Flags().StringArrayVar(&o.imageTimeoutStrs, "image-timeout", o.imageTimeoutStrs, "The length of time to gather data with a plug-in image, as IMAGE=DURATION, where IMAGE is as passed to --image. Overrides --timeout for that image.")
+	cmd.Flags().BoolVar(&o.RunSerially, "run-serially", o.RunSerially, "Run the plug-in images one after another, in the order given, instead of all at once.")
+	cmd.Flags().BoolVar(&o.parallel, "parallel", true, "If false, run the plug-in images one after another, in the order given, as with --run-serially.")
+	cmd.Flags().StringSliceVar(&o.RedactProfiles, "redact", o.RedactProfiles, fmt.Sprintf("Redact sensitive data from every file downloaded, using built-in profiles (%s) or profile files.", strings.Join(redact.BuiltinProfiles(), ", ")))
+	cmd.Flags().StringVar(&o.RedactionMap, "redaction-map", o.RedactionMap, "A file to read pseudonyms from and to save them to when redacting. Must not be inside the destination directory.")
+	cmd.Flags().StringVar(&o.Archive, "archive", o.Archive, "Compress the gathered data into this .tar.gz or .tar.zst file, and write its checksum next to it.")
+	cmd.Flags().StringVar(&o.UploadTo, "upload-to", o.UploadTo, "Upload the archive to an http:// or https:// URL, or to an s3://HOST/REGION/BUCKET[/PREFIX] bucket. Defaults --archive to the destination directory with a .tar.gz extension.")
 	cmd.Flags().BoolVar(&o.Keep, "keep", o.Keep, "Do not delete temporary resources when command completes.")
 	cmd.Flags().MarkHidden("keep")
 
@@ -111,6 +152,12 @@ func NewMustGatherOptions(streams genericclioptions.IOStreams) *MustGatherOption
 }
 
 func (o *MustGatherOptions) Complete(f kcmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Changed("parallel") {
+		if cmd.Flags().Changed("run-serially") {
+			return fmt.Errorf("--run-serially and --parallel are mutually exclusive: please specify one or the other")
+		}
+		o.RunSerially = !o.parallel
+	}
 	o.RESTClientGetter = f
 	var err error
 	if o.Config, err = f.ToRESTConfig(); err != nil {
@@ -148,9 +195,15 @@ func (o *MustGatherOptions) Complete(f kcmdutil.Factory, cmd *cobra.Command, arg
 	if len(o.DestDir) == 0 {
 		o.DestDir = fmt.Sprintf("must-gather.local.%06d", rand.Int63())
 	}
+	if len(o.UploadTo) > 0 && len(o.Archive) == 0 {
+		o.Archive = filepath.Clean(o.DestDir) + ".tar.gz"
+	}
 	if err := o.completeImages(); err != nil {
 		return err
 	}
+	if o.ImageTimeouts, err = parseImageTimeouts(o.imageTimeoutStrs); err != nil {
+		return err
+	}
 	o.PrinterCreated, err = printers.NewTypeSetter(scheme.Scheme).WrapToPrinter(&printers.NamePrinter{Operation: "created"}, nil)
 	if err != nil {
 		return err
@@ -160,6 +213,16 @@ func (o *MustGatherOptions) Complete(f kcmdutil.Factory, cmd *cobra.Command, arg
 		return err
 	}
 	o.RsyncRshCmd = rsync.DefaultRsyncRemoteShellToUse(cmd)
+	if len(o.RedactProfiles) > 0 {
+		if o.Redactor, err = redact.New(o.RedactProfiles...); err != nil {
+			return err
+		}
+		if len(o.RedactionMap) > 0 {
+			if err := o.Redactor.LoadMap(o.RedactionMap); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -239,8 +302,22 @@ type MustGatherOptions struct {
 	timeoutStr   string
 	Keep         bool
 
+	// ImageTimeouts overrides Timeout for some images.
+	ImageTimeouts    map[string]time.Duration
+	imageTimeoutStrs []string
+	RunSerially      bool
+	parallel         bool
+
 	RsyncRshCmd string
 
+	RedactProfiles []string
+	RedactionMap   string
+	Redactor       *redact.Redactor
+
+	Archive      string
+	UploadTo     string
+	uploadTarget *url.URL
+
 	PrinterCreated printers.ResourcePrinter
 	PrinterDeleted printers.ResourcePrinter
 	LogOut         io.Writer
@@ -255,13 +332,64 @@ func (o *MustGatherOptions) Validate() error {
 	if o.NodeName != "" && o.NodeSelector != "" {
 		return fmt.Errorf("--node-name and --node-selector are mutually exclusive: please specify one or the other")
 	}
+	images := sets.NewString(o.Images...)
+	for image := range o.ImageTimeouts {
+		if !images.Has(image) {
+			return fmt.Errorf("--image-timeout is set for %s, which is not one of the images to run", image)
+		}
+	}
+	if len(o.RedactionMap) > 0 {
+		if len(o.RedactProfiles) == 0 {
+			return fmt.Errorf("--redaction-map may only be specified with --redact")
+		}
+		if err := redact.ValidateMapPath(o.RedactionMap, o.DestDir); err != nil {
+			return err
+		}
+	}
+	if len(o.Archive) > 0 {
+		if _, err := archiveFormat(o.Archive); err != nil {
+			return err
+		}
+		within, err := isWithin(o.Archive, o.DestDir)
+		if err != nil {
+			return err
+		}
+		if within {
+			return fmt.Errorf("--archive must not be inside the destination directory %s", o.DestDir)
+		}
+	}
+	if len(o.UploadTo) > 0 {
+		target, err := validateUploadTarget(o.UploadTo)
+		if err != nil {
+			return err
+		}
+		o.uploadTarget = target
+	}
 	return nil
 }
 
 // Run creates and runs a must-gather pod.d
-func (o *MustGatherOptions) Run() error {
+func (o *MustGatherOptions) Run() (err error) {
 	var errs []error
 
+	// the archive is written last, once nothing else will be written to the destination directory
+	if len(o.Archive) > 0 {
+		defer func() {
+			if archiveErr := o.archiveAndUpload(); archiveErr != nil {
+				err = errors.NewAggregate([]error{err, archiveErr})
+			}
+		}()
+	}
+
+	// the pseudonyms are saved last, as the backup collection shares the redactor
+	if o.Redactor != nil && len(o.RedactionMap) > 0 {
+		defer func() {
+			if err := o.Redactor.WriteMap(o.RedactionMap); err != nil {
+				fmt.Fprintf(o.ErrOut, "error writing redaction map: %v\n", err)
+			}
+		}()
+	}
+
 	// print at both the beginning and at the end.  This information is important enough to be in both spots.
 	o.PrintBasicClusterState(context.TODO())
 	defer func() {
@@ -323,37 +451,12 @@ func (o *MustGatherOptions) Run() error {
 		}()
 	}
 
-	// ... and finally must-gather pod
-	var pods []*corev1.Pod
+	// ... and finally must-gather pods
 	for _, image := range o.Images {
-		_, err := imagereference.Parse(image)
-		if err != nil {
+		if _, err := imagereference.Parse(image); err != nil {
 			o.log("unable to parse image reference %s: %v", image, err)
 			return err
 		}
-		if o.NodeSelector != "" {
-			nodes, err := o.Client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{
-				LabelSelector: o.NodeSelector,
-			})
-			if err != nil {
-				return err
-			}
-			for _, node := range nodes.Items {
-				pod, err := o.Client.CoreV1().Pods(ns.Name).Create(context.TODO(), o.newPod(node.Name, image), metav1.CreateOptions{})
-				if err != nil {
-					return err
-				}
-				o.log("pod: %s on node: %s for plug-in image %s created", pod.Name, node.Name, image)
-				pods = append(pods, pod)
-			}
-		} else {
-			pod, err := o.Client.CoreV1().Pods(ns.Name).Create(context.TODO(), o.newPod(o.NodeName, image), metav1.CreateOptions{})
-			if err != nil {
-				return err
-			}
-			o.log("pod for plug-in image %s created", image)
-			pods = append(pods, pod)
-		}
 	}
 
 	// log timestamps...
@@ -365,54 +468,30 @@ func (o *MustGatherOptions) Run() error {
 	}
 	defer o.logTimestamp()
 
-	var wg sync.WaitGroup
-	wg.Add(len(pods))
-	errCh := make(chan error, len(pods))
-	for _, pod := range pods {
-		go func(pod *corev1.Pod) {
-			defer wg.Done()
-
-			log := newPodOutLogger(o.Out, pod.Name)
-
-			// wait for gather container to be running (gather is running)
-			if err := o.waitForGatherContainerRunning(pod); err != nil {
-				log("gather did not start: %s", err)
-				errCh <- fmt.Errorf("gather did not start for pod %s: %s", pod.Name, err)
-				return
-			}
-			// stream gather container logs
-			if err := o.getGatherContainerLogs(pod); err != nil {
-				log("gather logs unavailable: %v", err)
-			}
-
-			// wait for pod to be running (gather has completed)
-			log("waiting for gather to complete")
-			if err := o.waitForGatherToComplete(pod); err != nil {
-				log("gather never finished: %v", err)
-				errCh <- fmt.Errorf("gather never finished for pod %s: %s", pod.Name, err)
-				return
-			}
-
-			// copy the gathered files into the local destination dir
-			log("downloading gather output")
-			pod, err = o.Client.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
-			if err != nil {
-				log("gather output not downloaded: %v\n", err)
-				errCh <- fmt.Errorf("unable to download output from pod %s: %s", pod.Name, err)
-				return
-			}
-			if err := o.copyFilesFromPod(pod); err != nil {
-				log("gather output not downloaded: %v\n", err)
-				errCh <- fmt.Errorf("unable to download output from pod %s: %s", pod.Name, err)
-				return
+	var statusLock sync.Mutex
+	var statuses []*gatherStatus
+	gatherImages := make([]func() error, 0, len(o.Images))
+	for _, image := range o.Images {
+		image := image
+		gatherImages = append(gatherImages, func() error {
+			imageStatuses, err := o.gatherImage(ns.Name, image)
+			statusLock.Lock()
+			defer statusLock.Unlock()
+			statuses = append(statuses, imageStatuses...)
+			return err
+		})
+	}
+	if o.RunSerially {
+		for _, gatherImage := range gatherImages {
+			if err := gatherImage(); err != nil {
+				errs = append(errs, err)
 			}
-		}(pod)
+		}
+	} else {
+		errs = append(errs, parallel.Run(gatherImages...)...)
 	}
-	wg.Wait()
-	close(errCh)
-
-	for i := range errCh {
-		errs = append(errs, i)
+	if err := o.writeGatherStatus(statuses); err != nil {
+		errs = append(errs, err)
 	}
 	if len(errs) == 0 {
 		// If we didn't have an error during collection, then we don't need to do our backup collection.
@@ -428,6 +507,10 @@ func (o *MustGatherOptions) Run() error {
 	if err := inspect.CreateEventFilterPage(o.DestDir); err != nil {
 		errs = append(errs, err)
 	}
+	// alerts are helpful but not essential, so failing to get them is not an error
+	if err := o.writeAlerts(context.TODO()); err != nil {
+		fmt.Fprintf(o.ErrOut, "unable to save alerts: %v\n", err)
+	}
 
 	return errors.NewAggregate(errs)
 }
@@ -452,16 +535,24 @@ func (o *MustGatherOptions) logTimestamp() error {
 	return err
 }
 
+// writeAlerts saves the alerts of the cluster into the destination directory.
+func (o *MustGatherOptions) writeAlerts(ctx context.Context) error {
+	_, data, err := alerts.Fetch(ctx, o.Client)
+	if err != nil {
+		return err
+	}
+	if o.Redactor != nil {
+		if data, err = o.Redactor.Document(data); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(path.Join(o.DestDir, "alerts.json"), data, 0644)
+}
+
 func (o *MustGatherOptions) copyFilesFromPod(pod *corev1.Pod) error {
 	streams := o.IOStreams
 	streams.Out = newPrefixWriter(streams.Out, fmt.Sprintf("[%s] OUT", pod.Name))
-	imageFolder := regexp.MustCompile("[^A-Za-z0-9]+").ReplaceAllString(pod.Status.ContainerStatuses[0].ImageID, "-")
-	var destDir string
-	if o.NodeSelector != "" {
-		destDir = path.Join(o.DestDir, regexp.MustCompile("[^A-Za-z0-9]+").ReplaceAllString(pod.Spec.NodeName, "-"), imageFolder)
-	} else {
-		destDir = path.Join(o.DestDir, imageFolder)
-	}
+	destDir := o.podDestDir(pod)
 	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
 		return err
 	}
@@ -477,11 +568,22 @@ func (o *MustGatherOptions) copyFilesFromPod(pod *corev1.Pod) error {
 		IOStreams:     streams,
 	}
 	rsyncOptions.Strategy = rsync.NewDefaultCopyStrategy(rsyncOptions)
-	err := rsyncOptions.RunRsync()
-	if err != nil {
-		klog.V(4).Infof("re-trying rsync after initial failure %v", err)
-		// re-try copying data before letting it go
-		err = rsyncOptions.RunRsync()
+	// re-try copying data before letting it go, rsync picks up where the previous attempt stopped
+	var err error
+	for attempt := 1; attempt <= copyAttempts; attempt++ {
+		if err = rsyncOptions.RunRsync(); err == nil {
+			break
+		}
+		if attempt < copyAttempts {
+			klog.V(4).Infof("re-trying rsync after failed attempt %d: %v", attempt, err)
+			time.Sleep(time.Duration(attempt) * copyRetryInterval)
+		}
+	}
+	// redact what was copied even if the copy failed, the partial data is kept
+	if o.Redactor != nil {
+		if err := o.Redactor.Dir(destDir); err != nil {
+			return fmt.Errorf("unable to redact gathered data, remove %s before sharing it: %v", destDir, err)
+		}
 	}
 	return err
 }
@@ -531,8 +633,8 @@ func newPrefixWriter(out io.Writer, prefix string) io.Writer {
 	return writer
 }
 
-func (o *MustGatherOptions) waitForGatherToComplete(pod *corev1.Pod) error {
-	return wait.PollImmediate(10*time.Second, o.Timeout, func() (bool, error) {
+func (o *MustGatherOptions) waitForGatherToComplete(pod *corev1.Pod, timeout time.Duration) error {
+	return wait.PollImmediate(10*time.Second, timeout, func() (bool, error) {
 		return o.isGatherDone(pod)
 	})
 }
@@ -546,13 +648,7 @@ func (o *MustGatherOptions) isGatherDone(pod *corev1.Pod) (bool, error) {
 		}
 		return false, nil
 	}
-	var state *corev1.ContainerState
-	for _, cstate := range pod.Status.ContainerStatuses {
-		if cstate.Name == "gather" {
-			state = &cstate.State
-			break
-		}
-	}
+	state := gatherContainerState(pod)
 
 	// missing status for gather container => timeout in the worst case
 	if state == nil {
@@ -568,8 +664,8 @@ func (o *MustGatherOptions) isGatherDone(pod *corev1.Pod) (bool, error) {
 	return false, nil
 }
 
-func (o *MustGatherOptions) waitForGatherContainerRunning(pod *corev1.Pod) error {
-	return wait.PollImmediate(10*time.Second, o.Timeout, func() (bool, error) {
+func (o *MustGatherOptions) waitForGatherContainerRunning(pod *corev1.Pod, timeout time.Duration) error {
+	return wait.PollImmediate(10*time.Second, timeout, func() (bool, error) {
 		var err error
 		if pod, err = o.Client.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{}); err == nil {
 			if len(pod.Status.ContainerStatuses) == 0 {
@@ -616,6 +712,13 @@ func (o *MustGatherOptions) newClusterRoleBinding(ns string) *rbacv1.ClusterRole
 	}
 }
 
+// gatherCommand runs command and flushes what it gathered to disk. The exit code of command is
+// written to the termination message rather than returned, so that a failing command still lets
+// the gathered data be copied.
+func gatherCommand(command string) string {
+	return fmt.Sprintf("%s; status=$?; sync; echo $status > %s", command, corev1.TerminationMessagePathDefault)
+}
+
 // newPod creates a pod with 2 containers with a shared volume mount:
 // - gather: init containers that run gather command
 // - copy: no-op container we can exec into
@@ -657,7 +760,7 @@ func (o *MustGatherOptions) newPod(node, image string) *corev1.Pod {
 					Image:           image,
 					ImagePullPolicy: corev1.PullIfNotPresent,
 					// always force disk flush to ensure that all data gathered is accessible in the copy container
-					Command: []string{"/bin/bash", "-c", "/usr/bin/gather; sync"},
+					Command: []string{"/bin/bash", "-c", gatherCommand("/usr/bin/gather")},
 					Env: []corev1.EnvVar{
 						{
 							Name: "NODE_NAME",
@@ -710,7 +813,7 @@ func (o *MustGatherOptions) newPod(node, image string) *corev1.Pod {
 	}
 	if len(o.Command) > 0 {
 		// always force disk flush to ensure that all data gathered is accessible in the copy container
-		ret.Spec.Containers[0].Command = []string{"/bin/bash", "-c", fmt.Sprintf("%s; sync", strings.Join(o.Command, " "))}
+		ret.Spec.Containers[0].Command = []string{"/bin/bash", "-c", gatherCommand(strings.Join(o.Command, " "))}
 	}
 	if o.HostNetwork {
 		// If a user specified hostNetwork he might have intended to perform
@@ -739,6 +842,7 @@ func (o *MustGatherOptions) BackupGathering(ctx context.Context, errs []error) {
 	inspectOptions := inspect.NewInspectOptions(o.IOStreams)
 	inspectOptions.RESTConfig = rest.CopyConfig(o.Config)
 	inspectOptions.DestDir = path.Join(o.DestDir, fmt.Sprintf("inspect.local.%06d", rand.Int63()))
+	inspectOptions.Redactor = o.Redactor
 
 	if err := inspectOptions.Complete([]string{"clusteroperators.v1.config.openshift.io"}); err != nil {
 		fmt.Fprintf(o.ErrOut, "error completing backup collection: %v\n", err)
@@ -9,6 +9,7 @@ import (
 	"github.com/docker/go-units"
 	configv1 "github.com/openshift/api/config/v1"
 	"github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
+	"github.com/openshift/oc/pkg/cli/admin/alerts"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
@@ -39,7 +40,12 @@ func (o *MustGatherOptions) PrintBasicClusterState(ctx context.Context) {
 	fmt.Fprintf(o.RawOut, "ClusterOperators:\n")
 	fmt.Fprintf(o.RawOut, humanSummaryForInterestingClusterOperators(clusterOperators)+"\n")
 
-	// TODO gather and display firing alerts
+	fmt.Fprintf(o.RawOut, "Alerts:\n")
+	if firing, _, err := alerts.Fetch(ctx, o.Client); err != nil {
+		fmt.Fprintf(o.RawOut, "\terror getting alerts: %v\n", err)
+	} else {
+		fmt.Fprintln(o.RawOut, alerts.HumanSummary(firing, time.Now()))
+	}
 	fmt.Fprintf(o.RawOut, "\n\n")
 }
 
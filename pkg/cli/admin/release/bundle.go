@@ -0,0 +1,487 @@
+package release
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/pkg/archive"
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/yaml"
+
+	imagereference "github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/oc/pkg/cli/admin/catalog"
+	"github.com/openshift/oc/pkg/cli/image/imagesource"
+	imagemanifest "github.com/openshift/oc/pkg/cli/image/manifest"
+	"github.com/openshift/oc/pkg/cli/image/mirror"
+)
+
+const (
+	// bundleManifestFile is written to the root of every bundle and describes its contents.
+	bundleManifestFile = "bundle.yaml"
+	// bundleManifestKind identifies a bundle manifest.
+	bundleManifestKind = "ReleaseBundle"
+
+	// bundleSignaturesDir holds the release image signature config maps within a bundle.
+	bundleSignaturesDir = "signatures"
+	// bundleCatalogsDir holds the catalog mirror mappings within a bundle.
+	bundleCatalogsDir = "catalogs"
+
+	// bundleReleaseRepository, bundleCatalogRepository and bundleImageRepository are the
+	// file:// locations within a bundle that releases, catalogs and additional images are
+	// stored under.
+	bundleReleaseRepository = "file://openshift/release"
+	bundleCatalogRepository = "file://catalogs"
+	bundleImageRepository   = "images"
+)
+
+// BundleConfig describes the content that is packaged into a bundle.
+type BundleConfig struct {
+	// Releases are the release images to include.
+	Releases []BundleRelease `json:"releases,omitempty"`
+	// Catalogs are the operator catalog index images to include.
+	Catalogs []BundleCatalog `json:"catalogs,omitempty"`
+	// Images are additional images to include.
+	Images []string `json:"images,omitempty"`
+}
+
+// BundleRelease is a release image included in a bundle.
+type BundleRelease struct {
+	// Image is the pull spec of the release image.
+	Image string `json:"image"`
+}
+
+// BundleCatalog is an operator catalog included in a bundle.
+type BundleCatalog struct {
+	// Index is the pull spec of the catalog index image.
+	Index string `json:"index"`
+	// Packages, if set, limits the bundle to the named operator packages.
+	Packages []string `json:"packages,omitempty"`
+}
+
+// bundleManifest records where each input of a bundle was stored.
+type bundleManifest struct {
+	Kind     string           `json:"kind"`
+	Releases []bundledRelease `json:"releases,omitempty"`
+	Catalogs []bundledCatalog `json:"catalogs,omitempty"`
+	Images   []bundledImage   `json:"images,omitempty"`
+}
+
+type bundledRelease struct {
+	// Source is the pull spec the release was bundled from.
+	Source string `json:"source"`
+	// Version is the version of the release.
+	Version string `json:"version"`
+	// Image is the file:// location of the release image in the bundle.
+	Image string `json:"image"`
+}
+
+type bundledCatalog struct {
+	// Source is the pull spec the catalog index was bundled from.
+	Source string `json:"source"`
+	// Image is the file:// location of the catalog index in the bundle.
+	Image string `json:"image"`
+	// Mapping is the path of the mapping from the original images of the catalog to
+	// their location in the bundle, relative to the bundle root.
+	Mapping string `json:"mapping"`
+}
+
+type bundledImage struct {
+	// Source is the pull spec the image was bundled from.
+	Source string `json:"source"`
+	// Image is the file:// location of the image in the bundle.
+	Image string `json:"image"`
+}
+
+// NewBundleOptions creates the options for bundling release content.
+func NewBundleOptions(streams genericclioptions.IOStreams) *BundleOptions {
+	return &BundleOptions{
+		IOStreams:       streams,
+		ParallelOptions: imagemanifest.ParallelOptions{MaxPerRegistry: 6},
+	}
+}
+
+// NewBundle creates a command that packages releases, catalogs and images for a disconnected
+// environment.
+func NewBundle(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewBundleOptions(streams)
+	cmd := &cobra.Command{
+		Use:   "bundle --config=FILE (--to-dir=DIR | --to-file=FILE)",
+		Short: "Package releases, catalogs and images for a disconnected environment",
+		Long: templates.LongDesc(`
+			Package release images, operator catalogs and additional images into a single
+			directory or archive that can be carried into a disconnected environment.
+
+			The content to package is described by a configuration file:
+
+			    releases:
+			    - image: quay.io/openshift-release-dev/ocp-release:4.10.0-x86_64
+			    catalogs:
+			    - index: registry.redhat.io/redhat/redhat-operator-index:v4.10
+			    images:
+			    - registry.example.com/tools/debug:latest
+
+			Release images are mirrored together with their release image signatures, catalogs
+			are mirrored with every image they reference, and the additional images are copied
+			as is. A bundle.yaml file at the root of the bundle records where each input was
+			stored. Use 'oc adm release unbundle' to push the contents of a bundle into a
+			registry and generate the manifests needed to use the mirrored content.
+		`),
+		Example: templates.Examples(`
+			# Package the content described in bundle-config.yaml into a directory
+			oc adm release bundle --config bundle-config.yaml --to-dir /mnt/bundle
+
+			# Package the content described in bundle-config.yaml into an archive
+			oc adm release bundle --config bundle-config.yaml --to-file bundle.tar.gz
+		`),
+		Run: func(cmd *cobra.Command, args []string) {
+			kcmdutil.CheckErr(o.Complete(cmd, args))
+			kcmdutil.CheckErr(o.Validate())
+			kcmdutil.CheckErr(o.Run())
+		},
+	}
+	flags := cmd.Flags()
+	o.SecurityOptions.Bind(flags)
+	o.ParallelOptions.Bind(flags)
+
+	flags.StringVar(&o.ConfigFile, "config", o.ConfigFile, "A file describing the releases, catalogs and images to bundle.")
+	flags.StringVar(&o.ToDir, "to-dir", o.ToDir, "A directory to write the bundle to.")
+	flags.StringVar(&o.ToFile, "to-file", o.ToFile, "A gzipped tar file to write the bundle to.")
+	flags.BoolVar(&o.DryRun, "dry-run", o.DryRun, "Display information about the bundle without copying any images.")
+	return cmd
+}
+
+type BundleOptions struct {
+	genericclioptions.IOStreams
+
+	SecurityOptions imagemanifest.SecurityOptions
+	ParallelOptions imagemanifest.ParallelOptions
+
+	ConfigFile string
+	ToDir      string
+	ToFile     string
+	DryRun     bool
+
+	Config *BundleConfig
+
+	cmd *cobra.Command
+}
+
+func (o *BundleOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("bundle does not accept arguments")
+	}
+	if len(o.ConfigFile) == 0 {
+		return fmt.Errorf("--config is required")
+	}
+	data, err := ioutil.ReadFile(o.ConfigFile)
+	if err != nil {
+		return err
+	}
+	config := &BundleConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return fmt.Errorf("unable to load bundle configuration %s: %v", o.ConfigFile, err)
+	}
+	o.Config = config
+	o.cmd = cmd
+	return nil
+}
+
+func (o *BundleOptions) Validate() error {
+	if len(o.ToDir) == 0 && len(o.ToFile) == 0 {
+		return fmt.Errorf("must specify --to-dir or --to-file")
+	}
+	if o.Config == nil {
+		return fmt.Errorf("a bundle configuration is required")
+	}
+	if len(o.Config.Releases) == 0 && len(o.Config.Catalogs) == 0 && len(o.Config.Images) == 0 {
+		return fmt.Errorf("the bundle configuration must contain at least one release, catalog or image")
+	}
+	for i, release := range o.Config.Releases {
+		if len(release.Image) == 0 {
+			return fmt.Errorf("releases[%d]: image is required", i)
+		}
+	}
+	for i, c := range o.Config.Catalogs {
+		if len(c.Index) == 0 {
+			return fmt.Errorf("catalogs[%d]: index is required", i)
+		}
+	}
+	for _, image := range o.Config.Images {
+		ref, err := imagesource.ParseReference(image)
+		if err != nil {
+			return fmt.Errorf("images: %v", err)
+		}
+		if ref.Type != imagesource.DestinationRegistry {
+			return fmt.Errorf("images: %s must be an image in a registry", image)
+		}
+		if len(ref.Ref.Tag) == 0 && len(ref.Ref.ID) == 0 {
+			return fmt.Errorf("images: %s must have a tag or digest", image)
+		}
+	}
+	return nil
+}
+
+func (o *BundleOptions) Run() error {
+	dir := o.ToDir
+	if len(dir) == 0 {
+		tmp, err := ioutil.TempDir("", "release-bundle")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	manifest := &bundleManifest{Kind: bundleManifestKind}
+	for _, release := range o.Config.Releases {
+		bundled, err := o.bundleRelease(dir, release)
+		if err != nil {
+			return fmt.Errorf("unable to bundle release %s: %v", release.Image, err)
+		}
+		manifest.Releases = append(manifest.Releases, *bundled)
+	}
+	for _, c := range o.Config.Catalogs {
+		bundled, err := o.bundleCatalog(dir, c)
+		if err != nil {
+			return fmt.Errorf("unable to bundle catalog %s: %v", c.Index, err)
+		}
+		manifest.Catalogs = append(manifest.Catalogs, *bundled)
+	}
+	if len(o.Config.Images) > 0 {
+		images, err := o.bundleImages(dir, o.Config.Images)
+		if err != nil {
+			return fmt.Errorf("unable to bundle images: %v", err)
+		}
+		manifest.Images = images
+	}
+
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, bundleManifestFile), data, 0644); err != nil {
+		return err
+	}
+
+	if len(o.ToFile) > 0 {
+		if err := writeBundleArchive(dir, o.ToFile); err != nil {
+			return fmt.Errorf("unable to write bundle archive: %v", err)
+		}
+		fmt.Fprintf(o.Out, "Wrote bundle to %s\n", o.ToFile)
+		return nil
+	}
+	fmt.Fprintf(o.Out, "Wrote bundle to %s\n", dir)
+	return nil
+}
+
+func (o *BundleOptions) bundleRelease(dir string, release BundleRelease) (*bundledRelease, error) {
+	m := NewMirrorOptions(o.IOStreams)
+	m.SecurityOptions = o.SecurityOptions
+	m.ParallelOptions = o.ParallelOptions
+	m.DryRun = o.DryRun
+	m.From = release.Image
+	m.To = bundleReleaseRepository
+	m.ToDir = dir
+	m.ReleaseImageSignatureToDir = filepath.Join(dir, bundleSignaturesDir)
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if err := m.Run(); err != nil {
+		return nil, err
+	}
+	return &bundledRelease{
+		Source:  release.Image,
+		Version: m.ImageStream.Name,
+		Image:   imagesource.TypedImageReference{Type: imagesource.DestinationFile, Ref: m.TargetFn("")}.String(),
+	}, nil
+}
+
+func (o *BundleOptions) bundleCatalog(dir string, c BundleCatalog) (*bundledCatalog, error) {
+	src, err := imagesource.ParseReference(c.Index)
+	if err != nil {
+		return nil, err
+	}
+	mappingDir := filepath.Join(bundleCatalogsDir, bundleCatalogName(src))
+
+	m := catalog.NewMirrorCatalogOptions(o.IOStreams)
+	m.SecurityOptions = o.SecurityOptions
+	m.ParallelOptions = o.ParallelOptions
+	m.DryRun = o.DryRun
+	m.FileDir = dir
+	m.ManifestDir = filepath.Join(dir, mappingDir)
+	m.PackageFilter.Packages = c.Packages
+	if err := m.Complete(o.cmd, []string{c.Index, bundleCatalogRepository}); err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if err := m.Run(); err != nil {
+		return nil, err
+	}
+
+	mapping, err := readBundleMapping(filepath.Join(m.ManifestDir, "mapping.txt"))
+	if err != nil {
+		return nil, err
+	}
+	index, ok := mapping[src.String()]
+	if !ok {
+		return nil, fmt.Errorf("the catalog index was not mirrored")
+	}
+	return &bundledCatalog{
+		Source:  c.Index,
+		Image:   index.String(),
+		Mapping: filepath.ToSlash(filepath.Join(mappingDir, "mapping.txt")),
+	}, nil
+}
+
+func (o *BundleOptions) bundleImages(dir string, images []string) ([]bundledImage, error) {
+	var bundled []bundledImage
+	var mappings []mirror.Mapping
+	for _, image := range images {
+		src, err := imagesource.ParseReference(image)
+		if err != nil {
+			return nil, err
+		}
+		dst := bundleImageLocation(src)
+		mappings = append(mappings, mirror.Mapping{Source: src, Destination: dst})
+		if len(src.Ref.ID) > 0 {
+			dst.Ref.Tag = ""
+			dst.Ref.ID = src.Ref.ID
+		}
+		bundled = append(bundled, bundledImage{Source: image, Image: dst.String()})
+	}
+
+	// images are copied with every variant so that digests are preserved
+	allManifests := imagemanifest.FilterOptions{FilterByOS: ".*"}
+	if err := allManifests.Validate(); err != nil {
+		return nil, err
+	}
+	m := mirror.NewMirrorImageOptions(o.IOStreams)
+	m.SecurityOptions = o.SecurityOptions
+	m.ParallelOptions = o.ParallelOptions
+	m.FilterOptions = allManifests
+	m.KeepManifestList = true
+	m.DryRun = o.DryRun
+	m.FileDir = dir
+	m.Mappings = mappings
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if err := m.Run(); err != nil {
+		return nil, err
+	}
+	return bundled, nil
+}
+
+// bundleCatalogName returns a name for a catalog index that is safe to use as a directory name.
+func bundleCatalogName(ref imagesource.TypedImageReference) string {
+	name := ref.Ref.RepositoryName()
+	if len(ref.Ref.Tag) > 0 {
+		name = name + "-" + ref.Ref.Tag
+	}
+	return strings.NewReplacer("/", "-", ":", "-", "@", "-").Replace(name)
+}
+
+// bundleImageLocation returns the repository in a bundle that an additional image is stored in.
+// The registry is dropped so that the image keeps the same repository path when it is pushed
+// to a mirror registry.
+func bundleImageLocation(src imagesource.TypedImageReference) imagesource.TypedImageReference {
+	ref := src.Ref.DockerClientDefaults()
+	return imagesource.TypedImageReference{
+		Type: imagesource.DestinationFile,
+		Ref: imagereference.DockerImageReference{
+			Namespace: bundleImageRepository,
+			Name:      strings.TrimPrefix(ref.Namespace+"/"+ref.Name, "/"),
+			Tag:       src.Ref.Tag,
+		},
+	}
+}
+
+// readBundleMapping reads a mapping.txt file written by 'oc adm catalog mirror' and returns
+// the destination of each source keyed by the source pull spec.
+func readBundleMapping(path string) (map[string]imagesource.TypedImageReference, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mapping := make(map[string]imagesource.TypedImageReference)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if len(line) == 0 {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s: invalid mapping %q", path, line)
+		}
+		dst, err := imagesource.ParseReference(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		mapping[parts[0]] = dst
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// readBundleManifest loads the manifest at the root of a bundle.
+func readBundleManifest(dir string) (*bundleManifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, bundleManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a bundle: %v", dir, err)
+	}
+	manifest := &bundleManifest{}
+	if err := yaml.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("unable to load bundle manifest: %v", err)
+	}
+	if manifest.Kind != bundleManifestKind {
+		return nil, fmt.Errorf("%s is not a bundle: unrecognized kind %q", dir, manifest.Kind)
+	}
+	return manifest, nil
+}
+
+// writeBundleArchive writes the contents of dir as a gzipped tar file to path.
+func writeBundleArchive(dir, path string) error {
+	r, err := archive.TarWithOptions(dir, &archive.TarOptions{Compression: archive.Gzip})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// extractBundleArchive extracts the bundle archive at path into dir.
+func extractBundleArchive(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return archive.Untar(f, dir, &archive.TarOptions{NoLchown: true})
+}
@@ -21,8 +21,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
@@ -43,6 +41,7 @@ import (
 	"github.com/openshift/library-go/pkg/verify/store/configmap"
 	"github.com/openshift/library-go/pkg/verify/store/sigstore"
 	"github.com/openshift/library-go/pkg/verify/util"
+	"github.com/openshift/oc/pkg/cli/admin/catalog"
 	"github.com/openshift/oc/pkg/cli/image/extract"
 	"github.com/openshift/oc/pkg/cli/image/imagesource"
 	imagemanifest "github.com/openshift/oc/pkg/cli/image/manifest"
@@ -69,8 +68,9 @@ var archMap = map[string]string{
 // NewMirrorOptions creates the options for mirroring a release.
 func NewMirrorOptions(streams genericclioptions.IOStreams) *MirrorOptions {
 	return &MirrorOptions{
-		IOStreams:       streams,
-		ParallelOptions: imagemanifest.ParallelOptions{MaxPerRegistry: 6},
+		IOStreams:        streams,
+		ParallelOptions:  imagemanifest.ParallelOptions{MaxPerRegistry: 6},
+		OutputMirrorKind: catalog.MirrorKindICSP,
 	}
 }
 
@@ -162,6 +162,7 @@ func NewMirror(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.C
 
 	flags.BoolVar(&o.SkipRelease, "skip-release-image", o.SkipRelease, "Do not push the release image.")
 	flags.StringVar(&o.ToRelease, "to-release-image", o.ToRelease, "Specify an alternate locations for the release image instead as tag 'release' in --to.")
+	flags.StringVar(&o.OutputMirrorKind, "output-mirror-kind", o.OutputMirrorKind, "The kind of mirror configuration printed for using the mirrored release. Allowed values: ImageContentSourcePolicy, ImageDigestMirrorSet.")
 	flags.BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "Used with --apply-release-image-signature to update an existing signature configmap.")
 	return cmd
 }
@@ -191,12 +192,18 @@ type MirrorOptions struct {
 
 	DryRun                        bool
 	PrintImageContentInstructions bool
+	// OutputMirrorKind is the kind of mirror configuration printed with the image content instructions.
+	OutputMirrorKind string
 
 	ImageClientFn  func() (imageclient.Interface, string, error)
 	CoreV1ClientFn func() (corev1client.ConfigMapInterface, error)
 
 	ImageStream *imagev1.ImageStream
 	TargetFn    func(component string) imagereference.DockerImageReference
+
+	// Repositories is set by Run to the sorted list of source repositories that hold the
+	// images referenced by the release.
+	Repositories []string
 }
 
 func (o *MirrorOptions) Complete(cmd *cobra.Command, f kcmdutil.Factory, args []string) error {
@@ -255,6 +262,11 @@ func (o *MirrorOptions) Validate() error {
 	if len(o.From) == 0 && o.ImageStream == nil {
 		return fmt.Errorf("must specify a release image with --from")
 	}
+	if len(o.OutputMirrorKind) > 0 {
+		if err := catalog.ValidateOutputMirrorKind(o.OutputMirrorKind); err != nil {
+			return err
+		}
+	}
 
 	outputs := 0
 	if len(o.To) > 0 {
@@ -638,6 +650,11 @@ func (o *MirrorOptions) Run() error {
 	if len(mappings) == 0 {
 		fmt.Fprintf(o.ErrOut, "warning: Release image contains no image references - is this a valid release?\n")
 	}
+	o.Repositories = make([]string, 0, len(repositories))
+	for repository := range repositories {
+		o.Repositories = append(o.Repositories, repository)
+	}
+	sort.Strings(o.Repositories)
 
 	if o.ToMirror {
 		for _, mapping := range mappings {
@@ -812,7 +829,7 @@ func (o *MirrorOptions) Run() error {
 		}
 	} else if len(toList) > 0 {
 		if o.PrintImageContentInstructions {
-			if err := printImageContentInstructions(o.Out, o.From, toList, o.ReleaseImageSignatureToDir, repositories); err != nil {
+			if err := printImageContentInstructions(o.Out, o.From, toList, o.ReleaseImageSignatureToDir, o.OutputMirrorKind, repositories); err != nil {
 				return fmt.Errorf("Error creating mirror usage instructions: %v", err)
 			}
 		}
@@ -841,9 +858,9 @@ func (o *MirrorOptions) Run() error {
 
 // printImageContentInstructions provides examples to the user for using the new repository mirror
 // https://github.com/openshift/installer/blob/master/docs/dev/alternative_release_image_sources.md
-func printImageContentInstructions(out io.Writer, from string, toList []string, signatureToDir string, repositories map[string]struct{}) error {
-	type installConfigSubsection struct {
-		ImageContentSources []operatorv1alpha1.RepositoryDigestMirrors `json:"imageContentSources"`
+func printImageContentInstructions(out io.Writer, from string, toList []string, signatureToDir, mirrorKind string, repositories map[string]struct{}) error {
+	if len(mirrorKind) == 0 {
+		mirrorKind = catalog.MirrorKindICSP
 	}
 
 	var sources []operatorv1alpha1.RepositoryDigestMirrors
@@ -884,43 +901,26 @@ func printImageContentInstructions(out io.Writer, from string, toList []string,
 	uniqueSources := dedupeSortSources(sources)
 	sources = uniqueSources
 
-	// Create and display install-config.yaml example
-	imageContentSources := installConfigSubsection{
-		ImageContentSources: sources}
-	installConfigExample, err := yaml.Marshal(imageContentSources)
+	// Create and display install-config.yaml example, newer installers replace
+	// imageContentSources with imageDigestSources
+	installConfigKey := "imageContentSources"
+	if mirrorKind == catalog.MirrorKindIDMS {
+		installConfigKey = "imageDigestSources"
+	}
+	installConfigExample, err := yaml.Marshal(map[string][]operatorv1alpha1.RepositoryDigestMirrors{installConfigKey: sources})
 	if err != nil {
 		return fmt.Errorf("Unable to marshal install-config.yaml example yaml: %v", err)
 	}
 	fmt.Fprintf(out, "\nTo use the new mirrored repository to install, add the following section to the install-config.yaml:\n\n")
 	fmt.Fprintf(out, string(installConfigExample))
 
-	// Create and display ImageContentSourcePolicy example
-	icsp := operatorv1alpha1.ImageContentSourcePolicy{
-		TypeMeta: metav1.TypeMeta{
-			APIVersion: operatorv1alpha1.GroupVersion.String(),
-			Kind:       "ImageContentSourcePolicy"},
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "example",
-		},
-		Spec: operatorv1alpha1.ImageContentSourcePolicySpec{
-			RepositoryDigestMirrors: sources,
-		},
-	}
-
-	// Create an unstructured object for removing creationTimestamp
-	unstructuredObj := unstructured.Unstructured{}
-	unstructuredObj.Object, err = runtime.DefaultUnstructuredConverter.ToUnstructured(&icsp)
-	if err != nil {
-		return fmt.Errorf("ToUnstructured error: %v", err)
-	}
-	delete(unstructuredObj.Object["metadata"].(map[string]interface{}), "creationTimestamp")
-
-	icspExample, err := yaml.Marshal(unstructuredObj.Object)
+	// Create and display mirror configuration example
+	example, err := marshalMirrorSet(mirrorKind, "example", sources)
 	if err != nil {
-		return fmt.Errorf("Unable to marshal ImageContentSourcePolicy example yaml: %v", err)
+		return err
 	}
-	fmt.Fprintf(out, "\n\nTo use the new mirrored repository for upgrades, use the following to create an ImageContentSourcePolicy:\n\n")
-	fmt.Fprintf(out, string(icspExample))
+	fmt.Fprintf(out, "\n\nTo use the new mirrored repository for upgrades, use the following to create an %s:\n\n", mirrorKind)
+	fmt.Fprintf(out, string(example))
 
 	if len(signatureToDir) != 0 {
 		fmt.Fprintf(out, "\n\nTo apply signature configmaps use 'oc apply' on files found in %s\n\n", signatureToDir)
@@ -929,6 +929,16 @@ func printImageContentInstructions(out io.Writer, from string, toList []string,
 	return nil
 }
 
+// marshalMirrorSet returns the YAML for a mirror configuration of the given kind with the
+// given name and mirrors.
+func marshalMirrorSet(kind, name string, sources []operatorv1alpha1.RepositoryDigestMirrors) ([]byte, error) {
+	mirrors := make([]catalog.ImageMirrors, 0, len(sources))
+	for _, source := range sources {
+		mirrors = append(mirrors, catalog.ImageMirrors{Source: source.Source, Mirrors: source.Mirrors})
+	}
+	return catalog.MarshalMirrorSet(kind, name, nil, mirrors)
+}
+
 // HTTPClient provides a method for generating an HTTP client
 // with the proxy and trust settings, if set in the cluster.
 func (o *MirrorOptions) HTTPClient() (*http.Client, error) {
@@ -15,6 +15,7 @@ import (
 	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -92,6 +93,12 @@ func NewRelease(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.
 
 			will override the default cluster-version-operator image with one pulled from
 			registry.example.com.
+
+			To build a release that is byte-for-byte identical every time it is built from the
+			same inputs, pass --source-date-epoch or set the SOURCE_DATE_EPOCH environment
+			variable to a fixed number of seconds since the Unix epoch. That time is used for
+			the creation time and default name of the release and for every file in the
+			release layer, including image-references and release-metadata.
 		`),
 		Example: templates.Examples(`
 			# Create a release from the latest origin images and push to a DockerHub repo
@@ -107,6 +114,10 @@ func NewRelease(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.
 
 			# Run a verification pass to ensure the release can be reproduced
 			oc adm release new --from-release registry.svc.ci.openshift.org/origin/release:v4.1
+
+			# Create a reproducible release whose timestamps are taken from the last commit
+			SOURCE_DATE_EPOCH=$(git log -1 --format=%ct) oc adm release new --from-dir ./manifests \
+				--name 4.1.1 --to-file release.tar.gz
 		`),
 		Run: func(cmd *cobra.Command, args []string) {
 			kcmdutil.CheckErr(o.Complete(f, cmd, args))
@@ -130,6 +141,7 @@ func NewRelease(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.
 
 	// properties of the release
 	flags.StringVar(&o.Name, "name", o.Name, "The name of the release. Will default to the current time.")
+	flags.StringVar(&o.SourceDateEpoch, "source-date-epoch", o.SourceDateEpoch, "Seconds since the Unix epoch to use for every timestamp in the release instead of the current time. Defaults to the SOURCE_DATE_EPOCH environment variable.")
 	flags.StringSliceVar(&o.PreviousVersions, "previous", o.PreviousVersions, "A list of semantic versions that should precede this version in the release manifest.")
 	flags.StringVar(&o.ReleaseMetadata, "metadata", o.ReleaseMetadata, "A JSON object to attach as the metadata for the release manifest.")
 	flags.BoolVar(&o.ForceManifest, "release-manifest", o.ForceManifest, "If true, a release manifest will be created using --name as the semantic version.")
@@ -188,6 +200,11 @@ type NewOptions struct {
 	ReleaseMetadata  string
 	PreviousVersions []string
 
+	// SourceDateEpoch, if set, is the number of seconds since the Unix epoch that
+	// replaces the current time and the modification times of input manifests so
+	// that the same inputs always produce the same release.
+	SourceDateEpoch string
+
 	DryRun bool
 
 	ToFile         string
@@ -228,6 +245,10 @@ func (o *NewOptions) Complete(f kcmdutil.Factory, cmd *cobra.Command, args []str
 	mappings = append(mappings, argMappings...)
 	o.Mappings = mappings
 
+	if !cmd.Flags().Changed("source-date-epoch") {
+		o.SourceDateEpoch = os.Getenv("SOURCE_DATE_EPOCH")
+	}
+
 	if len(o.FromImageStream) > 0 {
 		cfg, err := f.ToRESTConfig()
 		if err != nil {
@@ -351,6 +372,10 @@ func (o *NewOptions) Run() error {
 			return fmt.Errorf("invalid --metadata: %v", err)
 		}
 	}
+	sourceDate, err := parseSourceDateEpoch(o.SourceDateEpoch)
+	if err != nil {
+		return fmt.Errorf("--source-date-epoch is invalid: %v", err)
+	}
 
 	hasMetadataOverrides := len(o.Name) > 0 ||
 		len(o.ReleaseMetadata) > 0 ||
@@ -376,6 +401,9 @@ func (o *NewOptions) Run() error {
 	var ordered []string
 	var is *imageapi.ImageStream
 	now := time.Now().UTC().Truncate(time.Second)
+	if sourceDate != nil {
+		now = *sourceDate
+	}
 
 	switch {
 	case len(o.FromReleaseImage) > 0:
@@ -505,6 +533,8 @@ func (o *NewOptions) Run() error {
 		} else {
 			klog.V(2).Infof("No metadata changes, building canonical release")
 			now = is.CreationTimestamp.Time.UTC()
+			// the canonical release already carries its own timestamps
+			sourceDate = nil
 			if o.VerifyOutputFn == nil {
 				o.VerifyOutputFn = func(actual digest.Digest) error {
 					// TODO: check contents, digests, image stream, the layers, and the manifest
@@ -757,7 +787,7 @@ func (o *NewOptions) Run() error {
 	pr, pw := io.Pipe()
 	go func() {
 		var err error
-		operators, err = writePayload(pw, is, cm, ordered, metadata, o.AllowMissingImages, verifiers)
+		operators, err = writePayload(pw, is, cm, ordered, metadata, o.AllowMissingImages, verifiers, sourceDate)
 		pw.CloseWithError(err)
 	}()
 
@@ -1305,7 +1335,27 @@ func writeNestedTarHeader(tw *tar.Writer, parts []string, existing map[string]st
 	return nil
 }
 
-func writePayload(w io.Writer, is *imageapi.ImageStream, cm *CincinnatiMetadata, ordered []string, metadata map[string]imageData, allowMissingImages bool, verifiers []PayloadVerifier) ([]string, error) {
+// parseSourceDateEpoch parses a SOURCE_DATE_EPOCH style value (whole seconds since the Unix
+// epoch) and returns nil if value is empty.
+func parseSourceDateEpoch(value string) (*time.Time, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("must be an integer number of seconds since the Unix epoch: %v", err)
+	}
+	if seconds < 0 {
+		return nil, fmt.Errorf("must not be negative")
+	}
+	t := time.Unix(seconds, 0).UTC()
+	return &t, nil
+}
+
+// writePayload writes the release-manifests layer for the release to w as a gzipped tar. If
+// sourceDate is set every entry in the layer uses it as its modification time, otherwise the
+// modification times of the extracted manifests are preserved.
+func writePayload(w io.Writer, is *imageapi.ImageStream, cm *CincinnatiMetadata, ordered []string, metadata map[string]imageData, allowMissingImages bool, verifiers []PayloadVerifier, sourceDate *time.Time) ([]string, error) {
 	var operators []string
 	directories := make(map[string]struct{})
 	files := make(map[string]int)
@@ -1314,7 +1364,9 @@ func writePayload(w io.Writer, is *imageapi.ImageStream, cm *CincinnatiMetadata,
 
 	// find the newest content date in the input
 	var newest time.Time
-	if err := iterateExtractedManifests(ordered, metadata, func(contents []os.FileInfo, name string, image imageData) error {
+	if sourceDate != nil {
+		newest = *sourceDate
+	} else if err := iterateExtractedManifests(ordered, metadata, func(contents []os.FileInfo, name string, image imageData) error {
 		for _, fi := range contents {
 			if fi.IsDir() {
 				continue
@@ -1416,7 +1468,11 @@ func writePayload(w io.Writer, is *imageapi.ImageStream, cm *CincinnatiMetadata,
 			if err != nil {
 				return err
 			}
-			if err := tw.WriteHeader(&tar.Header{Mode: 0444, ModTime: fi.ModTime(), Typeflag: tar.TypeReg, Name: dst, Size: int64(len(modified))}); err != nil {
+			modTime := fi.ModTime()
+			if sourceDate != nil {
+				modTime = *sourceDate
+			}
+			if err := tw.WriteHeader(&tar.Header{Mode: 0444, ModTime: modTime, Typeflag: tar.TypeReg, Name: dst, Size: int64(len(modified))}); err != nil {
 				return err
 			}
 			klog.V(6).Infof("Writing payload to %s\n%s", dst, string(modified))
@@ -0,0 +1,81 @@
+package release
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	imageapi "github.com/openshift/api/image/v1"
+)
+
+func Test_parseSourceDateEpoch(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    *time.Time
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "seconds", value: "1600000000", want: timePtr(time.Unix(1600000000, 0).UTC())},
+		{name: "zero", value: "0", want: timePtr(time.Unix(0, 0).UTC())},
+		{name: "negative", value: "-1", wantErr: true},
+		{name: "not a number", value: "2020-09-13", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSourceDateEpoch(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSourceDateEpoch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("parseSourceDateEpoch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writePayload_sourceDate(t *testing.T) {
+	sourceDate := time.Unix(1600000000, 0).UTC()
+	build := func(modTime time.Time) []byte {
+		dir, err := ioutil.TempDir("", "release-payload")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		operatorDir := filepath.Join(dir, "operator")
+		if err := os.MkdirAll(operatorDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(operatorDir, "0000_50_operator_deployment.yaml")
+		if err := ioutil.WriteFile(file, []byte("kind: Deployment\napiVersion: apps/v1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+
+		is := &imageapi.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "0.0.1", CreationTimestamp: metav1.Time{Time: sourceDate}}}
+		cm := &CincinnatiMetadata{Kind: "cincinnati-metadata-v0", Version: "0.0.1", Previous: []string{}}
+		metadata := map[string]imageData{"operator": {Directory: operatorDir}}
+		buf := &bytes.Buffer{}
+		if _, err := writePayload(buf, is, cm, []string{"operator"}, metadata, false, nil, &sourceDate); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	first := build(time.Now())
+	second := build(time.Now().Add(-48 * time.Hour))
+	if !bytes.Equal(first, second) {
+		t.Errorf("payloads built with the same source date differ")
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,434 @@
+package release
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	imagereference "github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/oc/pkg/cli/admin/catalog"
+	"github.com/openshift/oc/pkg/cli/image/imagesource"
+	imagemanifest "github.com/openshift/oc/pkg/cli/image/manifest"
+	"github.com/openshift/oc/pkg/cli/image/mirror"
+)
+
+// NewUnbundleOptions creates the options for pushing the contents of a bundle to a registry.
+func NewUnbundleOptions(streams genericclioptions.IOStreams) *UnbundleOptions {
+	return &UnbundleOptions{
+		IOStreams:         streams,
+		ParallelOptions:   imagemanifest.ParallelOptions{MaxPerRegistry: 6},
+		ToManifests:       "unbundle-manifests",
+		MaxPathComponents: 2,
+		OutputMirrorKind:  catalog.MirrorKindICSP,
+	}
+}
+
+// NewUnbundle creates a command that pushes a bundle created by 'oc adm release bundle' into a
+// registry.
+func NewUnbundle(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewUnbundleOptions(streams)
+	cmd := &cobra.Command{
+		Use:   "unbundle (--from-dir=DIR | --from-file=FILE) --to=REGISTRY/NAMESPACE",
+		Short: "Push the contents of a bundle into a registry",
+		Long: templates.LongDesc(`
+			Push the releases, catalogs and images in a bundle created by 'oc adm release bundle'
+			into a registry.
+
+			Every image is pushed under the --to location using the repository path it had
+			before it was bundled. The manifests needed to use the mirrored content are written
+			to --to-manifests: an imageContentSourcePolicy.yaml for the releases and additional
+			images, the release image signature config maps in 'signatures', and the
+			ImageContentSourcePolicy and CatalogSource of each catalog in 'catalogs'. Pass
+			--output-mirror-kind=ImageDigestMirrorSet to write ImageDigestMirrorSets, and
+			ImageTagMirrorSets for catalog images referenced by tag, instead.
+		`),
+		Example: templates.Examples(`
+			# Push the contents of a bundle directory into a registry
+			oc adm release unbundle --from-dir /mnt/bundle --to myregistry.local/mirror
+
+			# Push the contents of a bundle archive into a registry and apply the manifests
+			oc adm release unbundle --from-file bundle.tar.gz --to myregistry.local/mirror
+			oc apply -R -f unbundle-manifests/
+		`),
+		Run: func(cmd *cobra.Command, args []string) {
+			kcmdutil.CheckErr(o.Complete(cmd, args))
+			kcmdutil.CheckErr(o.Validate())
+			kcmdutil.CheckErr(o.Run())
+		},
+	}
+	flags := cmd.Flags()
+	o.SecurityOptions.Bind(flags)
+	o.ParallelOptions.Bind(flags)
+
+	flags.StringVar(&o.FromDir, "from-dir", o.FromDir, "A bundle directory to push.")
+	flags.StringVar(&o.FromFile, "from-file", o.FromFile, "A bundle archive to push.")
+	flags.StringVar(&o.To, "to", o.To, "The registry and optional namespace to push the contents of the bundle to.")
+	flags.StringVar(&o.ToManifests, "to-manifests", o.ToManifests, "A directory to write the manifests for using the mirrored content to.")
+	flags.IntVar(&o.MaxPathComponents, "max-components", o.MaxPathComponents, "The maximum number of path components allowed in a destination mapping for catalog content.")
+	flags.StringVar(&o.OutputMirrorKind, "output-mirror-kind", o.OutputMirrorKind, "The kind of mirror configuration written to --to-manifests. Allowed values: ImageContentSourcePolicy, ImageDigestMirrorSet.")
+	flags.BoolVar(&o.DryRun, "dry-run", o.DryRun, "Display information about what would be pushed without pushing any images.")
+	return cmd
+}
+
+type UnbundleOptions struct {
+	genericclioptions.IOStreams
+
+	SecurityOptions imagemanifest.SecurityOptions
+	ParallelOptions imagemanifest.ParallelOptions
+
+	FromDir           string
+	FromFile          string
+	To                string
+	ToManifests       string
+	MaxPathComponents int
+	OutputMirrorKind  string
+	DryRun            bool
+
+	cmd *cobra.Command
+}
+
+func (o *UnbundleOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unbundle does not accept arguments")
+	}
+	o.To = strings.TrimSuffix(o.To, "/")
+	o.cmd = cmd
+	return nil
+}
+
+func (o *UnbundleOptions) Validate() error {
+	if (len(o.FromDir) == 0) == (len(o.FromFile) == 0) {
+		return fmt.Errorf("exactly one of --from-dir or --from-file must be specified")
+	}
+	if len(o.To) == 0 {
+		return fmt.Errorf("--to is required")
+	}
+	ref, err := imagesource.ParseReference(o.To)
+	if err != nil {
+		return fmt.Errorf("--to must be a registry or a repository: %v", err)
+	}
+	if ref.Type != imagesource.DestinationRegistry {
+		return fmt.Errorf("--to must be a registry")
+	}
+	if len(ref.Ref.Tag) > 0 || len(ref.Ref.ID) > 0 {
+		return fmt.Errorf("--to may not contain a tag or digest")
+	}
+	if len(o.ToManifests) == 0 {
+		return fmt.Errorf("--to-manifests is required")
+	}
+	if o.MaxPathComponents == 1 || o.MaxPathComponents < 0 {
+		return fmt.Errorf("--max-components must be 0 (no limit) or greater than 1")
+	}
+	return catalog.ValidateOutputMirrorKind(o.OutputMirrorKind)
+}
+
+func (o *UnbundleOptions) Run() error {
+	dir := o.FromDir
+	if len(o.FromFile) > 0 {
+		tmp, err := ioutil.TempDir("", "release-bundle")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		if err := extractBundleArchive(o.FromFile, tmp); err != nil {
+			return fmt.Errorf("unable to extract bundle archive: %v", err)
+		}
+		dir = tmp
+	}
+	manifest, err := readBundleManifest(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(o.ToManifests, 0755); err != nil {
+		return err
+	}
+
+	var icsps [][]byte
+	for _, release := range manifest.Releases {
+		icsp, err := o.unbundleRelease(dir, release)
+		if err != nil {
+			return fmt.Errorf("unable to push release %s: %v", release.Version, err)
+		}
+		icsps = append(icsps, icsp)
+	}
+	if len(manifest.Releases) > 0 {
+		if err := copyBundleSignatures(filepath.Join(dir, bundleSignaturesDir), filepath.Join(o.ToManifests, bundleSignaturesDir)); err != nil {
+			return fmt.Errorf("unable to copy release image signatures: %v", err)
+		}
+	}
+	if len(manifest.Images) > 0 {
+		icsp, err := o.unbundleImages(dir, manifest.Images)
+		if err != nil {
+			return fmt.Errorf("unable to push images: %v", err)
+		}
+		if icsp != nil {
+			icsps = append(icsps, icsp)
+		}
+	}
+	if len(icsps) > 0 {
+		var data []byte
+		for _, icsp := range icsps {
+			data = append(data, []byte("---\n")...)
+			data = append(data, icsp...)
+		}
+		if err := ioutil.WriteFile(filepath.Join(o.ToManifests, catalog.MirrorSetFilename(o.OutputMirrorKind)), data, 0644); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range manifest.Catalogs {
+		if err := o.unbundleCatalog(dir, c); err != nil {
+			return fmt.Errorf("unable to push catalog %s: %v", c.Source, err)
+		}
+	}
+
+	fmt.Fprintf(o.Out, "Wrote mirroring manifests to %s\n", o.ToManifests)
+	return nil
+}
+
+func (o *UnbundleOptions) unbundleRelease(dir string, release bundledRelease) ([]byte, error) {
+	src, err := imagereference.Parse(release.Source)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := unbundleTarget(o.To, src)
+	if err != nil {
+		return nil, err
+	}
+	dst.Tag, dst.ID = "", ""
+
+	m := NewMirrorOptions(o.IOStreams)
+	m.SecurityOptions = o.SecurityOptions
+	m.ParallelOptions = o.ParallelOptions
+	m.DryRun = o.DryRun
+	m.From = release.Image
+	m.FromDir = dir
+	m.To = dst.Exact()
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if err := m.Run(); err != nil {
+		return nil, err
+	}
+
+	var sources []operatorv1alpha1.RepositoryDigestMirrors
+	for _, repository := range append([]string{src.AsRepository().Exact()}, m.Repositories...) {
+		sources = append(sources, operatorv1alpha1.RepositoryDigestMirrors{Source: repository, Mirrors: []string{dst.Exact()}})
+	}
+	return marshalMirrorSet(o.OutputMirrorKind, unbundleObjectName("release-"+release.Version), dedupeSortSources(sources))
+}
+
+func (o *UnbundleOptions) unbundleImages(dir string, images []bundledImage) ([]byte, error) {
+	var mappings []mirror.Mapping
+	var sources []operatorv1alpha1.RepositoryDigestMirrors
+	for _, image := range images {
+		src, err := imagesource.ParseReference(image.Image)
+		if err != nil {
+			return nil, err
+		}
+		original, err := imagereference.Parse(image.Source)
+		if err != nil {
+			return nil, err
+		}
+		dst, err := unbundleTarget(o.To, original)
+		if err != nil {
+			return nil, err
+		}
+		if len(original.ID) > 0 {
+			// images referenced by digest are pushed by digest
+			dst.Tag, dst.ID = "", ""
+			sources = append(sources, operatorv1alpha1.RepositoryDigestMirrors{
+				Source:  original.AsRepository().Exact(),
+				Mirrors: []string{dst.Exact()},
+			})
+		}
+		mappings = append(mappings, mirror.Mapping{
+			Source:      src,
+			Destination: imagesource.TypedImageReference{Type: imagesource.DestinationRegistry, Ref: dst},
+		})
+	}
+
+	allManifests := imagemanifest.FilterOptions{FilterByOS: ".*"}
+	if err := allManifests.Validate(); err != nil {
+		return nil, err
+	}
+	m := mirror.NewMirrorImageOptions(o.IOStreams)
+	m.SecurityOptions = o.SecurityOptions
+	m.ParallelOptions = o.ParallelOptions
+	m.FilterOptions = allManifests
+	m.KeepManifestList = true
+	m.DryRun = o.DryRun
+	m.FromFileDir = dir
+	m.Mappings = mappings
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if err := m.Run(); err != nil {
+		return nil, err
+	}
+
+	if len(sources) == 0 {
+		return nil, nil
+	}
+	return marshalMirrorSet(o.OutputMirrorKind, "bundle-images", dedupeSortSources(sources))
+}
+
+func (o *UnbundleOptions) unbundleCatalog(dir string, c bundledCatalog) error {
+	original, err := imagesource.ParseReference(c.Source)
+	if err != nil {
+		return err
+	}
+	dest, err := imagesource.ParseReference(o.To)
+	if err != nil {
+		return err
+	}
+	manifestDir := filepath.Join(o.ToManifests, bundleCatalogsDir, bundleCatalogName(original))
+
+	m := catalog.NewMirrorCatalogOptions(o.IOStreams)
+	m.SecurityOptions = o.SecurityOptions
+	m.ParallelOptions = o.ParallelOptions
+	m.DryRun = o.DryRun
+	m.FileDir = dir
+	m.FromFileDir = dir
+	m.MaxPathComponents = o.MaxPathComponents
+	m.ManifestDir = manifestDir
+	if err := m.Complete(o.cmd, []string{c.Image, o.To}); err != nil {
+		return err
+	}
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if err := m.Run(); err != nil {
+		return err
+	}
+
+	// the catalog was mirrored from the bundle, rewrite the manifests so that they
+	// point from the original locations of each image to the mirror
+	bundled, err := readBundleMapping(filepath.Join(dir, filepath.FromSlash(c.Mapping)))
+	if err != nil {
+		return err
+	}
+	pushed, err := readBundleMapping(filepath.Join(manifestDir, "mapping.txt"))
+	if err != nil {
+		return err
+	}
+	mapping, err := composeBundleMappings(bundled, pushed)
+	if err != nil {
+		return err
+	}
+	return catalog.WriteManifests(o.Out, original, dest, manifestDir, m.IcspScope, o.OutputMirrorKind, m.MaxICSPSize, mapping)
+}
+
+// composeBundleMappings joins the mapping from the original images of a catalog to the bundle with
+// the mapping from the bundle to the mirror registry, and returns the mapping from the original
+// images to the mirror registry.
+func composeBundleMappings(bundled, pushed map[string]imagesource.TypedImageReference) (map[imagesource.TypedImageReference]imagesource.TypedImageReference, error) {
+	type key struct {
+		repository string
+		id         string
+	}
+	targets := make(map[key]imagesource.TypedImageReference)
+	for from, to := range pushed {
+		ref, err := imagesource.ParseReference(from)
+		if err != nil {
+			return nil, err
+		}
+		id := ref.Ref.ID
+		if len(id) == 0 {
+			id = ref.Ref.Tag
+		}
+		targets[key{repository: ref.Ref.AsRepository().Exact(), id: id}] = to
+	}
+
+	mapping := make(map[imagesource.TypedImageReference]imagesource.TypedImageReference)
+	for from, stored := range bundled {
+		original, err := imagesource.ParseReference(from)
+		if err != nil {
+			return nil, err
+		}
+		id := original.Ref.ID
+		if len(id) == 0 {
+			id = original.Ref.Tag
+		}
+		target, ok := targets[key{repository: stored.Ref.AsRepository().Exact(), id: id}]
+		if !ok {
+			continue
+		}
+		// mapping files do not record the digest of the destination
+		target.Ref.ID = original.Ref.ID
+		mapping[original] = target
+	}
+	return mapping, nil
+}
+
+// unbundleTarget returns the location under to that src is pushed to, preserving the repository
+// path of src.
+func unbundleTarget(to string, src imagereference.DockerImageReference) (imagereference.DockerImageReference, error) {
+	src = src.DockerClientDefaults()
+	path := strings.TrimPrefix(src.Namespace+"/"+src.Name, "/")
+	dst, err := imagereference.Parse(to + "/" + path)
+	if err != nil {
+		return imagereference.DockerImageReference{}, err
+	}
+	dst.Tag = src.Tag
+	dst.ID = src.ID
+	return dst, nil
+}
+
+// unbundleObjectName converts s into a valid object name.
+func unbundleObjectName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, s)
+}
+
+// copyBundleSignatures copies the release image signature config maps in from to the directory to.
+func copyBundleSignatures(from, to string) error {
+	files, err := ioutil.ReadDir(from)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var names []string
+	for _, fi := range files {
+		if !fi.IsDir() {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(to, 0755); err != nil {
+		return err
+	}
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(from, name))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(to, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package release
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/diff"
+
+	"github.com/openshift/oc/pkg/cli/image/imagesource"
+)
+
+const (
+	digest0000 = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	digest1111 = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	digest1234 = "sha256:1234123412341234123412341234123412341234123412341234123412341234"
+	digest2222 = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	digest5678 = "sha256:5678567856785678567856785678567856785678567856785678567856785678"
+	digest9999 = "sha256:9999999999999999999999999999999999999999999999999999999999999999"
+	digestABCD = "sha256:abcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcd"
+	digestFFFF = "sha256:ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+)
+
+func mustParseTypedReference(t *testing.T, s string) imagesource.TypedImageReference {
+	ref, err := imagesource.ParseReference(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+func Test_composeBundleMappings(t *testing.T) {
+	bundled := map[string]imagesource.TypedImageReference{
+		"quay.io/my/index:v1":                   mustParseTypedReference(t, "file://catalogs/my/index:v1"),
+		"quay.io/my/operator@" + digest1234:     mustParseTypedReference(t, "file://catalogs/my/index/my/operator:6c7b1a2f"),
+		"quay.io/my/operand:1.0@" + digestABCD:  mustParseTypedReference(t, "file://catalogs/my/index/my/operand:1.0"),
+		"quay.io/my/not-pushed@" + digestFFFF:   mustParseTypedReference(t, "file://catalogs/my/index/my/not-pushed:11111111"),
+		"quay.io/other/operator@" + digest1234:  mustParseTypedReference(t, "file://catalogs/my/index/other/operator:7d8c2b3a"),
+		"quay.io/other/operand@" + digest5678:   mustParseTypedReference(t, "file://catalogs/my/index/other/operand:8e9d3c4b"),
+		"quay.io/other/untagged@" + digest9999:  mustParseTypedReference(t, "file://catalogs/my/index/other/untagged:9fae4d5c"),
+		"quay.io/other/different@" + digest0000: mustParseTypedReference(t, "file://catalogs/my/index/other/different:aaaa"),
+	}
+	pushed := map[string]imagesource.TypedImageReference{
+		"file://catalogs/my/index:v1":                            mustParseTypedReference(t, "mirror.local/my/index:v1"),
+		"file://catalogs/my/index/my/operator@" + digest1234:     mustParseTypedReference(t, "mirror.local/my/operator:6c7b1a2f"),
+		"file://catalogs/my/index/my/operand:1.0@" + digestABCD:  mustParseTypedReference(t, "mirror.local/my/operand:1.0"),
+		"file://catalogs/my/index/other/operator@" + digest1234:  mustParseTypedReference(t, "mirror.local/other/operator:7d8c2b3a"),
+		"file://catalogs/my/index/other/operand@" + digest5678:   mustParseTypedReference(t, "mirror.local/other/operand:8e9d3c4b"),
+		"file://catalogs/my/index/other/untagged@" + digest9999:  mustParseTypedReference(t, "mirror.local/other/untagged:9fae4d5c"),
+		"file://catalogs/my/index/other/different@" + digest1111: mustParseTypedReference(t, "mirror.local/other/different:bbbb"),
+		"file://catalogs/my/index/unrelated/image@" + digest2222: mustParseTypedReference(t, "mirror.local/unrelated/image:cccc"),
+	}
+
+	got, err := composeBundleMappings(bundled, pushed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[imagesource.TypedImageReference]imagesource.TypedImageReference{
+		mustParseTypedReference(t, "quay.io/my/index:v1"):                mustParseTypedReference(t, "mirror.local/my/index:v1"),
+		mustParseTypedReference(t, "quay.io/my/operator@"+digest1234):    mustParseTypedReference(t, "mirror.local/my/operator:6c7b1a2f@"+digest1234),
+		mustParseTypedReference(t, "quay.io/my/operand:1.0@"+digestABCD): mustParseTypedReference(t, "mirror.local/my/operand:1.0@"+digestABCD),
+		mustParseTypedReference(t, "quay.io/other/operator@"+digest1234): mustParseTypedReference(t, "mirror.local/other/operator:7d8c2b3a@"+digest1234),
+		mustParseTypedReference(t, "quay.io/other/operand@"+digest5678):  mustParseTypedReference(t, "mirror.local/other/operand:8e9d3c4b@"+digest5678),
+		mustParseTypedReference(t, "quay.io/other/untagged@"+digest9999): mustParseTypedReference(t, "mirror.local/other/untagged:9fae4d5c@"+digest9999),
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected mapping: %s", diff.ObjectReflectDiff(want, got))
+	}
+}
+
+func Test_unbundleTarget(t *testing.T) {
+	tests := []struct {
+		to   string
+		src  string
+		want string
+	}{
+		{to: "mirror.local", src: "quay.io/openshift/release:4.10", want: "mirror.local/openshift/release:4.10"},
+		{to: "mirror.local/ns", src: "quay.io/openshift/release@" + digest1234, want: "mirror.local/ns/openshift/release@" + digest1234},
+		{to: "mirror.local:5000/ns", src: "busybox:latest", want: "mirror.local:5000/ns/library/busybox:latest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			got, err := unbundleTarget(tt.to, mustParseTypedReference(t, tt.src).Ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Exact() != tt.want {
+				t.Errorf("unbundleTarget() = %s, want %s", got.Exact(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_bundleImageLocation(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{src: "quay.io/my/image:latest", want: "file://images/my/image:latest"},
+		{src: "quay.io/my/image@" + digest1234, want: "file://images/my/image"},
+		{src: "busybox", want: "file://images/library/busybox"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			if got := bundleImageLocation(mustParseTypedReference(t, tt.src)).String(); got != tt.want {
+				t.Errorf("bundleImageLocation() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
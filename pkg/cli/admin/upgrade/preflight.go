@@ -0,0 +1,309 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	apiserverv1 "github.com/openshift/api/apiserver/v1"
+	configv1 "github.com/openshift/api/config/v1"
+)
+
+// preflightResult is the outcome of a single pre-flight check.
+type preflightResult string
+
+const (
+	preflightPass preflightResult = "pass"
+	preflightWarn preflightResult = "warn"
+	preflightFail preflightResult = "fail"
+)
+
+var (
+	machineConfigPoolsResource = schema.GroupVersionResource{Group: "machineconfiguration.openshift.io", Version: "v1", Resource: "machineconfigpools"}
+	apiRequestCountsResource   = apiserverv1.GroupVersion.WithResource("apirequestcounts")
+)
+
+// preflightCheck is one row of the pre-flight report. Details lists the objects responsible for a
+// warning or failure.
+type preflightCheck struct {
+	Name    string          `json:"name"`
+	Result  preflightResult `json:"result"`
+	Message string          `json:"message"`
+	Details []string        `json:"details,omitempty"`
+}
+
+// preflightReport is the pre-flight output. Result is the most severe result of any check.
+type preflightReport struct {
+	Result preflightResult  `json:"result"`
+	Checks []preflightCheck `json:"checks"`
+}
+
+func (r *preflightReport) add(check preflightCheck) {
+	r.Checks = append(r.Checks, check)
+	switch {
+	case check.Result == preflightFail:
+		r.Result = preflightFail
+	case check.Result == preflightWarn && r.Result != preflightFail:
+		r.Result = preflightWarn
+	}
+}
+
+// runPreflight evaluates whether the cluster is ready to begin an update and reports the result
+// of each check. An error is returned if any check failed.
+func (o *Options) runPreflight(cv *configv1.ClusterVersion) error {
+	ctx := context.TODO()
+	report := &preflightReport{Result: preflightPass}
+
+	report.add(evaluateClusterVersion(cv))
+
+	var operators []configv1.ClusterOperator
+	if list, err := o.Client.ConfigV1().ClusterOperators().List(ctx, metav1.ListOptions{}); err != nil {
+		report.add(preflightCheck{Name: "ClusterOperators", Result: preflightFail, Message: fmt.Sprintf("unable to list cluster operators: %v", err)})
+	} else {
+		operators = list.Items
+		report.add(evaluateClusterOperators(operators))
+	}
+	report.add(evaluateUpgradeable(cv, operators))
+
+	if list, err := o.DynamicClient.Resource(machineConfigPoolsResource).List(ctx, metav1.ListOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			report.add(preflightCheck{Name: "MachineConfigPools", Result: preflightPass, Message: "Machine config pools are not managed by this cluster"})
+		} else {
+			report.add(preflightCheck{Name: "MachineConfigPools", Result: preflightWarn, Message: fmt.Sprintf("unable to list machine config pools: %v", err)})
+		}
+	} else {
+		report.add(evaluateMachineConfigPools(list.Items))
+	}
+
+	if list, err := o.KubeClient.PolicyV1().PodDisruptionBudgets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{}); err != nil {
+		report.add(preflightCheck{Name: "PodDisruptionBudgets", Result: preflightWarn, Message: fmt.Sprintf("unable to list pod disruption budgets: %v", err)})
+	} else {
+		report.add(evaluatePodDisruptionBudgets(list.Items))
+	}
+
+	if list, err := o.DynamicClient.Resource(apiRequestCountsResource).List(ctx, metav1.ListOptions{}); err != nil {
+		report.add(preflightCheck{Name: "DeprecatedAPIs", Result: preflightWarn, Message: fmt.Sprintf("unable to list API request counts: %v", err)})
+	} else {
+		counts := make([]apiserverv1.APIRequestCount, 0, len(list.Items))
+		for _, item := range list.Items {
+			var count apiserverv1.APIRequestCount
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &count); err != nil {
+				return fmt.Errorf("unable to read API request count %s: %v", item.GetName(), err)
+			}
+			counts = append(counts, count)
+		}
+		report.add(evaluateAPIRequestCounts(counts))
+	}
+
+	switch o.Output {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(o.Out, string(data))
+	default:
+		writePreflightReport(o.Out, report)
+	}
+
+	if report.Result == preflightFail {
+		return fmt.Errorf("the cluster is not ready to be updated, correct the failed checks before updating")
+	}
+	return nil
+}
+
+// writePreflightReport prints the report as a table with the details of each check indented below it.
+func writePreflightReport(out io.Writer, report *preflightReport) {
+	writeTabSection(out, func(w io.Writer) {
+		fmt.Fprintf(w, "CHECK\tRESULT\tMESSAGE\n")
+		for _, check := range report.Checks {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", check.Name, check.Result, check.Message)
+			for _, detail := range check.Details {
+				fmt.Fprintf(w, "\t\t  %s\n", detail)
+			}
+		}
+	})
+	fmt.Fprintf(out, "\nResult: %s\n", report.Result)
+}
+
+// evaluateClusterVersion fails if the cluster version operator reports an invalid or failing state,
+// or is already updating.
+func evaluateClusterVersion(cv *configv1.ClusterVersion) preflightCheck {
+	check := preflightCheck{Name: "ClusterVersion", Result: preflightPass, Message: fmt.Sprintf("Cluster is at %s and is not updating", releaseVersionString(cv.Status.Desired))}
+	if c := findClusterOperatorStatusCondition(cv.Status.Conditions, "Invalid"); c != nil && c.Status == configv1.ConditionTrue {
+		check.Result = preflightFail
+		check.Details = append(check.Details, fmt.Sprintf("Invalid=True %s: %s", c.Reason, firstLine(c.Message)))
+	}
+	for _, conditionType := range []configv1.ClusterStatusConditionType{"Failing", configv1.OperatorDegraded} {
+		if c := findClusterOperatorStatusCondition(cv.Status.Conditions, conditionType); c != nil && c.Status == configv1.ConditionTrue {
+			check.Result = preflightFail
+			check.Details = append(check.Details, fmt.Sprintf("%s=True %s: %s", c.Type, c.Reason, firstLine(c.Message)))
+		}
+	}
+	if c := findClusterOperatorStatusCondition(cv.Status.Conditions, configv1.OperatorProgressing); c != nil && c.Status == configv1.ConditionTrue {
+		check.Result = preflightFail
+		check.Details = append(check.Details, fmt.Sprintf("Progressing=True %s: %s", c.Reason, firstLine(c.Message)))
+	}
+	if check.Result == preflightFail {
+		check.Message = "The cluster version operator is not ready to begin an update"
+		return check
+	}
+	if c := findClusterOperatorStatusCondition(cv.Status.Conditions, configv1.RetrievedUpdates); c != nil && c.Status == configv1.ConditionFalse {
+		check.Result = preflightWarn
+		check.Message = "Cannot retrieve available updates"
+		check.Details = append(check.Details, fmt.Sprintf("RetrievedUpdates=False %s: %s", c.Reason, firstLine(c.Message)))
+	}
+	return check
+}
+
+// evaluateClusterOperators fails if any cluster operator is degraded or unavailable.
+func evaluateClusterOperators(operators []configv1.ClusterOperator) preflightCheck {
+	check := preflightCheck{Name: "ClusterOperators", Result: preflightPass, Message: fmt.Sprintf("All %d cluster operators are available and not degraded", len(operators))}
+	failed := 0
+	for _, co := range sortedClusterOperators(operators) {
+		var problems []string
+		if c := findClusterOperatorStatusCondition(co.Status.Conditions, configv1.OperatorAvailable); c == nil || c.Status != configv1.ConditionTrue {
+			problems = append(problems, conditionSummary(configv1.OperatorAvailable, c))
+		}
+		if c := findClusterOperatorStatusCondition(co.Status.Conditions, configv1.OperatorDegraded); c != nil && c.Status == configv1.ConditionTrue {
+			problems = append(problems, conditionSummary(configv1.OperatorDegraded, c))
+		}
+		if len(problems) > 0 {
+			failed++
+			check.Details = append(check.Details, fmt.Sprintf("%s: %s", co.Name, strings.Join(problems, ", ")))
+		}
+	}
+	if failed > 0 {
+		check.Result = preflightFail
+		check.Message = fmt.Sprintf("%d of %d cluster operators are degraded or unavailable", failed, len(operators))
+	}
+	return check
+}
+
+// evaluateUpgradeable warns if the cluster version or any cluster operator reports Upgradeable=False,
+// which blocks updates to a new minor version but not patch updates.
+func evaluateUpgradeable(cv *configv1.ClusterVersion, operators []configv1.ClusterOperator) preflightCheck {
+	check := preflightCheck{Name: "Upgradeable", Result: preflightPass, Message: "No component is blocking minor version updates"}
+	if c := findClusterOperatorStatusCondition(cv.Status.Conditions, configv1.OperatorUpgradeable); c != nil && c.Status == configv1.ConditionFalse {
+		check.Details = append(check.Details, fmt.Sprintf("clusterversion/%s: %s", cv.Name, conditionSummary(configv1.OperatorUpgradeable, c)))
+	}
+	for _, co := range sortedClusterOperators(operators) {
+		if c := findClusterOperatorStatusCondition(co.Status.Conditions, configv1.OperatorUpgradeable); c != nil && c.Status == configv1.ConditionFalse {
+			check.Details = append(check.Details, fmt.Sprintf("%s: %s", co.Name, conditionSummary(configv1.OperatorUpgradeable, c)))
+		}
+	}
+	if len(check.Details) > 0 {
+		check.Result = preflightWarn
+		check.Message = "Updates to a new minor version are blocked, patch updates are allowed"
+	}
+	return check
+}
+
+// evaluateMachineConfigPools warns about paused pools, whose nodes will not be updated, and fails if
+// a pool is degraded.
+func evaluateMachineConfigPools(pools []unstructured.Unstructured) preflightCheck {
+	check := preflightCheck{Name: "MachineConfigPools", Result: preflightPass, Message: fmt.Sprintf("All %d machine config pools are ready to update", len(pools))}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].GetName() < pools[j].GetName() })
+	paused, degraded := 0, 0
+	for _, pool := range pools {
+		if ok, _, _ := unstructured.NestedBool(pool.Object, "spec", "paused"); ok {
+			paused++
+			check.Details = append(check.Details, fmt.Sprintf("%s: paused, nodes in this pool will not be updated until it is unpaused", pool.GetName()))
+		}
+		conditions, _, _ := unstructured.NestedSlice(pool.Object, "status", "conditions")
+		for _, condition := range conditions {
+			c, ok := condition.(map[string]interface{})
+			if !ok || c["type"] != "Degraded" || c["status"] != "True" {
+				continue
+			}
+			degraded++
+			message, _ := c["message"].(string)
+			check.Details = append(check.Details, fmt.Sprintf("%s: Degraded=True %v: %s", pool.GetName(), c["reason"], firstLine(message)))
+		}
+	}
+	switch {
+	case degraded > 0:
+		check.Result = preflightFail
+		check.Message = fmt.Sprintf("%d machine config pools are degraded", degraded)
+	case paused > 0:
+		check.Result = preflightWarn
+		check.Message = fmt.Sprintf("%d machine config pools are paused", paused)
+	}
+	return check
+}
+
+// evaluatePodDisruptionBudgets warns about budgets that currently allow no disruptions, because
+// they will block the drain of any node running one of their pods.
+func evaluatePodDisruptionBudgets(pdbs []policyv1.PodDisruptionBudget) preflightCheck {
+	check := preflightCheck{Name: "PodDisruptionBudgets", Result: preflightPass, Message: "No pod disruption budget will block node drains"}
+	sort.Slice(pdbs, func(i, j int) bool {
+		if pdbs[i].Namespace != pdbs[j].Namespace {
+			return pdbs[i].Namespace < pdbs[j].Namespace
+		}
+		return pdbs[i].Name < pdbs[j].Name
+	})
+	for _, pdb := range pdbs {
+		if pdb.Status.ExpectedPods == 0 || pdb.Status.DisruptionsAllowed > 0 {
+			continue
+		}
+		check.Details = append(check.Details, fmt.Sprintf("%s/%s: 0 disruptions allowed, %d of %d pods healthy", pdb.Namespace, pdb.Name, pdb.Status.CurrentHealthy, pdb.Status.ExpectedPods))
+	}
+	if len(check.Details) > 0 {
+		check.Result = preflightWarn
+		check.Message = fmt.Sprintf("%d pod disruption budgets will block node drains", len(check.Details))
+	}
+	return check
+}
+
+// evaluateAPIRequestCounts warns about APIs that are still in use and will be removed in a future
+// release.
+func evaluateAPIRequestCounts(counts []apiserverv1.APIRequestCount) preflightCheck {
+	check := preflightCheck{Name: "DeprecatedAPIs", Result: preflightPass, Message: "No APIs that will be removed in a future release are in use"}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Name < counts[j].Name })
+	for _, count := range counts {
+		if len(count.Status.RemovedInRelease) == 0 || count.Status.RequestCount == 0 {
+			continue
+		}
+		check.Details = append(check.Details, fmt.Sprintf("%s: removed in %s, %d requests in the last 24 hours", count.Name, count.Status.RemovedInRelease, count.Status.RequestCount))
+	}
+	if len(check.Details) > 0 {
+		check.Result = preflightWarn
+		check.Message = fmt.Sprintf("%d APIs that will be removed in a future release are in use", len(check.Details))
+	}
+	return check
+}
+
+func sortedClusterOperators(operators []configv1.ClusterOperator) []configv1.ClusterOperator {
+	sorted := make([]configv1.ClusterOperator, len(operators))
+	copy(sorted, operators)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+func conditionSummary(conditionType configv1.ClusterStatusConditionType, c *configv1.ClusterOperatorStatusCondition) string {
+	if c == nil {
+		return fmt.Sprintf("%s=Unknown", conditionType)
+	}
+	if len(c.Message) == 0 {
+		return fmt.Sprintf("%s=%s %s", conditionType, c.Status, c.Reason)
+	}
+	return fmt.Sprintf("%s=%s %s: %s", conditionType, c.Status, c.Reason, firstLine(c.Message))
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i] + " ..."
+	}
+	return s
+}
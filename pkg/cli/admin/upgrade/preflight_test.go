@@ -0,0 +1,141 @@
+package upgrade
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	apiserverv1 "github.com/openshift/api/apiserver/v1"
+	configv1 "github.com/openshift/api/config/v1"
+)
+
+func clusterOperator(name string, conditions ...configv1.ClusterOperatorStatusCondition) configv1.ClusterOperator {
+	return configv1.ClusterOperator{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     configv1.ClusterOperatorStatus{Conditions: conditions},
+	}
+}
+
+func TestEvaluateClusterOperators(t *testing.T) {
+	available := configv1.ClusterOperatorStatusCondition{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue}
+	operators := []configv1.ClusterOperator{
+		clusterOperator("network", available),
+		clusterOperator("etcd", available, configv1.ClusterOperatorStatusCondition{Type: configv1.OperatorDegraded, Status: configv1.ConditionTrue, Reason: "MembersDown", Message: "1 member down\nsee logs"}),
+		clusterOperator("dns", configv1.ClusterOperatorStatusCondition{Type: configv1.OperatorAvailable, Status: configv1.ConditionFalse, Reason: "NoPods"}),
+	}
+	check := evaluateClusterOperators(operators)
+	if check.Result != preflightFail {
+		t.Errorf("expected fail, got %s", check.Result)
+	}
+	want := []string{
+		"dns: Available=False NoPods",
+		"etcd: Degraded=True MembersDown: 1 member down ...",
+	}
+	if !reflect.DeepEqual(want, check.Details) {
+		t.Errorf("unexpected details: %q", check.Details)
+	}
+
+	if check := evaluateClusterOperators(operators[:1]); check.Result != preflightPass {
+		t.Errorf("expected pass, got %s: %v", check.Result, check.Details)
+	}
+}
+
+func TestEvaluateUpgradeable(t *testing.T) {
+	cv := &configv1.ClusterVersion{ObjectMeta: metav1.ObjectMeta{Name: "version"}}
+	operators := []configv1.ClusterOperator{
+		clusterOperator("storage", configv1.ClusterOperatorStatusCondition{Type: configv1.OperatorUpgradeable, Status: configv1.ConditionFalse, Reason: "VSphereOlderVersion"}),
+		clusterOperator("network", configv1.ClusterOperatorStatusCondition{Type: configv1.OperatorUpgradeable, Status: configv1.ConditionTrue}),
+	}
+	check := evaluateUpgradeable(cv, operators)
+	if check.Result != preflightWarn || !reflect.DeepEqual(check.Details, []string{"storage: Upgradeable=False VSphereOlderVersion"}) {
+		t.Errorf("unexpected check: %#v", check)
+	}
+	if check := evaluateUpgradeable(cv, operators[1:]); check.Result != preflightPass {
+		t.Errorf("expected pass, got %#v", check)
+	}
+}
+
+func TestEvaluateMachineConfigPools(t *testing.T) {
+	pool := func(name string, paused bool, degraded string) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name},
+			"spec":     map[string]interface{}{"paused": paused},
+			"status": map[string]interface{}{"conditions": []interface{}{
+				map[string]interface{}{"type": "Degraded", "status": degraded, "reason": "RenderFailed", "message": "bad config"},
+			}},
+		}}
+	}
+	tests := []struct {
+		name  string
+		pools []unstructured.Unstructured
+		want  preflightResult
+	}{
+		{name: "ready", pools: []unstructured.Unstructured{pool("master", false, "False"), pool("worker", false, "False")}, want: preflightPass},
+		{name: "paused", pools: []unstructured.Unstructured{pool("master", false, "False"), pool("worker", true, "False")}, want: preflightWarn},
+		{name: "degraded", pools: []unstructured.Unstructured{pool("master", false, "True"), pool("worker", true, "False")}, want: preflightFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if check := evaluateMachineConfigPools(tt.pools); check.Result != tt.want {
+				t.Errorf("expected %s, got %#v", tt.want, check)
+			}
+		})
+	}
+}
+
+func TestEvaluatePodDisruptionBudgets(t *testing.T) {
+	pdb := func(namespace, name string, expected, allowed int32) policyv1.PodDisruptionBudget {
+		return policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Status:     policyv1.PodDisruptionBudgetStatus{ExpectedPods: expected, CurrentHealthy: expected, DisruptionsAllowed: allowed},
+		}
+	}
+	check := evaluatePodDisruptionBudgets([]policyv1.PodDisruptionBudget{
+		pdb("b", "blocking", 1, 0),
+		pdb("a", "allowed", 3, 1),
+		pdb("a", "no-pods", 0, 0),
+	})
+	if check.Result != preflightWarn || !reflect.DeepEqual(check.Details, []string{"b/blocking: 0 disruptions allowed, 1 of 1 pods healthy"}) {
+		t.Errorf("unexpected check: %#v", check)
+	}
+}
+
+func TestEvaluateAPIRequestCounts(t *testing.T) {
+	count := func(name, removedIn string, requests int64) apiserverv1.APIRequestCount {
+		return apiserverv1.APIRequestCount{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     apiserverv1.APIRequestCountStatus{RemovedInRelease: removedIn, RequestCount: requests},
+		}
+	}
+	check := evaluateAPIRequestCounts([]apiserverv1.APIRequestCount{
+		count("pods.v1", "", 1000),
+		count("podsecuritypolicies.v1beta1.policy", "1.25", 12),
+		count("cronjobs.v1beta1.batch", "1.25", 0),
+	})
+	if check.Result != preflightWarn || !reflect.DeepEqual(check.Details, []string{"podsecuritypolicies.v1beta1.policy: removed in 1.25, 12 requests in the last 24 hours"}) {
+		t.Errorf("unexpected check: %#v", check)
+	}
+}
+
+func TestPreflightReport(t *testing.T) {
+	report := &preflightReport{Result: preflightPass}
+	report.add(preflightCheck{Name: "A", Result: preflightPass, Message: "ok"})
+	report.add(preflightCheck{Name: "B", Result: preflightFail, Message: "broken", Details: []string{"detail"}})
+	report.add(preflightCheck{Name: "C", Result: preflightWarn, Message: "careful"})
+	if report.Result != preflightFail {
+		t.Errorf("expected the report to fail, got %s", report.Result)
+	}
+
+	buf := &bytes.Buffer{}
+	writePreflightReport(buf, report)
+	for _, s := range []string{"CHECK", "B     fail   broken", "detail", "Result: fail"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected output to contain %q:\n%s", s, buf.String())
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package status
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
+)
+
+// updateSnapshot is the progress of an update at a point in time.
+type updateSnapshot struct {
+	Observed time.Time
+
+	// Target is the version, or the image if the version is unknown, that the cluster is updating to.
+	Target    string
+	State     configv1.UpdateState
+	Started   time.Time
+	Completed time.Time
+
+	Progressing        bool
+	ProgressingMessage string
+	Failing            bool
+	FailingMessage     string
+
+	Operators []operatorProgress
+	Pools     []poolProgress
+}
+
+// operatorProgress is the state of a cluster operator. Reached is when the operator reported the
+// target version, and is zero if that is not known.
+type operatorProgress struct {
+	Name     string
+	Version  string
+	AtTarget bool
+	Reached  time.Time
+
+	Available   configv1.ConditionStatus
+	Progressing configv1.ConditionStatus
+	Degraded    configv1.ConditionStatus
+	Message     string
+}
+
+// poolProgress is the number of nodes of a machine config pool in each state.
+type poolProgress struct {
+	Name     string
+	Paused   bool
+	Machines int64
+	Updated  int64
+	Ready    int64
+	Degraded int64
+}
+
+func newSnapshot(cv *configv1.ClusterVersion, operators []configv1.ClusterOperator, pools []unstructured.Unstructured, now time.Time) *updateSnapshot {
+	s := &updateSnapshot{
+		Observed: now,
+		Target:   cv.Status.Desired.Version,
+		State:    configv1.PartialUpdate,
+	}
+	if len(s.Target) == 0 {
+		s.Target = cv.Status.Desired.Image
+	}
+	if len(cv.Status.History) > 0 {
+		last := cv.Status.History[0]
+		s.State = last.State
+		s.Started = last.StartedTime.Time
+		if last.CompletionTime != nil {
+			s.Completed = last.CompletionTime.Time
+		}
+	}
+	if c := v1helpers.FindStatusCondition(cv.Status.Conditions, configv1.OperatorProgressing); c != nil {
+		s.Progressing = c.Status == configv1.ConditionTrue
+		s.ProgressingMessage = c.Message
+	}
+	for _, conditionType := range []configv1.ClusterStatusConditionType{"Failing", configv1.OperatorDegraded} {
+		if c := v1helpers.FindStatusCondition(cv.Status.Conditions, conditionType); c != nil && c.Status == configv1.ConditionTrue {
+			s.Failing = true
+			s.FailingMessage = firstLine(c.Message)
+			break
+		}
+	}
+
+	for _, co := range operators {
+		p := operatorProgress{Name: co.Name}
+		for _, v := range co.Status.Versions {
+			if v.Name == "operator" {
+				p.Version = v.Version
+			}
+		}
+		p.AtTarget = len(p.Version) > 0 && p.Version == cv.Status.Desired.Version
+		p.Available = conditionStatus(co.Status.Conditions, configv1.OperatorAvailable)
+		p.Progressing = conditionStatus(co.Status.Conditions, configv1.OperatorProgressing)
+		p.Degraded = conditionStatus(co.Status.Conditions, configv1.OperatorDegraded)
+		if c := v1helpers.FindStatusCondition(co.Status.Conditions, configv1.OperatorDegraded); c != nil && c.Status == configv1.ConditionTrue {
+			p.Message = firstLine(c.Message)
+		} else if c := v1helpers.FindStatusCondition(co.Status.Conditions, configv1.OperatorAvailable); c != nil && c.Status != configv1.ConditionTrue {
+			p.Message = firstLine(c.Message)
+		}
+		// an operator that finished progressing after the update started reached the target then
+		if c := v1helpers.FindStatusCondition(co.Status.Conditions, configv1.OperatorProgressing); p.AtTarget && !s.Started.IsZero() && c != nil && c.Status == configv1.ConditionFalse && !c.LastTransitionTime.Time.Before(s.Started) {
+			p.Reached = c.LastTransitionTime.Time
+		}
+		s.Operators = append(s.Operators, p)
+	}
+	sort.Slice(s.Operators, func(i, j int) bool { return s.Operators[i].Name < s.Operators[j].Name })
+
+	for _, pool := range pools {
+		p := poolProgress{Name: pool.GetName()}
+		p.Paused, _, _ = unstructured.NestedBool(pool.Object, "spec", "paused")
+		p.Machines, _, _ = unstructured.NestedInt64(pool.Object, "status", "machineCount")
+		p.Updated, _, _ = unstructured.NestedInt64(pool.Object, "status", "updatedMachineCount")
+		p.Ready, _, _ = unstructured.NestedInt64(pool.Object, "status", "readyMachineCount")
+		p.Degraded, _, _ = unstructured.NestedInt64(pool.Object, "status", "degradedMachineCount")
+		s.Pools = append(s.Pools, p)
+	}
+	sort.Slice(s.Pools, func(i, j int) bool { return s.Pools[i].Name < s.Pools[j].Name })
+	return s
+}
+
+// preserve copies the times at which operators were first observed at the target version from a
+// previous snapshot of the same update, and records operators that reached the target since then.
+func (s *updateSnapshot) preserve(previous *updateSnapshot) {
+	if previous == nil || previous.Target != s.Target {
+		return
+	}
+	reached := make(map[string]operatorProgress, len(previous.Operators))
+	for _, p := range previous.Operators {
+		reached[p.Name] = p
+	}
+	for i := range s.Operators {
+		p := &s.Operators[i]
+		if !p.AtTarget || !p.Reached.IsZero() {
+			continue
+		}
+		prev, ok := reached[p.Name]
+		switch {
+		case ok && prev.AtTarget:
+			p.Reached = prev.Reached
+		case ok:
+			p.Reached = s.Observed
+		}
+	}
+}
+
+// done returns true if the cluster version was applied and every pool that is not paused has updated
+// all of its nodes.
+func (s *updateSnapshot) done() bool {
+	if s.State != configv1.CompletedUpdate || s.Progressing {
+		return false
+	}
+	for _, p := range s.Pools {
+		if !p.Paused && (p.Updated != p.Machines || p.Degraded > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// failed returns true if the cluster version operator reports that it is failing and is no longer
+// trying to make progress.
+func (s *updateSnapshot) failed() bool {
+	return s.Failing && !s.Progressing
+}
+
+// compareSnapshots describes the changes between two snapshots of an update.
+func compareSnapshots(previous, current *updateSnapshot) []string {
+	var events []string
+	if previous.Target != current.Target {
+		events = append(events, fmt.Sprintf("Update to %s started", current.Target))
+	}
+	if previous.State != current.State && current.State == configv1.CompletedUpdate {
+		events = append(events, fmt.Sprintf("Cluster version %s applied", current.Target))
+	}
+	if previous.Failing != current.Failing || (current.Failing && previous.FailingMessage != current.FailingMessage) {
+		if current.Failing {
+			events = append(events, fmt.Sprintf("Cluster version operator is failing: %s", current.FailingMessage))
+		} else {
+			events = append(events, "Cluster version operator is no longer failing")
+		}
+	}
+
+	operators := make(map[string]operatorProgress, len(previous.Operators))
+	for _, p := range previous.Operators {
+		operators[p.Name] = p
+	}
+	for _, p := range current.Operators {
+		prev, ok := operators[p.Name]
+		if !ok {
+			events = append(events, fmt.Sprintf("clusteroperator/%s created", p.Name))
+			continue
+		}
+		if p.AtTarget && !prev.AtTarget {
+			events = append(events, fmt.Sprintf("clusteroperator/%s updated to %s", p.Name, p.Version))
+		}
+		for _, c := range []struct {
+			conditionType  configv1.ClusterStatusConditionType
+			before, status configv1.ConditionStatus
+		}{
+			{configv1.OperatorAvailable, prev.Available, p.Available},
+			{configv1.OperatorProgressing, prev.Progressing, p.Progressing},
+			{configv1.OperatorDegraded, prev.Degraded, p.Degraded},
+		} {
+			if c.before == c.status {
+				continue
+			}
+			event := fmt.Sprintf("clusteroperator/%s %s=%s", p.Name, c.conditionType, c.status)
+			if c.conditionType != configv1.OperatorProgressing && len(p.Message) > 0 {
+				event += ": " + p.Message
+			}
+			events = append(events, event)
+		}
+	}
+
+	pools := make(map[string]poolProgress, len(previous.Pools))
+	for _, p := range previous.Pools {
+		pools[p.Name] = p
+	}
+	for _, p := range current.Pools {
+		prev, ok := pools[p.Name]
+		if ok && prev == p {
+			continue
+		}
+		event := fmt.Sprintf("machineconfigpool/%s %d of %d nodes updated", p.Name, p.Updated, p.Machines)
+		if p.Degraded > 0 {
+			event += fmt.Sprintf(", %d degraded", p.Degraded)
+		}
+		if p.Paused {
+			event += " (paused)"
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// writeStatus prints the version being updated to, the progress of each operator and the progress of
+// each machine config pool.
+func writeStatus(out io.Writer, s *updateSnapshot, now time.Time) {
+	atTarget := 0
+	for _, p := range s.Operators {
+		if p.AtTarget {
+			atTarget++
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	switch {
+	case s.State == configv1.CompletedUpdate && !s.Completed.IsZero():
+		fmt.Fprintf(w, "Cluster version:\t%s (applied %s ago)\n", s.Target, duration.HumanDuration(now.Sub(s.Completed)))
+	case !s.Started.IsZero():
+		fmt.Fprintf(w, "Target version:\t%s (update started %s ago)\n", s.Target, duration.HumanDuration(now.Sub(s.Started)))
+	default:
+		fmt.Fprintf(w, "Target version:\t%s\n", s.Target)
+	}
+	if s.Progressing && len(s.ProgressingMessage) > 0 {
+		fmt.Fprintf(w, "Progress:\t%s\n", firstLine(s.ProgressingMessage))
+	}
+	if s.Failing {
+		fmt.Fprintf(w, "Failing:\t%s\n", s.FailingMessage)
+	}
+	fmt.Fprintf(w, "Operators:\t%d of %d at %s\n", atTarget, len(s.Operators), s.Target)
+	w.Flush()
+
+	if len(s.Operators) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "OPERATOR\tVERSION\tUPDATED\tELAPSED\tAVAILABLE\tPROGRESSING\tDEGRADED\tMESSAGE\n")
+		for _, p := range s.Operators {
+			updated := "no"
+			if p.AtTarget {
+				updated = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", p.Name, p.Version, updated, operatorElapsed(s, p), p.Available, p.Progressing, p.Degraded, p.Message)
+		}
+		w.Flush()
+	}
+
+	if len(s.Pools) > 0 {
+		fmt.Fprintln(out)
+		writePools(out, s.Pools)
+	}
+}
+
+// writeSummary prints how long the update and each operator took, and the final state of each
+// machine config pool.
+func writeSummary(out io.Writer, s *updateSnapshot) {
+	switch {
+	case s.done() && !s.Started.IsZero() && !s.Completed.IsZero():
+		fmt.Fprintf(out, "Update to %s completed in %s.\n", s.Target, s.Completed.Sub(s.Started).Round(time.Second))
+	case s.done():
+		fmt.Fprintf(out, "Update to %s completed.\n", s.Target)
+	case s.failed():
+		fmt.Fprintf(out, "Update to %s failed: %s\n", s.Target, s.FailingMessage)
+	default:
+		fmt.Fprintf(out, "Update to %s has not completed.\n", s.Target)
+	}
+
+	var updated, pending []operatorProgress
+	for _, p := range s.Operators {
+		if p.AtTarget {
+			updated = append(updated, p)
+		} else {
+			pending = append(pending, p)
+		}
+	}
+	sort.SliceStable(updated, func(i, j int) bool {
+		a, b := updated[i].Reached, updated[j].Reached
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		return a.Before(b)
+	})
+	fmt.Fprintf(out, "\n%d of %d operators updated:\n\n", len(updated), len(s.Operators))
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "  OPERATOR\tELAPSED\n")
+	for _, p := range updated {
+		fmt.Fprintf(w, "  %s\t%s\n", p.Name, operatorElapsed(s, p))
+	}
+	w.Flush()
+	if len(pending) > 0 {
+		names := make([]string, 0, len(pending))
+		for _, p := range pending {
+			names = append(names, p.Name)
+		}
+		fmt.Fprintf(out, "\nNot updated: %s\n", strings.Join(names, ", "))
+	}
+
+	if len(s.Pools) > 0 {
+		fmt.Fprintln(out)
+		writePools(out, s.Pools)
+	}
+}
+
+func writePools(out io.Writer, pools []poolProgress) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "POOL\tUPDATED\tREADY\tDEGRADED\tTOTAL\tPAUSED\n")
+	for _, p := range pools {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%t\n", p.Name, p.Updated, p.Ready, p.Degraded, p.Machines, p.Paused)
+	}
+	w.Flush()
+}
+
+// operatorElapsed returns the time an operator took to reach the target version after the update
+// started.
+func operatorElapsed(s *updateSnapshot, p operatorProgress) string {
+	if !p.AtTarget || p.Reached.IsZero() || s.Started.IsZero() {
+		return "-"
+	}
+	return p.Reached.Sub(s.Started).Round(time.Second).String()
+}
+
+// elapsedString formats the time since the update started for the timeline.
+func elapsedString(started, now time.Time) string {
+	if started.IsZero() {
+		return now.Format("15:04:05")
+	}
+	return fmt.Sprintf("+%s", now.Sub(started).Round(time.Second))
+}
+
+func conditionStatus(conditions []configv1.ClusterOperatorStatusCondition, name configv1.ClusterStatusConditionType) configv1.ConditionStatus {
+	if c := v1helpers.FindStatusCondition(conditions, name); c != nil {
+		return c.Status
+	}
+	return configv1.ConditionUnknown
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i] + " ..."
+	}
+	return s
+}
@@ -0,0 +1,164 @@
+package status
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	configv1 "github.com/openshift/api/config/v1"
+)
+
+var started = time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func clusterVersion(target string, state configv1.UpdateState, conditions ...configv1.ClusterOperatorStatusCondition) *configv1.ClusterVersion {
+	history := configv1.UpdateHistory{State: state, Version: target, StartedTime: metav1.NewTime(started)}
+	if state == configv1.CompletedUpdate {
+		completed := metav1.NewTime(started.Add(time.Hour))
+		history.CompletionTime = &completed
+	}
+	return &configv1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "version"},
+		Status: configv1.ClusterVersionStatus{
+			Desired:    configv1.Release{Version: target},
+			History:    []configv1.UpdateHistory{history},
+			Conditions: conditions,
+		},
+	}
+}
+
+func clusterOperator(name, version string, progressed time.Time, degraded configv1.ConditionStatus) configv1.ClusterOperator {
+	return configv1.ClusterOperator{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: configv1.ClusterOperatorStatus{
+			Versions: []configv1.OperandVersion{{Name: "operator", Version: version}},
+			Conditions: []configv1.ClusterOperatorStatusCondition{
+				{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue},
+				{Type: configv1.OperatorProgressing, Status: configv1.ConditionFalse, LastTransitionTime: metav1.NewTime(progressed)},
+				{Type: configv1.OperatorDegraded, Status: degraded, Message: "broken"},
+			},
+		},
+	}
+}
+
+func machineConfigPool(name string, machines, updated int64, paused bool) unstructured.Unstructured {
+	return unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name},
+		"spec":     map[string]interface{}{"paused": paused},
+		"status": map[string]interface{}{
+			"machineCount":        machines,
+			"updatedMachineCount": updated,
+			"readyMachineCount":   updated,
+		},
+	}}
+}
+
+func TestNewSnapshot(t *testing.T) {
+	cv := clusterVersion("4.10.1", configv1.PartialUpdate, configv1.ClusterOperatorStatusCondition{Type: configv1.OperatorProgressing, Status: configv1.ConditionTrue, Message: "Working towards 4.10.1"})
+	operators := []configv1.ClusterOperator{
+		clusterOperator("network", "4.10.0", started.Add(-time.Hour), configv1.ConditionFalse),
+		clusterOperator("etcd", "4.10.1", started.Add(5*time.Minute), configv1.ConditionFalse),
+		clusterOperator("dns", "4.10.1", started.Add(-time.Hour), configv1.ConditionFalse),
+	}
+	pools := []unstructured.Unstructured{machineConfigPool("worker", 3, 1, false), machineConfigPool("master", 3, 3, false)}
+
+	s := newSnapshot(cv, operators, pools, started.Add(10*time.Minute))
+	if s.Target != "4.10.1" || !s.Started.Equal(started) || !s.Progressing || s.done() || s.failed() {
+		t.Errorf("unexpected snapshot: %#v", s)
+	}
+	var names []string
+	for _, p := range s.Operators {
+		names = append(names, p.Name)
+	}
+	if want := []string{"dns", "etcd", "network"}; !reflect.DeepEqual(want, names) {
+		t.Errorf("expected operators sorted by name, got %v", names)
+	}
+	if got := operatorElapsed(s, s.Operators[1]); got != "5m0s" {
+		t.Errorf("expected etcd to take 5m0s, got %s", got)
+	}
+	if got := operatorElapsed(s, s.Operators[0]); got != "-" {
+		t.Errorf("expected dns elapsed time to be unknown, got %s", got)
+	}
+	if want := (poolProgress{Name: "master", Machines: 3, Updated: 3, Ready: 3}); s.Pools[0] != want {
+		t.Errorf("unexpected pool: %#v", s.Pools[0])
+	}
+}
+
+func TestSnapshotDone(t *testing.T) {
+	cv := clusterVersion("4.10.1", configv1.CompletedUpdate)
+	tests := []struct {
+		name  string
+		pools []unstructured.Unstructured
+		want  bool
+	}{
+		{name: "no pools", want: true},
+		{name: "pools updated", pools: []unstructured.Unstructured{machineConfigPool("master", 3, 3, false)}, want: true},
+		{name: "paused pool", pools: []unstructured.Unstructured{machineConfigPool("master", 3, 3, false), machineConfigPool("worker", 3, 0, true)}, want: true},
+		{name: "pool updating", pools: []unstructured.Unstructured{machineConfigPool("worker", 3, 2, false)}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newSnapshot(cv, nil, tt.pools, started).done(); got != tt.want {
+				t.Errorf("done() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+
+	failing := clusterVersion("4.10.1", configv1.PartialUpdate, configv1.ClusterOperatorStatusCondition{Type: "Failing", Status: configv1.ConditionTrue, Message: "verification failed"})
+	if s := newSnapshot(failing, nil, nil, started); !s.failed() || s.FailingMessage != "verification failed" {
+		t.Errorf("expected a failed update, got %#v", s)
+	}
+}
+
+func TestCompareSnapshots(t *testing.T) {
+	cv := clusterVersion("4.10.1", configv1.PartialUpdate)
+	before := newSnapshot(cv, []configv1.ClusterOperator{
+		clusterOperator("etcd", "4.10.0", started.Add(-time.Hour), configv1.ConditionFalse),
+		clusterOperator("dns", "4.10.0", started.Add(-time.Hour), configv1.ConditionFalse),
+	}, []unstructured.Unstructured{machineConfigPool("master", 3, 1, false)}, started.Add(time.Minute))
+	after := newSnapshot(cv, []configv1.ClusterOperator{
+		clusterOperator("etcd", "4.10.1", started.Add(-time.Hour), configv1.ConditionFalse),
+		clusterOperator("dns", "4.10.0", started.Add(-time.Hour), configv1.ConditionTrue),
+	}, []unstructured.Unstructured{machineConfigPool("master", 3, 2, false)}, started.Add(2*time.Minute))
+	after.preserve(before)
+
+	want := []string{
+		"clusteroperator/dns Degraded=True: broken",
+		"clusteroperator/etcd updated to 4.10.1",
+		"machineconfigpool/master 2 of 3 nodes updated",
+	}
+	if got := compareSnapshots(before, after); !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected events:\n%s", strings.Join(got, "\n"))
+	}
+	if got := operatorElapsed(after, after.Operators[1]); got != "2m0s" {
+		t.Errorf("expected etcd to be observed at the target after 2m0s, got %s", got)
+	}
+	if events := compareSnapshots(after, after); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	cv := clusterVersion("4.10.1", configv1.CompletedUpdate)
+	s := newSnapshot(cv, []configv1.ClusterOperator{
+		clusterOperator("etcd", "4.10.1", started.Add(20*time.Minute), configv1.ConditionFalse),
+		clusterOperator("dns", "4.10.1", started.Add(10*time.Minute), configv1.ConditionFalse),
+		clusterOperator("network", "4.10.0", started.Add(-time.Hour), configv1.ConditionFalse),
+	}, []unstructured.Unstructured{machineConfigPool("master", 3, 3, false)}, started.Add(2*time.Hour))
+
+	buf := &bytes.Buffer{}
+	writeSummary(buf, s)
+	out := buf.String()
+	for _, s := range []string{"Update to 4.10.1 completed in 1h0m0s.", "2 of 3 operators updated", "Not updated: network", "master"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected summary to contain %q:\n%s", s, out)
+		}
+	}
+	if strings.Index(out, "dns") > strings.Index(out, "etcd") {
+		t.Errorf("expected operators in the order they were updated:\n%s", out)
+	}
+}
@@ -0,0 +1,210 @@
+// Package status contains a command for following the progress of a cluster update.
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	configv1client "github.com/openshift/client-go/config/clientset/versioned"
+)
+
+var machineConfigPoolsResource = schema.GroupVersionResource{Group: "machineconfiguration.openshift.io", Version: "v1", Resource: "machineconfigpools"}
+
+var statusExample = templates.Examples(`
+	# Display the progress of the current update
+	oc adm upgrade status
+
+	# Follow the current update until it completes, printing operator and node progress as it happens
+	oc adm upgrade status --watch
+
+	# Follow the current update and exit with an error if nothing changes for 20 minutes
+	oc adm upgrade status --watch --stall-timeout=20m
+`)
+
+func NewOptions(streams genericclioptions.IOStreams) *Options {
+	return &Options{
+		IOStreams:    streams,
+		Interval:     10 * time.Second,
+		StallTimeout: time.Hour,
+		now:          time.Now,
+	}
+}
+
+func New(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "status",
+		Short:   "Display the progress of a cluster update",
+		Example: statusExample,
+		Long: templates.LongDesc(`
+			Display the progress of a cluster update.
+
+			This command shows the version the cluster is updating to, which cluster operators
+			have reached that version and how long each took, and how many nodes of each machine
+			config pool have been updated.
+
+			Passing --watch follows the update until it completes, printing a timeline of cluster
+			operator version and condition changes and machine config pool progress. When the
+			cluster version has been applied and every machine config pool that is not paused has
+			updated its nodes a summary is printed. The command exits with an error if the cluster
+			version operator reports that the update failed, if nothing changes for longer than
+			--stall-timeout, or if the update does not complete within --timeout.
+		`),
+		Run: func(cmd *cobra.Command, args []string) {
+			kcmdutil.CheckErr(o.Complete(f, cmd, args))
+			kcmdutil.CheckErr(o.Validate())
+			kcmdutil.CheckErr(o.Run())
+		},
+	}
+	flags := cmd.Flags()
+	flags.BoolVarP(&o.Watch, "watch", "w", o.Watch, "Follow the update until it completes, fails or stalls.")
+	flags.DurationVar(&o.Interval, "interval", o.Interval, "How often to check the progress of the update with --watch.")
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout, "The maximum time to wait for the update to complete with --watch. Zero means no limit.")
+	flags.DurationVar(&o.StallTimeout, "stall-timeout", o.StallTimeout, "Exit with an error if no progress is observed for this long with --watch. Zero means no limit.")
+	return cmd
+}
+
+type Options struct {
+	genericclioptions.IOStreams
+
+	Watch        bool
+	Interval     time.Duration
+	Timeout      time.Duration
+	StallTimeout time.Duration
+
+	Client        configv1client.Interface
+	DynamicClient dynamic.Interface
+
+	now func() time.Time
+}
+
+func (o *Options) Complete(f kcmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return kcmdutil.UsageErrorf(cmd, "no positional arguments are accepted")
+	}
+
+	cfg, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	if o.Client, err = configv1client.NewForConfig(cfg); err != nil {
+		return err
+	}
+	if o.DynamicClient, err = dynamic.NewForConfig(cfg); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o *Options) Validate() error {
+	if o.Interval <= 0 {
+		return fmt.Errorf("--interval must be greater than zero")
+	}
+	if o.Timeout < 0 || o.StallTimeout < 0 {
+		return fmt.Errorf("--timeout and --stall-timeout may not be negative")
+	}
+	return nil
+}
+
+func (o *Options) Run() error {
+	ctx := context.TODO()
+	current, err := o.snapshot(ctx, nil)
+	if err != nil {
+		return err
+	}
+	writeStatus(o.Out, current, o.now())
+	if !o.Watch {
+		return nil
+	}
+	if current.done() {
+		fmt.Fprintln(o.Out)
+		writeSummary(o.Out, current)
+		return nil
+	}
+
+	fmt.Fprintf(o.Out, "\nTimeline:\n")
+	lastChange := o.now()
+	var result error
+	poll := func() (bool, error) {
+		next, err := o.snapshot(ctx, current)
+		if err != nil {
+			// the API server is expected to be briefly unavailable during an update
+			fmt.Fprintf(o.ErrOut, "warning: Unable to retrieve update progress: %v\n", err)
+			return false, nil
+		}
+		now := o.now()
+		events := compareSnapshots(current, next)
+		for _, event := range events {
+			fmt.Fprintf(o.Out, "  %s  %s\n", elapsedString(next.Started, now), event)
+		}
+		if len(events) > 0 {
+			lastChange = now
+		}
+		current = next
+
+		switch {
+		case current.done():
+			return true, nil
+		case current.failed():
+			result = fmt.Errorf("the update to %s failed: %s", current.Target, current.FailingMessage)
+			return true, nil
+		case o.StallTimeout > 0 && now.Sub(lastChange) > o.StallTimeout:
+			result = fmt.Errorf("the update to %s has not made progress for %s", current.Target, o.StallTimeout)
+			return true, nil
+		}
+		return false, nil
+	}
+
+	if o.Timeout > 0 {
+		err = wait.PollImmediate(o.Interval, o.Timeout, poll)
+	} else {
+		err = wait.PollImmediateInfinite(o.Interval, poll)
+	}
+	if err == wait.ErrWaitTimeout {
+		result = fmt.Errorf("the update to %s did not complete within %s", current.Target, o.Timeout)
+	} else if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(o.Out)
+	writeSummary(o.Out, current)
+	return result
+}
+
+// snapshot retrieves the current state of the update. Operator completion times observed in previous
+// are preserved.
+func (o *Options) snapshot(ctx context.Context, previous *updateSnapshot) (*updateSnapshot, error) {
+	cv, err := o.Client.ConfigV1().ClusterVersions().Get(ctx, "version", metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("no cluster version information available - you must be connected to an OpenShift version 4 server to fetch the current version")
+		}
+		return nil, err
+	}
+	operators, err := o.Client.ConfigV1().ClusterOperators().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var pools []unstructured.Unstructured
+	if list, err := o.DynamicClient.Resource(machineConfigPoolsResource).List(ctx, metav1.ListOptions{}); err == nil {
+		pools = list.Items
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	snapshot := newSnapshot(cv, operators.Items, pools, o.now())
+	snapshot.preserve(previous)
+	return snapshot, nil
+}
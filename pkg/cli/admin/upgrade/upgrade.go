@@ -17,6 +17,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/util/templates"
 
@@ -25,6 +27,7 @@ import (
 	imagereference "github.com/openshift/library-go/pkg/image/reference"
 
 	"github.com/openshift/oc/pkg/cli/admin/upgrade/channel"
+	"github.com/openshift/oc/pkg/cli/admin/upgrade/status"
 )
 
 var upgradeExample = templates.Examples(`
@@ -33,6 +36,12 @@ var upgradeExample = templates.Examples(`
 
 	# Update to the latest version
 	oc adm upgrade --to-latest=true
+
+	# Check whether the cluster is ready to be updated
+	oc adm upgrade --preflight
+
+	# Check whether the cluster is ready to be updated and print the result as JSON
+	oc adm upgrade --preflight -o json
 `)
 
 func NewOptions(streams genericclioptions.IOStreams) *Options {
@@ -81,6 +90,13 @@ func New(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command
 			is likely to cause data corruption or to completely break a cluster. Avoid upgrading
 			when the cluster is reporting errors or when another upgrade is in progress unless the
 			upgrade cannot make progress.
+
+			Passing --preflight checks whether the cluster is ready to begin an update without
+			changing it. Each check reports pass, warn or fail: degraded or unavailable cluster
+			operators, Upgradeable=False conditions that block minor version updates, paused or
+			degraded machine config pools, pod disruption budgets that would block node drains,
+			and use of APIs that will be removed in a future release. Pass -o json for output
+			suitable for automation. The command exits with an error if any check fails.
 		`),
 		Run: func(cmd *cobra.Command, args []string) {
 			kcmdutil.CheckErr(o.Complete(f, cmd, args))
@@ -97,8 +113,11 @@ func New(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command
 	flags.BoolVar(&o.AllowUpgradeWithWarnings, "allow-upgrade-with-warnings", o.AllowUpgradeWithWarnings, "Upgrade even if an upgrade is in process or a cluster error is blocking the update.")
 	flags.BoolVar(&o.IncludeNotRecommended, "include-not-recommended", o.IncludeNotRecommended, "Display additional updates which are not recommended based on your cluster configuration.")
 	flags.BoolVar(&o.AllowNotRecommended, "allow-not-recommended", o.AllowNotRecommended, "Allows upgrade to a version when it is supported but not recommended for updates")
+	flags.BoolVar(&o.Preflight, "preflight", o.Preflight, "Check whether the cluster is ready to be updated without changing it.")
+	flags.StringVarP(&o.Output, "output", "o", o.Output, "Output format of --preflight. Supports 'json'.")
 
 	cmd.AddCommand(channel.New(f, streams))
+	cmd.AddCommand(status.New(f, streams))
 
 	return cmd
 }
@@ -117,7 +136,12 @@ type Options struct {
 	IncludeNotRecommended    bool
 	AllowNotRecommended      bool
 
-	Client configv1client.Interface
+	Preflight bool
+	Output    string
+
+	Client        configv1client.Interface
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
 }
 
 func (o *Options) Complete(f kcmdutil.Factory, cmd *cobra.Command, args []string) error {
@@ -127,6 +151,18 @@ func (o *Options) Complete(f kcmdutil.Factory, cmd *cobra.Command, args []string
 	if len(o.To) > 0 && len(o.ToImage) > 0 {
 		return fmt.Errorf("only one of --to or --to-image may be provided")
 	}
+	if o.Preflight && (o.Clear || len(o.ToImage) > 0 || len(o.To) > 0 || o.ToLatestAvailable) {
+		return fmt.Errorf("--preflight may not be specified with --clear, --to, --to-image or --to-latest")
+	}
+	switch o.Output {
+	case "":
+	case "json":
+		if !o.Preflight {
+			return fmt.Errorf("--output may only be specified with --preflight")
+		}
+	default:
+		return fmt.Errorf("--output must be 'json'")
+	}
 
 	if len(o.To) > 0 {
 		if _, err := semver.Parse(o.To); err != nil {
@@ -163,6 +199,14 @@ func (o *Options) Complete(f kcmdutil.Factory, cmd *cobra.Command, args []string
 		return err
 	}
 	o.Client = client
+	if o.Preflight {
+		if o.KubeClient, err = kubernetes.NewForConfig(cfg); err != nil {
+			return err
+		}
+		if o.DynamicClient, err = dynamic.NewForConfig(cfg); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -176,6 +220,9 @@ func (o *Options) Run() error {
 	}
 
 	switch {
+	case o.Preflight:
+		return o.runPreflight(cv)
+
 	case o.Clear:
 		if cv.Spec.DesiredUpdate == nil {
 			fmt.Fprintf(o.Out, "info: No update in progress\n")
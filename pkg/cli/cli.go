@@ -58,6 +58,7 @@ import (
 	"github.com/openshift/oc/pkg/cli/version"
 	"github.com/openshift/oc/pkg/cli/whoami"
 	cmdutil "github.com/openshift/oc/pkg/helpers/cmd"
+	"github.com/openshift/oc/pkg/helpers/offline"
 	"github.com/openshift/oc/pkg/helpers/term"
 )
 
@@ -159,9 +160,11 @@ func NewOcCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 	flags := cmds.PersistentFlags()
 	flags.BoolVar(&warningsAsErrors, "warnings-as-errors", warningsAsErrors, "Treat warnings received from the server as errors and exit with a non-zero exit code")
 
+	// fromDir is set by the --from-inspect-dir flag of the commands that read resources
+	fromDir := ""
 	kubeConfigFlags := genericclioptions.NewConfigFlags(true).WithDiscoveryBurst(350).WithDiscoveryQPS(50.0)
 	kubeConfigFlags.AddFlags(flags)
-	matchVersionKubeConfigFlags := kcmdutil.NewMatchVersionFlags(kubeConfigFlags)
+	matchVersionKubeConfigFlags := kcmdutil.NewMatchVersionFlags(offline.NewClientGetter(kubeConfigFlags, &fromDir))
 	matchVersionKubeConfigFlags.AddFlags(cmds.PersistentFlags())
 	cmds.PersistentFlags().AddGoFlagSet(flag.CommandLine)
 	f := kcmdutil.NewFactory(matchVersionKubeConfigFlags)
@@ -170,6 +173,14 @@ func NewOcCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 
 	loginCmd := login.NewCmdLogin(f, ioStreams)
 	secretcmds := secrets.NewCmdSecrets(f, ioStreams)
+	statusCmd := status.NewCmdStatus(f, ioStreams)
+	getCmd := kubectlwrappers.NewCmdGet(f, ioStreams)
+	describeCmd := kubectlwrappers.NewCmdDescribe(f, ioStreams)
+	logsCmd := logs.NewCmdLogs(f, ioStreams)
+	apiResourcesCmd := kubectlwrappers.NewCmdApiResources(f, ioStreams)
+	for _, cmd := range []*cobra.Command{statusCmd, getCmd, describeCmd, logsCmd, apiResourcesCmd} {
+		cmd.Flags().StringVar(&fromDir, "from-inspect-dir", fromDir, "Read resources from the output of 'oc adm inspect' or 'oc adm must-gather' in this directory instead of the server.")
+	}
 
 	groups := ktemplates.CommandGroups{
 		{
@@ -178,7 +189,7 @@ func NewOcCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 				loginCmd,
 				requestproject.NewCmdRequestProject(f, ioStreams),
 				newapp.NewCmdNewApplication(f, ioStreams),
-				status.NewCmdStatus(f, ioStreams),
+				statusCmd,
 				project.NewCmdProject(f, ioStreams),
 				projects.NewCmdProjects(f, ioStreams),
 				kubectlwrappers.NewCmdExplain(f, ioStreams),
@@ -201,8 +212,8 @@ func NewOcCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 			Commands: []*cobra.Command{
 				kubectlwrappers.NewCmdCreate(f, ioStreams),
 				kubectlwrappers.NewCmdApply(f, ioStreams),
-				kubectlwrappers.NewCmdGet(f, ioStreams),
-				kubectlwrappers.NewCmdDescribe(f, ioStreams),
+				getCmd,
+				describeCmd,
 				kubectlwrappers.NewCmdEdit(f, ioStreams),
 				set.NewCmdSet(f, ioStreams),
 				kubectlwrappers.NewCmdLabel(f, ioStreams),
@@ -218,7 +229,7 @@ func NewOcCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 		{
 			Message: "Troubleshooting and Debugging Commands:",
 			Commands: []*cobra.Command{
-				logs.NewCmdLogs(f, ioStreams),
+				logsCmd,
 				rsh.NewCmdRsh(f, ioStreams),
 				rsync.NewCmdRsync(f, ioStreams),
 				kubectlwrappers.NewCmdPortForward(f, ioStreams),
@@ -246,7 +257,7 @@ func NewOcCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 				registry.NewCmd(f, ioStreams),
 				idle.NewCmdIdle(f, ioStreams),
 				kubectlwrappers.NewCmdApiVersions(f, ioStreams),
-				kubectlwrappers.NewCmdApiResources(f, ioStreams),
+				apiResourcesCmd,
 				kubectlwrappers.NewCmdClusterInfo(f, ioStreams),
 				kubectlwrappers.NewCmdDiff(f, ioStreams),
 				kubectlwrappers.NewCmdKustomize(ioStreams),
@@ -26,6 +26,8 @@ import (
 	"github.com/docker/distribution/registry/client/auth"
 	"github.com/docker/distribution/registry/client/transport"
 	godigest "github.com/opencontainers/go-digest"
+
+	"github.com/openshift/library-go/pkg/image/registryclient"
 )
 
 type s3Driver struct {
@@ -33,6 +35,8 @@ type s3Driver struct {
 	Region    string
 	Creds     auth.CredentialStore
 	CopyFrom  []string
+	// Endpoint is set for S3-compatible services other than AWS.
+	Endpoint string
 
 	repositories map[string]*s3.S3
 }
@@ -79,6 +83,10 @@ func (d *s3Driver) newObject(server *url.URL, region string, insecure bool, secu
 	awsConfig.WithCredentials(creds)
 	awsConfig.WithRegion(region)
 	awsConfig.WithDisableSSL(insecure)
+	if len(d.Endpoint) > 0 {
+		awsConfig.WithEndpoint(d.Endpoint)
+		awsConfig.WithS3ForcePathStyle(true)
+	}
 
 	switch {
 	case klog.V(10).Enabled():
@@ -135,6 +143,39 @@ func (d *s3Driver) Repository(ctx context.Context, server *url.URL, repoName str
 	return repo, nil
 }
 
+// UploadObject uploads the contents of body to an S3 bucket named by a URL of the form
+// s3://HOST/REGION/BUCKET/KEY. Hosts other than s3.amazonaws.com are treated as S3-compatible
+// endpoints. Credentials are read from creds, the AWS environment variables, or the shared AWS
+// credentials file.
+func UploadObject(ctx context.Context, creds auth.CredentialStore, target *url.URL, insecure bool, body io.Reader) error {
+	parts := strings.SplitN(strings.TrimPrefix(target.Path, "/"), "/", 3)
+	if target.Scheme != "s3" || len(parts) < 3 || len(parts[0]) == 0 || len(parts[1]) == 0 || len(parts[2]) == 0 {
+		return fmt.Errorf("%s must be of the form s3://HOST/REGION/BUCKET/KEY", target)
+	}
+	if creds == nil {
+		creds = registryclient.NoCredentials
+	}
+	scheme := "https"
+	if insecure {
+		scheme = "http"
+	}
+	server := &url.URL{Scheme: scheme, Host: target.Host}
+	driver := &s3Driver{Creds: creds}
+	if target.Host != "s3.amazonaws.com" {
+		driver.Endpoint = server.String()
+	}
+	s3obj, err := driver.newObject(server, parts[0], insecure, &url.URL{Scheme: server.Scheme, Host: server.Host, Path: target.Path})
+	if err != nil {
+		return err
+	}
+	_, err = s3manager.NewUploaderWithClient(s3obj).UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(parts[1]),
+		Key:    aws.String(parts[2]),
+		Body:   body,
+	})
+	return err
+}
+
 type s3Repository struct {
 	ctx      context.Context
 	s3       *s3.S3
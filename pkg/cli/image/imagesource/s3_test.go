@@ -0,0 +1,40 @@
+package imagesource
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestUploadObject(t *testing.T) {
+	os.Setenv("AWS_ACCESS_KEY_ID", "access")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	var method, path string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	target := &url.URL{Scheme: "s3", Host: serverURL.Host, Path: "/us-east-1/bucket/case/must-gather.tar.gz"}
+	if err := UploadObject(context.TODO(), nil, target, true, bytes.NewBufferString("archive")); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || path != "/bucket/case/must-gather.tar.gz" || string(body) != "archive" {
+		t.Errorf("unexpected request: %s %s %q", method, path, body)
+	}
+
+	if err := UploadObject(context.TODO(), nil, &url.URL{Scheme: "s3", Host: serverURL.Host, Path: "/us-east-1/bucket"}, true, bytes.NewBufferString("")); err == nil {
+		t.Error("expected an error for a URL without a key")
+	}
+}
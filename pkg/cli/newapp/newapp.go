@@ -3,6 +3,7 @@ package newapp
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -38,6 +39,7 @@ import (
 	"k8s.io/kubectl/pkg/polymorphichelpers"
 	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/yaml"
 
 	appsv1 "github.com/openshift/api/apps/v1"
 	"github.com/openshift/api/build"
@@ -57,6 +59,7 @@ import (
 	newappapp "github.com/openshift/oc/pkg/helpers/newapp/app"
 	newcmd "github.com/openshift/oc/pkg/helpers/newapp/cmd"
 	dockerutil "github.com/openshift/oc/pkg/helpers/newapp/docker"
+	"github.com/openshift/oc/pkg/helpers/newapp/skeleton"
 )
 
 // RoutePollTimoutSeconds sets how long new-app command waits for route host to be prepopulated
@@ -77,6 +80,16 @@ var (
 		You may either specify components using the various existing flags or let oc new-app autodetect
 		what kind of components you have provided.
 
+		If the source code has a devfile.yaml but no Jenkinsfile, Dockerfile or detected language, and no
+		build strategy is given, the builds, deployment, services and routes are generated from its
+		container, image and volume components and from its default build and run commands. Devfiles that
+		use features with no equivalent, such as parent devfiles or kubernetes components, are ignored
+		with a warning.
+
+		If the source code has no known files at its root, as in a monorepo, each subdirectory and checked
+		out git submodule with its own source is a separate component, built with its directory as the
+		context directory.
+
 		If you provide source code, a new build will be automatically triggered.
 		You can use 'oc status' to check the progress.`)
 
@@ -90,6 +103,9 @@ var (
 		# Create an application myapp with Docker based build strategy expecting binary input
 		oc new-app  --strategy=docker --binary --name myapp
 
+		# Create an application that builds the "prod" stage of the multi-stage Dockerfile of a repository
+		oc new-app https://github.com/youruser/yourgitrepo --strategy=docker --target=prod
+
 		# Create a Ruby application based on the provided [image]~[source code] combination
 		oc new-app centos/ruby-25-centos7~https://github.com/sclorg/ruby-ex.git
 
@@ -102,6 +118,13 @@ var (
 		# Create an application from a remote repository using its beta4 branch
 		oc new-app https://github.com/openshift/ruby-hello-world#beta4
 
+		# Create an application from an image and expose it with a route, a health check and resource requests
+		oc new-app --image=registry/repo/webimage --expose --probe=http:8080/healthz --requests=cpu=100m,memory=256Mi --limits=memory=512Mi
+
+		# Write the objects of an application as a Kustomize base with overlays, or as a Helm chart, instead of creating them
+		oc new-app https://github.com/sclorg/nodejs-ex.git -o kustomize=./deploy
+		oc new-app https://github.com/sclorg/nodejs-ex.git -o helm=./chart
+
 		# Create an application based on a stored template, explicitly setting a parameter value
 		oc new-app --template=ruby-helloworld-sample --param=MYSQL_USER=admin
 
@@ -123,6 +146,9 @@ var (
 
 		# Search for "ruby" in stored templates and print the output as YAML
 		oc new-app --search --template=ruby --output=yaml
+
+		# Search for "postgres", tolerating typos, and print the scored matches as JSON
+		oc new-app --search postgress --show-scores --output=json
 	`)
 
 	newAppNoInput = `You must specify one or more images, image streams, templates, or source code locations to create an application.
@@ -156,6 +182,9 @@ type ObjectGeneratorOptions struct {
 
 	LogsForObject polymorphichelpers.LogsForObjectFunc
 	Printer       printers.ResourcePrinter
+	// Skeleton writes the objects as a Kustomize or Helm layout instead of
+	// printing them, for the kustomize=DIR and helm=DIR output formats.
+	Skeleton *skeleton.Writer
 
 	genericclioptions.IOStreams
 }
@@ -176,6 +205,15 @@ func (o *ObjectGeneratorOptions) Complete(f kcmdutil.Factory, c *cobra.Command,
 	o.Action.IOStreams = o.IOStreams
 
 	if o.PrintFlags.OutputFormat != nil {
+		format, dir, ok, err := skeleton.ParseOutput(*o.PrintFlags.OutputFormat)
+		if err != nil {
+			return err
+		}
+		if ok {
+			o.Skeleton = &skeleton.Writer{Format: format, Dir: dir, Typer: newAppScheme}
+			// the objects are generated as they are for the yaml output
+			*o.PrintFlags.OutputFormat = "yaml"
+		}
 		o.Action.Output = *o.PrintFlags.OutputFormat
 	}
 
@@ -263,6 +301,7 @@ func NewCmdNewApplication(f kcmdutil.Factory, streams genericclioptions.IOStream
 	}
 
 	o.PrintFlags.AddFlags(cmd)
+	AddSkeletonOutputUsage(cmd)
 
 	cmd.Flags().BoolVar(&o.Config.AsTestDeployment, "as-test", o.Config.AsTestDeployment, "If true create this application as a test deployment, which validates that the deployment succeeds and then scales down.")
 	cmd.Flags().BoolVar(&o.Config.DeploymentConfig, "as-deployment-config", o.Config.DeploymentConfig, "If true create this application as a deployment config, which allows for hooks and custom strategies.")
@@ -287,17 +326,26 @@ func NewCmdNewApplication(f kcmdutil.Factory, streams genericclioptions.IOStream
 	cmd.MarkFlagFilename("build-env-file")
 	cmd.Flags().StringVar(&o.Config.Name, "name", o.Config.Name, "Set name to use for generated application artifacts")
 	cmd.Flags().Var(&o.Config.Strategy, "strategy", "Specify the build strategy to use if you don't want to detect (docker|pipeline|source). NOTICE: the pipeline strategy is deprecated; consider using Jenkinsfiles directly on Jenkins or OpenShift Pipelines.")
+	cmd.Flags().StringVar(&o.Config.Target, "target", o.Config.Target, "Specify the stage of a multi-stage Dockerfile to build, as with docker build --target.")
 	cmd.Flags().StringP("labels", "l", "", "Label to set in all resources for this application.")
 	cmd.Flags().BoolVar(&o.Config.IgnoreUnknownParameters, "ignore-unknown-parameters", o.Config.IgnoreUnknownParameters, "If true, will not stop processing if a provided parameter does not exist in the template.")
 	cmd.Flags().BoolVar(&o.Config.InsecureRegistry, "insecure-registry", o.Config.InsecureRegistry, "If true, indicates that the referenced container images are on insecure registries and should bypass certificate checking")
 	cmd.Flags().BoolVarP(&o.Config.AsList, "list", "L", o.Config.AsList, "List all local templates and image streams that can be used to create.")
 	cmd.Flags().BoolVarP(&o.Config.AsSearch, "search", "S", o.Config.AsSearch, "Search all templates, image streams, and container images that match the arguments provided.")
+	cmd.Flags().BoolVar(&o.Config.ShowScores, "show-scores", o.Config.ShowScores, "If true, print the score of each match of --search or --list, lower is better. With --output=json or yaml, the scored matches are printed instead of the matched objects.")
 	cmd.Flags().BoolVar(&o.Config.AllowMissingImages, "allow-missing-images", o.Config.AllowMissingImages, "If true, indicates that referenced container images that cannot be found locally or in a registry should still be used.")
 	cmd.Flags().BoolVar(&o.Config.AllowMissingImageStreamTags, "allow-missing-imagestream-tags", o.Config.AllowMissingImageStreamTags, "If true, indicates that image stream tags that don't exist should still be used.")
 	cmd.Flags().BoolVar(&o.Config.AllowSecretUse, "grant-install-rights", o.Config.AllowSecretUse, "If true, a component that requires access to your account may use your token to install software into your project. Only grant images you trust the right to run with your token.")
 	cmd.Flags().StringVar(&o.Config.SourceSecret, "source-secret", o.Config.SourceSecret, "The name of an existing secret that should be used for cloning a private git repository.")
 	cmd.Flags().BoolVar(&o.Config.SkipGeneration, "no-install", o.Config.SkipGeneration, "Do not attempt to run images that describe themselves as being installable")
 	cmd.Flags().BoolVar(&o.Config.BinaryBuild, "binary", o.Config.BinaryBuild, "Instead of expecting a source URL, set the build to expect binary contents. Will disable triggers.")
+	cmd.Flags().StringVar(&o.Config.DetectorConfig, "detector-config", o.Config.DetectorConfig, "Path to a file of rules that detect the platform of source code and the builder image stream to use for it, tried before the built-in detectors.")
+	cmd.MarkFlagFilename("detector-config", "yaml", "yml", "json")
+	cmd.Flags().StringVar(&o.Config.Expose, "expose", o.Config.Expose, "Create a route to the service of the application. The value sets the host of the route, if omitted the router chooses one.")
+	cmd.Flags().Lookup("expose").NoOptDefVal = "true"
+	cmd.Flags().StringVar(&o.Config.Probe, "probe", o.Config.Probe, "Set a readiness and liveness probe on the containers, as TYPE[:PORT][/PATH] with TYPE one of http, https or tcp. The port defaults to the port the image names after TYPE, or its first TCP port.")
+	cmd.Flags().StringVar(&o.Config.Requests, "requests", o.Config.Requests, "The resource requirement requests for the containers. For example, 'cpu=100m,memory=256Mi'.")
+	cmd.Flags().StringVar(&o.Config.Limits, "limits", o.Config.Limits, "The resource requirement limits for the containers. For example, 'cpu=200m,memory=512Mi'.")
 
 	o.Action.BindForOutput(cmd.Flags(), "output", "template")
 	cmd.Flags().String("output-version", "", "The preferred API versions of the output objects")
@@ -318,17 +366,49 @@ func (o *AppOptions) Complete(f kcmdutil.Factory, c *cobra.Command, args []strin
 	return nil
 }
 
+// AddSkeletonOutputUsage documents the kustomize=DIR and helm=DIR output
+// formats in the usage of the output flag.
+func AddSkeletonOutputUsage(cmd *cobra.Command) {
+	if flag := cmd.Flags().Lookup("output"); flag != nil {
+		flag.Usage += " Use kustomize=DIR or helm=DIR to write the objects to DIR as a Kustomize base with overlays, or as a Helm chart."
+	}
+}
+
+// WriteSkeleton writes the generated objects as a Kustomize or Helm layout, and
+// prints the written files.
+func (o *ObjectGeneratorOptions) WriteSkeleton(result *newcmd.AppResult) error {
+	o.Skeleton.Name = result.Name
+	if len(o.Skeleton.Name) == 0 {
+		o.Skeleton.Name = "app"
+	}
+	paths, err := o.Skeleton.Write(result.List.Items)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		fmt.Fprintf(o.Out, "wrote %s\n", path)
+	}
+	return nil
+}
+
 // RunNewApp contains all the necessary functionality for the OpenShift cli new-app command
 func (o *AppOptions) RunNewApp() error {
 	config := o.Config
 	out := o.Action.Out
 
 	if config.Querying() {
+		if o.Skeleton != nil {
+			return fmt.Errorf("--output=%s cannot be used with --search or --list", o.Skeleton.Format)
+		}
 		result, err := config.RunQuery()
 		if err != nil {
 			return HandleError(err, o.CommandPath, config, TransformRunError)
 		}
 
+		if config.ShowScores && len(o.Action.Output) > 0 {
+			return printScoredQueryResult(result, o.Action.Output, out)
+		}
+
 		if o.Action.ShouldPrint() {
 			list := &unstructured.UnstructuredList{
 				Object: map[string]interface{}{
@@ -348,7 +428,7 @@ func (o *AppOptions) RunNewApp() error {
 			return o.Printer.PrintObj(list, o.Out)
 		}
 
-		return printHumanReadableQueryResult(result, out)
+		return printHumanReadableQueryResult(result, out, config.ShowScores)
 	}
 
 	CheckGitInstalled(out)
@@ -392,6 +472,9 @@ func (o *AppOptions) RunNewApp() error {
 		return err
 	}
 
+	if o.Skeleton != nil {
+		return o.WriteSkeleton(result)
+	}
 	if o.Action.ShouldPrint() {
 		// TODO(juanvallejo): this needs to be fixed by updating QueryResult.List to be of type corev1.List
 		printableList := &corev1.List{
@@ -704,6 +787,10 @@ func CompleteAppConfig(config *newcmd.AppConfig, f kcmdutil.Factory, c *cobra.Co
 	}
 	config.SetOpenShiftClient(imageClient, templateClient, routeClient, namespace, dockerClient)
 
+	if err := config.AddDetectorConfig(); err != nil {
+		return err
+	}
+
 	if config.AllowSecretUse {
 		cfg, err := f.ToRESTConfig()
 		if err != nil {
@@ -742,6 +829,9 @@ func CompleteAppConfig(config *newcmd.AppConfig, f kcmdutil.Factory, c *cobra.Co
 	if config.AllowMissingImages && config.AsSearch {
 		return kcmdutil.UsageErrorf(c, "--allow-missing-images and --search are mutually exclusive.")
 	}
+	if config.ShowScores && !config.Querying() {
+		return kcmdutil.UsageErrorf(c, "--show-scores can only be used with --search or --list.")
+	}
 
 	if len(config.SourceImage) != 0 && len(config.SourceImagePath) == 0 {
 		return kcmdutil.UsageErrorf(c, "--source-image-path must be specified when --source-image is specified.")
@@ -757,6 +847,9 @@ func CompleteAppConfig(config *newcmd.AppConfig, f kcmdutil.Factory, c *cobra.Co
 	if len(config.BuildArgs) > 0 && config.Strategy != newapp.StrategyUnspecified && config.Strategy != newapp.StrategyDocker {
 		return kcmdutil.UsageErrorf(c, "Cannot use '--build-arg' without a Docker build")
 	}
+	if len(config.Target) > 0 && config.Strategy != newapp.StrategyUnspecified && config.Strategy != newapp.StrategyDocker {
+		return kcmdutil.UsageErrorf(c, "Cannot use '--target' without a Docker build")
+	}
 	return nil
 }
 
@@ -1083,7 +1176,7 @@ func UsageError(commandPath, format string, args ...interface{}) error {
 	return fmt.Errorf("%s\nSee '%s -h' for help and examples", msg, commandPath)
 }
 
-func printHumanReadableQueryResult(r *newcmd.QueryResult, out io.Writer) error {
+func printHumanReadableQueryResult(r *newcmd.QueryResult, out io.Writer, showScores bool) error {
 	if len(r.Matches) == 0 {
 		return fmt.Errorf("no matches found")
 	}
@@ -1116,6 +1209,9 @@ func printHumanReadableQueryResult(r *newcmd.QueryResult, out io.Writer) error {
 
 			fmt.Fprintln(out, template.Name)
 			fmt.Fprintf(out, "  Project: %v\n", template.Namespace)
+			if showScores {
+				fmt.Fprintf(out, "  Score:   %v\n", match.Score)
+			}
 			if len(description) > 0 {
 				fmt.Fprintf(out, "  %v\n", description)
 			}
@@ -1150,6 +1246,9 @@ func printHumanReadableQueryResult(r *newcmd.QueryResult, out io.Writer) error {
 				fmt.Fprintf(out, "  Tracks:  %v\n", imageStream.Spec.DockerImageRepository)
 			}
 			fmt.Fprintf(out, "  Tags:    %v\n", tags)
+			if showScores {
+				fmt.Fprintf(out, "  Score:   %v\n", match.Score)
+			}
 			if len(description) > 0 {
 				fmt.Fprintf(out, "  %v\n", description)
 			}
@@ -1172,6 +1271,9 @@ func printHumanReadableQueryResult(r *newcmd.QueryResult, out io.Writer) error {
 			fmt.Fprintln(out, name)
 			fmt.Fprintf(out, "  Registry: %v\n", match.Meta["registry"])
 			fmt.Fprintf(out, "  Tags:     %v\n", tag)
+			if showScores {
+				fmt.Fprintf(out, "  Score:    %v\n", match.Score)
+			}
 
 			if len(image.Comment) > 0 {
 				fmt.Fprintf(out, "  %v\n", image.Comment)
@@ -1183,6 +1285,61 @@ func printHumanReadableQueryResult(r *newcmd.QueryResult, out io.Writer) error {
 	return nil
 }
 
+// scoredMatch is a match of a search as printed by --show-scores.
+type scoredMatch struct {
+	Name        string  `json:"name"`
+	Kind        string  `json:"kind"`
+	Namespace   string  `json:"namespace,omitempty"`
+	Tag         string  `json:"tag,omitempty"`
+	Argument    string  `json:"argument"`
+	Description string  `json:"description,omitempty"`
+	Score       float32 `json:"score"`
+}
+
+// printScoredQueryResult prints the matches of a query ranked by score, as
+// JSON or YAML.
+func printScoredQueryResult(r *newcmd.QueryResult, output string, out io.Writer) error {
+	if output != "json" && output != "yaml" {
+		return fmt.Errorf("--show-scores can only be used with --output=json or --output=yaml")
+	}
+	matches := append(newappapp.ScoredComponentMatches{}, r.Matches...)
+	sort.Sort(matches)
+	scored := []scoredMatch{}
+	for _, match := range matches {
+		m := scoredMatch{
+			Name:        match.Name,
+			Tag:         match.ImageTag,
+			Argument:    match.Argument,
+			Description: match.Description,
+			Score:       match.Score,
+		}
+		switch {
+		case match.IsTemplate():
+			m.Kind, m.Namespace = "Template", match.Template.Namespace
+		case match.ImageStream != nil:
+			m.Kind, m.Namespace = "ImageStream", match.ImageStream.Namespace
+		default:
+			m.Kind = "Image"
+		}
+		scored = append(scored, m)
+	}
+	result := map[string][]scoredMatch{"matches": scored}
+
+	var data []byte
+	var err error
+	if output == "json" {
+		data, err = json.MarshalIndent(result, "", "    ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(result)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
 type configSecretRetriever struct {
 	config *restclient.Config
 }
@@ -53,6 +53,13 @@ var (
 		# Create a build config using a Dockerfile specified as an argument
 		oc new-build -D $'FROM centos:7\nRUN yum install -y httpd'
 
+		# Create a build config that builds the "test" stage of the multi-stage Dockerfile of a repository
+		oc new-build https://github.com/youruser/yourgitrepo --strategy=docker --target=test
+
+		# Create a build config for a repository whose platform is detected with custom rules, which map
+		# it to a builder image stream
+		oc new-build https://github.com/youruser/yourgitrepo --detector-config=detectors.yaml
+
 		# Create a build config from a remote repository and add custom environment variables
 		oc new-build https://github.com/openshift/ruby-hello-world -e RACK_ENV=development
 
@@ -145,7 +152,10 @@ func NewCmdNewBuild(f kcmdutil.Factory, streams genericclioptions.IOStreams) *co
 	cmd.Flags().Var(&o.Config.Strategy, "strategy", "Specify the build strategy to use if you don't want to detect (docker|pipeline|source). NOTICE: the pipeline strategy is deprecated; consider using Jenkinsfiles directly on Jenkins or OpenShift Pipelines.")
 	cmd.Flags().StringVarP(&o.Config.Dockerfile, "dockerfile", "D", o.Config.Dockerfile, "Specify the contents of a Dockerfile to build directly, implies --strategy=docker. Pass '-' to read from STDIN.")
 	cmd.Flags().StringArrayVar(&o.Config.BuildArgs, "build-arg", o.Config.BuildArgs, "Specify a key-value pair to pass to Docker during the build.")
+	cmd.Flags().StringVar(&o.Config.Target, "target", o.Config.Target, "Specify the stage of a multi-stage Dockerfile to build, as with docker build --target.")
 	cmd.Flags().BoolVar(&o.Config.BinaryBuild, "binary", o.Config.BinaryBuild, "Instead of expecting a source URL, set the build to expect binary contents. Will disable triggers.")
+	cmd.Flags().StringVar(&o.Config.DetectorConfig, "detector-config", o.Config.DetectorConfig, "Path to a file of rules that detect the platform of source code and the builder image stream to use for it, tried before the built-in detectors.")
+	cmd.MarkFlagFilename("detector-config", "yaml", "yml", "json")
 	cmd.Flags().StringP("labels", "l", "", "Label to set in all generated resources.")
 	cmd.Flags().BoolVar(&o.Config.InsecureRegistry, "insecure-registry", o.Config.InsecureRegistry, "If true, indicates that the referenced container images are on insecure registries and should bypass certificate checking")
 	cmd.Flags().BoolVar(&o.Config.AllowMissingImages, "allow-missing-images", o.Config.AllowMissingImages, "If true, indicates that referenced container images that cannot be found locally or in a registry should still be used.")
@@ -159,6 +169,7 @@ func NewCmdNewBuild(f kcmdutil.Factory, streams genericclioptions.IOStreams) *co
 	cmd.Flags().String("output-version", "", "The preferred API versions of the output objects")
 
 	o.PrintFlags.AddFlags(cmd)
+	ocnewapp.AddSkeletonOutputUsage(cmd)
 	return cmd
 }
 
@@ -203,6 +214,9 @@ func (o *BuildOptions) RunNewBuild() error {
 		return err
 	}
 
+	if o.Skeleton != nil {
+		return o.WriteSkeleton(result)
+	}
 	if o.Action.ShouldPrint() {
 		// TODO(juanvallejo): this needs to be fixed by updating QueryResult.List to be of type corev1.List
 		printableList := &corev1.List{
@@ -0,0 +1,341 @@
+package startbuild
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/pkg/fileutils"
+	units "github.com/docker/go-units"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+
+	s2ifs "github.com/openshift/oc/pkg/helpers/source-to-image/fs"
+	"github.com/openshift/oc/pkg/helpers/source-to-image/tar"
+)
+
+// The ignore files that --exclude-from reads.
+const (
+	dockerignoreSource = "dockerignore"
+	gitignoreSource    = "gitignore"
+)
+
+// archivedFile is a regular file of an archive.
+type archivedFile struct {
+	path string
+	size int64
+}
+
+// excludedPath is a path left out of an archive, and what excluded it.
+type excludedPath struct {
+	path   string
+	source string
+}
+
+// directoryArchiver creates the gzipped tar archive of a directory that is
+// uploaded by --from-dir, and records what the archive contains.
+type directoryArchiver struct {
+	// Exclude excludes the paths it matches. As with --exclude, the full path
+	// is matched, and an empty pattern excludes nothing.
+	Exclude *regexp.Regexp
+	// ExcludeFrom are the ignore files whose patterns exclude paths,
+	// dockerignore or gitignore.
+	ExcludeFrom []string
+	// OutPath is a file that receives a copy of the archive, if set. It is
+	// never archived.
+	OutPath string
+
+	// Files and Excluded are the files and the excluded paths of the last
+	// archive, in the order they were walked, and Size is its size.
+	Files    []archivedFile
+	Excluded []excludedPath
+	Size     int64
+}
+
+// Archive writes the archive of dir to w.
+func (a *directoryArchiver) Archive(dir string, w io.Writer) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	filter, err := a.newFilter(dir)
+	if err != nil {
+		return err
+	}
+	a.Files, a.Excluded, a.Size = nil, nil, 0
+
+	counter := &countingWriter{w: w}
+	var out io.Writer = counter
+	outPath := ""
+	if len(a.OutPath) > 0 {
+		if outPath, err = filepath.Abs(a.OutPath); err != nil {
+			return err
+		}
+		f, err := os.Create(outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = io.MultiWriter(counter, f)
+	}
+	gw := gzip.NewWriter(out)
+	t := tar.New(&filteringFileSystem{FileSystem: s2ifs.NewFileSystem(), root: dir, skip: outPath, filter: filter, archiver: a})
+	// the filter applies the exclusion pattern
+	t.SetExclusionPattern(nil)
+	if err := t.CreateTarStream(dir, false, gw); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	a.Size = counter.n
+	return nil
+}
+
+func (a *directoryArchiver) excluded(path string, isDir bool, source string) {
+	// only the top excluded directory is recorded
+	if len(a.Excluded) > 0 {
+		if last := a.Excluded[len(a.Excluded)-1].path; strings.HasSuffix(last, "/") && strings.HasPrefix(path, last) {
+			return
+		}
+	}
+	if isDir {
+		path += "/"
+	}
+	a.Excluded = append(a.Excluded, excludedPath{path: path, source: source})
+}
+
+// Excludes returns true if the archive of dir leaves out path, which is in
+// dir.
+func (a *directoryArchiver) Excludes(dir, path string) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	if len(a.OutPath) > 0 {
+		if outPath, err := filepath.Abs(a.OutPath); err == nil && outPath == path {
+			return true
+		}
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	filter, err := a.newFilter(dir)
+	if err != nil {
+		return false
+	}
+	// match path and its parent directories as the walk of the archive does
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := range parts {
+		partRel := strings.Join(parts[:i+1], "/")
+		partPath := filepath.Join(dir, filepath.FromSlash(partRel))
+		last := i == len(parts)-1
+		isDir := !last
+		if last {
+			if info, err := os.Lstat(partPath); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if source, all := filter.excludes(partPath, partRel, isDir); len(source) > 0 && (all || last) {
+			return true
+		}
+		if isDir && filter.gitignore {
+			if err := filter.readGitignore(partPath, partRel); err != nil {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// PrintSummary prints the files, the excluded paths and the size of the last
+// archive.
+func (a *directoryArchiver) PrintSummary(out io.Writer) {
+	var total int64
+	for _, f := range a.Files {
+		fmt.Fprintf(out, "%10s  %s\n", units.HumanSize(float64(f.size)), f.path)
+		total += f.size
+	}
+	if len(a.Excluded) > 0 {
+		fmt.Fprintf(out, "\nExcluded:\n")
+		for _, e := range a.Excluded {
+			fmt.Fprintf(out, "  %s (%s)\n", e.path, e.source)
+		}
+	}
+	fmt.Fprintf(out, "\n%d files, %s (%s compressed), %d excluded paths\n", len(a.Files), units.HumanSize(float64(total)), units.HumanSize(float64(a.Size)), len(a.Excluded))
+}
+
+// archiveFilter decides which paths of a directory are excluded from its
+// archive.
+type archiveFilter struct {
+	exclude           *regexp.Regexp
+	dockerignore      *fileutils.PatternMatcher
+	gitignore         bool
+	gitignorePatterns []gitignore.Pattern
+}
+
+func (a *directoryArchiver) newFilter(dir string) (*archiveFilter, error) {
+	f := &archiveFilter{exclude: a.Exclude}
+	for _, source := range a.ExcludeFrom {
+		switch source {
+		case dockerignoreSource:
+			patterns, err := readDockerignore(filepath.Join(dir, ".dockerignore"))
+			if err != nil {
+				return nil, err
+			}
+			if f.dockerignore, err = fileutils.NewPatternMatcher(patterns); err != nil {
+				return nil, fmt.Errorf("invalid .dockerignore: %v", err)
+			}
+		case gitignoreSource:
+			f.gitignore = true
+			if err := f.readGitignore(dir, ""); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown ignore file %q, expected %s or %s", source, dockerignoreSource, gitignoreSource)
+		}
+	}
+	return f, nil
+}
+
+// excludes returns what excludes path, whose slash separated path in the
+// directory is rel, if it is excluded, and whether the paths under it are
+// excluded too. The paths under a directory that the exclusion pattern
+// matches are matched on their own, as tar does.
+func (f *archiveFilter) excludes(path, rel string, isDir bool) (string, bool) {
+	if f.exclude != nil && len(f.exclude.String()) > 0 && f.exclude.MatchString(filepath.ToSlash(path)) {
+		return "--exclude", false
+	}
+	if f.dockerignore != nil {
+		if matched, _ := f.dockerignore.Matches(rel); matched {
+			// files under a directory may be included again by exceptions
+			return ".dockerignore", !f.dockerignore.Exclusions()
+		}
+	}
+	if f.gitignore && gitignore.NewMatcher(f.gitignorePatterns).Match(strings.Split(rel, "/"), isDir) {
+		return ".gitignore", true
+	}
+	return "", false
+}
+
+// readGitignore adds the patterns of the .gitignore file of dir, if it has
+// one, whose slash separated path in the archived directory is rel.
+func (f *archiveFilter) readGitignore(dir, rel string) error {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var domain []string
+	if len(rel) > 0 {
+		domain = strings.Split(rel, "/")
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") && len(strings.TrimSpace(line)) > 0 {
+			f.gitignorePatterns = append(f.gitignorePatterns, gitignore.ParsePattern(line, domain))
+		}
+	}
+	return scanner.Err()
+}
+
+// readDockerignore returns the patterns of a .dockerignore file, as docker
+// build reads them, or none if there is no such file.
+func readDockerignore(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	patterns := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		pattern := strings.TrimSpace(scanner.Text())
+		if len(pattern) == 0 || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		exception := strings.HasPrefix(pattern, "!")
+		if exception {
+			pattern = strings.TrimSpace(pattern[1:])
+		}
+		if len(pattern) > 0 {
+			pattern = filepath.Clean(pattern)
+			pattern = filepath.ToSlash(pattern)
+			if len(pattern) > 1 && pattern[0] == '/' {
+				pattern = pattern[1:]
+			}
+		}
+		if exception {
+			pattern = "!" + pattern
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, scanner.Err()
+}
+
+// filteringFileSystem is a file system whose walk leaves out the paths that
+// a filter excludes, and records the walked files in an archiver.
+type filteringFileSystem struct {
+	s2ifs.FileSystem
+	root     string
+	skip     string
+	filter   *archiveFilter
+	archiver *directoryArchiver
+}
+
+func (fs *filteringFileSystem) Walk(root string, walkFn filepath.WalkFunc) error {
+	return fs.FileSystem.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == fs.root {
+			return walkFn(path, info, err)
+		}
+		if path == fs.skip {
+			return nil
+		}
+		rel, err := filepath.Rel(fs.root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		isDir := info.IsDir()
+		if source, all := fs.filter.excludes(path, rel, isDir); len(source) > 0 {
+			fs.archiver.excluded(rel, isDir, source)
+			if isDir && all {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isDir && fs.filter.gitignore {
+			if err := fs.filter.readGitignore(path, rel); err != nil {
+				return err
+			}
+		}
+		if info.Mode().IsRegular() {
+			fs.archiver.Files = append(fs.archiver.Files, archivedFile{path: rel, size: info.Size()})
+		}
+		return walkFn(path, info, nil)
+	})
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
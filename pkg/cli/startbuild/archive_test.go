@@ -0,0 +1,206 @@
+package startbuild
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+
+	s2itar "github.com/openshift/oc/pkg/helpers/source-to-image/tar"
+)
+
+func TestDirectoryArchiver(t *testing.T) {
+	files := map[string]string{
+		".git/HEAD":               "ref: refs/heads/master",
+		".dockerignore":           "# comment\n/dist\n*.log\n!keep.log\n",
+		".gitignore":              "node_modules/\n",
+		"main.go":                 "package main",
+		"debug.log":               "debug",
+		"keep.log":                "keep",
+		"dist/app":                "binary",
+		"node_modules/a/index.js": "module",
+		"web/.gitignore":          "*.tmp\n",
+		"web/index.html":          "<html>",
+		"web/cache.tmp":           "cache",
+	}
+
+	tests := []struct {
+		name        string
+		exclude     *regexp.Regexp
+		excludeFrom []string
+		files       []string
+		excluded    []excludedPath
+	}{
+		{
+			name:    "default exclusion pattern",
+			exclude: s2itar.DefaultExclusionPattern,
+			files:   []string{".dockerignore", ".gitignore", "debug.log", "dist/app", "keep.log", "main.go", "node_modules/a/index.js", "web/.gitignore", "web/cache.tmp", "web/index.html"},
+			excluded: []excludedPath{
+				{path: ".git/", source: "--exclude"},
+			},
+		},
+		{
+			name:        "dockerignore",
+			exclude:     s2itar.DefaultExclusionPattern,
+			excludeFrom: []string{dockerignoreSource},
+			files:       []string{".dockerignore", ".gitignore", "keep.log", "main.go", "node_modules/a/index.js", "web/.gitignore", "web/cache.tmp", "web/index.html"},
+			excluded: []excludedPath{
+				{path: ".git/", source: "--exclude"},
+				{path: "debug.log", source: ".dockerignore"},
+				{path: "dist/", source: ".dockerignore"},
+			},
+		},
+		{
+			name:        "gitignore",
+			exclude:     s2itar.DefaultExclusionPattern,
+			excludeFrom: []string{gitignoreSource},
+			files:       []string{".dockerignore", ".gitignore", "debug.log", "dist/app", "keep.log", "main.go", "web/.gitignore", "web/index.html"},
+			excluded: []excludedPath{
+				{path: ".git/", source: "--exclude"},
+				{path: "node_modules/", source: ".gitignore"},
+				{path: "web/cache.tmp", source: ".gitignore"},
+			},
+		},
+		{
+			name:        "no exclusion pattern",
+			exclude:     regexp.MustCompile(""),
+			excludeFrom: []string{dockerignoreSource, gitignoreSource},
+			files:       []string{".dockerignore", ".git/HEAD", ".gitignore", "keep.log", "main.go", "web/.gitignore", "web/index.html"},
+			excluded: []excludedPath{
+				{path: "debug.log", source: ".dockerignore"},
+				{path: "dist/", source: ".dockerignore"},
+				{path: "node_modules/", source: ".gitignore"},
+				{path: "web/cache.tmp", source: ".gitignore"},
+			},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// the copy of the archive is not archived
+			out := filepath.Join(dir, "out.tar.gz")
+			archiver := &directoryArchiver{Exclude: tc.exclude, ExcludeFrom: tc.excludeFrom, OutPath: out}
+			archive := &bytes.Buffer{}
+			if err := archiver.Archive(dir, archive); err != nil {
+				t.Fatal(err)
+			}
+
+			recorded := []string{}
+			for _, f := range archiver.Files {
+				if f.size != int64(len(files[f.path])) {
+					t.Errorf("expected %s to have size %d, got %d", f.path, len(files[f.path]), f.size)
+				}
+				recorded = append(recorded, f.path)
+			}
+			sort.Strings(recorded)
+			if !reflect.DeepEqual(recorded, tc.files) {
+				t.Errorf("expected files %v, got %v", tc.files, recorded)
+			}
+			if !reflect.DeepEqual(archiver.Excluded, tc.excluded) {
+				t.Errorf("expected excluded paths %v, got %v", tc.excluded, archiver.Excluded)
+			}
+
+			if archiver.Size != int64(archive.Len()) {
+				t.Errorf("expected size %d, got %d", archive.Len(), archiver.Size)
+			}
+			copy, err := ioutil.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(copy, archive.Bytes()) {
+				t.Errorf("the copy of the archive differs from the archive")
+			}
+			archived := archivedFiles(t, archive)
+			if !reflect.DeepEqual(archived, tc.files) {
+				t.Errorf("expected archived files %v, got %v", tc.files, archived)
+			}
+		})
+	}
+}
+
+func archivedFiles(t *testing.T, r io.Reader) []string {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	files := []string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			files = append(files, header.Name)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestDirectoryArchiverExcludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		".dockerignore":  "*.log\n",
+		"web/.gitignore": "dist/\n",
+		"web/dist/app":   "binary",
+		"web/index.html": "<html>",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archiver := &directoryArchiver{
+		Exclude:     s2itar.DefaultExclusionPattern,
+		ExcludeFrom: []string{dockerignoreSource, gitignoreSource},
+		OutPath:     filepath.Join(dir, "out.tar.gz"),
+	}
+	for path, expected := range map[string]bool{
+		"web/index.html":  false,
+		"web/new.go":      false,
+		".git/index":      true,
+		"debug.log":       true,
+		"web/dist":        true,
+		"web/dist/app":    true,
+		"out.tar.gz":      true,
+		"../elsewhere.go": false,
+	} {
+		if excluded := archiver.Excludes(dir, filepath.Join(dir, filepath.FromSlash(path))); excluded != expected {
+			t.Errorf("expected %s to be excluded %t, got %t", path, expected, excluded)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package startbuild
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	buildv1client "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
+	buildhelpers "github.com/openshift/oc/pkg/helpers/build"
+	buildclientmanual "github.com/openshift/oc/pkg/helpers/build/client/v1"
+)
+
+// chainTriggerTimeout is how long --follow-chain waits for a completed build
+// to trigger the builds of the build configs whose image change triggers
+// reference its output.
+const chainTriggerTimeout = time.Minute
+
+// buildChainFollower follows the logs of a build and of the builds it
+// triggers through image change triggers, until they all complete or fail.
+type buildChainFollower struct {
+	BuildClient buildv1client.BuildV1Interface
+	LogClient   func(namespace string) buildclientmanual.BuildLogInterface
+	Out         io.Writer
+	ErrOut      io.Writer
+
+	// Interval and Timeout are how often and how long the builds triggered by
+	// a completed build are looked for.
+	Interval time.Duration
+	Timeout  time.Duration
+
+	// lock guards Out, ErrOut, followed and failed
+	lock     sync.Mutex
+	followed sets.String
+	failed   []string
+	wg       sync.WaitGroup
+}
+
+// Follow follows build and the builds it triggers, and returns an error if
+// any of them fails.
+func (f *buildChainFollower) Follow(ctx context.Context, build *buildv1.Build) error {
+	f.followed = sets.NewString(buildKey(build))
+	f.wg.Add(1)
+	go f.follow(ctx, build, sets.NewString())
+	f.wg.Wait()
+
+	if len(f.failed) > 0 {
+		return fmt.Errorf("the builds %s of the chain failed", strings.Join(f.failed, ", "))
+	}
+	return nil
+}
+
+// follow streams the logs of build, waits for it to complete and follows the
+// builds it triggers. ancestors are the build configs of the builds that
+// triggered build, which are not followed again when a chain has a cycle.
+func (f *buildChainFollower) follow(ctx context.Context, build *buildv1.Build, ancestors sets.String) {
+	defer f.wg.Done()
+
+	out := &prefixWriter{lock: &f.lock, out: f.Out, prefix: fmt.Sprintf("[%s] ", build.Name)}
+	if err := f.streamLogs(ctx, build, out); err != nil {
+		f.printf("[%s] Failed to stream the build logs - to view the logs, run oc logs build/%s\nError: %v\n", build.Name, build.Name, err)
+	}
+	out.Flush()
+
+	builds := f.BuildClient.Builds(build.Namespace)
+	if err := WaitForBuildComplete(ctx, builds, build.Name); err != nil {
+		f.fail(build, err)
+		return
+	}
+	completed, err := builds.Get(ctx, build.Name, metav1.GetOptions{})
+	if err != nil {
+		f.fail(build, fmt.Errorf("unable to find the builds triggered by the build: %v", err))
+		return
+	}
+
+	if config := buildConfigName(completed); len(config) > 0 {
+		ancestors = sets.NewString(ancestors.List()...).Insert(completed.Namespace + "/" + config)
+	}
+	triggered, err := f.triggeredBuilds(ctx, completed, ancestors)
+	if err != nil {
+		f.fail(build, fmt.Errorf("unable to find the builds triggered by the build: %v", err))
+		return
+	}
+	for _, t := range triggered {
+		f.lock.Lock()
+		if f.followed.Has(buildKey(t)) {
+			f.lock.Unlock()
+			continue
+		}
+		f.followed.Insert(buildKey(t))
+		fmt.Fprintf(f.Out, "[%s] build.build.openshift.io/%s triggered\n", build.Name, t.Name)
+		f.lock.Unlock()
+
+		f.wg.Add(1)
+		go f.follow(ctx, t, ancestors)
+	}
+}
+
+func (f *buildChainFollower) streamLogs(ctx context.Context, build *buildv1.Build, out io.Writer) error {
+	rd, err := f.LogClient(build.Namespace).Logs(build.Name, buildv1.BuildLogOptions{Follow: true}).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+	_, err = io.Copy(out, rd)
+	return err
+}
+
+// fail records that the chain failed at build, as build failed or the builds it
+// triggered could not be followed.
+func (f *buildChainFollower) fail(build *buildv1.Build, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.failed = append(f.failed, build.Name)
+	fmt.Fprintf(f.ErrOut, "[%s] %v\n", build.Name, err)
+}
+
+func (f *buildChainFollower) printf(format string, args ...interface{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	fmt.Fprintf(f.ErrOut, format, args...)
+}
+
+// triggeredBuilds returns the builds that the image pushed by a completed
+// build triggered, waiting for the build configs whose image change triggers
+// reference its output to start them. The build configs in ancestors are
+// ignored.
+func (f *buildChainFollower) triggeredBuilds(ctx context.Context, build *buildv1.Build, ancestors sets.String) ([]*buildv1.Build, error) {
+	to := build.Spec.Output.To
+	if to == nil || to.Kind != "ImageStreamTag" {
+		return nil, nil
+	}
+	namespace := to.Namespace
+	if len(namespace) == 0 {
+		namespace = build.Namespace
+	}
+	digest := ""
+	if build.Status.Output.To != nil {
+		digest = build.Status.Output.To.ImageDigest
+	}
+
+	configs, err := f.BuildClient.BuildConfigs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pending := sets.NewString()
+	for i := range configs.Items {
+		config := &configs.Items[i]
+		if !ancestors.Has(config.Namespace+"/"+config.Name) && triggeredBy(config, namespace, to.Name) {
+			pending.Insert(config.Name)
+		}
+	}
+	if pending.Len() == 0 {
+		return nil, nil
+	}
+
+	triggered := []*buildv1.Build{}
+	err = wait.PollImmediate(f.Interval, f.Timeout, func() (bool, error) {
+		builds, err := f.BuildClient.Builds(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if kerrors.IsForbidden(err) {
+				return false, err
+			}
+			klog.V(4).Infof("Unable to list the builds of %s: %v", namespace, err)
+			return false, nil
+		}
+		for i := range builds.Items {
+			b := &builds.Items[i]
+			config := buildConfigName(b)
+			if pending.Has(config) && causedByImage(b, namespace, to.Name, digest) {
+				pending.Delete(config)
+				triggered = append(triggered, b)
+			}
+		}
+		return pending.Len() == 0, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		f.printf("[%s] No build was triggered for the build configs %s\n", build.Name, strings.Join(pending.List(), ", "))
+		err = nil
+	}
+	return triggered, err
+}
+
+// triggeredBy returns true if an image change trigger of config that is not
+// paused references the image stream tag name in namespace.
+func triggeredBy(config *buildv1.BuildConfig, namespace, name string) bool {
+	for _, trigger := range config.Spec.Triggers {
+		if trigger.Type != buildv1.ImageChangeBuildTriggerType || trigger.ImageChange == nil || trigger.ImageChange.Paused {
+			continue
+		}
+		from := trigger.ImageChange.From
+		if from == nil {
+			from = buildhelpers.GetInputReference(config.Spec.Strategy)
+		}
+		if from == nil || from.Kind != "ImageStreamTag" || from.Name != name {
+			continue
+		}
+		if from.Namespace == namespace || (len(from.Namespace) == 0 && config.Namespace == namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// causedByImage returns true if build was triggered by a change of the image
+// stream tag name in namespace, to the image with digest if it is known.
+func causedByImage(build *buildv1.Build, namespace, name, digest string) bool {
+	for _, cause := range build.Spec.TriggeredBy {
+		change := cause.ImageChangeBuild
+		if change == nil || change.FromRef == nil || change.FromRef.Name != name {
+			continue
+		}
+		if len(change.FromRef.Namespace) > 0 && change.FromRef.Namespace != namespace {
+			continue
+		}
+		if len(digest) == 0 || strings.HasSuffix(change.ImageID, "@"+digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildConfigName returns the name of the build config of build, if any.
+func buildConfigName(build *buildv1.Build) string {
+	if build.Status.Config != nil {
+		return build.Status.Config.Name
+	}
+	if name, ok := build.Annotations[buildv1.BuildConfigAnnotation]; ok {
+		return name
+	}
+	return build.Labels[buildv1.BuildConfigLabel]
+}
+
+func buildKey(build *buildv1.Build) string {
+	return build.Namespace + "/" + build.Name
+}
+
+// prefixWriter writes the lines written to it to out with a prefix, a whole
+// line at a time while holding lock.
+type prefixWriter struct {
+	lock   *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// Flush writes the last line if it is not terminated.
+func (w *prefixWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := append(w.buf, '\n')
+	w.buf = nil
+	return w.writeLine(line)
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	_, err := fmt.Fprintf(w.out, "%s%s", w.prefix, line)
+	return err
+}
@@ -0,0 +1,190 @@
+package startbuild
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/apitesting"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	restfake "k8s.io/client-go/rest/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/openshift/api"
+	buildv1 "github.com/openshift/api/build/v1"
+	fakebuildclientset "github.com/openshift/client-go/build/clientset/versioned/fake"
+	buildclientmanual "github.com/openshift/oc/pkg/helpers/build/client/v1"
+)
+
+func imageChangeConfig(name string, from *corev1.ObjectReference, paused bool) *buildv1.BuildConfig {
+	return &buildv1.BuildConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test"},
+		Spec: buildv1.BuildConfigSpec{
+			CommonSpec: buildv1.CommonSpec{
+				Strategy: buildv1.BuildStrategy{
+					SourceStrategy: &buildv1.SourceBuildStrategy{
+						From: corev1.ObjectReference{Kind: "ImageStreamTag", Name: "builder:latest"},
+					},
+				},
+			},
+			Triggers: []buildv1.BuildTriggerPolicy{
+				{
+					Type:        buildv1.ImageChangeBuildTriggerType,
+					ImageChange: &buildv1.ImageChangeTrigger{From: from, Paused: paused},
+				},
+			},
+		},
+	}
+}
+
+func imageChangeBuild(name, config, from, imageID string) *buildv1.Build {
+	return &buildv1.Build{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Annotations: map[string]string{buildv1.BuildConfigAnnotation: config}},
+		Spec: buildv1.BuildSpec{
+			TriggeredBy: []buildv1.BuildTriggerCause{
+				{
+					ImageChangeBuild: &buildv1.ImageChangeCause{
+						ImageID: imageID,
+						FromRef: &corev1.ObjectReference{Kind: "ImageStreamTag", Name: from},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestTriggeredBuilds(t *testing.T) {
+	upstream := &buildv1.Build{
+		ObjectMeta: metav1.ObjectMeta{Name: "base-1", Namespace: "test", Annotations: map[string]string{buildv1.BuildConfigAnnotation: "base"}},
+		Spec: buildv1.BuildSpec{
+			CommonSpec: buildv1.CommonSpec{
+				Output: buildv1.BuildOutput{To: &corev1.ObjectReference{Kind: "ImageStreamTag", Name: "builder:latest"}},
+			},
+		},
+		Status: buildv1.BuildStatus{
+			Phase:  buildv1.BuildPhaseComplete,
+			Output: buildv1.BuildStatusOutput{To: &buildv1.BuildStatusOutputTo{ImageDigest: "sha256:new"}},
+		},
+	}
+	client := fakebuildclientset.NewSimpleClientset(
+		upstream,
+		// triggered through the strategy image
+		imageChangeConfig("app", nil, false),
+		imageChangeBuild("app-1", "app", "builder:latest", "registry/test/builder@sha256:old"),
+		imageChangeBuild("app-2", "app", "builder:latest", "registry/test/builder@sha256:new"),
+		// triggered through the trigger image
+		imageChangeConfig("tools", &corev1.ObjectReference{Kind: "ImageStreamTag", Name: "builder:latest", Namespace: "test"}, false),
+		imageChangeBuild("tools-1", "tools", "builder:latest", "registry/test/builder@sha256:new"),
+		// not triggered
+		imageChangeConfig("paused", nil, true),
+		imageChangeBuild("paused-1", "paused", "builder:latest", "registry/test/builder@sha256:new"),
+		imageChangeConfig("other", &corev1.ObjectReference{Kind: "ImageStreamTag", Name: "other:latest"}, false),
+		imageChangeBuild("other-1", "other", "other:latest", "registry/test/other@sha256:new"),
+		imageChangeConfig("cycle", nil, false),
+		imageChangeBuild("cycle-1", "cycle", "builder:latest", "registry/test/builder@sha256:new"),
+		// never triggered
+		imageChangeConfig("late", nil, false),
+	)
+
+	errOut := &bytes.Buffer{}
+	f := &buildChainFollower{
+		BuildClient: client.BuildV1(),
+		Out:         ioutil.Discard,
+		ErrOut:      errOut,
+		Interval:    10 * time.Millisecond,
+		Timeout:     50 * time.Millisecond,
+	}
+	builds, err := f.triggeredBuilds(context.TODO(), upstream, sets.NewString("test/base", "test/cycle"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, b := range builds {
+		names = append(names, b.Name)
+	}
+	sort.Strings(names)
+	if expected := []string{"app-2", "tools-1"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected triggered builds %v, got %v", expected, names)
+	}
+	if expected := "[base-1] No build was triggered for the build configs late\n"; errOut.String() != expected {
+		t.Errorf("expected %q, got %q", expected, errOut.String())
+	}
+
+	upstream.Spec.Output.To = &corev1.ObjectReference{Kind: "DockerImage", Name: "registry/test/builder:latest"}
+	builds, err = f.triggeredBuilds(context.TODO(), upstream, sets.NewString())
+	if err != nil || len(builds) != 0 {
+		t.Errorf("expected no triggered builds for a docker image output, got %v, %v", builds, err)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := &prefixWriter{lock: &sync.Mutex{}, out: out, prefix: "[app-1] "}
+	for _, s := range []string{"Cloning ", "source\nSTEP 1", "/2\n\nSTEP 2/2"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "[app-1] Cloning source\n[app-1] STEP 1/2\n[app-1] \n[app-1] STEP 2/2\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestFollowChainUnableToFindTriggeredBuilds(t *testing.T) {
+	upstream := &buildv1.Build{
+		ObjectMeta: metav1.ObjectMeta{Name: "base-1", Namespace: "test", Annotations: map[string]string{buildv1.BuildConfigAnnotation: "base"}},
+		Spec: buildv1.BuildSpec{
+			CommonSpec: buildv1.CommonSpec{
+				Output: buildv1.BuildOutput{To: &corev1.ObjectReference{Kind: "ImageStreamTag", Name: "builder:latest"}},
+			},
+		},
+		Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseComplete},
+	}
+	client := fakebuildclientset.NewSimpleClientset(upstream)
+	client.PrependReactor("list", "buildconfigs", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, kerrors.NewForbidden(buildv1.Resource("buildconfigs"), "", fmt.Errorf("denied"))
+	})
+	scheme, codecFactory := apitesting.SchemeForOrDie(api.Install)
+	logs := &restfake.RESTClient{
+		NegotiatedSerializer: codecFactory,
+		GroupVersion:         buildv1.GroupVersion,
+		Client: restfake.CreateHTTPClient(func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewBufferString("done\n"))}, nil
+		}),
+	}
+
+	errOut := &bytes.Buffer{}
+	f := &buildChainFollower{
+		BuildClient: client.BuildV1(),
+		LogClient: func(namespace string) buildclientmanual.BuildLogInterface {
+			return buildclientmanual.NewBuildLogClient(logs, namespace, scheme)
+		},
+		Out:      ioutil.Discard,
+		ErrOut:   errOut,
+		Interval: 10 * time.Millisecond,
+		Timeout:  50 * time.Millisecond,
+	}
+	err := f.Follow(context.TODO(), upstream)
+	if err == nil || !strings.Contains(err.Error(), "base-1") {
+		t.Errorf("expected the chain to fail at base-1, got %v", err)
+	}
+	if !strings.Contains(errOut.String(), "[base-1] unable to find the builds triggered by the build") {
+		t.Errorf("unexpected error output: %q", errOut.String())
+	}
+}
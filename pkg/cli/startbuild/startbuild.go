@@ -3,7 +3,6 @@ package startbuild
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -46,7 +45,6 @@ import (
 	cmdutil "github.com/openshift/oc/pkg/helpers/cmd"
 	utilenv "github.com/openshift/oc/pkg/helpers/env"
 	ocerrors "github.com/openshift/oc/pkg/helpers/errors"
-	s2ifs "github.com/openshift/oc/pkg/helpers/source-to-image/fs"
 	"github.com/openshift/oc/pkg/helpers/source-to-image/tar"
 )
 
@@ -65,6 +63,20 @@ var (
 		pass a http or https url to --from-file and --from-archive, however authentication is not supported
 		and in case of https the certificate must be valid and recognized by your system.
 
+		Files of --from-dir are excluded from the archive by the --exclude regular expression, and by the
+		patterns of the .dockerignore file of the directory and the .gitignore files in it with
+		--exclude-from. Pass --dry-run to list the files that would be uploaded, their total size and the
+		excluded paths without starting a build, and --archive-out to save the archive to a file.
+
+		Pass --follow-chain to also follow the builds of the build configs whose image change triggers
+		reference the output of the build, and the builds that these trigger in turn. The log lines are
+		prefixed by the name of their build, and the command exits with a non-zero return code if any build
+		of the chain fails.
+
+		Pass --watch with --from-dir to start a new build each time the files of the directory change. The
+		build in progress is canceled, the logs of the new build are streamed, and the command exits when
+		interrupted.
+
 		Note that builds triggered from binary input will not preserve the source on the server, so rebuilds
 		triggered by base image changes will use the source specified on the build config.`)
 
@@ -78,6 +90,13 @@ var (
 		# Use the contents of a directory as build input
 		oc start-build hello-world --from-dir=src/
 
+		# List the files of a directory that would be uploaded, leaving out the paths its .dockerignore
+		# file excludes, and save the archive to a file
+		oc start-build hello-world --from-dir=src/ --exclude-from=dockerignore --dry-run --archive-out=src.tar.gz
+
+		# Start a new build from the contents of a directory each time they change, and watch its logs
+		oc start-build hello-world --from-dir=src/ --watch
+
 		# Send the contents of a Git repository to the server from tag 'v2'
 		oc start-build hello-world --from-repo=../hello-world --commit=v2
 
@@ -85,6 +104,10 @@ var (
 		# completes or fails
 		oc start-build hello-world --follow
 
+		# Start a new build for build config "hello-world" and watch its logs and the logs of the builds
+		# that its output image triggers, until they all complete or fail
+		oc start-build hello-world --follow-chain
+
 		# Start a new build for build config "hello-world" and wait until the build completes. It
 		# exits with a non-zero return code if the build fails
 		oc start-build hello-world --wait
@@ -110,11 +133,16 @@ type StartBuildOptions struct {
 	FromRepo      string
 	FromArchive   string
 	ExcludeRegExp string
+	ExcludeFrom   []string
+	ArchiveOut    string
+	DryRun        bool
 
 	Env  []string
 	Args []string
 
 	Follow              bool
+	FollowChain         bool
+	Watch               bool
 	WaitForComplete     bool
 	IncrementalOverride bool
 	Incremental         bool
@@ -133,6 +161,7 @@ type StartBuildOptions struct {
 
 	AsBinary    bool
 	ShortOutput bool
+	Exclude     *regexp.Regexp
 	EnvVar      []corev1.EnvVar
 	BuildArgs   []corev1.EnvVar
 	Name        string
@@ -172,6 +201,8 @@ func NewCmdStartBuild(f kcmdutil.Factory, streams genericclioptions.IOStreams) *
 	cmd.Flags().StringVar(&o.FromBuild, "from-build", o.FromBuild, "Specify the name of a build which should be re-run")
 
 	cmd.Flags().BoolVarP(&o.Follow, "follow", "F", o.Follow, "Start a build and watch its logs until it completes or fails")
+	cmd.Flags().BoolVar(&o.FollowChain, "follow-chain", o.FollowChain, "Start a build and watch its logs and the logs of the builds it triggers through image change triggers until they complete or fail")
+	cmd.Flags().BoolVar(&o.Watch, "watch", o.Watch, "When using the --from-dir option: start a build, then start a new build and watch its logs each time the directory changes, canceling the build in progress")
 	cmd.Flags().BoolVarP(&o.WaitForComplete, "wait", "w", o.WaitForComplete, "Wait for a build to complete and exit with a non-zero return code if the build fails")
 	cmd.Flags().BoolVar(&o.Incremental, "incremental", o.Incremental, "Overrides the incremental setting in a source-strategy build, ignored if not specified")
 	cmd.Flags().BoolVar(&o.NoCache, "no-cache", o.NoCache, "Overrides the noCache setting in a docker-strategy build, ignored if not specified")
@@ -182,6 +213,9 @@ func NewCmdStartBuild(f kcmdutil.Factory, streams genericclioptions.IOStreams) *
 	cmd.Flags().StringVar(&o.FromRepo, "from-repo", o.FromRepo, "The path to a local source code repository to use as the binary input for a build.")
 	cmd.Flags().StringVar(&o.Commit, "commit", o.Commit, "Specify the source code commit identifier the build should use; requires a build based on a Git repository")
 	cmd.Flags().StringVarP(&o.ExcludeRegExp, "exclude", "", tar.DefaultExclusionPattern.String(), "When using the --from-dir option: regular expression for selecting files from the source tree to exclude from the build; the default excludes the '.git' directory (see https://golang.org/pkg/regexp for syntax, but note that \"\" will be interpreted as allow all files and exclude no files)")
+	cmd.Flags().StringSliceVar(&o.ExcludeFrom, "exclude-from", o.ExcludeFrom, "When using the --from-dir option: ignore files whose patterns exclude files from the build, 'dockerignore' for the .dockerignore file of the directory or 'gitignore' for the .gitignore files in it")
+	cmd.Flags().StringVar(&o.ArchiveOut, "archive-out", o.ArchiveOut, "When using the --from-dir option: save the archive of the directory to this file")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "When using the --from-dir option: list the files that would be uploaded and the excluded paths without starting a build")
 
 	cmd.Flags().StringVar(&o.ListWebhooks, "list-webhooks", o.ListWebhooks, "List the webhooks for the specified build config or build; accepts 'all', 'generic', or 'github'")
 	cmd.Flags().StringVar(&o.FromWebhook, "from-webhook", o.FromWebhook, "Specify a generic webhook URL for an existing build config to trigger")
@@ -232,6 +266,10 @@ func (o *StartBuildOptions) Complete(ctx context.Context, f kcmdutil.Factory, cm
 	if cmd.Flags().Lookup("exclude").Changed && len(o.FromDir) == 0 {
 		return fmt.Errorf("the --exclude flag is only supported with --from-dir")
 	}
+	o.Exclude, err = regexp.Compile(o.ExcludeRegExp)
+	if err != nil {
+		return err
+	}
 
 	o.Printer, err = o.PrintFlags.ToPrinter()
 	if err != nil {
@@ -374,6 +412,32 @@ func (o *StartBuildOptions) Validate() error {
 		return fmt.Errorf("a resource name is required either as an argument or by using --from-build")
 	}
 
+	if len(o.ExcludeFrom) > 0 || len(o.ArchiveOut) > 0 || o.DryRun || o.Watch {
+		if len(o.FromArchive) > 0 || len(o.FromDir) == 0 {
+			return fmt.Errorf("--exclude-from, --archive-out, --dry-run and --watch are only supported with --from-dir")
+		}
+		if stat, err := os.Stat(o.FromDir); err != nil || !stat.IsDir() {
+			return fmt.Errorf("--exclude-from, --archive-out, --dry-run and --watch require --from-dir to be a local directory")
+		}
+	}
+	for _, source := range o.ExcludeFrom {
+		if source != dockerignoreSource && source != gitignoreSource {
+			return fmt.Errorf("--exclude-from must be '%s' or '%s'", dockerignoreSource, gitignoreSource)
+		}
+	}
+	if o.DryRun && (o.Follow || o.FollowChain || o.WaitForComplete) {
+		return fmt.Errorf("--dry-run cannot be used with --follow, --follow-chain or --wait")
+	}
+	if o.Watch && (o.DryRun || o.Follow || o.FollowChain || o.WaitForComplete) {
+		return fmt.Errorf("--watch cannot be used with --dry-run, --follow, --follow-chain or --wait")
+	}
+	if o.FollowChain && (o.Follow || o.WaitForComplete) {
+		return fmt.Errorf("--follow-chain cannot be used with --follow or --wait")
+	}
+	if o.FollowChain && (len(o.FromWebhook) > 0 || len(o.ListWebhooks) > 0) {
+		return fmt.Errorf("--follow-chain cannot be used with --from-webhook or --list-webhooks")
+	}
+
 	if len(o.ListWebhooks) > 0 {
 		switch o.ListWebhooks {
 		case "all":
@@ -397,6 +461,15 @@ func (o *StartBuildOptions) Run(ctx context.Context) error {
 		return o.RunListBuildWebHooks(ctx)
 	}
 
+	archiver := &directoryArchiver{Exclude: o.Exclude, ExcludeFrom: o.ExcludeFrom, OutPath: o.ArchiveOut}
+	if o.DryRun {
+		if err := archiver.Archive(o.FromDir, ioutil.Discard); err != nil {
+			return err
+		}
+		archiver.PrintSummary(o.Out)
+		return nil
+	}
+
 	buildRequestCauses := []buildv1.BuildTriggerCause{}
 	request := &buildv1.BuildRequest{
 		TriggeredBy: append(buildRequestCauses,
@@ -437,30 +510,16 @@ func (o *StartBuildOptions) Run(ctx context.Context) error {
 	var newBuild *buildv1.Build
 	switch {
 	case o.AsBinary:
-		request := &buildv1.BinaryBuildRequestOptions{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      o.Name,
-				Namespace: o.Namespace,
-			},
-			Commit: o.Commit,
-		}
 		if len(o.EnvVar) > 0 {
 			fmt.Fprintf(o.ErrOut, "WARNING: Specifying environment variables with binary builds is not supported.\n")
 		}
 		if len(o.BuildArgs) > 0 {
 			fmt.Fprintf(o.ErrOut, "WARNING: Specifying build arguments with binary builds is not supported.\n")
 		}
-
-		// check for resources that BuildConfig expects to exist but are missing
-		if err = o.checkNonExistantResources(ctx); err != nil {
-			return err
+		if o.Watch {
+			return o.RunWatch(ctx, archiver)
 		}
-
-		instantiateClient := buildclientmanual.NewBuildInstantiateBinaryClient(o.BuildClient.RESTClient(), o.Namespace)
-		if newBuild, err = streamPathToBuild(o.Git, o.In, o.ErrOut, instantiateClient, o.FromDir, o.FromFile, o.FromRepo, o.ExcludeRegExp, request); err != nil {
-			if kerrors.IsAlreadyExists(err) {
-				return transformIsAlreadyExistsError(err, o.Name)
-			}
+		if newBuild, err = o.startBinaryBuild(ctx, archiver); err != nil {
 			return err
 		}
 	case len(o.FromBuild) > 0:
@@ -489,6 +548,20 @@ func (o *StartBuildOptions) Run(ctx context.Context) error {
 		fmt.Fprintf(o.ErrOut, "%v\n", err)
 	}
 
+	if o.FollowChain {
+		follower := &buildChainFollower{
+			BuildClient: o.BuildClient,
+			LogClient: func(namespace string) buildclientmanual.BuildLogInterface {
+				return buildclientmanual.NewBuildLogClient(o.BuildClient.RESTClient(), namespace, scheme.Scheme)
+			},
+			Out:      o.Out,
+			ErrOut:   o.ErrOut,
+			Interval: 2 * time.Second,
+			Timeout:  chainTriggerTimeout,
+		}
+		return follower.Follow(ctx, newBuild)
+	}
+
 	// Stream the logs from the build
 	if o.Follow {
 		err = o.streamBuildLogs(ctx, newBuild)
@@ -504,6 +577,32 @@ func (o *StartBuildOptions) Run(ctx context.Context) error {
 	return nil
 }
 
+// startBinaryBuild starts a build from the binary input of the options.
+func (o *StartBuildOptions) startBinaryBuild(ctx context.Context, archiver *directoryArchiver) (*buildv1.Build, error) {
+	request := &buildv1.BinaryBuildRequestOptions{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      o.Name,
+			Namespace: o.Namespace,
+		},
+		Commit: o.Commit,
+	}
+
+	// check for resources that BuildConfig expects to exist but are missing
+	if err := o.checkNonExistantResources(ctx); err != nil {
+		return nil, err
+	}
+
+	instantiateClient := buildclientmanual.NewBuildInstantiateBinaryClient(o.BuildClient.RESTClient(), o.Namespace)
+	newBuild, err := streamPathToBuild(o.Git, o.In, o.ErrOut, instantiateClient, o.FromDir, o.FromFile, o.FromRepo, archiver, request)
+	if err != nil {
+		if kerrors.IsAlreadyExists(err) {
+			return nil, transformIsAlreadyExistsError(err, o.Name)
+		}
+		return nil, err
+	}
+	return newBuild, nil
+}
+
 func (o *StartBuildOptions) streamBuildLogs(ctx context.Context, build *buildv1.Build) error {
 	opts := buildv1.BuildLogOptions{
 		Follow: true,
@@ -675,7 +774,7 @@ func (o *StartBuildOptions) RunListBuildWebHooks(ctx context.Context) error {
 	return nil
 }
 
-func streamPathToBuild(repo git.Repository, in io.Reader, out io.Writer, client buildclientmanual.BuildInstantiateBinaryInterface, fromDir, fromFile, fromRepo string, excludeRegExp string, options *buildv1.BinaryBuildRequestOptions) (*buildv1.Build, error) {
+func streamPathToBuild(repo git.Repository, in io.Reader, out io.Writer, client buildclientmanual.BuildInstantiateBinaryInterface, fromDir, fromFile, fromRepo string, archiver *directoryArchiver, options *buildv1.BinaryBuildRequestOptions) (*buildv1.Build, error) {
 	asDir, asFile, asRepo := len(fromDir) > 0, len(fromFile) > 0, len(fromRepo) > 0
 
 	if asRepo && !git.IsGitInstalled() {
@@ -810,20 +909,11 @@ func streamPathToBuild(repo git.Repository, in io.Reader, out io.Writer, client
 				fmt.Fprintf(out, "Uploading directory %q as binary input for the build ...\n", clean)
 			}
 
-			re, err := regexp.Compile(excludeRegExp)
-			if err != nil {
-				return nil, err
-			}
-
 			pr, pw := io.Pipe()
 			go func() {
-				w := gzip.NewWriter(pw)
-				t := tar.New(s2ifs.NewFileSystem())
-				t.SetExclusionPattern(re)
-				if err := t.CreateTarStream(path, false, w); err != nil {
+				if err := archiver.Archive(path, pw); err != nil {
 					pw.CloseWithError(err)
 				} else {
-					w.Close()
 					pw.CloseWithError(io.EOF)
 				}
 
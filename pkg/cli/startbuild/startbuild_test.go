@@ -284,9 +284,9 @@ func TestHttpBinary(t *testing.T) {
 			fromDir = server.URL + tc.urlPath
 		}
 
-		defaultExclusionPattern := tar.DefaultExclusionPattern.String()
+		archiver := &directoryArchiver{Exclude: tar.DefaultExclusionPattern}
 
-		build, err := streamPathToBuild(nil, stdin, stdout, &FakeBuildConfigs{t: t, expectAsFile: tc.fromFile}, fromDir, fromFile, "", defaultExclusionPattern, &options)
+		build, err := streamPathToBuild(nil, stdin, stdout, &FakeBuildConfigs{t: t, expectAsFile: tc.fromFile}, fromDir, fromFile, "", archiver, &options)
 
 		if len(tc.expectedError) > 0 {
 			if err == nil {
@@ -0,0 +1,225 @@
+package startbuild
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/oc/pkg/cli/rsync/fsnotification"
+	buildhelpers "github.com/openshift/oc/pkg/helpers/build"
+)
+
+// watchDelay is how long the files of a watched directory must not change
+// before a new build is started, so that a build does not start while a large
+// set of changes is in progress.
+const watchDelay = 2 * time.Second
+
+// RunWatch starts a build from the directory of the options, then starts a new
+// build each time the files of the directory that are archived change,
+// canceling the build in progress and streaming the logs of the new build,
+// until interrupted.
+func (o *StartBuildOptions) RunWatch(ctx context.Context, archiver *directoryArchiver) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dir, err := filepath.Abs(o.FromDir)
+	if err != nil {
+		return err
+	}
+
+	// these variables must be accessed while holding the changeLock
+	// mutex as they are shared between goroutines to communicate
+	// change events.
+	var (
+		changeLock sync.Mutex
+		dirty      bool
+		lastChange time.Time
+		watchError error
+	)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error setting up filesystem watcher: %v", err)
+	}
+	defer watcher.Close()
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				klog.V(5).Infof("filesystem watch event: %s", event)
+				excluded := archiver.Excludes(dir, event.Name)
+				changeLock.Lock()
+				if !excluded {
+					lastChange = time.Now()
+					dirty = true
+				}
+				if event.Op&fsnotify.Remove == fsnotify.Remove {
+					if e := watcher.Remove(event.Name); e != nil {
+						klog.V(5).Infof("error removing watch for %s: %v", event.Name, e)
+					}
+				} else {
+					if e := fsnotification.AddRecursiveWatch(watcher, event.Name); e != nil && watchError == nil {
+						watchError = e
+					}
+				}
+				changeLock.Unlock()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				changeLock.Lock()
+				watchError = fmt.Errorf("error watching filesystem for changes: %v", err)
+				changeLock.Unlock()
+			}
+		}
+	}()
+
+	if err := fsnotification.AddRecursiveWatch(watcher, dir); err != nil {
+		return fmt.Errorf("error watching source path %s: %v", o.FromDir, err)
+	}
+
+	var current *watchedBuild
+	start := func() error {
+		build, err := o.startBinaryBuild(ctx, archiver)
+		if err != nil {
+			return err
+		}
+		if err := o.Printer.PrintObj(build, o.Out); err != nil {
+			fmt.Fprintf(o.ErrOut, "%v\n", err)
+		}
+		current = o.followWatchedBuild(ctx, build)
+		return nil
+	}
+	if err := start(); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	ticker := time.NewTicker(watchDelay)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			current.stop()
+			fmt.Fprintf(o.ErrOut, "\nStopped watching %q\n", o.FromDir)
+			return nil
+		case <-ticker.C:
+		}
+
+		changeLock.Lock()
+		if watchError != nil {
+			changeLock.Unlock()
+			current.stop()
+			return watchError
+		}
+		// start a build once no change happened for 'delay' seconds
+		changed := dirty && time.Now().After(lastChange.Add(watchDelay))
+		if changed {
+			dirty = false
+		}
+		changeLock.Unlock()
+		if !changed {
+			continue
+		}
+
+		fmt.Fprintf(o.ErrOut, "Changes detected in %q, starting a new build ...\n", o.FromDir)
+		if current != nil {
+			current.stop()
+			if err := o.cancelBuild(ctx, current.build.Name); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(o.ErrOut, "WARNING: Unable to cancel the build %s: %v\n", current.build.Name, err)
+			}
+			current = nil
+		}
+		if err := start(); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(o.ErrOut, "error: %v\n", err)
+		}
+	}
+}
+
+// watchedBuild is a build of RunWatch whose logs are streamed.
+type watchedBuild struct {
+	build  *buildv1.Build
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stop stops streaming the logs of the build.
+func (w *watchedBuild) stop() {
+	if w == nil {
+		return
+	}
+	w.cancel()
+	<-w.done
+}
+
+// followWatchedBuild streams the logs of build and reports whether it
+// completed, until stopped.
+func (o *StartBuildOptions) followWatchedBuild(ctx context.Context, build *buildv1.Build) *watchedBuild {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &watchedBuild{build: build, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		rd, err := o.BuildLogClient.Logs(build.Name, buildv1.BuildLogOptions{Follow: true}).Stream(ctx)
+		if err == nil {
+			_, err = io.Copy(o.Out, rd)
+			rd.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(o.ErrOut, "Failed to stream the build logs - to view the logs, run oc logs build/%s\nError: %v\n", build.Name, err)
+		}
+		if err := WaitForBuildComplete(ctx, o.BuildClient.Builds(o.Namespace), build.Name); err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintf(o.ErrOut, "%v, watching %q for changes ...\n", err, o.FromDir)
+			}
+			return
+		}
+		fmt.Fprintf(o.ErrOut, "The build %s/%s is complete, watching %q for changes ...\n", build.Namespace, build.Name, o.FromDir)
+	}()
+	return w
+}
+
+// cancelBuild cancels the build name unless it already completed.
+func (o *StartBuildOptions) cancelBuild(ctx context.Context, name string) error {
+	builds := o.BuildClient.Builds(o.Namespace)
+	return wait.PollImmediate(500*time.Millisecond, 30*time.Second, func() (bool, error) {
+		build, err := builds.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if buildhelpers.IsBuildComplete(build) || build.Status.Cancelled {
+			return true, nil
+		}
+		build.Status.Cancelled = true
+		_, err = builds.Update(ctx, build, metav1.UpdateOptions{})
+		switch {
+		case err == nil:
+			fmt.Fprintf(o.ErrOut, "build.build.openshift.io/%s cancelled\n", name)
+			return true, nil
+		case kerrors.IsConflict(err):
+			return false, nil
+		}
+		return false, err
+	})
+}
@@ -0,0 +1,38 @@
+package startbuild
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	fakebuildclientset "github.com/openshift/client-go/build/clientset/versioned/fake"
+)
+
+func TestCancelBuild(t *testing.T) {
+	client := fakebuildclientset.NewSimpleClientset(
+		&buildv1.Build{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "test"}, Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseRunning}},
+		&buildv1.Build{ObjectMeta: metav1.ObjectMeta{Name: "app-2", Namespace: "test"}, Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseComplete}},
+	)
+	o := &StartBuildOptions{
+		BuildClient: client.BuildV1(),
+		Namespace:   "test",
+		IOStreams:   genericclioptions.IOStreams{Out: ioutil.Discard, ErrOut: ioutil.Discard},
+	}
+
+	for name, cancelled := range map[string]bool{"app-1": true, "app-2": false} {
+		if err := o.cancelBuild(context.TODO(), name); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		build, err := client.BuildV1().Builds("test").Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if build.Status.Cancelled != cancelled {
+			t.Errorf("expected %s to be cancelled %t, got %t", name, cancelled, build.Status.Cancelled)
+		}
+	}
+}
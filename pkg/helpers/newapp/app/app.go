@@ -113,6 +113,8 @@ type SourceRef struct {
 	Dir        string
 	Name       string
 	ContextDir string
+	// Submodule is the path of the submodule that ContextDir is, if it is one.
+	Submodule  string
 	Secrets    []buildv1.SecretBuildSource
 	ConfigMaps []buildv1.ConfigMapBuildSource
 
@@ -200,6 +202,10 @@ func (r *SourceRef) BuildSource() (*buildv1.BuildSource, []buildv1.BuildTriggerP
 type BuildStrategyRef struct {
 	Strategy newapp.Strategy
 	Base     *ImageRef
+	// FromStage is true when the last stage of the Dockerfile builds on an
+	// earlier stage, so its FROM instruction must not be replaced by the base
+	// image, which then only triggers builds.
+	FromStage bool
 }
 
 // BuildStrategy builds an OpenShift BuildStrategy from a BuildStrategyRef
@@ -221,7 +227,16 @@ func (s *BuildStrategyRef) BuildStrategy(env Environment, dockerStrategyOptions
 		if dockerStrategyOptions != nil {
 			strategy.BuildArgs = dockerStrategyOptions.BuildArgs
 		}
-		if s.Base != nil {
+		switch {
+		case s.Base != nil && s.FromStage:
+			ref := s.Base.ObjectReference()
+			triggers = s.Base.BuildTriggers()
+			for i := range triggers {
+				if triggers[i].ImageChange != nil {
+					triggers[i].ImageChange.From = &ref
+				}
+			}
+		case s.Base != nil:
 			ref := s.Base.ObjectReference()
 			strategy.From = &ref
 			triggers = s.Base.BuildTriggers()
@@ -326,6 +341,9 @@ type DeploymentConfigRef struct {
 	Labels   map[string]string
 	AsTest   bool
 	PostHook *DeploymentConfigHook
+
+	// ContainerOptions are applied to every container.
+	ContainerOptions *ContainerOptions
 }
 
 // DeploymentConfig creates a deploymentConfig resource from the deployment configuration reference
@@ -367,6 +385,9 @@ func (r *DeploymentConfigRef) DeploymentConfig() (*appsv1.DeploymentConfig, erro
 		if err != nil {
 			return nil, err
 		}
+		if err := r.ContainerOptions.Apply(c); err != nil {
+			return nil, err
+		}
 		triggers = append(triggers, containerTriggers...)
 		template.Containers = append(template.Containers, *c)
 	}
@@ -430,6 +451,9 @@ type DeploymentRef struct {
 	Labels   map[string]string
 	AsTest   bool
 	PostHook *DeploymentConfigHook
+
+	// ContainerOptions are applied to every container.
+	ContainerOptions *ContainerOptions
 }
 
 // Deployment creates a deployment resource from the deployment reference
@@ -465,6 +489,9 @@ func (r *DeploymentRef) Deployment() (*kappsv1.Deployment, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err := r.ContainerOptions.Apply(c); err != nil {
+			return nil, err
+		}
 		imageTriggers = append(imageTriggers, containerTriggers...)
 		template.Containers = append(template.Containers, *c)
 	}
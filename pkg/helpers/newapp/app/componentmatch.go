@@ -23,6 +23,11 @@ type ComponentMatch struct {
 	// actual image/pullspec.
 	Virtual bool
 
+	// NamespaceRank is the position of the namespace of the match in the
+	// searched namespaces, where the current project comes before the
+	// openshift namespace. Matches with equal scores are ranked by it.
+	NamespaceRank int
+
 	// The source of the match. Generally only a single source is
 	// available.
 	DockerImage *dockerv10.DockerImage
@@ -87,9 +92,19 @@ func (m ComponentMatches) Inexact() ComponentMatches {
 // ScoredComponentMatches is a set of component matches grouped by score
 type ScoredComponentMatches []*ComponentMatch
 
-func (m ScoredComponentMatches) Len() int           { return len(m) }
-func (m ScoredComponentMatches) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
-func (m ScoredComponentMatches) Less(i, j int) bool { return m[i].Score < m[j].Score }
+func (m ScoredComponentMatches) Len() int      { return len(m) }
+func (m ScoredComponentMatches) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+
+// Less ranks matches by score, then by namespace, then by name.
+func (m ScoredComponentMatches) Less(i, j int) bool {
+	if m[i].Score != m[j].Score {
+		return m[i].Score < m[j].Score
+	}
+	if m[i].NamespaceRank != m[j].NamespaceRank {
+		return m[i].NamespaceRank < m[j].NamespaceRank
+	}
+	return m[i].Name < m[j].Name
+}
 
 // Exact returns all the exact component matches
 func (m ScoredComponentMatches) Exact() []*ComponentMatch {
@@ -0,0 +1,292 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	kappsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kuval "k8s.io/apimachinery/pkg/util/validation"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	routev1 "github.com/openshift/api/route/v1"
+)
+
+// ExposeServicesLabel is the image label that names the services an image
+// provides on its exposed ports, as a comma separated list of PORT[/PROTO]:NAME
+// entries, for instance "8080:http,8443/tcp:https".
+const ExposeServicesLabel = "io.openshift.expose-services"
+
+// livenessProbeDelay is the number of seconds the liveness probe waits for the
+// container to start, so that slow starting applications are not restarted
+// before they could answer.
+const livenessProbeDelay = 30
+
+// exposedServiceNames parses the value of the ExposeServicesLabel into the
+// names of the ports, by the port name of the service generated for them.
+// Entries that are not valid port names are ignored.
+func exposedServiceNames(label string) map[string]string {
+	names := map[string]string{}
+	for _, entry := range strings.Split(label, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		port, protocol := parts[0], corev1.ProtocolTCP
+		if i := strings.Index(port, "/"); i != -1 {
+			port, protocol = port[:i], corev1.Protocol(strings.ToUpper(port[i+1:]))
+		}
+		number, err := strconv.Atoi(port)
+		if err != nil {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(parts[1]))
+		if len(kuval.IsValidPortName(name)) > 0 {
+			continue
+		}
+		names[portName(number, protocol)] = name
+	}
+	return names
+}
+
+// nameContainerPorts names the ports of a container after the services the
+// image declares with the ExposeServicesLabel.
+func nameContainerPorts(container *corev1.Container, labels map[string]string) {
+	label, ok := labels[ExposeServicesLabel]
+	if !ok {
+		return
+	}
+	names := exposedServiceNames(label)
+	used := map[string]bool{}
+	for i := range container.Ports {
+		port := &container.Ports[i]
+		name, ok := names[portName(int(port.ContainerPort), port.Protocol)]
+		if !ok || used[name] {
+			continue
+		}
+		used[name] = true
+		port.Name = name
+	}
+}
+
+// Probe describes a health check of the containers, as given to new-app with
+// --probe=TYPE[:PORT][/PATH].
+type Probe struct {
+	// Type is http, https or tcp.
+	Type string
+	// Port is the port to check, or 0 to use the port named after Type or the
+	// first TCP port of the container.
+	Port int
+	// Path is the path requested by http and https probes.
+	Path string
+}
+
+// ParseProbe parses a probe given as TYPE[:PORT][/PATH], where TYPE is one of
+// http, https or tcp.
+func ParseProbe(spec string) (*Probe, error) {
+	probe := &Probe{}
+	rest := spec
+	if i := strings.Index(rest, "/"); i != -1 {
+		rest, probe.Path = rest[:i], rest[i:]
+	}
+	parts := strings.SplitN(rest, ":", 2)
+	probe.Type = strings.ToLower(parts[0])
+	switch probe.Type {
+	case "http", "https":
+		if len(probe.Path) == 0 {
+			probe.Path = "/"
+		}
+	case "tcp":
+		if len(probe.Path) > 0 {
+			return nil, fmt.Errorf("the probe %q cannot have a path, tcp probes only open a connection", spec)
+		}
+	default:
+		return nil, fmt.Errorf("the probe %q must be of the form TYPE[:PORT][/PATH], where TYPE is one of http, https or tcp", spec)
+	}
+	if len(parts) == 2 {
+		port, err := strconv.Atoi(parts[1])
+		if err != nil || len(kuval.IsValidPortNum(port)) > 0 {
+			return nil, fmt.Errorf("the probe %q has an invalid port %q", spec, parts[1])
+		}
+		probe.Port = port
+	}
+	return probe, nil
+}
+
+// handler returns the action of the probe for a container, or an error if
+// the port to check cannot be determined from the ports of the container.
+func (p *Probe) handler(container *corev1.Container) (corev1.ProbeHandler, error) {
+	port := intstr.FromInt(p.Port)
+	if p.Port == 0 {
+		found := false
+		for _, name := range []string{p.Type, ""} {
+			for _, containerPort := range container.Ports {
+				if containerPort.Protocol != "" && containerPort.Protocol != corev1.ProtocolTCP {
+					continue
+				}
+				if len(name) > 0 && containerPort.Name != name {
+					continue
+				}
+				port, found = intstr.FromInt(int(containerPort.ContainerPort)), true
+				break
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return corev1.ProbeHandler{}, fmt.Errorf("the container %q exposes no TCP port to probe, set one with --probe=%s:PORT", container.Name, p.Type)
+		}
+	}
+	if p.Type == "tcp" {
+		return corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: port}}, nil
+	}
+	scheme := corev1.URISchemeHTTP
+	if p.Type == "https" {
+		scheme = corev1.URISchemeHTTPS
+	}
+	return corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: p.Path, Port: port, Scheme: scheme}}, nil
+}
+
+// ContainerOptions are applied to every container new-app generates.
+type ContainerOptions struct {
+	// Probe, if set, is used as the readiness and liveness probe.
+	Probe *Probe
+	// Resources are the requests and limits of the containers.
+	Resources corev1.ResourceRequirements
+}
+
+// Apply sets the probes and resources of a container.
+func (o *ContainerOptions) Apply(container *corev1.Container) error {
+	if o == nil {
+		return nil
+	}
+	if len(o.Resources.Requests) > 0 || len(o.Resources.Limits) > 0 {
+		container.Resources = *o.Resources.DeepCopy()
+	}
+	if o.Probe != nil {
+		handler, err := o.Probe.handler(container)
+		if err != nil {
+			return err
+		}
+		container.ReadinessProbe = &corev1.Probe{ProbeHandler: handler}
+		container.LivenessProbe = &corev1.Probe{ProbeHandler: *handler.DeepCopy(), InitialDelaySeconds: livenessProbeDelay}
+	}
+	return nil
+}
+
+// ValidateRouteHost returns an error if host cannot be used as the host of a
+// route.
+func ValidateRouteHost(host string) error {
+	if errs := kuval.IsDNS1123Subdomain(host); len(errs) > 0 {
+		return fmt.Errorf("invalid route host %q: %s", host, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// AddRoutes adds a route to the service of each deployment and deployment
+// config, as generated by AddServices, unless the service already has one.
+// The route targets the port named http, or the port named https with
+// passthrough termination, or else the first TCP port. If host is set, it is
+// used as the host of the only route; an error is returned if more than one
+// route would be created.
+func AddRoutes(objects Objects, host string) (Objects, error) {
+	services := map[string]bool{}
+	for _, o := range objects {
+		if svc, ok := o.(*corev1.Service); ok {
+			services[svc.Name] = true
+		}
+	}
+	for _, o := range objects {
+		if route, ok := o.(*routev1.Route); ok && route.Spec.To.Kind == "Service" {
+			services[route.Spec.To.Name] = false
+		}
+	}
+
+	routes := Objects{}
+	for _, o := range objects {
+		var route *routev1.Route
+		switch t := o.(type) {
+		case *appsv1.DeploymentConfig:
+			route = generateRoute(t.ObjectMeta, t.Spec.Template.Spec.Containers)
+		case *kappsv1.Deployment:
+			route = generateRoute(t.ObjectMeta, t.Spec.Template.Spec.Containers)
+		}
+		if route == nil || !services[route.Spec.To.Name] {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	if len(host) > 0 {
+		if len(routes) > 1 {
+			return nil, fmt.Errorf("a route host can only be set when a single component is exposed, found %d", len(routes))
+		}
+		for _, route := range routes {
+			route.(*routev1.Route).Spec.Host = host
+		}
+	}
+	return append(objects, routes...), nil
+}
+
+// generateRoute returns a route to the service of a workload, or nil if the
+// workload has no TCP port or no valid service name.
+func generateRoute(meta metav1.ObjectMeta, containers []corev1.Container) *routev1.Route {
+	name, _ := makeValidServiceName(meta.Name)
+	if len(name) == 0 {
+		return nil
+	}
+	var ports []corev1.ContainerPort
+	for _, port := range AllContainerPorts(containers...) {
+		if port.Protocol == "" || port.Protocol == corev1.ProtocolTCP {
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+	target, tls := ports[0], false
+	for _, preferred := range []string{"http", "https"} {
+		found := false
+		for _, port := range ports {
+			if port.Name == preferred {
+				target, tls, found = port, preferred == "https", true
+				break
+			}
+		}
+		if found {
+			break
+		}
+	}
+
+	route := RouteForPort(meta, name, target)
+	if tls {
+		route.Spec.TLS = &routev1.TLSConfig{Termination: routev1.TLSTerminationPassthrough}
+	}
+	return route
+}
+
+// RouteForPort returns a route named name to a container port of the service
+// of the same name, as generated by AddServices.
+func RouteForPort(meta metav1.ObjectMeta, name string, port corev1.ContainerPort) *routev1.Route {
+	service, _ := makeValidServiceName(meta.Name)
+	return &routev1.Route{
+		// this is ok because we know exactly how we want to be serialized
+		TypeMeta: metav1.TypeMeta{APIVersion: routev1.SchemeGroupVersion.String(), Kind: "Route"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: meta.Labels,
+		},
+		Spec: routev1.RouteSpec{
+			To: routev1.RouteTargetReference{
+				Kind: "Service",
+				Name: service,
+			},
+			Port: &routev1.RoutePort{
+				TargetPort: intstr.FromString(portName(int(port.ContainerPort), port.Protocol)),
+			},
+		},
+	}
+}
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"testing"
+
+	kappsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	dockerv10 "github.com/openshift/api/image/docker10"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+func TestExposedServiceNames(t *testing.T) {
+	imageRef := &ImageRef{
+		Reference: reference.DockerImageReference{Name: "web", Tag: "latest"},
+		Info: &dockerv10.DockerImage{
+			Config: &dockerv10.DockerConfig{
+				ExposedPorts: map[string]struct{}{"8080/tcp": {}, "8443/tcp": {}, "9000/udp": {}, "9090/tcp": {}},
+				Labels:       map[string]string{ExposeServicesLabel: "8080:http, 8443/tcp:HTTPS,9000/tcp:metrics,9090:not_valid"},
+			},
+		},
+	}
+	container, _, err := imageRef.DeployableContainer(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[int32]string{}
+	for _, port := range container.Ports {
+		names[port.ContainerPort] = port.Name
+	}
+	if names[8080] != "http" || names[8443] != "https" || names[9000] != "" || names[9090] != "" {
+		t.Errorf("unexpected port names: %v", names)
+	}
+}
+
+func TestParseProbe(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Probe
+		wantErr bool
+	}{
+		{spec: "http", want: Probe{Type: "http", Path: "/"}},
+		{spec: "HTTPS:8443/healthz", want: Probe{Type: "https", Port: 8443, Path: "/healthz"}},
+		{spec: "http/ready", want: Probe{Type: "http", Path: "/ready"}},
+		{spec: "tcp:5432", want: Probe{Type: "tcp", Port: 5432}},
+		{spec: "tcp:5432/path", wantErr: true},
+		{spec: "exec:5432", wantErr: true},
+		{spec: "http:0", wantErr: true},
+		{spec: "http:port/", wantErr: true},
+	}
+	for _, test := range tests {
+		probe, err := ParseProbe(test.spec)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: unexpected error: %v", test.spec, err)
+			continue
+		}
+		if err == nil && *probe != test.want {
+			t.Errorf("%s: unexpected probe: %#v", test.spec, probe)
+		}
+	}
+}
+
+func TestContainerOptionsApply(t *testing.T) {
+	options := &ContainerOptions{
+		Probe: &Probe{Type: "http", Path: "/healthz"},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+		},
+	}
+	container := &corev1.Container{
+		Name:  "web",
+		Ports: []corev1.ContainerPort{{ContainerPort: 5000, Protocol: corev1.ProtocolUDP}, {ContainerPort: 9000}, {Name: "http", ContainerPort: 8080}},
+	}
+	if err := options.Apply(container); err != nil {
+		t.Fatal(err)
+	}
+	if container.ReadinessProbe == nil || container.ReadinessProbe.HTTPGet.Port != intstr.FromInt(8080) || container.ReadinessProbe.HTTPGet.Path != "/healthz" {
+		t.Errorf("unexpected readiness probe: %#v", container.ReadinessProbe)
+	}
+	if container.LivenessProbe == nil || container.LivenessProbe.InitialDelaySeconds != livenessProbeDelay {
+		t.Errorf("unexpected liveness probe: %#v", container.LivenessProbe)
+	}
+	if cpu := container.Resources.Requests[corev1.ResourceCPU]; cpu.String() != "100m" {
+		t.Errorf("unexpected resources: %#v", container.Resources)
+	}
+
+	options.Probe = &Probe{Type: "tcp"}
+	container.Ports[2].Name = ""
+	if err := options.Apply(container); err != nil {
+		t.Fatal(err)
+	}
+	if container.ReadinessProbe.TCPSocket == nil || container.ReadinessProbe.TCPSocket.Port != intstr.FromInt(9000) {
+		t.Errorf("unexpected readiness probe: %#v", container.ReadinessProbe)
+	}
+
+	if err := options.Apply(&corev1.Container{Name: "noports"}); err == nil {
+		t.Error("expected an error for a container without ports")
+	}
+	if err := (*ContainerOptions)(nil).Apply(container); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAddRoutes(t *testing.T) {
+	dc := &appsv1.DeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Labels: map[string]string{"app": "web"}},
+		Spec: appsv1.DeploymentConfigSpec{
+			Template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "web", Ports: []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}, {Name: "https", ContainerPort: 8443, Protocol: corev1.ProtocolTCP}}}},
+				},
+			},
+		},
+	}
+	deployment := &kappsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "db"},
+		Spec: kappsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "db", Ports: []corev1.ContainerPort{{ContainerPort: 5432, Protocol: corev1.ProtocolTCP}}}},
+				},
+			},
+		},
+	}
+	worker := &kappsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "worker"}}
+
+	objects, err := AddRoutes(AddServices(Objects{dc, deployment, worker}, false), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var routes []*routev1.Route
+	for _, o := range objects {
+		if route, ok := o.(*routev1.Route); ok {
+			routes = append(routes, route)
+		}
+	}
+	if len(routes) != 2 {
+		t.Fatalf("unexpected routes: %#v", routes)
+	}
+	if r := routes[0]; r.Name != "web" || r.Spec.To.Name != "web" || r.Spec.Port.TargetPort.StrVal != "8443-tcp" || r.Spec.TLS == nil || r.Labels["app"] != "web" {
+		t.Errorf("unexpected route: %#v", r)
+	}
+	if r := routes[1]; r.Name != "db" || r.Spec.Port.TargetPort.StrVal != "5432-tcp" || r.Spec.TLS != nil {
+		t.Errorf("unexpected route: %#v", r)
+	}
+
+	if _, err := AddRoutes(AddServices(Objects{dc, deployment}, false), "web.example.com"); err == nil {
+		t.Error("expected an error when setting the host of several routes")
+	}
+	objects, err = AddRoutes(AddServices(Objects{dc}, false), "web.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if route := objects[len(objects)-1].(*routev1.Route); route.Spec.Host != "web.example.com" {
+		t.Errorf("unexpected host: %s", route.Spec.Host)
+	}
+
+	existing := RouteForPort(dc.ObjectMeta, "web-admin", dc.Spec.Template.Spec.Containers[0].Ports[1])
+	objects, err = AddRoutes(AddServices(Objects{dc, existing}, false), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 3 {
+		t.Errorf("expected no route for a service that already has one: %#v", objects)
+	}
+
+	if err := ValidateRouteHost("Not_A_Host"); err == nil {
+		t.Error("expected an error for an invalid host")
+	}
+}
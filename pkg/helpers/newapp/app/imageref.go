@@ -457,6 +457,7 @@ func (r *ImageRef) DeployableContainer(isDeployment bool) (container *corev1.Con
 				Protocol:      corev1.Protocol(strings.ToUpper(dp.Proto())),
 			})
 		}
+		nameContainerPorts(container, r.Info.Config.Labels)
 
 		// Create volume mounts with names based on container name
 		maxDigits := len(fmt.Sprintf("%d", len(r.Info.Config.Volumes)))
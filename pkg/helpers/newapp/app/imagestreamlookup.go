@@ -61,7 +61,7 @@ func (r ImageStreamSearcher) Search(precise bool, terms ...string) (ComponentMat
 			searchTag = imagev1.DefaultImageTag
 			followTag = true
 		}
-		for _, namespace := range namespaces {
+		for rank, namespace := range namespaces {
 			klog.V(4).Infof("checking ImageStreams %s/%s with ref %q", namespace, ref.Name, searchTag)
 			exact := false
 			streams, err := r.Client.ImageStreams(namespace).List(context.TODO(), metav1.ListOptions{})
@@ -76,7 +76,7 @@ func (r ImageStreamSearcher) Search(precise bool, terms ...string) (ComponentMat
 			ref.Namespace = namespace
 			for i := range streams.Items {
 				stream := &streams.Items[i]
-				score, scored := imageStreamScorer(*stream, ref.Name)
+				score, scored := imageStreamScorer(*stream, ref.Name, precise)
 				if !scored {
 					klog.V(2).Infof("unscored %s: %v", stream.Name, score)
 					continue
@@ -106,16 +106,17 @@ func (r ImageStreamSearcher) Search(precise bool, terms ...string) (ComponentMat
 					}
 
 					match := &ComponentMatch{
-						Value:       term,
-						Argument:    argument,
-						Name:        name,
-						Description: description,
-						Score:       matchScore,
-						ImageStream: stream,
-						DockerImage: image,
-						ImageTag:    tag,
-						Meta:        meta,
-						NoTagsFound: notFound,
+						Value:         term,
+						Argument:      argument,
+						Name:          name,
+						Description:   description,
+						Score:         matchScore,
+						NamespaceRank: rank,
+						ImageStream:   stream,
+						DockerImage:   image,
+						ImageTag:      tag,
+						Meta:          meta,
+						NoTagsFound:   notFound,
 					}
 					klog.V(2).Infof("Adding %s as component match for %q with score %v", match.Description, term, matchScore)
 					componentMatches = append(componentMatches, match)
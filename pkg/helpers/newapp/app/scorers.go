@@ -7,13 +7,39 @@ import (
 	templatev1 "github.com/openshift/api/template/v1"
 )
 
-func templateScorer(template templatev1.Template, term string) (float32, bool) {
+// The annotations of templates, image streams and their tags that are
+// searched when the search is not precise.
+const (
+	tagsAnnotation        = "tags"
+	displayNameAnnotation = "openshift.io/display-name"
+	descriptionAnnotation = "description"
+)
+
+// templateScorer scores a template by its name. Searches that are not
+// precise also match the annotations of the template, and names that are
+// a few typos away from the term.
+func templateScorer(template templatev1.Template, term string, precise bool) (float32, bool) {
 	score := stringProximityScorer(template.Name, term)
+	if !precise {
+		score = min32(score, fuzzyScorer(template.Name, term), annotationScorer(template.Annotations, term))
+	}
 	return score, score < 0.3
 }
 
-func imageStreamScorer(imageStream imagev1.ImageStream, term string) (float32, bool) {
+// imageStreamScorer scores an image stream by its name. Searches that are not
+// precise also match the annotations and the tags of the image stream, and
+// names that are a few typos away from the term.
+func imageStreamScorer(imageStream imagev1.ImageStream, term string, precise bool) (float32, bool) {
 	score := stringProximityScorer(imageStream.Name, term)
+	if !precise {
+		score = min32(score, fuzzyScorer(imageStream.Name, term), annotationScorer(imageStream.Annotations, term))
+		for _, tag := range imageStream.Spec.Tags {
+			if strings.EqualFold(tag.Name, term) {
+				score = min32(score, 0.27)
+			}
+			score = min32(score, annotationScorer(tag.Annotations, term))
+		}
+	}
 	return score, score < 0.3
 }
 
@@ -44,6 +70,98 @@ func stringProximityScorer(s, query string) float32 {
 	return score
 }
 
+// fuzzyScorer scores names that are a small edit distance away from the
+// query, or that have a dash, dot or underscore separated part that is. One
+// edit is allowed for every four characters of the query, up to two, so that
+// postgress finds postgresql. Fuzzy matches score below the substring matches
+// of stringProximityScorer.
+func fuzzyScorer(s, query string) float32 {
+	maxDistance := len(query) / 4
+	if maxDistance > 2 {
+		maxDistance = 2
+	}
+	if maxDistance == 0 {
+		return 1.0
+	}
+	sLower := strings.ToLower(s)
+	queryLower := strings.ToLower(query)
+	candidates := append([]string{sLower}, strings.FieldsFunc(sLower, func(r rune) bool {
+		return r == '-' || r == '.' || r == '_'
+	})...)
+	distance := maxDistance + 1
+	for _, candidate := range candidates {
+		if d := editDistance(candidate, queryLower); d < distance {
+			distance = d
+		}
+	}
+	if distance > maxDistance {
+		return 1.0
+	}
+	return 0.24 + 0.02*float32(distance)
+}
+
+// annotationScorer scores the query against the tags annotation, the
+// display name and the description in annotations, in that order of
+// preference.
+func annotationScorer(annotations map[string]string, query string) float32 {
+	if len(query) == 0 || query == "*" {
+		return 1.0
+	}
+	for _, tag := range strings.Split(annotations[tagsAnnotation], ",") {
+		if strings.EqualFold(strings.TrimSpace(tag), query) {
+			return 0.25
+		}
+	}
+	queryLower := strings.ToLower(query)
+	if strings.Contains(strings.ToLower(annotations[displayNameAnnotation]), queryLower) {
+		return 0.26
+	}
+	if strings.Contains(strings.ToLower(annotations[descriptionAnnotation]), queryLower) {
+		return 0.28
+	}
+	return 1.0
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(t)]
+}
+
+func min32(a float32, others ...float32) float32 {
+	for _, b := range others {
+		if b < a {
+			a = b
+		}
+	}
+	return a
+}
+
+func minInt(a int, others ...int) int {
+	for _, b := range others {
+		if b < a {
+			a = b
+		}
+	}
+	return a
+}
+
 func partialScorer(a, b string, prefix bool, partial, none float32) (bool, float32) {
 	switch {
 	// If either one is empty, it's a partial match because the values do not conflict.
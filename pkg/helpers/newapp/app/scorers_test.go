@@ -0,0 +1,105 @@
+package app
+
+import (
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	templatev1 "github.com/openshift/api/template/v1"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{a: "", b: "abc", distance: 3},
+		{a: "postgresql", b: "postgress", distance: 2},
+		{a: "mysql", b: "mysql", distance: 0},
+		{a: "pyton", b: "python", distance: 1},
+	}
+	for _, test := range tests {
+		if d := editDistance(test.a, test.b); d != test.distance {
+			t.Errorf("expected a distance of %d between %q and %q, got %d", test.distance, test.a, test.b, d)
+		}
+	}
+}
+
+func TestTemplateScorer(t *testing.T) {
+	template := templatev1.Template{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "postgresql-persistent",
+			Annotations: map[string]string{
+				"tags":                      "database,postgresql",
+				"openshift.io/display-name": "PostgreSQL",
+				"description":               "A relational database with persistent storage.",
+			},
+		},
+	}
+	tests := []struct {
+		term    string
+		precise bool
+		score   float32
+		scored  bool
+	}{
+		{term: "postgresql-persistent", score: 0.0, scored: true},
+		{term: "postgres", score: 0.1, scored: true},
+		{term: "postgress", score: 0.28, scored: true},
+		{term: "postgress", precise: true, score: 1.0},
+		{term: "database", score: 0.25, scored: true},
+		{term: "relational", score: 0.28, scored: true},
+		{term: "relational", precise: true, score: 1.0},
+		{term: "mysql", score: 1.0},
+	}
+	for _, test := range tests {
+		score, scored := templateScorer(template, test.term, test.precise)
+		if score != test.score || scored != test.scored {
+			t.Errorf("%q (precise %t): expected %v %t, got %v %t", test.term, test.precise, test.score, test.scored, score, scored)
+		}
+	}
+}
+
+func TestImageStreamScorer(t *testing.T) {
+	stream := imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Name: "python"},
+		Spec: imagev1.ImageStreamSpec{
+			Tags: []imagev1.TagReference{
+				{Name: "3.9-ubi8", Annotations: map[string]string{"tags": "builder,django"}},
+			},
+		},
+	}
+	tests := []struct {
+		term   string
+		score  float32
+		scored bool
+	}{
+		{term: "pyton", score: 0.26, scored: true},
+		{term: "django", score: 0.25, scored: true},
+		{term: "3.9-UBI8", score: 0.27, scored: true},
+		{term: "ruby", score: 1.0},
+	}
+	for _, test := range tests {
+		score, scored := imageStreamScorer(stream, test.term, false)
+		if score != test.score || scored != test.scored {
+			t.Errorf("%q: expected %v %t, got %v %t", test.term, test.score, test.scored, score, scored)
+		}
+	}
+}
+
+func TestScoredComponentMatchesRanking(t *testing.T) {
+	matches := ScoredComponentMatches{
+		{Name: "openshift/php", Score: 0.1, NamespaceRank: 1},
+		{Name: "myproject/python", Score: 0.1},
+		{Name: "openshift/nodejs", Score: 0.0, NamespaceRank: 1},
+		{Name: "myproject/perl", Score: 0.1},
+	}
+	sort.Sort(matches)
+	expected := []string{"openshift/nodejs", "myproject/perl", "myproject/python", "openshift/php"}
+	for i, name := range expected {
+		if matches[i].Name != name {
+			t.Errorf("expected %s at position %d, got %s", name, i, matches[i].Name)
+		}
+	}
+}
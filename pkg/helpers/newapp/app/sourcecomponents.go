@@ -0,0 +1,215 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Submodule is a git submodule of a source repository, as declared in its
+// .gitmodules file.
+type Submodule struct {
+	Name string
+	// Path is the slash separated path of the submodule in the repository.
+	Path string
+	URL  string
+}
+
+// ReadSubmodules returns the submodules declared in the .gitmodules file of
+// dir, or none if it has no such file.
+func ReadSubmodules(dir string) ([]Submodule, error) {
+	f, err := os.Open(filepath.Join(dir, ".gitmodules"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	submodules := []Submodule{}
+	var current *Submodule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case len(line) == 0, strings.HasPrefix(line, "#"), strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "["):
+			current = nil
+			section := strings.TrimSpace(strings.Trim(line, "[]"))
+			if !strings.HasPrefix(section, "submodule") {
+				continue
+			}
+			name := strings.Trim(strings.TrimSpace(strings.TrimPrefix(section, "submodule")), `"`)
+			submodules = append(submodules, Submodule{Name: name})
+			current = &submodules[len(submodules)-1]
+		case current != nil:
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			value := strings.Trim(strings.TrimSpace(parts[1]), `"`)
+			switch strings.ToLower(strings.TrimSpace(parts[0])) {
+			case "path":
+				current.Path = path.Clean(value)
+			case "url":
+				current.URL = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read the .gitmodules file of %s: %v", dir, err)
+	}
+
+	result := []Submodule{}
+	for _, submodule := range submodules {
+		if len(submodule.Path) > 0 {
+			result = append(result, submodule)
+		}
+	}
+	return result, nil
+}
+
+// IsSSH returns true if the submodule is cloned over SSH, which requires
+// credentials.
+func (s Submodule) IsSSH() bool {
+	return strings.HasPrefix(s.URL, "ssh://") || (strings.Contains(s.URL, "@") && strings.Contains(s.URL, ":") && !strings.Contains(s.URL, "://"))
+}
+
+// DetectComponents detects the source of the subdirectories and submodules
+// of a repository that has no source at its root, such as a monorepo, and
+// keeps the ones hasSource accepts as the components of the repository.
+// Submodules that are not checked out are recorded, as their source cannot
+// be detected.
+func (r *SourceRepository) DetectComponents(d Detector, dockerStrategy bool, hasSource func(*SourceRepositoryInfo) bool) error {
+	if r.info == nil {
+		return nil
+	}
+	dir := r.info.Path
+	submodules, err := ReadSubmodules(dir)
+	if err != nil {
+		return err
+	}
+
+	dirs := sets.NewString()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			dirs.Insert(entry.Name())
+		}
+	}
+	inSubmodule := map[string]Submodule{}
+	for _, submodule := range submodules {
+		if !checkedOut(filepath.Join(dir, filepath.FromSlash(submodule.Path))) {
+			if !sets.NewString(r.info.MissingSubmodules...).Has(submodule.Path) {
+				r.info.MissingSubmodules = append(r.info.MissingSubmodules, submodule.Path)
+			}
+			continue
+		}
+		dirs.Insert(submodule.Path)
+		inSubmodule[submodule.Path] = submodule
+	}
+
+	detected := []string{}
+	for _, subdir := range dirs.List() {
+		// the source of a component includes its subdirectories
+		if hasAncestor(subdir, detected) {
+			continue
+		}
+		info, err := d.Detect(filepath.Join(dir, filepath.FromSlash(subdir)), dockerStrategy)
+		if err != nil {
+			return err
+		}
+		if !hasSource(info) {
+			continue
+		}
+		if submodule, ok := inSubmodule[subdir]; ok {
+			info.Submodule = &submodule
+		}
+		r.info.Components = append(r.info.Components, info)
+		detected = append(detected, subdir)
+	}
+	return nil
+}
+
+func hasAncestor(dir string, ancestors []string) bool {
+	for _, ancestor := range ancestors {
+		if strings.HasPrefix(dir, ancestor+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// missingSubmodules returns the paths of the submodules of dir that are not
+// checked out.
+func missingSubmodules(dir string) ([]string, error) {
+	submodules, err := ReadSubmodules(dir)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, submodule := range submodules {
+		if !checkedOut(filepath.Join(dir, filepath.FromSlash(submodule.Path))) {
+			missing = append(missing, submodule.Path)
+		}
+	}
+	return missing, nil
+}
+
+// checkedOut returns true if dir exists and is not empty.
+func checkedOut(dir string) bool {
+	entries, err := ioutil.ReadDir(dir)
+	return err == nil && len(entries) > 0
+}
+
+// component returns a repository for a component of the repository, whose
+// context directory is the directory of the component.
+func (r *SourceRepository) component(info *SourceRepositoryInfo) (*SourceRepository, error) {
+	rel, err := filepath.Rel(r.info.Path, info.Path)
+	if err != nil {
+		return nil, err
+	}
+	c := *r
+	c.contextDir = path.Join(r.contextDir, filepath.ToSlash(rel))
+	c.localDir = info.Path
+	c.info = info
+	c.name = filepath.Base(info.Path)
+	c.usedBy = nil
+	if info.Submodule != nil && info.Submodule.IsSSH() {
+		c.requiresAuth = true
+	}
+	return &c, nil
+}
+
+// SplitComponents returns the repositories with each repository that is
+// not used and has components replaced by a repository per component.
+func (rr SourceRepositories) SplitComponents() (SourceRepositories, error) {
+	result := SourceRepositories{}
+	for _, r := range rr {
+		if r.InUse() || r.info == nil || len(r.info.Components) == 0 {
+			result = append(result, r)
+			continue
+		}
+		components := SourceRepositories{}
+		for _, info := range r.info.Components {
+			c, err := r.component(info)
+			if err != nil {
+				return nil, err
+			}
+			components = append(components, c)
+		}
+		result = append(result, components...)
+	}
+	return result, nil
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/git"
+	"github.com/openshift/oc/pkg/helpers/newapp"
+)
+
+// markerDetector detects nodejs source from a package.json file.
+type markerDetector struct{}
+
+func (markerDetector) Detect(dir string, dockerStrategy bool) (*SourceRepositoryInfo, error) {
+	info := &SourceRepositoryInfo{Path: dir}
+	if _, err := os.Stat(filepath.Join(dir, "package.json")); err == nil {
+		info.Types = append(info.Types, SourceLanguageType{Platform: "nodejs"})
+	}
+	return info, nil
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadSubmodules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "submodules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{".gitmodules": `# shared code
+[submodule "auth"]
+	path = libs/auth
+	url = git@github.com:example/auth.git
+[core]
+	path = ignored
+[submodule "docs"]
+	path = "docs/"
+	url = https://github.com/example/docs.git
+`})
+	submodules, err := ReadSubmodules(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Submodule{
+		{Name: "auth", Path: "libs/auth", URL: "git@github.com:example/auth.git"},
+		{Name: "docs", Path: "docs", URL: "https://github.com/example/docs.git"},
+	}
+	if !reflect.DeepEqual(submodules, expected) {
+		t.Errorf("unexpected submodules: %#v", submodules)
+	}
+	if !submodules[0].IsSSH() || submodules[1].IsSSH() {
+		t.Error("expected only the first submodule to be cloned over SSH")
+	}
+}
+
+func TestSplitComponents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monorepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"README.md":                   "",
+		".gitmodules":                 "[submodule \"auth\"]\n\tpath = libs/auth\n\turl = git@github.com:example/auth.git\n[submodule \"missing\"]\n\tpath = libs/missing\n\turl = ../missing.git\n",
+		"frontend/package.json":       "{}",
+		"frontend/tools/package.json": "{}",
+		"docs/index.md":               "",
+		"libs/auth/package.json":      "{}",
+	})
+
+	repo, err := NewSourceRepository("https://github.com/example/monorepo.git", newapp.StrategySource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.SetInfo(&SourceRepositoryInfo{Path: dir})
+	hasSource := func(info *SourceRepositoryInfo) bool { return len(info.Types) > 0 }
+	if err := repo.DetectComponents(markerDetector{}, false, hasSource); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(repo.Info().MissingSubmodules, []string{"libs/missing"}) {
+		t.Errorf("unexpected missing submodules: %v", repo.Info().MissingSubmodules)
+	}
+
+	repositories, err := SourceRepositories{repo}.SplitComponents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repositories) != 2 {
+		t.Fatalf("expected 2 components, got %v", repositories)
+	}
+	expected := []struct {
+		name, contextDir, submodule string
+		requiresAuth                bool
+	}{
+		{name: "frontend", contextDir: "frontend"},
+		{name: "auth", contextDir: "libs/auth", submodule: "libs/auth", requiresAuth: true},
+	}
+	for i, repo := range repositories {
+		_, source, err := StrategyAndSourceForRepository(repo, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if source.Name != expected[i].name || source.ContextDir != expected[i].contextDir || source.Submodule != expected[i].submodule || source.RequiresAuth != expected[i].requiresAuth {
+			t.Errorf("unexpected source of component %d: %#v", i, source)
+		}
+		if source.URL.String() != "https://github.com/example/monorepo.git" {
+			t.Errorf("expected the source of the repository, got %s", source.URL)
+		}
+	}
+}
+
+// unreachableSubmodulesRepository clones and checks out repositories whose
+// submodules cannot be updated.
+type unreachableSubmodulesRepository struct {
+	git.Repository
+}
+
+func (unreachableSubmodulesRepository) CloneWithOptions(dir string, url string, args ...string) error {
+	return nil
+}
+
+func (unreachableSubmodulesRepository) Clone(dir string, url string) error {
+	return nil
+}
+
+func (unreachableSubmodulesRepository) Checkout(dir string, ref string) error {
+	return nil
+}
+
+func (unreachableSubmodulesRepository) SubmoduleUpdate(dir string, init, recursive bool) error {
+	return errors.New("Permission denied (publickey)")
+}
+
+func TestCloneWithUnreachableSubmodules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "submodules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		".gitmodules": `[submodule "auth"]
+	path = libs/auth
+	url = git@github.com:example/auth.git
+[submodule "docs"]
+	path = docs
+	url = https://github.com/example/docs.git
+`,
+		"docs/index.md": "# docs",
+	})
+
+	path, err := CloneAndCheckoutSources(unreachableSubmodulesRepository{}, "https://github.com/example/app.git", "main", dir, "")
+	if err != nil {
+		t.Fatalf("expected the clone to succeed without its submodules: %v", err)
+	}
+	missing, err := missingSubmodules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(missing, []string{"libs/auth"}) {
+		t.Errorf("unexpected missing submodules: %v", missing)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	buildv1 "github.com/openshift/api/build/v1"
 	"github.com/openshift/library-go/pkg/git"
 	"github.com/openshift/oc/pkg/helpers/newapp"
+	"github.com/openshift/oc/pkg/helpers/newapp/docker/dockerfile"
 	"github.com/openshift/oc/pkg/helpers/newapp/source"
 )
 
@@ -107,6 +108,10 @@ type SourceRepository struct {
 	sourceImageFrom string
 	sourceImageTo   string
 
+	// name is the name of the component of a repository with several
+	// components, see SplitComponents.
+	name string
+
 	usedBy           []ComponentReference
 	strategy         newapp.Strategy
 	ignoreRepository bool
@@ -230,6 +235,13 @@ func (r *SourceRepository) Detect(d Detector, dockerStrategy bool) error {
 	if err != nil {
 		return err
 	}
+	// the submodules of a clone may not be checked out when they cannot be
+	// reached from here, the builds clone them with their own secrets
+	if r.Remote() {
+		if r.info.MissingSubmodules, err = missingSubmodules(path); err != nil {
+			return err
+		}
+	}
 	if err = r.DetectAuth(); err != nil {
 		return err
 	}
@@ -396,6 +408,23 @@ func (r *SourceRepository) AddDockerfile(contents string) error {
 	return nil
 }
 
+// SetDockerfileTarget replaces the Dockerfile of the SourceRepository with its
+// stages up to the stage named target, and adds it to the build, so that the
+// build stops at that stage as docker build --target does.
+func (r *SourceRepository) SetDockerfileTarget(target string) error {
+	if r.info == nil || r.info.Dockerfile == nil {
+		return fmt.Errorf("no Dockerfile was found in the repository %q to build the stage %q of", r, target)
+	}
+	contents, err := dockerfile.StageContents(r.info.Dockerfile.Contents(), r.info.Dockerfile.AST(), target)
+	if err != nil {
+		return err
+	}
+	if contents == r.info.Dockerfile.Contents() {
+		return nil
+	}
+	return r.AddDockerfile(contents)
+}
+
 // AddBuildConfigMaps adds the defined configMaps into the build. The input format for
 // the secrets is "<secretName>:<destinationDir>". The destinationDir is
 // optional and when not specified the default is the current working directory.
@@ -498,6 +527,16 @@ type SourceRepositoryInfo struct {
 	Types       []SourceLanguageType
 	Dockerfile  Dockerfile
 	Jenkinsfile bool
+	// Devfile is the path of the devfile of the repository, if it has one.
+	Devfile string
+	// Components are the subdirectories with their own source, detected if
+	// the repository has none at its root.
+	Components []*SourceRepositoryInfo
+	// MissingSubmodules are the paths of the submodules that are not checked
+	// out, whose source could not be detected.
+	MissingSubmodules []string
+	// Submodule is the submodule of a component whose directory is one.
+	Submodule *Submodule
 }
 
 // Terms returns which languages the source repository was
@@ -515,11 +554,17 @@ func (info *SourceRepositoryInfo) Terms() []string {
 type SourceLanguageType struct {
 	Platform string
 	Version  string
+	// Builder is the builder image stream to use, if the detector knows it.
+	Builder string
 }
 
 // Term returns a search term for the given source language type
-// the term will be in the form of language:version
+// the term will be the builder image stream if one is known, or else
+// in the form of language:version
 func (t *SourceLanguageType) Term() string {
+	if len(t.Builder) > 0 {
+		return t.Builder
+	}
 	if len(t.Version) == 0 {
 		return t.Platform
 	}
@@ -537,6 +582,7 @@ type SourceRepositoryEnumerator struct {
 	Detectors         source.Detectors
 	DockerfileTester  newapp.Tester
 	JenkinsfileTester newapp.Tester
+	DevfileTester     newapp.Tester
 }
 
 // Detect extracts source code information about the provided source repository
@@ -553,6 +599,7 @@ func (e SourceRepositoryEnumerator) Detect(dir string, noSourceDetection bool) (
 				info.Types = append(info.Types, SourceLanguageType{
 					Platform: detected.Platform,
 					Version:  detected.Version,
+					Builder:  detected.Builder,
 				})
 			}
 		}
@@ -567,6 +614,11 @@ func (e SourceRepositoryEnumerator) Detect(dir string, noSourceDetection bool) (
 	if _, ok, err := e.JenkinsfileTester.Has(dir); err == nil && ok {
 		info.Jenkinsfile = true
 	}
+	if e.DevfileTester != nil {
+		if path, ok, err := e.DevfileTester.Has(dir); err == nil && ok {
+			info.Devfile = path
+		}
+	}
 
 	return info, nil
 }
@@ -580,7 +632,11 @@ func StrategyAndSourceForRepository(repo *SourceRepository, image *ImageRef) (*B
 		Base:     image,
 		Strategy: repo.strategy,
 	}
+	if repo.strategy == newapp.StrategyDocker && repo.Info() != nil && repo.Info().Dockerfile != nil {
+		_, strategy.FromStage = dockerfile.LastStageBaseImage(repo.Info().Dockerfile.AST(), nil)
+	}
 	source := &SourceRef{
+		Name:         repo.name,
 		Binary:       repo.binary,
 		Secrets:      repo.secrets,
 		ConfigMaps:   repo.configMaps,
@@ -611,6 +667,9 @@ func StrategyAndSourceForRepository(repo *SourceRepository, image *ImageRef) (*B
 			source.Binary = true
 		}
 		source.ContextDir = repo.ContextDir()
+		if repo.Info() != nil && repo.Info().Submodule != nil {
+			source.Submodule = repo.Info().Submodule.Path
+		}
 	}
 
 	return strategy, source, nil
@@ -640,6 +699,15 @@ func CloneAndCheckoutSources(repo git.Repository, remote, ref, localDir, context
 				return "", fmt.Errorf("unable to checkout ref %q in %q repository: %v", ref, remote, err)
 			}
 		}
+		// check out the submodules at the commits recorded by the ref, as
+		// builds do
+		if _, err := os.Stat(filepath.Join(localDir, ".gitmodules")); err == nil {
+			// the submodules that are not checked out are reported when the
+			// source is detected
+			if err := repo.SubmoduleUpdate(localDir, true, true); err != nil {
+				klog.V(2).Infof("Unable to update the submodules of %q repository: %v", remote, err)
+			}
+		}
 	}
 	if len(contextDir) > 0 {
 		klog.V(5).Infof("Using context directory %q. The full source path is %q", contextDir, filepath.Join(localDir, contextDir))
@@ -125,3 +125,45 @@ func TestAddBuildConfigMaps(t *testing.T) {
 		}
 	}
 }
+
+func TestDockerfileTarget(t *testing.T) {
+	repo, err := NewSourceRepositoryForDockerfile(`FROM golang AS builder
+RUN make
+FROM builder AS test
+RUN make test
+FROM ubi8
+COPY --from=builder /app /app
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetDockerfileTarget("missing"); err == nil {
+		t.Error("expected an error for a missing stage")
+	}
+	if err := repo.SetDockerfileTarget("test"); err != nil {
+		t.Fatal(err)
+	}
+	if repo.Info().Dockerfile.Contents() != "FROM golang AS builder\nRUN make\nFROM builder AS test\nRUN make test\n" {
+		t.Errorf("unexpected Dockerfile:\n%s", repo.Info().Dockerfile.Contents())
+	}
+
+	image, err := NewImageRefGenerator().FromName("golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	image.AsImageStream = true
+	strategyRef, source, err := StrategyAndSourceForRepository(repo, image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strategyRef.FromStage || len(source.DockerfileContents) == 0 {
+		t.Errorf("unexpected strategy %#v and source %#v", strategyRef, source)
+	}
+	strategy, triggers := strategyRef.BuildStrategy(nil, nil)
+	if strategy.DockerStrategy.From != nil {
+		t.Errorf("the FROM of the test stage must not be replaced: %#v", strategy.DockerStrategy.From)
+	}
+	if len(triggers) != 1 || triggers[0].ImageChange.From == nil || triggers[0].ImageChange.From.Name != "golang:latest" {
+		t.Errorf("unexpected triggers: %#v", triggers)
+	}
+}
@@ -50,7 +50,7 @@ func (r TemplateSearcher) Search(precise bool, terms ...string) (ComponentMatche
 		}
 
 		checkedNamespaces := sets.NewString()
-		for _, namespace := range namespaces {
+		for rank, namespace := range namespaces {
 			if checkedNamespaces.Has(namespace) {
 				continue
 			}
@@ -69,19 +69,20 @@ func (r TemplateSearcher) Search(precise bool, terms ...string) (ComponentMatche
 			for i := range templates.Items {
 				template := &templates.Items[i]
 				klog.V(4).Infof("checking namespace %s for template %s", namespace, ref.Name)
-				if score, scored := templateScorer(*template, ref.Name); scored {
+				if score, scored := templateScorer(*template, ref.Name, precise); scored {
 					if score == 0.0 {
 						exact = true
 					}
 					klog.V(4).Infof("Adding template %q in project %q with score %f", template.Name, template.Namespace, score)
 					fullName := fmt.Sprintf("%s/%s", template.Namespace, template.Name)
 					matches = append(matches, &ComponentMatch{
-						Value:       term,
-						Argument:    fmt.Sprintf("--template=%q", fullName),
-						Name:        fullName,
-						Description: fmt.Sprintf("Template %q in project %q", template.Name, template.Namespace),
-						Score:       score,
-						Template:    template,
+						Value:         term,
+						Argument:      fmt.Sprintf("--template=%q", fullName),
+						Name:          fullName,
+						Description:   fmt.Sprintf("Template %q in project %q", template.Name, template.Namespace),
+						Score:         score,
+						NamespaceRank: rank,
+						Template:      template,
 					})
 				}
 			}
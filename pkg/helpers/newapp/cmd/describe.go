@@ -168,6 +168,11 @@ func describeBuildPipelineWithImage(out io.Writer, ref app.ComponentReference, p
 		}
 
 		fmt.Fprintf(out, "    * A %s build using %s will be created\n", pipeline.Build.Strategy.Strategy, source)
+		if len(pipeline.Build.Source.Submodule) > 0 {
+			fmt.Fprintf(out, "      * The source is in the submodule %q, which the build checks out at the commit recorded by the repository\n", pipeline.Build.Source.Submodule)
+		} else if len(pipeline.Build.Source.Name) > 0 && len(pipeline.Build.Source.ContextDir) > 0 {
+			fmt.Fprintf(out, "      * The source is in the directory %q of the repository\n", pipeline.Build.Source.ContextDir)
+		}
 		if buildOut, err := pipeline.Build.Output.BuildOutput(); err == nil && buildOut != nil && buildOut.To != nil {
 			switch to := buildOut.To; {
 			case to.Kind == "ImageStreamTag":
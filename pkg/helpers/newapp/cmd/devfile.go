@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/openshift/oc/pkg/helpers/newapp"
+	"github.com/openshift/oc/pkg/helpers/newapp/app"
+	"github.com/openshift/oc/pkg/helpers/newapp/devfile"
+)
+
+// devfileObjects generates the objects of the source repositories that have
+// a devfile and are not used by another component.
+func (c *AppConfig) devfileObjects(repositories app.SourceRepositories, env, buildenv app.Environment) (app.Objects, error) {
+	if c.Strategy != newapp.StrategyUnspecified {
+		return nil, nil
+	}
+	var devfileRepositories app.SourceRepositories
+	for _, repo := range repositories.NotUsed() {
+		if info := repo.Info(); info != nil && len(info.Devfile) > 0 {
+			devfileRepositories = append(devfileRepositories, repo)
+		}
+	}
+	if len(devfileRepositories) == 0 {
+		return nil, nil
+	}
+	if len(devfileRepositories) > 1 && len(c.Name) > 0 {
+		return nil, errors.New("only one source repository with a devfile can be used when specifying a name")
+	}
+	if len(c.To) > 0 {
+		return nil, errors.New("--to cannot be used with a source repository with a devfile, images are pushed to image streams named after the devfile components")
+	}
+	if c.Deploy && c.DeploymentConfig {
+		fmt.Fprintf(c.ErrOut, "--> WARNING: devfiles are deployed as deployments, not deployment configs\n")
+	}
+	containerOptions, err := c.containerOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	objects := app.Objects{}
+	for _, repo := range devfileRepositories {
+		d, err := devfile.ParseFile(repo.Info().Devfile)
+		if err != nil {
+			return nil, err
+		}
+		_, source, err := app.StrategyAndSourceForRepository(repo, nil)
+		if err != nil {
+			return nil, err
+		}
+		generator := &devfile.Generator{
+			Name:             c.Name,
+			Source:           source,
+			Deploy:           c.Deploy,
+			Env:              env,
+			BuildEnv:         buildenv,
+			Labels:           c.Labels,
+			ContainerOptions: containerOptions,
+		}
+		generated, warnings, err := generator.Generate(d)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", repo.Info().Devfile, err)
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(c.ErrOut, "--> WARNING: %s: %s\n", repo.Info().Devfile, warning)
+		}
+		objects = append(objects, generated...)
+	}
+	return objects, nil
+}
@@ -24,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes"
+	generateversioned "k8s.io/kubectl/pkg/generate/versioned"
 	"k8s.io/kubectl/pkg/scheme"
 
 	appsv1 "github.com/openshift/api/apps/v1"
@@ -44,6 +45,7 @@ import (
 	utilenv "github.com/openshift/oc/pkg/helpers/env"
 	"github.com/openshift/oc/pkg/helpers/newapp"
 	"github.com/openshift/oc/pkg/helpers/newapp/app"
+	"github.com/openshift/oc/pkg/helpers/newapp/devfile"
 	"github.com/openshift/oc/pkg/helpers/newapp/dockerfile"
 	"github.com/openshift/oc/pkg/helpers/newapp/jenkinsfile"
 	"github.com/openshift/oc/pkg/helpers/newapp/source"
@@ -67,6 +69,9 @@ type GenerationInputs struct {
 	BuildArgs          []string
 	Labels             map[string]string
 
+	// Target is the stage of the Dockerfile to build, as with docker build --target.
+	Target string
+
 	TemplateParameterFiles []string
 	EnvironmentFiles       []string
 	BuildEnvironmentFiles  []string
@@ -98,6 +103,17 @@ type GenerationInputs struct {
 	DeploymentConfig bool
 	AsTestDeployment bool
 
+	// Expose is the host of the route created for the deployed component,
+	// "true" to let the router choose one, or empty for no route.
+	Expose string
+	// Probe is the readiness and liveness probe of the deployed containers,
+	// as TYPE[:PORT][/PATH].
+	Probe string
+	// Requests and Limits are the resources of the deployed containers, as
+	// comma separated RESOURCE=QUANTITY pairs.
+	Requests string
+	Limits   string
+
 	AllowGenerationErrors bool
 }
 
@@ -110,6 +126,10 @@ type AppConfig struct {
 
 	SkipGeneration bool
 
+	// DetectorConfig is a file of source detectors that are tried before the
+	// default ones, see source.DetectorConfig.
+	DetectorConfig string
+
 	AllowSecretUse bool
 	SourceSecret   string
 	PushSecret     string
@@ -118,6 +138,8 @@ type AppConfig struct {
 	AsSearch bool
 	AsList   bool
 	DryRun   bool
+	// ShowScores prints the score of each match of a search or list.
+	ShowScores bool
 
 	In     io.Reader
 	Out    io.Writer
@@ -199,6 +221,7 @@ func NewAppConfig() *AppConfig {
 				Detectors:         source.DefaultDetectors,
 				DockerfileTester:  dockerfile.NewTester(),
 				JenkinsfileTester: jenkinsfile.NewTester(),
+				DevfileTester:     devfile.NewTester(),
 			},
 		},
 		EnvironmentClassificationErrors: map[string]ArgumentClassificationError{},
@@ -209,6 +232,25 @@ func NewAppConfig() *AppConfig {
 	}
 }
 
+// AddDetectorConfig loads the source detectors of the DetectorConfig file, which
+// are tried before the other detectors.
+func (c *AppConfig) AddDetectorConfig() error {
+	if len(c.DetectorConfig) == 0 {
+		return nil
+	}
+	detectors, err := source.LoadDetectorConfig(c.DetectorConfig)
+	if err != nil {
+		return err
+	}
+	enumerator, ok := c.Detector.(app.SourceRepositoryEnumerator)
+	if !ok {
+		return fmt.Errorf("--detector-config cannot be used with a custom source detector")
+	}
+	enumerator.Detectors = append(detectors, enumerator.Detectors...)
+	c.Detector = enumerator
+	return nil
+}
+
 func (c *AppConfig) DockerRegistrySearcher() app.Searcher {
 	r := NewImageRegistrySearcher()
 	r.ImageRetriever.SecurityOptions.Insecure = c.InsecureRegistry
@@ -457,6 +499,11 @@ func (c *AppConfig) buildPipelines(components app.ComponentReferences, environme
 		}
 	}
 
+	containerOptions, err := c.containerOptions()
+	if err != nil {
+		return nil, err
+	}
+
 	numDockerBuilds := 0
 
 	pipelineBuilder := app.NewPipelineBuilder(c.Name, buildEnvironment, DockerStrategyOptions, c.OutputDocker).To(c.To)
@@ -529,6 +576,12 @@ func (c *AppConfig) buildPipelines(components app.ComponentReferences, environme
 						return nil, fmt.Errorf("can't set up a deployment for %q: %v", refInput, err)
 					}
 				}
+				if pipeline.Deployment != nil {
+					pipeline.Deployment.ContainerOptions = containerOptions
+				}
+				if pipeline.DeploymentConfig != nil {
+					pipeline.DeploymentConfig.ContainerOptions = containerOptions
+				}
 			}
 			if c.NoOutput {
 				pipeline.Build.Output = nil
@@ -561,6 +614,43 @@ func (c *AppConfig) buildPipelines(components app.ComponentReferences, environme
 	return pipelines, nil
 }
 
+// containerOptions returns the probe and resources set on the deployed
+// containers, or nil if there are none.
+func (c *AppConfig) containerOptions() (*app.ContainerOptions, error) {
+	if len(c.Probe) == 0 && len(c.Requests) == 0 && len(c.Limits) == 0 {
+		return nil, nil
+	}
+	options := &app.ContainerOptions{}
+	if len(c.Probe) > 0 {
+		probe, err := app.ParseProbe(c.Probe)
+		if err != nil {
+			return nil, err
+		}
+		options.Probe = probe
+	}
+	resources, err := generateversioned.HandleResourceRequirementsV1(map[string]string{"requests": c.Requests, "limits": c.Limits})
+	if err != nil {
+		return nil, fmt.Errorf("invalid --requests or --limits: %v", err)
+	}
+	options.Resources = resources
+	return options, nil
+}
+
+// routeHost returns whether a route should be created for the deployed
+// component, and its host if one was given.
+func (c *AppConfig) routeHost() (bool, string, error) {
+	switch c.Expose {
+	case "", "false":
+		return false, "", nil
+	case "true":
+		return true, "", nil
+	}
+	if err := app.ValidateRouteHost(c.Expose); err != nil {
+		return false, "", err
+	}
+	return true, c.Expose, nil
+}
+
 // buildTemplates converts a set of resolved, valid references into references to template objects.
 func (c *AppConfig) buildTemplates(components app.ComponentReferences, parameters app.Environment, environment app.Environment, buildEnvironment app.Environment, templateProcessor templateprocessorclient.TemplateProcessorInterface) (string, []runtime.Object, error) {
 	objects := []runtime.Object{}
@@ -891,6 +981,11 @@ func (c *AppConfig) Run() (*AppResult, error) {
 		}
 	}
 
+	expose, routeHost, err := c.routeHost()
+	if err != nil {
+		return nil, err
+	}
+
 	if len(c.To) > 0 {
 		if err := validateOutputImageReference(c.To); err != nil {
 			return nil, err
@@ -935,8 +1030,18 @@ func (c *AppConfig) Run() (*AppResult, error) {
 		}
 		objects = append(objects, accepted...)
 	}
+	devfileObjects, err := c.devfileObjects(repositories, env, buildenv)
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, devfileObjects...)
 
 	objects = app.AddServices(objects, false)
+	if expose && c.Deploy {
+		if objects, err = app.AddRoutes(objects, routeHost); err != nil {
+			return nil, err
+		}
+	}
 
 	templateProcessor := templateprocessorclient.NewTemplateProcessorClient(c.TemplateClient.RESTClient(), c.OriginNamespace)
 	templateName, templateObjects, err := c.buildTemplates(components.TemplateComponentRefs(), parameters, env, buildenv, templateProcessor)
@@ -1008,6 +1113,14 @@ func (c *AppConfig) Run() (*AppResult, error) {
 			}
 		}
 	}
+	if len(name) == 0 {
+		for _, obj := range devfileObjects {
+			if d, ok := obj.(*v1.Deployment); ok {
+				name = d.Name
+				break
+			}
+		}
+	}
 	if len(name) == 0 {
 		for _, obj := range objects {
 			if bc, ok := obj.(*buildv1.BuildConfig); ok {
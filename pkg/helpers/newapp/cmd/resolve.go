@@ -3,6 +3,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 
 	kutilerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -11,6 +12,7 @@ import (
 	"github.com/openshift/library-go/pkg/git"
 	"github.com/openshift/oc/pkg/helpers/newapp"
 	"github.com/openshift/oc/pkg/helpers/newapp/app"
+	"github.com/openshift/oc/pkg/helpers/newapp/devfile"
 	dockerfileutil "github.com/openshift/oc/pkg/helpers/newapp/docker/dockerfile"
 )
 
@@ -175,6 +177,29 @@ func Resolve(appConfig *AppConfig) (*ResolvedComponents, error) {
 		return nil, err
 	}
 
+	for _, repo := range repositories {
+		if info := repo.Info(); info != nil {
+			for _, path := range info.MissingSubmodules {
+				if repo.Remote() {
+					fmt.Fprintf(appConfig.ErrOut, "--> WARNING: the submodule %s of %s could not be checked out, its source is not detected\n", path, repo)
+				} else {
+					fmt.Fprintf(appConfig.ErrOut, "--> WARNING: the submodule %s of %s is not checked out, run 'git submodule update --init' to detect its source\n", path, repo)
+				}
+			}
+		}
+	}
+
+	// Source repos that are not yet linked to a component and have components
+	// in their subdirectories, such as monorepos, are replaced by a repo per
+	// component
+	repositories, err = repositories.SplitComponents()
+	if err != nil {
+		return nil, err
+	}
+	if g.Strategy == newapp.StrategyUnspecified {
+		ignoreUnsupportedDevfiles(appConfig.ErrOut, repositories.NotUsed())
+	}
+
 	// For source repos that are not yet linked to a component, create components
 	sourceComponents, err := AddMissingComponentsToRefBuilder(b, repositories.NotUsed(), r.DockerfileResolver(), r.SourceResolver(), r.PipelineResolver(), g)
 	if err != nil {
@@ -263,26 +288,50 @@ func AddDockerfileToSourceRepositories(b *app.ReferenceBuilder, dockerfile strin
 // DetectSource runs a code detector on the passed in repositories to obtain a SourceRepositoryInfo
 func DetectSource(repositories []*app.SourceRepository, d app.Detector, g *GenerationInputs) error {
 	errs := []error{}
+	hasSource := func(info *app.SourceRepositoryInfo) bool {
+		switch g.Strategy {
+		case newapp.StrategyDocker:
+			return info.Dockerfile != nil
+		case newapp.StrategyPipeline:
+			return info.Jenkinsfile
+		default:
+			return info.Dockerfile != nil || info.Jenkinsfile || len(info.Types) > 0 || len(info.Devfile) > 0
+		}
+	}
 	for _, repo := range repositories {
-		err := repo.Detect(d, g.Strategy == newapp.StrategyDocker || g.Strategy == newapp.StrategyPipeline)
+		noSourceDetection := g.Strategy == newapp.StrategyDocker || g.Strategy == newapp.StrategyPipeline
+		err := repo.Detect(d, noSourceDetection)
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
-		switch g.Strategy {
-		case newapp.StrategyDocker:
-			if repo.Info().Dockerfile == nil {
-				errs = append(errs, errors.New("No Dockerfile was found in the repository and the requested build strategy is 'docker'"))
+		// a repository with no source at its root, such as a monorepo, may
+		// have components in its subdirectories
+		if !hasSource(repo.Info()) && len(repo.ContextDir()) == 0 {
+			if err := repo.DetectComponents(d, noSourceDetection, hasSource); err != nil {
+				errs = append(errs, err)
+				continue
 			}
-		case newapp.StrategyPipeline:
-			if !repo.Info().Jenkinsfile {
+		}
+		if !hasSource(repo.Info()) && len(repo.Info().Components) == 0 {
+			switch g.Strategy {
+			case newapp.StrategyDocker:
+				errs = append(errs, errors.New("No Dockerfile was found in the repository and the requested build strategy is 'docker'"))
+			case newapp.StrategyPipeline:
 				errs = append(errs, errors.New("No Jenkinsfile was found in the repository and the requested build strategy is 'pipeline'"))
-			}
-		default:
-			if repo.Info().Dockerfile == nil && !repo.Info().Jenkinsfile && len(repo.Info().Types) == 0 {
+			default:
 				errs = append(errs, errors.New("No language matched the source repository"))
 			}
 		}
+		if len(g.Target) > 0 && len(repo.Info().Components) > 0 {
+			errs = append(errs, fmt.Errorf("--target cannot be used with the repository %q, which has several components", repo))
+			continue
+		}
+		if len(g.Target) > 0 && repo.Info().Dockerfile != nil {
+			if err := repo.SetDockerfileTarget(g.Target); err != nil {
+				errs = append(errs, err)
+			}
+		}
 	}
 	return kutilerrors.NewAggregate(errs)
 }
@@ -557,7 +606,8 @@ func AddMissingComponentsToRefBuilder(
 
 		case info.Dockerfile != nil && (g.Strategy == newapp.StrategyUnspecified || g.Strategy == newapp.StrategyDocker):
 			node := info.Dockerfile.AST()
-			baseImage := dockerfileutil.LastBaseImage(node)
+			// the last stage may build on an earlier stage, whose base image is the one to use
+			baseImage, _ := dockerfileutil.LastStageBaseImage(node, dockerfileBuildArgs(g.BuildArgs))
 			if baseImage == "" {
 				errs = append(errs, fmt.Errorf("the Dockerfile in the repository %q has no FROM instruction", info.Path))
 				continue
@@ -572,6 +622,10 @@ func AddMissingComponentsToRefBuilder(
 			})
 			result = append(result, refs...)
 
+		case len(info.Devfile) > 0 && len(info.Types) == 0 && g.Strategy == newapp.StrategyUnspecified:
+			// the objects of repositories with only a devfile are generated from it
+			continue
+
 		default:
 			// TODO: Add support for searching for more than one language if len(info.Types) > 1
 			if len(info.Types) == 0 {
@@ -591,3 +645,32 @@ func AddMissingComponentsToRefBuilder(
 	}
 	return result, kutilerrors.NewAggregate(errs)
 }
+
+// ignoreUnsupportedDevfiles warns about the devfiles that would be used for repositories but
+// cannot be, and ignores them so that the source of their repositories is detected as if they
+// had none.
+func ignoreUnsupportedDevfiles(out io.Writer, repositories app.SourceRepositories) {
+	for _, repo := range repositories {
+		info := repo.Info()
+		if info == nil || len(info.Devfile) == 0 || info.Dockerfile != nil || info.Jenkinsfile || len(info.Types) > 0 {
+			continue
+		}
+		if _, err := devfile.ParseFile(info.Devfile); err != nil {
+			fmt.Fprintf(out, "--> WARNING: %s is ignored: %v\n", info.Devfile, err)
+			info.Devfile = ""
+		}
+	}
+}
+
+// dockerfileBuildArgs returns the KEY=VALUE build arguments, which may set the
+// images of the FROM instructions of a Dockerfile. Build arguments read from
+// STDIN are ignored, as they can only be read once.
+func dockerfileBuildArgs(buildArgs []string) map[string]string {
+	args := map[string]string{}
+	for _, arg := range buildArgs {
+		if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 {
+			args[parts[0]] = parts[1]
+		}
+	}
+	return args
+}
@@ -1,6 +1,11 @@
 package cmd
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/openshift/oc/pkg/helpers/newapp"
@@ -101,3 +106,62 @@ func checkResolveResult(t *testing.T, componentrefs app.ComponentReferences, err
 		t.Fatalf("expected componentrefs[0].Input().Uses.GetStrategy() == %s", strategy)
 	}
 }
+
+// TestResolveDevfileAndDockerfile ensures that if a repo has a devfile and a
+// Dockerfile, we use the Dockerfile, and that a repo with only a devfile gets
+// no component, as its objects are generated from the devfile.
+func TestResolveDevfileAndDockerfile(t *testing.T) {
+	dockerfile, _ := app.NewDockerfile("FROM centos\n")
+	i := app.SourceRepositoryInfo{Dockerfile: dockerfile, Devfile: "devfile.yaml"}
+
+	repo := app.SourceRepository{}
+	repo.SetInfo(&i)
+	repositories := app.SourceRepositories{&repo}
+
+	resolvers := Resolvers{}
+	componentrefs, err := AddMissingComponentsToRefBuilder(&app.ReferenceBuilder{}, repositories, resolvers.DockerfileResolver(), resolvers.SourceResolver(), resolvers.PipelineResolver(), &GenerationInputs{})
+
+	checkResolveResult(t, componentrefs, err, newapp.StrategyDocker)
+
+	i = app.SourceRepositoryInfo{Devfile: "devfile.yaml"}
+	repo = app.SourceRepository{}
+	repo.SetInfo(&i)
+	componentrefs, err = AddMissingComponentsToRefBuilder(&app.ReferenceBuilder{}, repositories, resolvers.DockerfileResolver(), resolvers.SourceResolver(), resolvers.PipelineResolver(), &GenerationInputs{})
+	if err != nil || len(componentrefs) != 0 {
+		t.Errorf("expected no component for a repository with only a devfile, got %v: %v", componentrefs, err)
+	}
+}
+
+func TestIgnoreUnsupportedDevfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	supported := filepath.Join(dir, "supported.yaml")
+	unsupported := filepath.Join(dir, "unsupported.yaml")
+	if err := ioutil.WriteFile(supported, []byte("schemaVersion: 2.2.0\ncomponents:\n- name: app\n  container:\n    image: app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(unsupported, []byte("schemaVersion: 2.2.0\nparent:\n  id: nodejs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var repositories app.SourceRepositories
+	for _, path := range []string{supported, unsupported} {
+		repo := &app.SourceRepository{}
+		repo.SetInfo(&app.SourceRepositoryInfo{Devfile: path})
+		repositories = append(repositories, repo)
+	}
+	out := &bytes.Buffer{}
+	ignoreUnsupportedDevfiles(out, repositories)
+	if repositories[0].Info().Devfile != supported {
+		t.Errorf("expected the supported devfile to be kept")
+	}
+	if len(repositories[1].Info().Devfile) != 0 {
+		t.Errorf("expected the unsupported devfile to be ignored")
+	}
+	if !strings.Contains(out.String(), "WARNING: "+unsupported+" is ignored") {
+		t.Errorf("expected a warning, got %q", out.String())
+	}
+}
@@ -0,0 +1,451 @@
+// Package devfile reads the devfile.yaml of source repositories and generates
+// the builds and deployments that run them, so that new-app can create an
+// application from a repository set up for devfile based tools.
+package devfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/oc/pkg/helpers/newapp"
+)
+
+// FileNames are the names a devfile may have at the root of a repository, in
+// order of preference.
+var FileNames = []string{"devfile.yaml", ".devfile.yaml", "devfile.yml", ".devfile.yml"}
+
+// Devfile is the subset of a devfile 2.x that new-app understands. Fields
+// that are read only to report them as unsupported are raw values.
+type Devfile struct {
+	SchemaVersion string            `json:"schemaVersion"`
+	Metadata      Metadata          `json:"metadata,omitempty"`
+	Variables     map[string]string `json:"variables,omitempty"`
+	Components    []Component       `json:"components,omitempty"`
+	Commands      []Command         `json:"commands,omitempty"`
+
+	Parent interface{} `json:"parent,omitempty"`
+	Events interface{} `json:"events,omitempty"`
+}
+
+// Metadata describes the application.
+type Metadata struct {
+	Name string `json:"name,omitempty"`
+}
+
+// Component is one of the parts of the application. Only container, image and
+// volume components are supported.
+type Component struct {
+	Name      string     `json:"name"`
+	Container *Container `json:"container,omitempty"`
+	Image     *Image     `json:"image,omitempty"`
+	Volume    *Volume    `json:"volume,omitempty"`
+
+	Kubernetes interface{} `json:"kubernetes,omitempty"`
+	Openshift  interface{} `json:"openshift,omitempty"`
+	Plugin     interface{} `json:"plugin,omitempty"`
+	Custom     interface{} `json:"custom,omitempty"`
+}
+
+// Container is a container of the application pod.
+type Container struct {
+	Image         string        `json:"image"`
+	Env           []EnvVar      `json:"env,omitempty"`
+	Endpoints     []Endpoint    `json:"endpoints,omitempty"`
+	Command       []string      `json:"command,omitempty"`
+	Args          []string      `json:"args,omitempty"`
+	MemoryLimit   string        `json:"memoryLimit,omitempty"`
+	MemoryRequest string        `json:"memoryRequest,omitempty"`
+	CPULimit      string        `json:"cpuLimit,omitempty"`
+	CPURequest    string        `json:"cpuRequest,omitempty"`
+	MountSources  *bool         `json:"mountSources,omitempty"`
+	SourceMapping string        `json:"sourceMapping,omitempty"`
+	VolumeMounts  []VolumeMount `json:"volumeMounts,omitempty"`
+	DedicatedPod  bool          `json:"dedicatedPod,omitempty"`
+}
+
+// EnvVar is an environment variable of a container or a command.
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Endpoint is a port a container listens on.
+type Endpoint struct {
+	Name       string `json:"name"`
+	TargetPort int    `json:"targetPort"`
+	// Exposure is public, internal or none. Public endpoints get a route.
+	Exposure string `json:"exposure,omitempty"`
+	// Protocol is http, https, ws, wss, tcp, udp or sctp.
+	Protocol string `json:"protocol,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// VolumeMount mounts a volume component in a container.
+type VolumeMount struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+}
+
+// Image is an image built from a Dockerfile of the repository.
+type Image struct {
+	ImageName  string      `json:"imageName"`
+	Dockerfile *Dockerfile `json:"dockerfile,omitempty"`
+}
+
+// Dockerfile locates the Dockerfile of an image component.
+type Dockerfile struct {
+	URI          string   `json:"uri,omitempty"`
+	BuildContext string   `json:"buildContext,omitempty"`
+	Args         []string `json:"args,omitempty"`
+
+	Git             interface{} `json:"git,omitempty"`
+	DevfileRegistry interface{} `json:"devfileRegistry,omitempty"`
+}
+
+// Volume is storage shared by the containers.
+type Volume struct {
+	Size      string `json:"size,omitempty"`
+	Ephemeral bool   `json:"ephemeral,omitempty"`
+}
+
+// Command is a command run on the components. Only exec commands, and
+// composite commands of exec commands, are used to build and run containers.
+type Command struct {
+	ID        string            `json:"id"`
+	Exec      *ExecCommand      `json:"exec,omitempty"`
+	Composite *CompositeCommand `json:"composite,omitempty"`
+	Apply     *ApplyCommand     `json:"apply,omitempty"`
+}
+
+// ExecCommand runs a command line in a container component.
+type ExecCommand struct {
+	CommandLine string        `json:"commandLine"`
+	Component   string        `json:"component"`
+	WorkingDir  string        `json:"workingDir,omitempty"`
+	Env         []EnvVar      `json:"env,omitempty"`
+	Group       *CommandGroup `json:"group,omitempty"`
+}
+
+// CompositeCommand runs other commands.
+type CompositeCommand struct {
+	Commands []string      `json:"commands"`
+	Parallel bool          `json:"parallel,omitempty"`
+	Group    *CommandGroup `json:"group,omitempty"`
+}
+
+// ApplyCommand applies a component, as the deploy commands do for images.
+type ApplyCommand struct {
+	Component string        `json:"component"`
+	Group     *CommandGroup `json:"group,omitempty"`
+}
+
+// CommandGroup is the kind of a command: build, run, test, debug or deploy.
+type CommandGroup struct {
+	Kind      string `json:"kind"`
+	IsDefault *bool  `json:"isDefault,omitempty"`
+}
+
+var variableRegexp = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_\-.]+)\s*\}\}`)
+
+// Parse reads a devfile and substitutes its variables. It returns an error
+// for the features of devfiles that new-app cannot turn into builds and
+// deployments.
+func Parse(data []byte) (*Devfile, error) {
+	header := struct {
+		Variables map[string]string `json:"variables,omitempty"`
+	}{}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("unable to parse the devfile: %v", err)
+	}
+	var undefined []string
+	data = variableRegexp.ReplaceAllFunc(data, func(match []byte) []byte {
+		name := string(variableRegexp.FindSubmatch(match)[1])
+		value, ok := header.Variables[name]
+		if !ok {
+			undefined = append(undefined, name)
+			return match
+		}
+		return []byte(value)
+	})
+	if len(undefined) > 0 {
+		return nil, fmt.Errorf("the devfile uses undefined variables: %s", strings.Join(undefined, ", "))
+	}
+
+	devfile := &Devfile{}
+	if err := yaml.Unmarshal(data, devfile); err != nil {
+		return nil, fmt.Errorf("unable to parse the devfile: %v", err)
+	}
+	if err := devfile.validate(); err != nil {
+		return nil, err
+	}
+	return devfile, nil
+}
+
+// ParseFile reads the devfile at path.
+func ParseFile(path string) (*Devfile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	devfile, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return devfile, nil
+}
+
+func (d *Devfile) validate() error {
+	major := strings.SplitN(d.SchemaVersion, ".", 2)[0]
+	if version, err := strconv.Atoi(major); err != nil || version != 2 {
+		return fmt.Errorf("devfile schema version %q is not supported, only 2.x devfiles are", d.SchemaVersion)
+	}
+	if d.Parent != nil {
+		return fmt.Errorf("devfiles with a parent are not supported, flatten the devfile first")
+	}
+	if d.Events != nil {
+		return fmt.Errorf("devfile events are not supported, as they only apply to development environments")
+	}
+
+	components := map[string]*Component{}
+	for i := range d.Components {
+		c := &d.Components[i]
+		if len(c.Name) == 0 {
+			return fmt.Errorf("component %d of the devfile has no name", i+1)
+		}
+		if components[c.Name] != nil {
+			return fmt.Errorf("the devfile has several components named %q", c.Name)
+		}
+		components[c.Name] = c
+		switch {
+		case c.Kubernetes != nil, c.Openshift != nil:
+			return fmt.Errorf("component %q: kubernetes and openshift components are not supported, create their objects with oc apply", c.Name)
+		case c.Plugin != nil, c.Custom != nil:
+			return fmt.Errorf("component %q: plugin and custom components are not supported", c.Name)
+		case c.Container != nil:
+			if err := c.Container.validate(); err != nil {
+				return fmt.Errorf("component %q: %v", c.Name, err)
+			}
+		case c.Image != nil:
+			if err := c.Image.validate(); err != nil {
+				return fmt.Errorf("component %q: %v", c.Name, err)
+			}
+		case c.Volume != nil:
+		default:
+			return fmt.Errorf("component %q has no container, image or volume", c.Name)
+		}
+	}
+
+	for _, c := range d.Components {
+		if c.Container == nil {
+			continue
+		}
+		for _, mount := range c.Container.VolumeMounts {
+			if v := components[mount.Name]; v == nil || v.Volume == nil {
+				return fmt.Errorf("component %q mounts %q, which is not a volume component", c.Name, mount.Name)
+			}
+		}
+	}
+
+	commands := map[string]*Command{}
+	for i := range d.Commands {
+		cmd := &d.Commands[i]
+		if len(cmd.ID) == 0 {
+			return fmt.Errorf("command %d of the devfile has no id", i+1)
+		}
+		commands[strings.ToLower(cmd.ID)] = cmd
+		switch {
+		case cmd.Exec != nil:
+			if c := components[cmd.Exec.Component]; c == nil || c.Container == nil {
+				return fmt.Errorf("command %q runs in %q, which is not a container component", cmd.ID, cmd.Exec.Component)
+			}
+		case cmd.Composite != nil:
+			if cmd.Composite.Parallel && cmd.Composite.Group != nil && (cmd.Composite.Group.Kind == "build" || cmd.Composite.Group.Kind == "run") {
+				return fmt.Errorf("command %q: parallel composite commands are not supported", cmd.ID)
+			}
+		case cmd.Apply != nil:
+			if components[cmd.Apply.Component] == nil {
+				return fmt.Errorf("command %q applies %q, which is not a component", cmd.ID, cmd.Apply.Component)
+			}
+		default:
+			return fmt.Errorf("command %q: only exec, composite and apply commands are supported", cmd.ID)
+		}
+	}
+	for _, cmd := range d.Commands {
+		if cmd.Composite == nil {
+			continue
+		}
+		for _, id := range cmd.Composite.Commands {
+			if commands[strings.ToLower(id)] == nil {
+				return fmt.Errorf("command %q runs %q, which is not a command", cmd.ID, id)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Container) validate() error {
+	if len(c.Image) == 0 {
+		return fmt.Errorf("the container has no image")
+	}
+	if c.DedicatedPod {
+		return fmt.Errorf("containers in a dedicated pod are not supported")
+	}
+	for _, e := range c.Endpoints {
+		if e.TargetPort <= 0 || e.TargetPort > 65535 {
+			return fmt.Errorf("endpoint %q has an invalid target port %d", e.Name, e.TargetPort)
+		}
+		switch e.Exposure {
+		case "", "public", "internal", "none":
+		default:
+			return fmt.Errorf("endpoint %q has an invalid exposure %q", e.Name, e.Exposure)
+		}
+		switch e.Protocol {
+		case "", "http", "https", "ws", "wss", "tcp", "udp", "sctp":
+		default:
+			return fmt.Errorf("endpoint %q has an invalid protocol %q", e.Name, e.Protocol)
+		}
+	}
+	if _, err := resources(c); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (i *Image) validate() error {
+	if len(i.ImageName) == 0 {
+		return fmt.Errorf("the image has no imageName")
+	}
+	if i.Dockerfile == nil {
+		return fmt.Errorf("only images built from a Dockerfile are supported")
+	}
+	if i.Dockerfile.Git != nil || i.Dockerfile.DevfileRegistry != nil {
+		return fmt.Errorf("only Dockerfiles of the repository are supported, not from git or a devfile registry")
+	}
+	if strings.Contains(i.Dockerfile.URI, "://") {
+		return fmt.Errorf("the Dockerfile %q must be a path in the repository", i.Dockerfile.URI)
+	}
+	for _, arg := range i.Dockerfile.Args {
+		if !strings.Contains(arg, "=") {
+			return fmt.Errorf("the build argument %q must be of the form KEY=VALUE", arg)
+		}
+	}
+	return nil
+}
+
+// Component returns the component with the given name, or nil.
+func (d *Devfile) Component(name string) *Component {
+	for i := range d.Components {
+		if d.Components[i].Name == name {
+			return &d.Components[i]
+		}
+	}
+	return nil
+}
+
+// command returns the command with the given id, which is case insensitive,
+// or nil.
+func (d *Devfile) command(id string) *Command {
+	for i := range d.Commands {
+		if strings.EqualFold(d.Commands[i].ID, id) {
+			return &d.Commands[i]
+		}
+	}
+	return nil
+}
+
+func (c *Command) group() *CommandGroup {
+	switch {
+	case c.Exec != nil:
+		return c.Exec.Group
+	case c.Composite != nil:
+		return c.Composite.Group
+	case c.Apply != nil:
+		return c.Apply.Group
+	}
+	return nil
+}
+
+// DefaultCommand returns the default command of a group kind: the one marked
+// as default, or the only one of that kind. It returns nil if there is none,
+// and an error if there are several and none is marked as default.
+func (d *Devfile) DefaultCommand(kind string) (*Command, error) {
+	var candidates []*Command
+	for i := range d.Commands {
+		group := d.Commands[i].group()
+		if group == nil || group.Kind != kind {
+			continue
+		}
+		if group.IsDefault != nil && *group.IsDefault {
+			return &d.Commands[i], nil
+		}
+		candidates = append(candidates, &d.Commands[i])
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, nil
+	case 1:
+		return candidates[0], nil
+	}
+	return nil, fmt.Errorf("the devfile has several %s commands and none is the default", kind)
+}
+
+// ExecCommands returns the exec commands that a command runs, in order. It
+// returns an error if composite commands run themselves.
+func (d *Devfile) ExecCommands(c *Command) ([]*ExecCommand, error) {
+	return d.execCommands(c, map[*Command]bool{})
+}
+
+func (d *Devfile) execCommands(c *Command, running map[*Command]bool) ([]*ExecCommand, error) {
+	switch {
+	case c.Exec != nil:
+		return []*ExecCommand{c.Exec}, nil
+	case c.Composite != nil:
+		if running[c] {
+			return nil, fmt.Errorf("the composite command %q runs itself", c.ID)
+		}
+		running[c] = true
+		defer delete(running, c)
+		var commands []*ExecCommand
+		for _, id := range c.Composite.Commands {
+			children, err := d.execCommands(d.command(id), running)
+			if err != nil {
+				return nil, err
+			}
+			commands = append(commands, children...)
+		}
+		return commands, nil
+	}
+	return nil, nil
+}
+
+// StatFunc tests whether a directory has a devfile.
+type StatFunc func(path string) (os.FileInfo, error)
+
+// Has returns the path of the devfile of dir.
+func (t StatFunc) Has(dir string) (string, bool, error) {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		_, err := t(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return path, true, nil
+	}
+	return "", false, nil
+}
+
+// NewTester returns a tester for the devfiles of directories.
+func NewTester() newapp.Tester {
+	return StatFunc(os.Stat)
+}
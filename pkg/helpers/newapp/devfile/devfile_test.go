@@ -0,0 +1,307 @@
+package devfile
+
+import (
+	"strings"
+	"testing"
+
+	kappsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	routev1 "github.com/openshift/api/route/v1"
+
+	"github.com/openshift/oc/pkg/helpers/newapp/app"
+	s2igit "github.com/openshift/oc/pkg/helpers/source-to-image/git"
+)
+
+const nodejsDevfile = `schemaVersion: 2.2.0
+metadata:
+  name: nodejs-app
+variables:
+  NODE_IMAGE: registry.access.redhat.com/ubi8/nodejs-16:latest
+components:
+- name: runtime
+  container:
+    image: "{{NODE_IMAGE}}"
+    memoryLimit: 1Gi
+    cpuRequest: 100m
+    command: [tail, -f, /dev/null]
+    env:
+    - name: DEBUG_PORT
+      value: "5858"
+    endpoints:
+    - name: http
+      targetPort: 3000
+    - name: debug
+      targetPort: 5858
+      exposure: none
+    volumeMounts:
+    - name: cache
+- name: redis
+  container:
+    image: redis:7
+    mountSources: false
+    endpoints:
+    - name: redis
+      targetPort: 6379
+      protocol: tcp
+      exposure: internal
+    volumeMounts:
+    - name: cache
+      path: /data
+- name: cache
+  volume:
+    ephemeral: true
+commands:
+- id: install
+  exec:
+    component: runtime
+    commandLine: npm install
+    workingDir: ${PROJECT_SOURCE}
+    env:
+    - name: NPM_CONFIG_LOGLEVEL
+      value: warn
+    group:
+      kind: build
+      isDefault: true
+- id: run
+  exec:
+    component: runtime
+    commandLine: npm start
+    group:
+      kind: run
+      isDefault: true
+`
+
+const dockerfileDevfile = `schemaVersion: 2.2.0
+metadata:
+  name: go-app
+components:
+- name: build
+  image:
+    imageName: go-app:latest
+    dockerfile:
+      uri: docker/Dockerfile.prod
+      buildContext: docker
+      args: [GO_VERSION=1.19]
+- name: app
+  container:
+    image: go-app:latest
+    endpoints:
+    - name: web
+      targetPort: 8080
+    - name: admin
+      targetPort: 8443
+      protocol: https
+commands:
+- id: deploy
+  apply:
+    component: build
+    group:
+      kind: deploy
+`
+
+func TestParseUnsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		devfile string
+		wantErr string
+	}{
+		{
+			name:    "schema version",
+			devfile: "schemaVersion: 1.0.0\n",
+			wantErr: "schema version",
+		},
+		{
+			name:    "parent",
+			devfile: "schemaVersion: 2.2.0\nparent:\n  id: nodejs\n",
+			wantErr: "parent",
+		},
+		{
+			name:    "kubernetes component",
+			devfile: "schemaVersion: 2.2.0\ncomponents:\n- name: deploy\n  kubernetes:\n    uri: deploy.yaml\n",
+			wantErr: "kubernetes and openshift components are not supported",
+		},
+		{
+			name:    "undefined variable",
+			devfile: "schemaVersion: 2.2.0\ncomponents:\n- name: app\n  container:\n    image: \"{{IMAGE}}\"\n",
+			wantErr: "undefined variables: IMAGE",
+		},
+		{
+			name:    "image from git",
+			devfile: "schemaVersion: 2.2.0\ncomponents:\n- name: img\n  image:\n    imageName: app\n    dockerfile:\n      git:\n        remotes: {origin: https://example.com/app.git}\n",
+			wantErr: "only Dockerfiles of the repository",
+		},
+		{
+			name:    "mount of a container",
+			devfile: "schemaVersion: 2.2.0\ncomponents:\n- name: app\n  container:\n    image: app\n    volumeMounts:\n    - name: app\n",
+			wantErr: "not a volume component",
+		},
+		{
+			name:    "invalid memory limit",
+			devfile: "schemaVersion: 2.2.0\ncomponents:\n- name: app\n  container:\n    image: app\n    memoryLimit: 1GB\n",
+			wantErr: `component "app": invalid memoryLimit "1GB"`,
+		},
+		{
+			name:    "command of an unknown component",
+			devfile: "schemaVersion: 2.2.0\ncommands:\n- id: run\n  exec:\n    component: app\n    commandLine: ./app\n",
+			wantErr: "not a container component",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.devfile))
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExecCommandsCycle(t *testing.T) {
+	d, err := Parse([]byte(`schemaVersion: 2.2.0
+commands:
+- id: a
+  composite:
+    commands: [b]
+- id: b
+  composite:
+    commands: [a]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.ExecCommands(&d.Commands[0]); err == nil {
+		t.Error("expected an error for composite commands that run themselves")
+	}
+}
+
+func generate(t *testing.T, devfile string, deploy bool) (app.Objects, []string) {
+	d, err := Parse([]byte(devfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Generator{
+		Source:   &app.SourceRef{URL: s2igit.MustParse("https://example.com/repo.git"), ContextDir: "src"},
+		Deploy:   deploy,
+		BuildEnv: app.Environment{"BUILD": "1"},
+	}
+	objects, warnings, err := g.Generate(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return objects, warnings
+}
+
+func TestGenerateFromCommands(t *testing.T) {
+	objects, warnings := generate(t, nodejsDevfile, true)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "replaced by its run command") {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	var bc *buildv1.BuildConfig
+	var deployment *kappsv1.Deployment
+	var routes []*routev1.Route
+	streams := 0
+	for _, o := range objects {
+		switch t := o.(type) {
+		case *buildv1.BuildConfig:
+			bc = t
+		case *kappsv1.Deployment:
+			deployment = t
+		case *routev1.Route:
+			routes = append(routes, t)
+		case *imagev1.ImageStream:
+			streams++
+		}
+	}
+	if bc == nil || deployment == nil || streams != 1 {
+		t.Fatalf("unexpected objects: %#v", objects)
+	}
+
+	if bc.Name != "runtime" || bc.Spec.Output.To.Name != "runtime:latest" || bc.Spec.Source.ContextDir != "src" || bc.Spec.Source.Git == nil {
+		t.Errorf("unexpected build config: %#v", bc.Spec)
+	}
+	dockerfile := *bc.Spec.Source.Dockerfile
+	for _, line := range []string{
+		"FROM registry.access.redhat.com/ubi8/nodejs-16:latest",
+		"COPY . /projects",
+		`RUN ["/bin/sh","-c","npm install"]`,
+		`CMD ["/bin/sh","-c","npm start"]`,
+	} {
+		if !strings.Contains(dockerfile, line+"\n") {
+			t.Errorf("the Dockerfile has no line %q:\n%s", line, dockerfile)
+		}
+	}
+	env := map[string]string{}
+	for _, e := range bc.Spec.Strategy.DockerStrategy.Env {
+		env[e.Name] = e.Value
+	}
+	if env["BUILD"] != "1" || env["NPM_CONFIG_LOGLEVEL"] != "warn" {
+		t.Errorf("unexpected build environment: %v", env)
+	}
+
+	spec := deployment.Spec.Template.Spec
+	if deployment.Name != "nodejs-app" || len(spec.Containers) != 2 {
+		t.Fatalf("unexpected deployment: %#v", deployment)
+	}
+	runtime, redis := spec.Containers[0], spec.Containers[1]
+	if runtime.Name != "runtime" || len(runtime.Command) != 0 || len(runtime.Ports) != 2 || runtime.Ports[0].Name != "http" {
+		t.Errorf("unexpected runtime container: %#v", runtime)
+	}
+	if memory := runtime.Resources.Limits[corev1.ResourceMemory]; memory.String() != "1Gi" {
+		t.Errorf("unexpected resources: %#v", runtime.Resources)
+	}
+	if redis.Image != "redis:7" || redis.VolumeMounts[0].MountPath != "/data" || runtime.VolumeMounts[0].MountPath != "/cache" {
+		t.Errorf("unexpected containers: %#v", spec.Containers)
+	}
+	if len(spec.Volumes) != 1 || spec.Volumes[0].EmptyDir == nil {
+		t.Errorf("unexpected volumes: %#v", spec.Volumes)
+	}
+
+	if len(routes) != 1 || routes[0].Name != "nodejs-app" || routes[0].Spec.To.Name != "nodejs-app" || routes[0].Spec.Port.TargetPort.StrVal != "3000-tcp" {
+		t.Errorf("unexpected routes: %#v", routes)
+	}
+}
+
+func TestGenerateFromDockerfile(t *testing.T) {
+	objects, _ := generate(t, dockerfileDevfile, true)
+	var bc *buildv1.BuildConfig
+	var deployment *kappsv1.Deployment
+	routes := map[string]*routev1.Route{}
+	for _, o := range objects {
+		switch t := o.(type) {
+		case *buildv1.BuildConfig:
+			bc = t
+		case *kappsv1.Deployment:
+			deployment = t
+		case *routev1.Route:
+			routes[t.Name] = t
+		}
+	}
+	if bc == nil || deployment == nil {
+		t.Fatalf("unexpected objects: %#v", objects)
+	}
+	strategy := bc.Spec.Strategy.DockerStrategy
+	if bc.Name != "app" || bc.Spec.Source.ContextDir != "src/docker" || strategy.DockerfilePath != "Dockerfile.prod" {
+		t.Errorf("unexpected build config: %#v", bc.Spec)
+	}
+	if len(strategy.BuildArgs) != 1 || strategy.BuildArgs[0].Name != "GO_VERSION" || strategy.BuildArgs[0].Value != "1.19" {
+		t.Errorf("unexpected build args: %#v", strategy.BuildArgs)
+	}
+	if deployment.Spec.Template.Spec.Containers[0].Image != " " || len(deployment.Annotations) == 0 {
+		t.Errorf("the deployment does not use the built image: %#v", deployment)
+	}
+	if web := routes["go-app-web"]; web == nil || web.Spec.TLS != nil {
+		t.Errorf("unexpected routes: %#v", routes)
+	}
+	if admin := routes["go-app-admin"]; admin == nil || admin.Spec.TLS == nil || admin.Spec.TLS.Termination != routev1.TLSTerminationPassthrough {
+		t.Errorf("unexpected routes: %#v", routes)
+	}
+
+	objects, _ = generate(t, dockerfileDevfile, false)
+	if len(objects) != 2 {
+		t.Errorf("expected only a build config and an image stream: %#v", objects)
+	}
+}
@@ -0,0 +1,508 @@
+package devfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	kappsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/openshift/library-go/pkg/image/reference"
+
+	"github.com/openshift/oc/pkg/helpers/newapp"
+	"github.com/openshift/oc/pkg/helpers/newapp/app"
+)
+
+const (
+	// projectsRoot is where devfile tools mount the sources, which generated
+	// Dockerfiles copy them to.
+	projectsRoot = "/projects"
+	// generatedDockerfileHeader starts the Dockerfiles generated for the
+	// build and run commands.
+	generatedDockerfileHeader = "# Generated from the devfile"
+)
+
+// Generator generates the objects of an application from its devfile.
+type Generator struct {
+	// Name is the name of the application, which defaults to the name in
+	// the devfile metadata.
+	Name string
+	// Source is the repository of the devfile, whose context directory is
+	// the directory of the devfile.
+	Source *app.SourceRef
+	// Deploy generates a deployment of the container components, and routes
+	// to their public endpoints. Otherwise only builds are generated.
+	Deploy bool
+	// Env is added to every container, and BuildEnv to every build.
+	Env      app.Environment
+	BuildEnv app.Environment
+	// Labels select the pods of the deployment.
+	Labels map[string]string
+	// ContainerOptions are applied to every container.
+	ContainerOptions *app.ContainerOptions
+}
+
+// build is an image built for a container component.
+type build struct {
+	// component is the container component that runs the image.
+	component string
+	// image is the image component that builds the image, or nil if the
+	// image is built from the build and run commands.
+	image *Image
+	// dockerfile is the generated Dockerfile of the build and run commands,
+	// and run is set if the image runs the run command.
+	dockerfile string
+	run        bool
+	// env is the environment of the build commands, and runEnv the one of
+	// the run command.
+	env    app.Environment
+	runEnv []EnvVar
+}
+
+// Generate returns the build configs, image streams, deployment and routes of
+// the devfile, along with warnings about the parts of the devfile that are
+// not carried over. Services are left to app.AddServices.
+func (g *Generator) Generate(d *Devfile) (app.Objects, []string, error) {
+	name := g.Name
+	if len(name) == 0 {
+		name = d.Metadata.Name
+	}
+	if len(name) == 0 {
+		name, _ = g.Source.SuggestName()
+	}
+	name = app.MakeSimpleName(name)
+	if len(name) == 0 {
+		return nil, nil, fmt.Errorf("unable to suggest a name for the devfile application, provide one with --name")
+	}
+
+	warnings := []string{}
+	builds, err := g.builds(d, &warnings)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(builds) == 0 && !g.Deploy {
+		return nil, nil, fmt.Errorf("the devfile builds no image: it has no image component and no build or run command")
+	}
+
+	components := []string{}
+	for component := range builds {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	objects := app.Objects{}
+	for _, component := range components {
+		built, err := g.buildObjects(builds[component])
+		if err != nil {
+			return nil, nil, err
+		}
+		objects = append(objects, built...)
+	}
+	if !g.Deploy {
+		return objects, warnings, nil
+	}
+
+	deployment, err := g.deployment(d, name, builds, &warnings)
+	if err != nil {
+		return nil, nil, err
+	}
+	if deployment == nil {
+		return objects, warnings, nil
+	}
+	objects = append(objects, deployment)
+	for _, route := range routes(d, deployment) {
+		objects = append(objects, route)
+	}
+	return objects, warnings, nil
+}
+
+// builds returns the builds of the container components, by their name: the
+// images of image components, and the image of the component that the build
+// and run commands run in.
+func (g *Generator) builds(d *Devfile, warnings *[]string) (map[string]*build, error) {
+	builds := map[string]*build{}
+	for _, c := range d.Components {
+		if c.Image == nil {
+			continue
+		}
+		var users []string
+		for _, other := range d.Components {
+			if other.Container != nil && other.Container.Image == c.Image.ImageName {
+				users = append(users, other.Name)
+			}
+		}
+		component := c.Name
+		switch len(users) {
+		case 0:
+		case 1:
+			component = users[0]
+		default:
+			return nil, fmt.Errorf("the image %q of component %q is run by several components: %s", c.Image.ImageName, c.Name, strings.Join(users, ", "))
+		}
+		if builds[component] != nil {
+			return nil, fmt.Errorf("component %q runs an image built by several image components", component)
+		}
+		builds[component] = &build{component: component, image: c.Image}
+	}
+
+	var commands []*ExecCommand
+	var run *ExecCommand
+	for _, kind := range []string{"build", "run"} {
+		command, err := d.DefaultCommand(kind)
+		if err != nil {
+			return nil, err
+		}
+		if command == nil {
+			continue
+		}
+		execs, err := d.ExecCommands(command)
+		if err != nil {
+			return nil, err
+		}
+		if kind == "run" {
+			if len(execs) == 0 {
+				continue
+			}
+			// the last command of a composite run command is the one that
+			// keeps running
+			run = execs[len(execs)-1]
+			execs = execs[:len(execs)-1]
+		}
+		commands = append(commands, execs...)
+	}
+	if run != nil {
+		commands = append(commands, run)
+	}
+	if len(commands) == 0 {
+		return builds, nil
+	}
+
+	component := commands[0].Component
+	for _, c := range commands {
+		if c.Component != component {
+			return nil, fmt.Errorf("the build and run commands must run in the same component, found %q and %q", component, c.Component)
+		}
+	}
+	if builds[component] != nil {
+		*warnings = append(*warnings, fmt.Sprintf("the build and run commands are ignored, as the image of component %q is built from its Dockerfile", component))
+		return builds, nil
+	}
+	container := d.Component(component).Container
+	if container.MountSources != nil && !*container.MountSources {
+		return nil, fmt.Errorf("the build and run commands run in component %q, which does not mount the sources", component)
+	}
+	if run != nil && (len(container.Command) > 0 || len(container.Args) > 0) {
+		*warnings = append(*warnings, fmt.Sprintf("the command and args of component %q are replaced by its run command", component))
+	}
+	b := &build{component: component, run: run != nil, env: app.Environment{}}
+	if run != nil {
+		b.runEnv = run.Env
+	}
+	dockerfile, err := generateDockerfile(container, commands, b.run, b.env)
+	if err != nil {
+		return nil, err
+	}
+	b.dockerfile = dockerfile
+	builds[component] = b
+	return builds, nil
+}
+
+// generateDockerfile returns a Dockerfile that copies the sources into the
+// image of a container and runs the commands, like devfile tools do in the
+// running container. If run is set, the last command is the one the image
+// runs. The environment of the other commands is added to env.
+func generateDockerfile(container *Container, commands []*ExecCommand, run bool, env app.Environment) (string, error) {
+	source := container.SourceMapping
+	if len(source) == 0 {
+		source = projectsRoot
+	}
+	expand := func(s string) string {
+		return strings.NewReplacer("${PROJECTS_ROOT}", projectsRoot, "${PROJECT_SOURCE}", source).Replace(s)
+	}
+	lines := []string{
+		generatedDockerfileHeader,
+		"FROM " + container.Image,
+		fmt.Sprintf("ENV PROJECTS_ROOT=%s PROJECT_SOURCE=%s", projectsRoot, source),
+		"COPY . " + source,
+	}
+	instruction := func(name string, c *ExecCommand) error {
+		dir := source
+		if len(c.WorkingDir) > 0 {
+			dir = expand(c.WorkingDir)
+		}
+		shell, err := json.Marshal([]string{"/bin/sh", "-c", c.CommandLine})
+		if err != nil {
+			return err
+		}
+		lines = append(lines, "WORKDIR "+dir, fmt.Sprintf("%s %s", name, shell))
+		return nil
+	}
+
+	for i, c := range commands {
+		if run && i == len(commands)-1 {
+			if err := instruction("CMD", c); err != nil {
+				return "", err
+			}
+			continue
+		}
+		for _, e := range c.Env {
+			env[e.Name] = e.Value
+		}
+		if err := instruction("RUN", c); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// buildObjects returns the build config of a build, and the image stream it
+// pushes to, which is named after the component that runs it.
+func (g *Generator) buildObjects(b *build) (app.Objects, error) {
+	source := *g.Source
+	source.Name = b.component
+	output := &app.ImageRef{
+		Reference:     reference.DockerImageReference{Name: b.component, Tag: "latest"},
+		AsImageStream: true,
+		OutputImage:   true,
+	}
+	env := app.NewEnvironment(g.BuildEnv, b.env)
+	ref := &app.BuildRef{
+		Source:   &source,
+		Strategy: &app.BuildStrategyRef{Strategy: newapp.StrategyDocker},
+		Output:   output,
+		Env:      env,
+		Binary:   source.Binary,
+	}
+
+	var dockerfilePath string
+	if b.image != nil {
+		dockerfile := b.image.Dockerfile
+		context := path.Clean(dockerfile.BuildContext)
+		uri := path.Clean(dockerfile.URI)
+		if path.IsAbs(context) || strings.HasPrefix(context, "../") || context == ".." {
+			return nil, fmt.Errorf("the build context %q of image %q must be a directory of the repository", dockerfile.BuildContext, b.image.ImageName)
+		}
+		if len(dockerfile.URI) > 0 {
+			if context != "." {
+				if !strings.HasPrefix(uri, context+"/") {
+					return nil, fmt.Errorf("the Dockerfile %q of image %q must be in its build context %q", dockerfile.URI, b.image.ImageName, dockerfile.BuildContext)
+				}
+				uri = strings.TrimPrefix(uri, context+"/")
+			}
+			if uri != "Dockerfile" {
+				dockerfilePath = uri
+			}
+		}
+		if context != "." {
+			source.ContextDir = path.Join(source.ContextDir, context)
+		}
+		options := &buildv1.DockerStrategyOptions{}
+		for _, arg := range dockerfile.Args {
+			parts := strings.SplitN(arg, "=", 2)
+			options.BuildArgs = append(options.BuildArgs, corev1.EnvVar{Name: parts[0], Value: parts[1]})
+		}
+		ref.DockerStrategyOptions = options
+	} else {
+		source.DockerfileContents = b.dockerfile
+	}
+
+	bc, err := ref.BuildConfig()
+	if err != nil {
+		return nil, err
+	}
+	bc.Spec.Strategy.DockerStrategy.DockerfilePath = dockerfilePath
+	stream, err := output.ImageStream()
+	if err != nil {
+		return nil, err
+	}
+	return app.Objects{bc, stream}, nil
+}
+
+// deployment returns a deployment of the container components, or nil if
+// the devfile has none.
+func (g *Generator) deployment(d *Devfile, name string, builds map[string]*build, warnings *[]string) (*kappsv1.Deployment, error) {
+	ref := &app.DeploymentRef{
+		Name:             name,
+		Env:              g.Env,
+		Labels:           g.Labels,
+		ContainerOptions: g.ContainerOptions,
+	}
+	for i := range d.Components {
+		c := &d.Components[i]
+		if c.Volume != nil && !c.Volume.Ephemeral {
+			*warnings = append(*warnings, fmt.Sprintf("volume %q is not persistent in the deployment, add storage with 'oc set volume'", c.Name))
+		}
+		if c.Container == nil {
+			continue
+		}
+		image := &app.ImageRef{ObjectName: c.Name}
+		b := builds[c.Name]
+		if b != nil {
+			image.Reference = reference.DockerImageReference{Name: c.Name, Tag: "latest"}
+			image.AsImageStream = true
+		} else {
+			ref, err := reference.Parse(c.Container.Image)
+			if err != nil {
+				return nil, fmt.Errorf("component %q has an invalid image %q: %v", c.Name, c.Container.Image, err)
+			}
+			image.Reference = ref
+		}
+		requirements, err := resources(c.Container)
+		if err != nil {
+			return nil, fmt.Errorf("component %q: %v", c.Name, err)
+		}
+		image.ContainerFn = containerFn(c.Container, requirements, b)
+		ref.Images = append(ref.Images, image)
+	}
+	if len(ref.Images) == 0 {
+		return nil, nil
+	}
+	deployment, err := ref.Deployment()
+	if err != nil {
+		return nil, err
+	}
+
+	// containers that share a volume each get one from app.DeploymentRef
+	volumes := []corev1.Volume{}
+	seen := map[string]bool{}
+	for _, v := range deployment.Spec.Template.Spec.Volumes {
+		if !seen[v.Name] {
+			seen[v.Name] = true
+			volumes = append(volumes, v)
+		}
+	}
+	deployment.Spec.Template.Spec.Volumes = volumes
+	return deployment, nil
+}
+
+// containerFn sets the ports, environment, resources, command and volumes of
+// a container from its component. The command and args are left to the image
+// if it is built to run the run command.
+func containerFn(container *Container, requirements corev1.ResourceRequirements, b *build) func(*corev1.Container) {
+	return func(c *corev1.Container) {
+		names := map[string]bool{}
+		for _, e := range container.Endpoints {
+			port := corev1.ContainerPort{ContainerPort: int32(e.TargetPort), Protocol: endpointProtocol(e)}
+			if len(validation.IsValidPortName(e.Name)) == 0 && !names[e.Name] {
+				port.Name = e.Name
+				names[e.Name] = true
+			}
+			c.Ports = append(c.Ports, port)
+		}
+		env := container.Env
+		if b != nil {
+			env = append(append([]EnvVar{}, env...), b.runEnv...)
+		}
+		for _, e := range env {
+			c.Env = append(c.Env, corev1.EnvVar{Name: e.Name, Value: e.Value})
+		}
+		c.Resources = requirements
+		if b == nil || !b.run {
+			c.Command = container.Command
+			c.Args = container.Args
+		}
+		for _, m := range container.VolumeMounts {
+			mountPath := m.Path
+			if len(mountPath) == 0 {
+				mountPath = "/" + m.Name
+			}
+			c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: m.Name, MountPath: mountPath})
+		}
+	}
+}
+
+// resources returns the limits and requests of a container, or an error naming
+// the first field that is not a valid quantity.
+func resources(container *Container) (corev1.ResourceRequirements, error) {
+	r := corev1.ResourceRequirements{}
+	fields := []struct {
+		list  *corev1.ResourceList
+		name  corev1.ResourceName
+		field string
+		value string
+	}{
+		{&r.Limits, corev1.ResourceCPU, "cpuLimit", container.CPULimit},
+		{&r.Limits, corev1.ResourceMemory, "memoryLimit", container.MemoryLimit},
+		{&r.Requests, corev1.ResourceCPU, "cpuRequest", container.CPURequest},
+		{&r.Requests, corev1.ResourceMemory, "memoryRequest", container.MemoryRequest},
+	}
+	for _, f := range fields {
+		if len(f.value) == 0 {
+			continue
+		}
+		q, err := resource.ParseQuantity(f.value)
+		if err != nil {
+			return r, fmt.Errorf("invalid %s %q: %v", f.field, f.value, err)
+		}
+		if *f.list == nil {
+			*f.list = corev1.ResourceList{}
+		}
+		(*f.list)[f.name] = q
+	}
+	return r, nil
+}
+
+func endpointProtocol(e Endpoint) corev1.Protocol {
+	switch e.Protocol {
+	case "udp":
+		return corev1.ProtocolUDP
+	case "sctp":
+		return corev1.ProtocolSCTP
+	}
+	return corev1.ProtocolTCP
+}
+
+// routes returns the routes to the public HTTP and WebSocket endpoints of the
+// deployment. A single route is named after the deployment, several after
+// their endpoints. Secure endpoints get edge terminated routes, and endpoints
+// that serve TLS themselves passthrough ones.
+func routes(d *Devfile, deployment *kappsv1.Deployment) []*routev1.Route {
+	var endpoints []Endpoint
+	seen := map[int]bool{}
+	for _, c := range d.Components {
+		if c.Container == nil {
+			continue
+		}
+		for _, e := range c.Container.Endpoints {
+			if e.Exposure != "" && e.Exposure != "public" {
+				continue
+			}
+			switch e.Protocol {
+			case "", "http", "https", "ws", "wss":
+				if !seen[e.TargetPort] {
+					seen[e.TargetPort] = true
+					endpoints = append(endpoints, e)
+				}
+			}
+		}
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool { return endpoints[i].TargetPort < endpoints[j].TargetPort })
+
+	var result []*routev1.Route
+	for _, e := range endpoints {
+		name := deployment.Name
+		if len(endpoints) > 1 {
+			name = app.MakeSimpleName(fmt.Sprintf("%s-%s", deployment.Name, e.Name))
+		}
+		route := app.RouteForPort(deployment.ObjectMeta, name, corev1.ContainerPort{ContainerPort: int32(e.TargetPort), Protocol: corev1.ProtocolTCP})
+		switch {
+		case e.Protocol == "https" || e.Protocol == "wss":
+			route.Spec.TLS = &routev1.TLSConfig{Termination: routev1.TLSTerminationPassthrough}
+		case e.Secure:
+			route.Spec.TLS = &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge}
+			fallthrough
+		default:
+			if len(e.Path) > 0 && e.Path != "/" {
+				route.Spec.Path = e.Path
+			}
+		}
+		result = append(result, route)
+	}
+	return result
+}
@@ -54,25 +54,35 @@ func InsertInstructions(node *parser.Node, pos int, instructions string) error {
 }
 
 // LastBaseImage takes a Dockerfile root node and returns the base image
-// declared in the last FROM instruction.
+// of the last stage, following the earlier stages it builds on.
 func LastBaseImage(node *parser.Node) string {
-	baseImages := baseImages(node)
-	if len(baseImages) == 0 {
-		return ""
-	}
-	return baseImages[len(baseImages)-1]
+	image, _ := LastStageBaseImage(node, nil)
+	return image
+}
+
+// LastStageBaseImage takes a Dockerfile root node and returns the image the
+// last stage is built on, with the values of buildArgs substituted, and
+// whether the last stage builds on an earlier stage rather than directly on
+// that image.
+func LastStageBaseImage(node *parser.Node, buildArgs map[string]string) (string, bool) {
+	stages := Stages(node, buildArgs)
+	if len(stages) == 0 {
+		return "", false
+	}
+	last := len(stages) - 1
+	return StageBaseImage(stages, last), stages[last].BaseStage >= 0
 }
 
 // baseImages takes a Dockerfile root node and returns a list of all base images
 // declared in the Dockerfile. Each base image is the argument of a FROM
-// instruction.
+// instruction that does not refer to an earlier stage.
 func baseImages(node *parser.Node) []string {
 	var images []string
-	for _, pos := range FindAll(node, command.From) {
-		if node.Children[pos].Next == nil {
+	for _, stage := range Stages(node, nil) {
+		if stage.BaseStage >= 0 || len(stage.From) == 0 {
 			continue
 		}
-		images = append(images, node.Children[pos].Next.Value)
+		images = append(images, stage.From)
 	}
 	return images
 }
@@ -110,21 +120,35 @@ func evalPorts(exposedPorts []string, node *parser.Node, from, to int) []string
 
 // LastExposedPorts takes a Dockerfile root node and returns a list of ports
 // exposed in the last image built by the Dockerfile, i.e., only the EXPOSE
-// instructions after the last FROM instruction are considered.
+// instructions of the last stage and of the earlier stages it builds on are
+// considered.
 //
 // It also evaluates the following scenarios
 // 1) env variable - evaluate from ENV and ARG with default value
 // 2) port range - adding the lowest port from range
 func LastExposedPorts(node *parser.Node) []string {
-	exposedPorts, exposeIndices := exposedPorts(node)
-	if len(exposedPorts) == 0 || len(exposeIndices) == 0 {
+	stages := Stages(node, nil)
+	if len(stages) == 0 {
 		return nil
 	}
-	lastExposed := exposedPorts[len(exposedPorts)-1]
-	froms := FindAll(node, command.From)
-	from := froms[len(froms)-1]
-	to := exposeIndices[len(exposeIndices)-1]
-	return evalPorts(lastExposed, node, from, to)
+	allPorts, exposeIndices := exposedPorts(node)
+	var ports []string
+	for i := len(stages) - 1; i >= 0; i = stages[i].BaseStage {
+		to := -1
+		for _, j := range exposeIndices {
+			if j >= stages[i].Start && j <= stages[i].End {
+				to = j
+			}
+		}
+		if to == -1 || len(allPorts[i]) == 0 {
+			continue
+		}
+		ports = append(evalPorts(allPorts[i], node, stages[i].Start, to), ports...)
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+	return ports
 }
 
 // exposedPorts takes a Dockerfile root node and returns a list of all ports
@@ -0,0 +1,134 @@
+package dockerfile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/moby/buildkit/frontend/dockerfile/command"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+)
+
+// Stage is a stage of a Dockerfile, which starts at a FROM instruction and
+// ends before the next one.
+type Stage struct {
+	// Name is the name given to the stage with FROM IMAGE AS NAME, in lower
+	// case, or empty.
+	Name string
+	// From is the argument of the FROM instruction, with the ARG instructions
+	// declared before the first FROM substituted.
+	From string
+	// BaseStage is the index of the earlier stage that From refers to, or -1
+	// if From is an image.
+	BaseStage int
+	// Start and End are the indices of the first and last children of the
+	// Dockerfile root node that belong to the stage.
+	Start, End int
+}
+
+// Stages takes a Dockerfile root node and returns its stages. The values of
+// buildArgs override the defaults of the ARG instructions that may be used in
+// FROM instructions, as docker build --build-arg does.
+func Stages(node *parser.Node, buildArgs map[string]string) []Stage {
+	froms := FindAll(node, command.From)
+	if len(froms) == 0 {
+		return nil
+	}
+	args := globalArgs(node, froms[0], buildArgs)
+	shlex := NewShellLex('\\')
+
+	stages := make([]Stage, 0, len(froms))
+	for i, pos := range froms {
+		stage := Stage{BaseStage: -1, Start: pos, End: len(node.Children) - 1}
+		if i+1 < len(froms) {
+			stage.End = froms[i+1] - 1
+		}
+		values := nextValues(node.Children[pos])
+		if len(values) > 0 {
+			stage.From = values[0]
+			if processed, err := shlex.ProcessWord(values[0], args); err == nil {
+				stage.From = processed
+			}
+		}
+		if len(values) == 3 && strings.EqualFold(values[1], "AS") {
+			stage.Name = strings.ToLower(values[2])
+		}
+		for j := range stages {
+			if len(stages[j].Name) > 0 && stages[j].Name == strings.ToLower(stage.From) {
+				stage.BaseStage = j
+			}
+		}
+		stages = append(stages, stage)
+	}
+	return stages
+}
+
+// globalArgs evaluates the ARG instructions before the first FROM instruction,
+// which is at index end, and returns them as used by ShellLex.
+func globalArgs(node *parser.Node, end int, buildArgs map[string]string) []string {
+	shlex := NewShellLex('\\')
+	var args []string
+	for i := 0; i < end; i++ {
+		if node.Children[i].Value != command.Arg {
+			continue
+		}
+		for _, arg := range nextValues(node.Children[i]) {
+			name, value := arg, ""
+			hasDefault := false
+			if m, ok := match(argSplitRegexp, arg); ok {
+				name, value, hasDefault = m[1], m[2], true
+			}
+			if override, ok := buildArgs[name]; ok {
+				args = append([]string{name + "=" + override}, args...)
+				continue
+			}
+			if !hasDefault {
+				continue
+			}
+			if processed, err := shlex.ProcessWord(value, args); err == nil {
+				value = processed
+			}
+			args = append([]string{name + "=" + value}, args...)
+		}
+	}
+	return args
+}
+
+// StageBaseImage returns the image that the i-th stage is built on, following
+// the earlier stages it builds on.
+func StageBaseImage(stages []Stage, i int) string {
+	for stages[i].BaseStage >= 0 {
+		i = stages[i].BaseStage
+	}
+	return stages[i].From
+}
+
+// FindStage returns the index of the stage with the given name, as given to
+// docker build --target.
+func FindStage(stages []Stage, name string) (int, error) {
+	for i := range stages {
+		if len(stages[i].Name) > 0 && stages[i].Name == strings.ToLower(name) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("the Dockerfile has no stage named %q", name)
+}
+
+// StageContents returns the contents of a Dockerfile up to the end of the
+// stage with the given name, so that building them builds that stage. node is
+// the root node of contents.
+func StageContents(contents string, node *parser.Node, name string) (string, error) {
+	stages := Stages(node, nil)
+	i, err := FindStage(stages, name)
+	if err != nil {
+		return "", err
+	}
+	if i == len(stages)-1 {
+		return contents, nil
+	}
+	next := node.Children[stages[i+1].Start].StartLine
+	lines := strings.SplitAfter(contents, "\n")
+	if next < 1 || next > len(lines) {
+		return "", fmt.Errorf("unable to find the end of the stage %q in the Dockerfile", name)
+	}
+	return strings.Join(lines[:next-1], ""), nil
+}
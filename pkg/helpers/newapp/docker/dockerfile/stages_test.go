@@ -0,0 +1,108 @@
+package dockerfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+)
+
+const multiStageDockerfile = `ARG BASE=registry.access.redhat.com/ubi8/ubi
+ARG TAG
+ARG BUILDER=golang:${GO_VERSION:-1.17}
+FROM ${BUILDER} AS Builder
+EXPOSE 9000
+RUN make
+
+FROM builder AS tested
+RUN make test
+EXPOSE 8080
+
+FROM $BASE:${TAG:-latest}
+COPY --from=builder /app /app
+EXPOSE 8443
+`
+
+func TestStages(t *testing.T) {
+	node, err := parser.Parse(strings.NewReader(multiStageDockerfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Stage{
+		{Name: "builder", From: "golang:1.17", BaseStage: -1, Start: 3, End: 5},
+		{Name: "tested", From: "builder", BaseStage: 0, Start: 6, End: 8},
+		{From: "registry.access.redhat.com/ubi8/ubi:latest", BaseStage: -1, Start: 9, End: 11},
+	}
+	stages := Stages(node.AST, nil)
+	if !reflect.DeepEqual(want, stages) {
+		t.Errorf("unexpected stages: %#v", stages)
+	}
+	if image := StageBaseImage(stages, 1); image != "golang:1.17" {
+		t.Errorf("unexpected base image of the tested stage: %s", image)
+	}
+
+	stages = Stages(node.AST, map[string]string{"BASE": "ubi9", "TAG": "9.0", "UNDECLARED": "x"})
+	if stages[2].From != "ubi9:9.0" {
+		t.Errorf("build arguments not applied: %s", stages[2].From)
+	}
+	if image, fromStage := LastStageBaseImage(node.AST, map[string]string{"BASE": "ubi9"}); image != "ubi9:latest" || fromStage {
+		t.Errorf("unexpected last base image: %s %t", image, fromStage)
+	}
+
+	if i, err := FindStage(stages, "Tested"); err != nil || i != 1 {
+		t.Errorf("unexpected stage %d: %v", i, err)
+	}
+	if _, err := FindStage(stages, "missing"); err == nil {
+		t.Error("expected an error for a missing stage")
+	}
+}
+
+func TestStageReferences(t *testing.T) {
+	node, err := parser.Parse(strings.NewReader(`FROM golang AS builder
+EXPOSE 9000
+FROM builder
+EXPOSE 8080
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image, fromStage := LastStageBaseImage(node.AST, nil); image != "golang" || !fromStage {
+		t.Errorf("unexpected last base image: %s %t", image, fromStage)
+	}
+	if images := baseImages(node.AST); !reflect.DeepEqual(images, []string{"golang"}) {
+		t.Errorf("unexpected base images: %v", images)
+	}
+	if ports := LastExposedPorts(node.AST); !reflect.DeepEqual(ports, []string{"9000", "8080"}) {
+		t.Errorf("unexpected ports: %v", ports)
+	}
+}
+
+func TestStageContents(t *testing.T) {
+	node, err := parser.Parse(strings.NewReader(multiStageDockerfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := StageContents(multiStageDockerfile, node.AST, "tested")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := multiStageDockerfile[:strings.Index(multiStageDockerfile, "FROM $BASE")]
+	if contents != want {
+		t.Errorf("unexpected contents:\n%s", contents)
+	}
+	trimmed, err := parser.Parse(strings.NewReader(contents))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ports := LastExposedPorts(trimmed.AST); !reflect.DeepEqual(ports, []string{"9000", "8080"}) {
+		t.Errorf("unexpected ports: %v", ports)
+	}
+
+	if contents, err := StageContents(multiStageDockerfile, node.AST, "builder"); err != nil || !strings.HasSuffix(contents, "RUN make\n\n") {
+		t.Errorf("unexpected contents %q: %v", contents, err)
+	}
+	if _, err := StageContents(multiStageDockerfile, node.AST, "missing"); err == nil {
+		t.Error("expected an error for a missing stage")
+	}
+}
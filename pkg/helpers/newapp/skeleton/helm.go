@@ -0,0 +1,173 @@
+package skeleton
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// The fields of workloads that are values of the chart are replaced by these
+// placeholders before the templates are written, and then by the template
+// actions that read the values.
+const (
+	replicasPlaceholder = "HELMVALUEREPLICAS"
+	imagePlaceholder    = "HELMVALUEIMAGE"
+	envPlaceholder      = "HELMVALUEENV"
+)
+
+var (
+	replicasPlaceholderRegexp = regexp.MustCompile(replicasPlaceholder)
+	imagePlaceholderRegexp    = regexp.MustCompile(imagePlaceholder + `(\d+)`)
+	envPlaceholderRegexp      = regexp.MustCompile(`(?m)^( *)(- )?env: ` + envPlaceholder + `(\d+)$`)
+)
+
+// chart is the Chart.yaml of a chart.
+type chart struct {
+	APIVersion  string `json:"apiVersion"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Version     string `json:"version"`
+}
+
+// workloadValues are the values of a workload, under deployments.<name> in
+// values.yaml.
+type workloadValues struct {
+	Replicas   int64                      `json:"replicas"`
+	Containers map[string]containerValues `json:"containers,omitempty"`
+}
+
+type containerValues struct {
+	Image string            `json:"image,omitempty"`
+	Env   map[string]string `json:"env,omitempty"`
+}
+
+// helmFiles returns a chart with a template per resource. The images,
+// replicas and environment of the workloads are the values of the chart.
+func helmFiles(name string, resources []*resource) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	data, err := yaml.Marshal(chart{
+		APIVersion:  "v2",
+		Name:        name,
+		Description: fmt.Sprintf("A Helm chart for %s, generated by new-app", name),
+		Type:        "application",
+		Version:     "0.1.0",
+	})
+	if err != nil {
+		return nil, err
+	}
+	files["Chart.yaml"] = data
+
+	values := map[string]workloadValues{}
+	for _, r := range resources {
+		var template []byte
+		if w := r.workload(); w != nil {
+			template, err = workloadTemplate(r, w, values)
+		} else {
+			template, err = yaml.Marshal(r.object.Object)
+			template = escapeTemplate(template)
+		}
+		if err != nil {
+			return nil, err
+		}
+		files["templates/"+r.file] = template
+	}
+	data, err = yaml.Marshal(map[string]interface{}{"deployments": values})
+	if err != nil {
+		return nil, err
+	}
+	files["values.yaml"] = data
+	return files, nil
+}
+
+// workloadTemplate returns the template of a workload, whose replicas,
+// container images and environment are read from its values. The environment
+// is only a value if it has no references to other objects.
+func workloadTemplate(r *resource, w *workload, values map[string]workloadValues) ([]byte, error) {
+	obj := r.object.DeepCopy()
+	v := workloadValues{Replicas: w.replicas, Containers: map[string]containerValues{}}
+	if err := unstructured.SetNestedField(obj.Object, replicasPlaceholder, "spec", "replicas"); err != nil {
+		return nil, err
+	}
+
+	containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if err != nil {
+		return nil, err
+	}
+	images, envs := map[string]string{}, map[string]string{}
+	for i, c := range w.containers {
+		cv := containerValues{}
+		container := containers[i].(map[string]interface{})
+		if len(c.image) > 0 {
+			cv.Image = c.image
+			container["image"] = imagePlaceholder + strconv.Itoa(i)
+			images[strconv.Itoa(i)] = c.name
+		}
+		if env, ok := plainEnv(c.env); ok && len(env) > 0 {
+			cv.Env = env
+			container["env"] = envPlaceholder + strconv.Itoa(i)
+			envs[strconv.Itoa(i)] = c.name
+		}
+		v.Containers[c.name] = cv
+	}
+	if len(containers) > 0 {
+		if err := unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers"); err != nil {
+			return nil, err
+		}
+	}
+	values[w.name] = v
+
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	data = escapeTemplate(data)
+	data = replicasPlaceholderRegexp.ReplaceAllLiteral(data, []byte("{{ $values.replicas }}"))
+	data = imagePlaceholderRegexp.ReplaceAllFunc(data, func(match []byte) []byte {
+		name := images[string(imagePlaceholderRegexp.FindSubmatch(match)[1])]
+		return []byte(fmt.Sprintf("{{ (index $values.containers %q).image | quote }}", name))
+	})
+	data = envPlaceholderRegexp.ReplaceAllFunc(data, func(match []byte) []byte {
+		m := envPlaceholderRegexp.FindSubmatch(match)
+		indent := string(m[1])
+		if len(m[2]) > 0 {
+			indent += "  "
+		}
+		name := envs[string(m[3])]
+		return []byte(strings.Join([]string{
+			fmt.Sprintf("%s%senv:", m[1], m[2]),
+			fmt.Sprintf("%s{{- range $name, $value := (index $values.containers %q).env }}", indent, name),
+			fmt.Sprintf("%s- name: {{ $name }}", indent),
+			fmt.Sprintf("%s  value: {{ $value | quote }}", indent),
+			fmt.Sprintf("%s{{- end }}", indent),
+		}, "\n"))
+	})
+	header := fmt.Sprintf("{{- $values := index .Values.deployments %q }}\n", w.name)
+	return append([]byte(header), data...), nil
+}
+
+// plainEnv returns the environment variables as a map, if they all have
+// plain values.
+func plainEnv(env []interface{}) (map[string]string, bool) {
+	result := map[string]string{}
+	for _, e := range env {
+		m, ok := e.(map[string]interface{})
+		if !ok || m["valueFrom"] != nil {
+			return nil, false
+		}
+		name, _ := m["name"].(string)
+		value, _ := m["value"].(string)
+		result[name] = value
+	}
+	return result, true
+}
+
+// escapeTemplate keeps Helm from interpreting the template delimiters that
+// objects contain.
+func escapeTemplate(data []byte) []byte {
+	return []byte(strings.Replace(string(data), "{{", `{{ "{{" }}`, -1))
+}
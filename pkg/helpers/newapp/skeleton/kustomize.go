@@ -0,0 +1,129 @@
+package skeleton
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// overlays are the overlays of the Kustomize layout, which start out
+// identical so that each environment can be edited on its own.
+var overlays = []string{"development", "production"}
+
+// kustomizeFiles returns a base with the resources, and overlays that set
+// the images, replicas and environment of the workloads.
+func kustomizeFiles(resources []*resource) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	base := newKustomization()
+	for _, r := range resources {
+		data, err := yaml.Marshal(r.object.Object)
+		if err != nil {
+			return nil, err
+		}
+		files["base/"+r.file] = data
+		base.Resources = append(base.Resources, r.file)
+	}
+	data, err := yaml.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+	files["base/kustomization.yaml"] = data
+
+	overlay := newKustomization()
+	overlay.Resources = []string{"../../base"}
+	images := map[string]bool{}
+	for _, r := range resources {
+		w := r.workload()
+		if w == nil {
+			continue
+		}
+		for _, c := range w.containers {
+			if len(c.image) == 0 || images[c.image] {
+				continue
+			}
+			images[c.image] = true
+			if image, ok := kustomizeImage(c.image); ok {
+				overlay.Images = append(overlay.Images, image)
+			}
+		}
+		patch, err := workloadPatch(w)
+		if err != nil {
+			return nil, err
+		}
+		// kustomize only knows the replicas field of Kubernetes workloads
+		if w.kind == "Deployment" {
+			overlay.Replicas = append(overlay.Replicas, types.Replica{Name: w.name, Count: w.replicas})
+		}
+		if len(patch) > 0 {
+			overlay.Patches = append(overlay.Patches, types.Patch{
+				Patch:  patch,
+				Target: &types.Selector{ResId: resid.ResId{Gvk: resid.Gvk{Kind: w.kind}, Name: w.name}},
+			})
+		}
+	}
+	data, err = yaml.Marshal(overlay)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range overlays {
+		files["overlays/"+name+"/kustomization.yaml"] = data
+	}
+	return files, nil
+}
+
+func newKustomization() *types.Kustomization {
+	return &types.Kustomization{
+		TypeMeta: types.TypeMeta{APIVersion: types.KustomizationVersion, Kind: types.KustomizationKind},
+	}
+}
+
+// kustomizeImage returns the image transformation that keeps an image, so
+// that overlays only have to change its name, tag or digest.
+func kustomizeImage(image string) (types.Image, bool) {
+	ref, err := reference.Parse(image)
+	if err != nil {
+		return types.Image{}, false
+	}
+	name := ref.AsRepository().String()
+	if len(ref.ID) > 0 {
+		return types.Image{Name: name, NewName: name, Digest: ref.ID}, true
+	}
+	return types.Image{Name: name, NewName: name, NewTag: ref.Tag}, true
+}
+
+// jsonPatchOperation is a JSON patch (RFC 6902) operation.
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// workloadPatch returns a JSON patch that sets the environment of the
+// containers of a workload, and the replicas of deployment configs, or an
+// empty string if there is nothing to set. Unlike strategic merge patches,
+// JSON patches apply the same way to deployment configs.
+func workloadPatch(w *workload) (string, error) {
+	operations := []jsonPatchOperation{}
+	if w.kind != "Deployment" {
+		operations = append(operations, jsonPatchOperation{Op: "replace", Path: "/spec/replicas", Value: w.replicas})
+	}
+	for i, c := range w.containers {
+		if len(c.env) == 0 {
+			continue
+		}
+		// add replaces the member if it exists
+		operations = append(operations, jsonPatchOperation{Op: "add", Path: fmt.Sprintf("/spec/template/spec/containers/%d/env", i), Value: c.env})
+	}
+	if len(operations) == 0 {
+		return "", nil
+	}
+	data, err := yaml.Marshal(operations)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
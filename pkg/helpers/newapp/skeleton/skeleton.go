@@ -0,0 +1,175 @@
+// Package skeleton writes the objects generated by new-app as a directory
+// layout that teams can edit and keep in source control: a Kustomize base
+// with overlays, or a Helm chart.
+package skeleton
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Format is a directory layout.
+type Format string
+
+const (
+	// Kustomize writes a base with the objects, and overlays that set the
+	// images, replicas and environment of the workloads.
+	Kustomize Format = "kustomize"
+	// Helm writes a chart whose values are the images, replicas and
+	// environment of the workloads.
+	Helm Format = "helm"
+)
+
+// ParseOutput parses an output format of the form kustomize=DIR or helm=DIR.
+// It returns false if output is another format.
+func ParseOutput(output string) (Format, string, bool, error) {
+	parts := strings.SplitN(output, "=", 2)
+	format := Format(parts[0])
+	if format != Kustomize && format != Helm {
+		return "", "", false, nil
+	}
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return "", "", true, fmt.Errorf("the %s output format requires a directory: -o %s=DIR", format, format)
+	}
+	return format, parts[1], true, nil
+}
+
+// Writer writes objects in a directory layout.
+type Writer struct {
+	Format Format
+	// Dir is the directory to write, which must not exist or be empty.
+	Dir string
+	// Name is the name of the application, used as the chart name.
+	Name string
+	// Typer sets the kind of objects that have none.
+	Typer runtime.ObjectTyper
+}
+
+// Write writes the objects and returns the paths of the written files.
+func (w *Writer) Write(objects []runtime.Object) ([]string, error) {
+	if entries, err := ioutil.ReadDir(w.Dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("the directory %s is not empty", w.Dir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	resources := []*resource{}
+	for _, obj := range objects {
+		r, err := w.newResource(obj)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, r)
+	}
+
+	var files map[string][]byte
+	var err error
+	switch w.Format {
+	case Kustomize:
+		files, err = kustomizeFiles(resources)
+	case Helm:
+		files, err = helmFiles(w.Name, resources)
+	default:
+		err = fmt.Errorf("unknown output layout %q", w.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, name := range sortedKeys(files) {
+		path := filepath.Join(w.Dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(path, files[name], 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// resource is an object as it is written.
+type resource struct {
+	object *unstructured.Unstructured
+	// file is the name of the file of the object.
+	file string
+}
+
+func (w *Writer) newResource(obj runtime.Object) (*resource, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	if len(u.GetKind()) == 0 && w.Typer != nil {
+		kinds, _, err := w.Typer.ObjectKinds(obj)
+		if err != nil {
+			return nil, err
+		}
+		u.SetGroupVersionKind(kinds[0])
+	}
+	// drop the fields that are set by the server
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(u.Object, "spec", "template", "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(u.Object, "status")
+
+	file := fmt.Sprintf("%s-%s.yaml", strings.ToLower(u.GetKind()), u.GetName())
+	return &resource{object: u, file: file}, nil
+}
+
+// workload is the part of a deployment or deployment config that is
+// parameterized.
+type workload struct {
+	kind       string
+	name       string
+	replicas   int64
+	containers []container
+}
+
+type container struct {
+	name  string
+	image string
+	env   []interface{}
+}
+
+// workload returns the workload of a deployment or deployment config, or nil.
+func (r *resource) workload() *workload {
+	kind := r.object.GetKind()
+	if kind != "Deployment" && kind != "DeploymentConfig" {
+		return nil
+	}
+	w := &workload{kind: kind, name: r.object.GetName(), replicas: 1}
+	if replicas, ok, _ := unstructured.NestedInt64(r.object.Object, "spec", "replicas"); ok {
+		w.replicas = replicas
+	}
+	containers, _, _ := unstructured.NestedSlice(r.object.Object, "spec", "template", "spec", "containers")
+	for _, c := range containers {
+		m, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(m, "name")
+		image, _, _ := unstructured.NestedString(m, "image")
+		env, _, _ := unstructured.NestedSlice(m, "env")
+		w.containers = append(w.containers, container{name: name, image: strings.TrimSpace(image), env: env})
+	}
+	return w
+}
+
+func sortedKeys(files map[string][]byte) []string {
+	keys := make([]string, 0, len(files))
+	for k := range files {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
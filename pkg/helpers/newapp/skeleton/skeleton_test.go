@@ -0,0 +1,175 @@
+package skeleton
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kappsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+)
+
+func testObjects() []runtime.Object {
+	replicas := int32(2)
+	return []runtime.Object{
+		&kappsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Spec: kappsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Name: "web", Image: "quay.io/example/web:1.0", Env: []corev1.EnvVar{{Name: "MODE", Value: "prod"}}},
+							{Name: "sidecar", Image: " "},
+						},
+					},
+				},
+			},
+		},
+		&appsv1.DeploymentConfig{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps.openshift.io/v1", Kind: "DeploymentConfig"},
+			ObjectMeta: metav1.ObjectMeta{Name: "db"},
+			Spec: appsv1.DeploymentConfigSpec{
+				Replicas: 1,
+				Template: &corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "db", Image: "postgres:13", Env: []corev1.EnvVar{
+							{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{Key: "password"}}},
+						}}},
+					},
+				},
+			},
+		},
+		&corev1.Service{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: map[string]string{"description": "{{ not a template }}"}},
+		},
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	if format, dir, ok, err := ParseOutput("helm=./chart"); err != nil || !ok || format != Helm || dir != "./chart" {
+		t.Errorf("unexpected result: %s %s %v %v", format, dir, ok, err)
+	}
+	if _, _, ok, err := ParseOutput("kustomize"); !ok || err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+	if _, _, ok, err := ParseOutput("yaml"); ok || err != nil {
+		t.Error("expected other formats to be ignored")
+	}
+}
+
+func writeLayout(t *testing.T, format Format) (string, map[string]string) {
+	dir, err := ioutil.TempDir("", "skeleton")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &Writer{Format: format, Dir: filepath.Join(dir, "out"), Name: "myapp"}
+	paths, err := w.Write(testObjects())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel, _ := filepath.Rel(w.Dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+	}
+	if _, err := w.Write(testObjects()); err == nil {
+		t.Error("expected an error when writing to a directory that is not empty")
+	}
+	return dir, files
+}
+
+func TestWriteKustomize(t *testing.T) {
+	dir, files := writeLayout(t, Kustomize)
+	defer os.RemoveAll(dir)
+
+	base := &types.Kustomization{}
+	if err := yaml.Unmarshal([]byte(files["base/kustomization.yaml"]), base); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(base.Resources, ",") != "deployment-web.yaml,deploymentconfig-db.yaml,service-web.yaml" {
+		t.Errorf("unexpected base resources: %v", base.Resources)
+	}
+	if strings.Contains(files["base/deployment-web.yaml"], "creationTimestamp") {
+		t.Errorf("unexpected server fields:\n%s", files["base/deployment-web.yaml"])
+	}
+
+	overlay := &types.Kustomization{}
+	if err := yaml.Unmarshal([]byte(files["overlays/production/kustomization.yaml"]), overlay); err != nil {
+		t.Fatal(err)
+	}
+	if files["overlays/production/kustomization.yaml"] != files["overlays/development/kustomization.yaml"] {
+		t.Error("expected identical overlays")
+	}
+	if len(overlay.Resources) != 1 || overlay.Resources[0] != "../../base" {
+		t.Errorf("unexpected overlay resources: %v", overlay.Resources)
+	}
+	if len(overlay.Images) != 2 || overlay.Images[0] != (types.Image{Name: "quay.io/example/web", NewName: "quay.io/example/web", NewTag: "1.0"}) {
+		t.Errorf("unexpected images: %#v", overlay.Images)
+	}
+	if len(overlay.Replicas) != 1 || overlay.Replicas[0] != (types.Replica{Name: "web", Count: 2}) {
+		t.Errorf("unexpected replicas: %#v", overlay.Replicas)
+	}
+	if len(overlay.Patches) != 2 || overlay.Patches[1].Target.Kind != "DeploymentConfig" || !strings.Contains(overlay.Patches[1].Patch, "path: /spec/replicas") {
+		t.Errorf("unexpected patches: %#v", overlay.Patches)
+	}
+	if !strings.Contains(overlay.Patches[0].Patch, "path: /spec/template/spec/containers/0/env") {
+		t.Errorf("unexpected patch: %s", overlay.Patches[0].Patch)
+	}
+}
+
+func TestWriteHelm(t *testing.T) {
+	dir, files := writeLayout(t, Helm)
+	defer os.RemoveAll(dir)
+
+	if !strings.Contains(files["Chart.yaml"], "name: myapp") {
+		t.Errorf("unexpected chart:\n%s", files["Chart.yaml"])
+	}
+	values := map[string]map[string]workloadValues{}
+	if err := yaml.Unmarshal([]byte(files["values.yaml"]), &values); err != nil {
+		t.Fatal(err)
+	}
+	web := values["deployments"]["web"]
+	if web.Replicas != 2 || web.Containers["web"].Image != "quay.io/example/web:1.0" || web.Containers["web"].Env["MODE"] != "prod" || web.Containers["sidecar"].Image != "" {
+		t.Errorf("unexpected values: %#v", values)
+	}
+	if db := values["deployments"]["db"]; db.Containers["db"].Env != nil {
+		t.Errorf("expected no env value for references: %#v", db)
+	}
+
+	template := files["templates/deployment-web.yaml"]
+	for _, s := range []string{
+		`{{- $values := index .Values.deployments "web" }}`,
+		"replicas: {{ $values.replicas }}",
+		`image: {{ (index $values.containers "web").image | quote }}`,
+		`{{- range $name, $value := (index $values.containers "web").env }}`,
+		"- name: {{ $name }}",
+	} {
+		if !strings.Contains(template, s) {
+			t.Errorf("the template has no %q:\n%s", s, template)
+		}
+	}
+	if strings.Contains(template, "HELMVALUE") {
+		t.Errorf("the template has placeholders left:\n%s", template)
+	}
+	if !strings.Contains(files["templates/deploymentconfig-db.yaml"], "valueFrom") {
+		t.Errorf("expected the env of the db to be kept:\n%s", files["templates/deploymentconfig-db.yaml"])
+	}
+	if !strings.Contains(files["templates/service-web.yaml"], `{{ "{{" }} not a template }}`) {
+		t.Errorf("expected template delimiters to be escaped:\n%s", files["templates/service-web.yaml"])
+	}
+}
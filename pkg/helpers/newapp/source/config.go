@@ -0,0 +1,98 @@
+package source
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// DetectorConfig is a user supplied set of rules that detect platforms and
+// the builder image stream to use for them, as read by LoadDetectorConfig:
+//
+//	detectors:
+//	- platform: deno
+//	  files: [deno.json, deno.jsonc]
+//	  versionFile: .dvmrc
+//	  builder: myproject/deno
+type DetectorConfig struct {
+	Detectors []DetectorRule `json:"detectors"`
+}
+
+// DetectorRule detects a platform from the files of a source directory.
+type DetectorRule struct {
+	// Platform is the name of the detected platform.
+	Platform string `json:"platform"`
+	// Files are the file name patterns, as used by filepath.Match, whose
+	// presence in the source directory identifies the platform.
+	Files []string `json:"files"`
+	// VersionFile is an optional file whose first line is the version of the
+	// platform the source asks for.
+	VersionFile string `json:"versionFile,omitempty"`
+	// Builder is an optional image stream reference of the builder image. If
+	// it has no tag, the detected version is used as the tag.
+	Builder string `json:"builder,omitempty"`
+}
+
+// LoadDetectorConfig reads a DetectorConfig from a YAML or JSON file and
+// returns its rules as Detectors.
+func LoadDetectorConfig(path string) (Detectors, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &DetectorConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("unable to read the detector configuration %s: %v", path, err)
+	}
+	detectors := Detectors{}
+	for i := range config.Detectors {
+		rule := config.Detectors[i]
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid detector %d in %s: %v", i+1, path, err)
+		}
+		detectors = append(detectors, rule.Detect)
+	}
+	return detectors, nil
+}
+
+func (r *DetectorRule) validate() error {
+	if len(r.Platform) == 0 {
+		return fmt.Errorf("a platform is required")
+	}
+	if len(r.Files) == 0 {
+		return fmt.Errorf("at least one file pattern is required for the platform %q", r.Platform)
+	}
+	for _, pattern := range r.Files {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid file pattern %q: %v", pattern, err)
+		}
+	}
+	if len(r.Builder) > 0 {
+		if ref, err := reference.Parse(r.Builder); err != nil || len(ref.Registry) > 0 {
+			return fmt.Errorf("the builder %q must be an image stream of the form [<namespace>/]<name>[:<tag>]", r.Builder)
+		}
+	}
+	return nil
+}
+
+// Detect is a DetectorFunc for the rule.
+func (r *DetectorRule) Detect(dir string) *Info {
+	info := detect(r.Platform, dir, r.Files...)
+	if info == nil {
+		return nil
+	}
+	if len(r.VersionFile) > 0 {
+		info.Version = firstLine(filepath.Join(dir, r.VersionFile))
+	}
+	if len(r.Builder) > 0 {
+		info.Builder = r.Builder
+		if ref, err := reference.Parse(r.Builder); err == nil && len(ref.Tag) == 0 && len(ref.ID) == 0 && len(info.Version) > 0 {
+			info.Builder = fmt.Sprintf("%s:%s", r.Builder, info.Version)
+		}
+	}
+	return info
+}
@@ -6,6 +6,9 @@ import "path/filepath"
 type Info struct {
 	Platform string
 	Version  string
+	// Builder is the builder image to use for the source, if the detector
+	// knows one, as an image stream reference.
+	Builder string
 }
 
 // DetectorFunc is a function that returns source Info from a given directory.
@@ -29,6 +32,10 @@ var DefaultDetectors = Detectors{
 	DetectLiteralDotNet,
 	DetectGolang,
 	DetectRust,
+	DetectKotlin,
+	DetectGradle,
+	DetectDeno,
+	DetectElixir,
 }
 
 // DetectRuby detects Ruby source
@@ -38,12 +45,12 @@ func DetectRuby(dir string) *Info {
 
 // DetectJava detects Java source
 func DetectJava(dir string) *Info {
-	return detect("jee", dir, "pom.xml")
+	return detectVersion("jee", dir, mavenJavaVersion, "pom.xml")
 }
 
 // DetectNodeJS detects NodeJS source
 func DetectNodeJS(dir string) *Info {
-	return detect("nodejs", dir, "app.json", "package.json")
+	return detectVersion("nodejs", dir, nodeVersion, "app.json", "package.json")
 }
 
 // DetectPHP detects PHP source
@@ -53,7 +60,7 @@ func DetectPHP(dir string) *Info {
 
 // DetectPython detects Python source
 func DetectPython(dir string) *Info {
-	return detect("python", dir, "requirements.txt", "setup.py")
+	return detectVersion("python", dir, pythonVersion, "requirements.txt", "setup.py", "Pipfile", "pyproject.toml")
 }
 
 // DetectPerl detects Perl source
@@ -78,7 +85,7 @@ func DetectLiteralDotNet(dir string) *Info {
 
 // DetectGolang detects Go source
 func DetectGolang(dir string) *Info {
-	return detect("golang", dir, "main.go", "Godeps")
+	return detectVersion("golang", dir, goVersion, "main.go", "Godeps", "go.mod")
 }
 
 // DetectRust detects Rust source
@@ -86,6 +93,37 @@ func DetectRust(dir string) *Info {
 	return detect("rust", dir, "Cargo.toml")
 }
 
+// DetectKotlin detects Kotlin source built with Gradle or Maven
+func DetectKotlin(dir string) *Info {
+	return detectVersion("kotlin", dir, jvmVersion, "src/main/kotlin", "*.kt")
+}
+
+// DetectGradle detects Java source built with Gradle
+func DetectGradle(dir string) *Info {
+	return detectVersion("gradle", dir, gradleJavaVersion, "build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts")
+}
+
+// DetectDeno detects Deno source
+func DetectDeno(dir string) *Info {
+	return detectVersion("deno", dir, denoVersion, "deno.json", "deno.jsonc")
+}
+
+// DetectElixir detects Elixir source
+func DetectElixir(dir string) *Info {
+	return detectVersion("elixir", dir, elixirVersion, "mix.exs")
+}
+
+// detectVersion returns an Info object with the given platform if the source
+// at dir contains any of the argument files, with the version returned by
+// version
+func detectVersion(platform string, dir string, version func(dir string) string, globs ...string) *Info {
+	info := detect(platform, dir, globs...)
+	if info != nil {
+		info.Version = version(dir)
+	}
+	return info
+}
+
 // detect returns an Info object with the given platform if the source at dir contains any of the argument files
 func detect(platform string, dir string, globs ...string) *Info {
 	for _, g := range globs {
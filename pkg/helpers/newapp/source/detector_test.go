@@ -0,0 +1,183 @@
+package source
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSourceFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "source-detector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDetectVersions(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		detect DetectorFunc
+		want   Info
+	}{
+		{
+			name:   "nvmrc",
+			files:  map[string]string{"package.json": `{"engines": {"node": ">=14"}}`, ".nvmrc": "v18.12.1\n"},
+			detect: DetectNodeJS,
+			want:   Info{Platform: "nodejs", Version: "18"},
+		},
+		{
+			name:   "package.json engines",
+			files:  map[string]string{"package.json": `{"engines": {"node": "^16.13.0 || >=18"}}`, ".nvmrc": "lts/*"},
+			detect: DetectNodeJS,
+			want:   Info{Platform: "nodejs", Version: "16"},
+		},
+		{
+			name:   "node without version",
+			files:  map[string]string{"package.json": `{"name": "app"}`},
+			detect: DetectNodeJS,
+			want:   Info{Platform: "nodejs"},
+		},
+		{
+			name:   "go directive",
+			files:  map[string]string{"go.mod": "module example.com/app\n\ngo 1.19\n\nrequire example.com/lib v1.2.3\n"},
+			detect: DetectGolang,
+			want:   Info{Platform: "golang", Version: "1.19"},
+		},
+		{
+			name:   "runtime.txt",
+			files:  map[string]string{"requirements.txt": "flask", "runtime.txt": "python-3.9.7"},
+			detect: DetectPython,
+			want:   Info{Platform: "python", Version: "3.9"},
+		},
+		{
+			name:   "python-version",
+			files:  map[string]string{"pyproject.toml": "", ".python-version": "# pyenv\n3.11\n", "runtime.txt": "python-3.9.7"},
+			detect: DetectPython,
+			want:   Info{Platform: "python", Version: "3.11"},
+		},
+		{
+			name: "maven compiler property",
+			files: map[string]string{"pom.xml": `<project>
+  <properties><java.version>1.8</java.version><maven.compiler.release>${java.version}</maven.compiler.release></properties>
+</project>`},
+			detect: DetectJava,
+			want:   Info{Platform: "jee", Version: "8"},
+		},
+		{
+			name: "maven toolchain",
+			files: map[string]string{"pom.xml": `<project>
+  <properties><maven.compiler.source>11</maven.compiler.source></properties>
+  <build><plugins>
+    <plugin><artifactId>maven-toolchains-plugin</artifactId><configuration><toolchains><jdk><version>17</version></jdk></toolchains></configuration></plugin>
+  </plugins></build>
+</project>`},
+			detect: DetectJava,
+			want:   Info{Platform: "jee", Version: "17"},
+		},
+		{
+			name:   "gradle toolchain",
+			files:  map[string]string{"build.gradle": "java {\n  toolchain {\n    languageVersion = JavaLanguageVersion.of(17)\n  }\n}\nsourceCompatibility = '11'\n"},
+			detect: DetectGradle,
+			want:   Info{Platform: "gradle", Version: "17"},
+		},
+		{
+			name:   "gradle source compatibility",
+			files:  map[string]string{"build.gradle.kts": "java {\n  sourceCompatibility = JavaVersion.VERSION_1_8\n}\n"},
+			detect: DetectGradle,
+			want:   Info{Platform: "gradle", Version: "8"},
+		},
+		{
+			name:   "kotlin",
+			files:  map[string]string{"build.gradle.kts": "kotlin {\n  jvmToolchain(17)\n}\n", "src/main/kotlin/Main.kt": ""},
+			detect: DetectKotlin,
+			want:   Info{Platform: "kotlin", Version: "17"},
+		},
+		{
+			name:   "deno",
+			files:  map[string]string{"deno.json": "{}", ".dvmrc": "1.30.3"},
+			detect: DetectDeno,
+			want:   Info{Platform: "deno", Version: "1.30"},
+		},
+		{
+			name:   "elixir",
+			files:  map[string]string{"mix.exs": "def project do\n  [app: :app, elixir: \"~> 1.14\"]\nend\n"},
+			detect: DetectElixir,
+			want:   Info{Platform: "elixir", Version: "1.14"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeSourceFiles(t, test.files)
+			defer os.RemoveAll(dir)
+			info := test.detect(dir)
+			if info == nil {
+				t.Fatal("source not detected")
+			}
+			if *info != test.want {
+				t.Errorf("got %#v, want %#v", *info, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadDetectorConfig(t *testing.T) {
+	dir := writeSourceFiles(t, map[string]string{
+		"detectors.yaml": `detectors:
+- platform: deno
+  files: [deno.json, deno.jsonc]
+  versionFile: .dvmrc
+  builder: myproject/deno
+- platform: zig
+  files: ["*.zig"]
+  builder: zig:0.10
+`,
+		"invalid.yaml": `detectors:
+- platform: zig
+  files: ["[.zig"]
+`,
+		"registry.yaml": `detectors:
+- platform: zig
+  files: ["*.zig"]
+  builder: quay.io/zig/zig
+`,
+		"src/deno.json":  "{}",
+		"src/.dvmrc":     "1.30",
+		"other/main.zig": "",
+	})
+	defer os.RemoveAll(dir)
+
+	detectors, err := LoadDetectorConfig(filepath.Join(dir, "detectors.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detectors) != 2 {
+		t.Fatalf("unexpected detectors: %d", len(detectors))
+	}
+	if info := detectors[0](filepath.Join(dir, "src")); info == nil || *info != (Info{Platform: "deno", Version: "1.30", Builder: "myproject/deno:1.30"}) {
+		t.Errorf("unexpected info: %#v", info)
+	}
+	if info := detectors[0](filepath.Join(dir, "other")); info != nil {
+		t.Errorf("unexpected info: %#v", info)
+	}
+	if info := detectors[1](filepath.Join(dir, "other")); info == nil || info.Builder != "zig:0.10" {
+		t.Errorf("unexpected info: %#v", info)
+	}
+
+	for _, name := range []string{"invalid.yaml", "registry.yaml", "missing.yaml"} {
+		if _, err := LoadDetectorConfig(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package source
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// The version readers below return the version of the platform a source
+// directory asks for, shortened to the components builder image tags use,
+// or an empty string if the source has no version hint.
+
+var (
+	versionRegexp = regexp.MustCompile(`\d+(\.\d+)*`)
+
+	goDirectiveRegexp = regexp.MustCompile(`(?m)^go\s+(\d+(\.\d+)*)`)
+	mixElixirRegexp   = regexp.MustCompile(`elixir:\s*"[^"\d]*(\d+(\.\d+)*)`)
+
+	// gradleJavaRegexps match the Java version in Gradle build scripts, in
+	// order of preference: toolchains, then source compatibility.
+	gradleJavaRegexps = []*regexp.Regexp{
+		regexp.MustCompile(`JavaLanguageVersion\.of\(\s*["']?(\d+)`),
+		regexp.MustCompile(`jvmToolchain\(\s*(\d+)`),
+		regexp.MustCompile(`sourceCompatibility\s*=\s*(?:JavaVersion\.VERSION_|["']?)(\d+(?:[._]\d+)?)`),
+	}
+)
+
+// shortVersion returns the first version found in s, with at most parts
+// components. Versions in ranges such as ">=16 <19" give their lower bound.
+func shortVersion(s string, parts int) string {
+	version := versionRegexp.FindString(s)
+	if len(version) == 0 {
+		return ""
+	}
+	components := strings.Split(version, ".")
+	if len(components) > parts {
+		components = components[:parts]
+	}
+	return strings.Join(components, ".")
+}
+
+// javaVersion returns the major version of Java, which older releases write as
+// 1.8 or 1_8.
+func javaVersion(s string) string {
+	s = strings.Replace(s, "_", ".", -1)
+	if strings.HasPrefix(s, "1.") {
+		s = strings.TrimPrefix(s, "1.")
+	}
+	return shortVersion(s, 1)
+}
+
+// firstLine returns the first line of a file that is not empty or a comment.
+func firstLine(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) > 0 && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
+
+// nodeVersion reads .nvmrc, .node-version and the engines of package.json.
+func nodeVersion(dir string) string {
+	for _, name := range []string{".nvmrc", ".node-version"} {
+		if version := shortVersion(firstLine(filepath.Join(dir, name)), 1); len(version) > 0 {
+			return version
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	pkg := struct {
+		Engines map[string]string `json:"engines"`
+	}{}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return ""
+	}
+	return shortVersion(pkg.Engines["node"], 1)
+}
+
+// pythonVersion reads .python-version and runtime.txt, as used by pyenv and
+// Heroku.
+func pythonVersion(dir string) string {
+	for _, name := range []string{".python-version", "runtime.txt"} {
+		if version := shortVersion(firstLine(filepath.Join(dir, name)), 2); len(version) > 0 {
+			return version
+		}
+	}
+	return ""
+}
+
+// goVersion reads the go directive of go.mod.
+func goVersion(dir string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	if m := goDirectiveRegexp.FindSubmatch(data); m != nil {
+		return shortVersion(string(m[1]), 2)
+	}
+	return ""
+}
+
+// elixirVersion reads the elixir requirement of mix.exs.
+func elixirVersion(dir string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "mix.exs"))
+	if err != nil {
+		return ""
+	}
+	if m := mixElixirRegexp.FindSubmatch(data); m != nil {
+		return shortVersion(string(m[1]), 2)
+	}
+	return ""
+}
+
+// denoVersion reads .dvmrc, as used by the Deno version manager.
+func denoVersion(dir string) string {
+	return shortVersion(firstLine(filepath.Join(dir, ".dvmrc")), 2)
+}
+
+// gradleJavaVersion reads the Java toolchain or source compatibility of the
+// Gradle build scripts.
+func gradleJavaVersion(dir string) string {
+	for _, name := range []string{"build.gradle", "build.gradle.kts"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		for _, re := range gradleJavaRegexps {
+			if m := re.FindSubmatch(data); m != nil {
+				return javaVersion(string(m[1]))
+			}
+		}
+	}
+	return ""
+}
+
+// jvmVersion reads the Java version of a Gradle or Maven build.
+func jvmVersion(dir string) string {
+	if version := gradleJavaVersion(dir); len(version) > 0 {
+		return version
+	}
+	return mavenJavaVersion(dir)
+}
+
+// pomProperty is a property of a Maven project.
+type pomProperty struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// pomPlugin is the part of a Maven plugin that may set the Java version.
+type pomPlugin struct {
+	ArtifactID    string `xml:"artifactId"`
+	Configuration struct {
+		Release    string `xml:"release"`
+		Source     string `xml:"source"`
+		JDKVersion string `xml:"toolchains>jdk>version"`
+	} `xml:"configuration"`
+}
+
+// mavenJavaVersion reads the Java version of pom.xml, from the toolchains
+// plugin, the compiler plugin or the usual properties.
+func mavenJavaVersion(dir string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "pom.xml"))
+	if err != nil {
+		return ""
+	}
+	pom := struct {
+		Properties struct {
+			Entries []pomProperty `xml:",any"`
+		} `xml:"properties"`
+		Plugins []pomPlugin `xml:"build>plugins>plugin"`
+	}{}
+	if err := xml.Unmarshal(data, &pom); err != nil {
+		return ""
+	}
+	properties := map[string]string{}
+	for _, p := range pom.Properties.Entries {
+		properties[p.XMLName.Local] = strings.TrimSpace(p.Value)
+	}
+	// values may refer to properties as ${name}
+	resolve := func(value string) string {
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
+			return properties[value[2:len(value)-1]]
+		}
+		return value
+	}
+
+	candidates := []string{}
+	for _, plugin := range pom.Plugins {
+		switch plugin.ArtifactID {
+		case "maven-toolchains-plugin":
+			candidates = append(candidates, plugin.Configuration.JDKVersion)
+		case "maven-compiler-plugin":
+			candidates = append(candidates, plugin.Configuration.Release, plugin.Configuration.Source)
+		}
+	}
+	candidates = append(candidates, properties["maven.compiler.release"], properties["maven.compiler.source"], properties["java.version"])
+	for _, candidate := range candidates {
+		if version := javaVersion(resolve(candidate)); len(version) > 0 {
+			return version
+		}
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package offline
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// offlineHost is the host of requests served from a directory. It is never contacted.
+const offlineHost = "https://offline.invalid"
+
+// ClientGetter returns clients that read resources from a directory when one is set, and clients
+// for the server of the delegate otherwise.
+type ClientGetter struct {
+	delegate genericclioptions.RESTClientGetter
+	dir      *string
+
+	lock      sync.Mutex
+	store     *Store
+	discovery discovery.CachedDiscoveryInterface
+}
+
+var _ genericclioptions.RESTClientGetter = &ClientGetter{}
+
+// NewClientGetter returns a client getter that reads from *dir, which is usually bound to a flag, if
+// it is not empty when the first client is requested.
+func NewClientGetter(delegate genericclioptions.RESTClientGetter, dir *string) *ClientGetter {
+	return &ClientGetter{delegate: delegate, dir: dir}
+}
+
+func (g *ClientGetter) enabled() bool {
+	return g.dir != nil && len(*g.dir) > 0
+}
+
+func (g *ClientGetter) loadStore() (*Store, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.store == nil {
+		store, err := Load(*g.dir)
+		if err != nil {
+			return nil, err
+		}
+		g.store = store
+	}
+	return g.store, nil
+}
+
+func (g *ClientGetter) ToRESTConfig() (*rest.Config, error) {
+	if !g.enabled() {
+		return g.delegate.ToRESTConfig()
+	}
+	store, err := g.loadStore()
+	if err != nil {
+		return nil, err
+	}
+	return &rest.Config{
+		Host:      offlineHost,
+		Transport: store,
+		QPS:       1000,
+		Burst:     1000,
+	}, nil
+}
+
+func (g *ClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	if !g.enabled() {
+		return g.delegate.ToDiscoveryClient()
+	}
+	config, err := g.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.discovery == nil {
+		client, err := discovery.NewDiscoveryClientForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		g.discovery = uncachedDiscovery{client}
+	}
+	return g.discovery, nil
+}
+
+func (g *ClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	if !g.enabled() {
+		return g.delegate.ToRESTMapper()
+	}
+	discoveryClient, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient)
+	return restmapper.NewShortcutExpander(mapper, discoveryClient), nil
+}
+
+func (g *ClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	if !g.enabled() {
+		return g.delegate.ToRawKubeConfigLoader()
+	}
+	return &clientConfig{delegate: g.delegate.ToRawKubeConfigLoader(), getter: g}
+}
+
+// uncachedDiscovery does not cache discovery, which is served from memory.
+type uncachedDiscovery struct {
+	discovery.DiscoveryInterface
+}
+
+func (uncachedDiscovery) Fresh() bool { return true }
+func (uncachedDiscovery) Invalidate() {}
+
+// clientConfig uses the namespace of the current context, but connects to the directory.
+type clientConfig struct {
+	delegate clientcmd.ClientConfig
+	getter   *ClientGetter
+}
+
+func (c *clientConfig) RawConfig() (clientcmdapi.Config, error) {
+	return c.delegate.RawConfig()
+}
+
+func (c *clientConfig) ConfigAccess() clientcmd.ConfigAccess {
+	return c.delegate.ConfigAccess()
+}
+
+func (c *clientConfig) ClientConfig() (*rest.Config, error) {
+	return c.getter.ToRESTConfig()
+}
+
+func (c *clientConfig) Namespace() (string, bool, error) {
+	namespace, overridden, err := c.delegate.Namespace()
+	if err != nil {
+		// no kubeconfig is needed to read a directory
+		return "default", false, nil
+	}
+	return namespace, overridden, nil
+}
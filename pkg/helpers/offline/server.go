@@ -0,0 +1,398 @@
+package offline
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+// shortNames are the short names the API server advertises for common resources.
+var shortNames = map[schema.GroupResource][]string{
+	{Resource: "configmaps"}:                                     {"cm"},
+	{Resource: "endpoints"}:                                      {"ep"},
+	{Resource: "events"}:                                         {"ev"},
+	{Resource: "namespaces"}:                                     {"ns"},
+	{Resource: "nodes"}:                                          {"no"},
+	{Resource: "persistentvolumeclaims"}:                         {"pvc"},
+	{Resource: "persistentvolumes"}:                              {"pv"},
+	{Resource: "pods"}:                                           {"po"},
+	{Resource: "replicationcontrollers"}:                         {"rc"},
+	{Resource: "serviceaccounts"}:                                {"sa"},
+	{Resource: "services"}:                                       {"svc"},
+	{Group: "apps", Resource: "daemonsets"}:                      {"ds"},
+	{Group: "apps", Resource: "deployments"}:                     {"deploy"},
+	{Group: "apps", Resource: "replicasets"}:                     {"rs"},
+	{Group: "apps", Resource: "statefulsets"}:                    {"sts"},
+	{Group: "apps.openshift.io", Resource: "deploymentconfigs"}:  {"dc"},
+	{Group: "build.openshift.io", Resource: "buildconfigs"}:      {"bc"},
+	{Group: "config.openshift.io", Resource: "clusteroperators"}: {"co"},
+	{Group: "image.openshift.io", Resource: "imagestreams"}:      {"is"},
+	{Group: "policy", Resource: "poddisruptionbudgets"}:          {"pdb"},
+}
+
+// allCategory lists the resources returned by oc get all.
+var allCategory = map[schema.GroupResource]bool{
+	{Resource: "pods"}:                                          true,
+	{Resource: "replicationcontrollers"}:                        true,
+	{Resource: "services"}:                                      true,
+	{Group: "apps", Resource: "daemonsets"}:                     true,
+	{Group: "apps", Resource: "deployments"}:                    true,
+	{Group: "apps", Resource: "replicasets"}:                    true,
+	{Group: "apps", Resource: "statefulsets"}:                   true,
+	{Group: "batch", Resource: "cronjobs"}:                      true,
+	{Group: "batch", Resource: "jobs"}:                          true,
+	{Group: "apps.openshift.io", Resource: "deploymentconfigs"}: true,
+	{Group: "build.openshift.io", Resource: "buildconfigs"}:     true,
+	{Group: "build.openshift.io", Resource: "builds"}:           true,
+	{Group: "image.openshift.io", Resource: "imagestreams"}:     true,
+	{Group: "route.openshift.io", Resource: "routes"}:           true,
+}
+
+// RoundTrip serves a request from the store. Only reads are supported.
+func (s *Store) RoundTrip(req *http.Request) (*http.Response, error) {
+	status, contentType, body := s.serve(req)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// request is the parsed path of an API request.
+type request struct {
+	group, version          string
+	namespace, resource     string
+	name, subresource       string
+	legacy, namespaceScoped bool
+}
+
+func (s *Store) serve(req *http.Request) (int, string, []byte) {
+	if req.Method != http.MethodGet {
+		return statusResponse(readOnly(strings.ToLower(req.Method)))
+	}
+	if req.URL.Query().Get("watch") == "true" || req.URL.Query().Get("watch") == "1" {
+		return statusResponse(readOnly("watch"))
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case req.URL.Path == "/version":
+		return jsonResponse(version.Info{GitVersion: "v0.0.0-offline"})
+	case req.URL.Path == "/api":
+		return jsonResponse(&metav1.APIVersions{
+			TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+			Versions: []string{"v1"},
+		})
+	case req.URL.Path == "/apis":
+		return jsonResponse(s.apiGroupList())
+	case parts[0] == "api" && len(parts) == 2:
+		return jsonResponse(s.apiResourceList("", parts[1]))
+	case parts[0] == "apis" && len(parts) == 3:
+		return jsonResponse(s.apiResourceList(parts[1], parts[2]))
+	}
+
+	r, ok := parseRequest(parts)
+	if !ok {
+		return statusResponse(apierrors.NewNotFound(schema.GroupResource{}, req.URL.Path))
+	}
+	gr := schema.GroupResource{Group: r.group, Resource: r.resource}
+	t, ok := s.types[gr]
+	if !ok {
+		return statusResponse(apierrors.NewNotFound(gr, r.name))
+	}
+	if r.namespaceScoped && !t.Namespaced {
+		return statusResponse(apierrors.NewNotFound(gr, r.name))
+	}
+
+	if len(r.name) == 0 {
+		objects, err := s.list(gr, r.namespace, req.URL.Query())
+		if err != nil {
+			return statusResponse(apierrors.NewBadRequest(err.Error()))
+		}
+		if wantsTable(req) {
+			return jsonResponse(newTable(t, objects))
+		}
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+		list.SetAPIVersion(schema.GroupVersion{Group: t.Group, Version: t.preferredVersion()}.String())
+		list.SetKind(t.Kind + "List")
+		list.SetResourceVersion("0")
+		for _, obj := range objects {
+			list.Items = append(list.Items, *obj)
+		}
+		return jsonResponse(list)
+	}
+
+	obj := s.get(gr, r.namespace, r.name)
+	if obj == nil {
+		return statusResponse(apierrors.NewNotFound(gr, r.name))
+	}
+	switch r.subresource {
+	case "":
+		if wantsTable(req) {
+			return jsonResponse(newTable(t, []*unstructured.Unstructured{obj}))
+		}
+		return jsonResponse(obj.Object)
+	case "log":
+		if gr == (schema.GroupResource{Resource: "pods"}) {
+			return s.podLog(obj, req)
+		}
+	}
+	return statusResponse(apierrors.NewNotFound(schema.GroupResource{Group: r.group, Resource: r.resource + "/" + r.subresource}, r.name))
+}
+
+// parseRequest parses the path of a resource request:
+//
+//	/api/VERSION/RESOURCE[/NAME[/SUBRESOURCE]]
+//	/api/VERSION/namespaces/NAMESPACE/RESOURCE[/NAME[/SUBRESOURCE]]
+//	/apis/GROUP/VERSION/...
+func parseRequest(parts []string) (request, bool) {
+	var r request
+	switch {
+	case parts[0] == "api" && len(parts) > 2:
+		r.legacy = true
+		r.version = parts[1]
+		parts = parts[2:]
+	case parts[0] == "apis" && len(parts) > 3:
+		r.group, r.version = parts[1], parts[2]
+		parts = parts[3:]
+	default:
+		return r, false
+	}
+	// namespaces/NAME is the namespace itself, namespaces/NAME/RESOURCE is a namespaced resource
+	if parts[0] == "namespaces" && len(parts) > 2 {
+		r.namespace = parts[1]
+		r.namespaceScoped = true
+		parts = parts[2:]
+	}
+	r.resource = parts[0]
+	if len(parts) > 1 {
+		r.name = parts[1]
+	}
+	if len(parts) > 2 {
+		r.subresource = strings.Join(parts[2:], "/")
+	}
+	return r, true
+}
+
+// list returns the objects of a resource in a namespace, or in all namespaces, that match the
+// label and field selectors of the query.
+func (s *Store) list(gr schema.GroupResource, namespace string, query map[string][]string) ([]*unstructured.Unstructured, error) {
+	labelSelector, err := labels.Parse(firstValue(query, "labelSelector"))
+	if err != nil {
+		return nil, err
+	}
+	fieldSelector, err := fields.ParseSelector(firstValue(query, "fieldSelector"))
+	if err != nil {
+		return nil, err
+	}
+	var objects []*unstructured.Unstructured
+	for _, obj := range s.objects[gr] {
+		if len(namespace) > 0 && obj.GetNamespace() != namespace {
+			continue
+		}
+		if !labelSelector.Matches(labels.Set(obj.GetLabels())) || !matchesFields(obj, fieldSelector) {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].GetNamespace() != objects[j].GetNamespace() {
+			return objects[i].GetNamespace() < objects[j].GetNamespace()
+		}
+		return objects[i].GetName() < objects[j].GetName()
+	})
+	return objects, nil
+}
+
+func (s *Store) get(gr schema.GroupResource, namespace, name string) *unstructured.Unstructured {
+	for _, obj := range s.objects[gr] {
+		if obj.GetNamespace() == namespace && obj.GetName() == name {
+			return obj
+		}
+	}
+	return nil
+}
+
+// matchesFields evaluates a field selector against the fields of an object, which is a superset of
+// the fields the API server supports.
+func matchesFields(obj *unstructured.Unstructured, selector fields.Selector) bool {
+	for _, req := range selector.Requirements() {
+		value, found, err := unstructured.NestedFieldNoCopy(obj.Object, strings.Split(req.Field, ".")...)
+		actual := ""
+		if found && err == nil && value != nil {
+			actual = fmt.Sprint(value)
+		}
+		switch req.Operator {
+		case "=", "==":
+			if actual != req.Value {
+				return false
+			}
+		case "!=":
+			if actual == req.Value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// podLog serves the log gathered for a container of a pod. The gathered logs include timestamps,
+// which are removed unless they were requested.
+func (s *Store) podLog(pod *unstructured.Unstructured, req *http.Request) (int, string, []byte) {
+	query := req.URL.Query()
+	container := query.Get("container")
+	if len(container) == 0 {
+		containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", "containers")
+		if len(containers) != 1 {
+			return statusResponse(apierrors.NewBadRequest(fmt.Sprintf("a container name must be specified for pod %s", pod.GetName())))
+		}
+		spec, ok := containers[0].(map[string]interface{})
+		if !ok {
+			return statusResponse(apierrors.NewInternalError(fmt.Errorf("unexpected container of pod %s: %v", pod.GetName(), containers[0])))
+		}
+		container, _, _ = unstructured.NestedString(spec, "name")
+	}
+	path := s.logFile(pod.GetNamespace(), pod.GetName(), container, query.Get("previous") == "true")
+	if len(path) == 0 {
+		return statusResponse(apierrors.NewNotFound(schema.GroupResource{Resource: "pods/log"}, pod.GetName()+"/"+container))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return statusResponse(apierrors.NewInternalError(err))
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	timestamps := query.Get("timestamps") == "true"
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !timestamps {
+			if i := strings.Index(line, " "); i > 0 {
+				line = line[i+1:]
+			}
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return statusResponse(apierrors.NewInternalError(err))
+	}
+	if tail, err := strconv.Atoi(query.Get("tailLines")); err == nil && tail >= 0 && tail < len(lines) {
+		lines = lines[len(lines)-tail:]
+	}
+	buf := &bytes.Buffer{}
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return http.StatusOK, "text/plain", buf.Bytes()
+}
+
+func (s *Store) apiGroupList() *metav1.APIGroupList {
+	list := &metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}}
+	groups := s.groups()
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var versions []string
+		for v := range groups[name] {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return compareVersions(versions[i], versions[j]) })
+		group := metav1.APIGroup{Name: name}
+		for _, v := range versions {
+			group.Versions = append(group.Versions, metav1.GroupVersionForDiscovery{GroupVersion: name + "/" + v, Version: v})
+		}
+		group.PreferredVersion = group.Versions[0]
+		list.Groups = append(list.Groups, group)
+	}
+	return list
+}
+
+func (s *Store) apiResourceList(group, version string) *metav1.APIResourceList {
+	list := &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: schema.GroupVersion{Group: group, Version: version}.String(),
+	}
+	for _, t := range s.groups()[group][version] {
+		gr := schema.GroupResource{Group: t.Group, Resource: t.Resource}
+		resource := metav1.APIResource{
+			Name:         t.Resource,
+			SingularName: strings.ToLower(t.Kind),
+			Namespaced:   t.Namespaced,
+			Kind:         t.Kind,
+			Verbs:        metav1.Verbs{"get", "list"},
+			ShortNames:   shortNames[gr],
+		}
+		if allCategory[gr] {
+			resource.Categories = []string{"all"}
+		}
+		list.APIResources = append(list.APIResources, resource)
+		if gr == (schema.GroupResource{Resource: "pods"}) {
+			list.APIResources = append(list.APIResources, metav1.APIResource{Name: "pods/log", Namespaced: true, Kind: "Pod", Verbs: metav1.Verbs{"get"}})
+		}
+	}
+	sort.Slice(list.APIResources, func(i, j int) bool { return list.APIResources[i].Name < list.APIResources[j].Name })
+	return list
+}
+
+func wantsTable(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), "as=Table")
+}
+
+func firstValue(query map[string][]string, key string) string {
+	if values := query[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// readOnly is returned for any request that would change or watch resources.
+func readOnly(action string) *apierrors.StatusError {
+	err := apierrors.NewMethodNotSupported(schema.GroupResource{}, action)
+	err.ErrStatus.Message = fmt.Sprintf("%s is not supported when reading resources from a directory", action)
+	return err
+}
+
+func jsonResponse(obj interface{}) (int, string, []byte) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return statusResponse(apierrors.NewInternalError(err))
+	}
+	return http.StatusOK, "application/json", data
+}
+
+func statusResponse(err *apierrors.StatusError) (int, string, []byte) {
+	status := err.Status()
+	status.Kind = "Status"
+	status.APIVersion = "v1"
+	data, _ := json.Marshal(status)
+	return int(status.Code), "application/json", data
+}
@@ -0,0 +1,333 @@
+// Package offline serves the resources gathered by oc adm inspect and oc adm must-gather as a
+// read-only API server, so that commands like oc get, oc describe and oc status can be run against
+// a directory instead of a cluster.
+package offline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/klog/v2"
+)
+
+const (
+	clusterScopedResourcesDirname = "cluster-scoped-resources"
+	namespaceResourcesDirname     = "namespaces"
+)
+
+// resourceType describes a resource served by the store.
+type resourceType struct {
+	Group      string
+	Versions   []string
+	Kind       string
+	Resource   string
+	Namespaced bool
+}
+
+// preferredVersion returns the highest version of the resource that was gathered.
+func (t *resourceType) preferredVersion() string {
+	return t.Versions[0]
+}
+
+// Store holds every object found in an inspect or must-gather directory, indexed by resource.
+type Store struct {
+	// roots are the directories that contain namespaces and cluster-scoped-resources directories.
+	roots   []string
+	types   map[schema.GroupResource]*resourceType
+	objects map[schema.GroupResource][]*unstructured.Unstructured
+	// keys records the objects that were already added, as the same object may be found in several
+	// files.
+	keys map[objectKey]bool
+}
+
+// objectKey identifies an object of a resource.
+type objectKey struct {
+	resource        schema.GroupResource
+	namespace, name string
+}
+
+// Load reads every resource below dir. The directory may be the output of oc adm inspect, or the
+// output of oc adm must-gather, which contains one such directory per image.
+func Load(dir string) (*Store, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	s := &Store{
+		types:   map[schema.GroupResource]*resourceType{},
+		objects: map[schema.GroupResource][]*unstructured.Unstructured{},
+		keys:    map[objectKey]bool{},
+	}
+	seen := map[string]bool{}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		switch info.Name() {
+		case clusterScopedResourcesDirname, namespaceResourcesDirname:
+			root := filepath.Dir(path)
+			if !seen[root] {
+				seen[root] = true
+				s.roots = append(s.roots, root)
+			}
+			if err := s.loadTree(root, path); err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(s.roots) == 0 {
+		return nil, fmt.Errorf("%s does not contain the output of oc adm inspect or oc adm must-gather", dir)
+	}
+	s.addProjects()
+	for _, t := range s.types {
+		sort.Slice(t.Versions, func(i, j int) bool { return compareVersions(t.Versions[i], t.Versions[j]) })
+	}
+	return s, nil
+}
+
+// loadTree reads the resource files below dir, which is one of the directories of root.
+func (s *Store) loadTree(root, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if err := s.loadFile(path, resourceFromPath(rel)); err != nil {
+			klog.V(2).Infof("Skipping %s: %v", path, err)
+		}
+		return nil
+	})
+}
+
+// loadFile reads the objects of a file. Lists are expanded into their items.
+func (s *Store) loadFile(path string, pathResource schema.GroupResource) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+		obj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, raw)
+		if err != nil {
+			return err
+		}
+		switch t := obj.(type) {
+		case *unstructured.Unstructured:
+			s.add(t, pathResource)
+		case *unstructured.UnstructuredList:
+			itemKind := strings.TrimSuffix(t.GetKind(), "List")
+			for i := range t.Items {
+				item := &t.Items[i]
+				if len(item.GetKind()) == 0 {
+					item.SetKind(itemKind)
+				}
+				if len(item.GetAPIVersion()) == 0 {
+					item.SetAPIVersion(t.GetAPIVersion())
+				}
+				s.add(item, pathResource)
+			}
+		}
+	}
+}
+
+// add records an object unless an object with the same resource, namespace and name was already
+// found in another file.
+func (s *Store) add(obj *unstructured.Unstructured, pathResource schema.GroupResource) {
+	gvk := obj.GroupVersionKind()
+	if len(gvk.Kind) == 0 || len(gvk.Version) == 0 || len(obj.GetName()) == 0 || gvk.Kind == "List" {
+		return
+	}
+
+	// the directory layout records the resource name, which can't always be guessed from the kind
+	gr := pathResource
+	if gr.Group != gvk.Group || len(gr.Resource) == 0 {
+		plural, _ := meta.UnsafeGuessKindToResource(gvk)
+		gr = plural.GroupResource()
+	}
+
+	t, ok := s.types[gr]
+	if !ok {
+		t = &resourceType{
+			Group:      gvk.Group,
+			Kind:       gvk.Kind,
+			Resource:   gr.Resource,
+			Namespaced: len(obj.GetNamespace()) > 0,
+		}
+		s.types[gr] = t
+	}
+	if t.Kind != gvk.Kind {
+		klog.V(2).Infof("Skipping %s %s/%s, %s are %s", gvk.Kind, obj.GetNamespace(), obj.GetName(), gr, t.Kind)
+		return
+	}
+	if !containsString(t.Versions, gvk.Version) {
+		t.Versions = append(t.Versions, gvk.Version)
+	}
+	key := objectKey{resource: gr, namespace: obj.GetNamespace(), name: obj.GetName()}
+	if s.keys[key] {
+		return
+	}
+	s.keys[key] = true
+	s.objects[gr] = append(s.objects[gr], obj)
+}
+
+// addProjects serves a project for every gathered namespace, as the API server does.
+func (s *Store) addProjects() {
+	projects := schema.GroupResource{Group: "project.openshift.io", Resource: "projects"}
+	for _, ns := range s.objects[schema.GroupResource{Resource: "namespaces"}] {
+		project := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		for _, field := range []string{"metadata", "spec", "status"} {
+			if value, ok := ns.Object[field]; ok {
+				project.Object[field] = value
+			}
+		}
+		project.SetAPIVersion("project.openshift.io/v1")
+		project.SetKind("Project")
+		s.add(project, projects)
+	}
+}
+
+// resourceFromPath returns the resource of the objects in a file from its path relative to the root
+// of the inspect output:
+//
+//	cluster-scoped-resources/GROUP/RESOURCE.yaml
+//	cluster-scoped-resources/GROUP/RESOURCE/NAME.yaml
+//	namespaces/NAMESPACE/NAMESPACE.yaml
+//	namespaces/NAMESPACE/GROUP/RESOURCE.yaml
+//	namespaces/NAMESPACE/GROUP/RESOURCE/NAME.yaml
+//	namespaces/NAMESPACE/pods/NAME/NAME.yaml
+func resourceFromPath(rel string) schema.GroupResource {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	trim := func(name string) string { return strings.TrimSuffix(name, filepath.Ext(name)) }
+	group := func(name string) string {
+		if name == "core" {
+			return ""
+		}
+		return name
+	}
+	switch {
+	case parts[0] == clusterScopedResourcesDirname && len(parts) == 3:
+		return schema.GroupResource{Group: group(parts[1]), Resource: trim(parts[2])}
+	case parts[0] == clusterScopedResourcesDirname && len(parts) == 4:
+		return schema.GroupResource{Group: group(parts[1]), Resource: parts[2]}
+	case parts[0] == namespaceResourcesDirname && len(parts) == 3:
+		return schema.GroupResource{Resource: "namespaces"}
+	case parts[0] == namespaceResourcesDirname && len(parts) == 4:
+		return schema.GroupResource{Group: group(parts[2]), Resource: trim(parts[3])}
+	case parts[0] == namespaceResourcesDirname && len(parts) == 5 && parts[2] == "pods":
+		return schema.GroupResource{Resource: "pods"}
+	case parts[0] == namespaceResourcesDirname && len(parts) == 5:
+		return schema.GroupResource{Group: group(parts[2]), Resource: parts[3]}
+	}
+	return schema.GroupResource{}
+}
+
+// Resources returns the gathered resources, ordered by group and name.
+func (s *Store) Resources() []schema.GroupResource {
+	var resources []schema.GroupResource
+	for gr := range s.objects {
+		resources = append(resources, gr)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Group != resources[j].Group {
+			return resources[i].Group < resources[j].Group
+		}
+		return resources[i].Resource < resources[j].Resource
+	})
+	return resources
+}
+
+// Objects returns the gathered objects of a resource.
+func (s *Store) Objects(gr schema.GroupResource) []*unstructured.Unstructured {
+	return s.objects[gr]
+}
+
+// groups returns the gathered resources by group and version.
+func (s *Store) groups() map[string]map[string][]*resourceType {
+	groups := map[string]map[string][]*resourceType{}
+	for _, t := range s.types {
+		if groups[t.Group] == nil {
+			groups[t.Group] = map[string][]*resourceType{}
+		}
+		for _, v := range t.Versions {
+			groups[t.Group][v] = append(groups[t.Group][v], t)
+		}
+	}
+	return groups
+}
+
+// logFile returns the path of a container log gathered for a pod, or an empty string.
+func (s *Store) logFile(namespace, pod, container string, previous bool) string {
+	names := []string{"current.log", "current.insecure.log"}
+	if previous {
+		names = []string{"previous.log", "previous.insecure.log"}
+	}
+	for _, root := range s.roots {
+		for _, name := range names {
+			path := filepath.Join(root, namespaceResourcesDirname, namespace, "pods", pod, container, container, "logs", name)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// compareVersions orders API versions from the most to the least preferred.
+func compareVersions(a, b string) bool {
+	return version.CompareKubeAwareVersionStrings(a, b) > 0
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package offline
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var testFiles = map[string]string{
+	"namespaces/demo/demo.yaml": `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: demo
+`,
+	"namespaces/demo/core/pods.yaml": `
+apiVersion: v1
+kind: PodList
+items:
+- apiVersion: v1
+  kind: Pod
+  metadata: {name: web-1, namespace: demo, labels: {app: web}}
+  spec:
+    containers: [{name: web, image: web}]
+- apiVersion: v1
+  kind: Pod
+  metadata: {name: db-1, namespace: demo, labels: {app: db}}
+  spec:
+    containers: [{name: db, image: db}, {name: sidecar, image: sidecar}]
+`,
+	"namespaces/demo/pods/web-1/web-1.yaml": `
+apiVersion: v1
+kind: Pod
+metadata: {name: web-1, namespace: demo, labels: {app: web}}
+spec:
+  containers: [{name: web, image: web}]
+`,
+	"namespaces/demo/pods/web-1/web/web/logs/current.log": "2022-01-01T00:00:01Z first\n2022-01-01T00:00:02Z second\n",
+	"namespaces/demo/core/endpoints.yaml": `
+apiVersion: v1
+kind: EndpointsList
+items:
+- metadata: {name: web, namespace: demo}
+`,
+	"namespaces/demo/core/events.yaml": `
+apiVersion: v1
+kind: EventList
+items:
+- apiVersion: v1
+  kind: Event
+  metadata: {name: web-1.1, namespace: demo}
+  involvedObject: {kind: Pod, name: web-1, namespace: demo}
+  reason: Started
+- apiVersion: v1
+  kind: Event
+  metadata: {name: db-1.1, namespace: demo}
+  involvedObject: {kind: Pod, name: db-1, namespace: demo}
+  reason: Failed
+`,
+	"cluster-scoped-resources/config.openshift.io/clusteroperators/etcd.yaml": `
+apiVersion: config.openshift.io/v1
+kind: ClusterOperator
+metadata: {name: etcd}
+`,
+	"cluster-scoped-resources/core/nodes/node-a.yaml": `
+apiVersion: v1
+kind: Node
+metadata: {name: node-a}
+`,
+	"not-a-resource.yaml": "just: text\n",
+}
+
+// writeTestDir writes the test files below a must-gather style image directory.
+func writeTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range testFiles {
+		path := filepath.Join(dir, "quay-io-openshift-must-gather", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestResourceFromPath(t *testing.T) {
+	tests := map[string]schema.GroupResource{
+		"cluster-scoped-resources/core/nodes.yaml":                             {Resource: "nodes"},
+		"cluster-scoped-resources/config.openshift.io/clusteroperators/a.yaml": {Group: "config.openshift.io", Resource: "clusteroperators"},
+		"namespaces/demo/demo.yaml":                                            {Resource: "namespaces"},
+		"namespaces/demo/core/endpoints.yaml":                                  {Resource: "endpoints"},
+		"namespaces/demo/apps/deployments/web.yaml":                            {Group: "apps", Resource: "deployments"},
+		"namespaces/demo/pods/web-1/web-1.yaml":                                {Resource: "pods"},
+		"namespaces/demo/pods/web-1/web/web/logs/current.log":                  {},
+	}
+	for path, want := range tests {
+		if got := resourceFromPath(path); got != want {
+			t.Errorf("%s: got %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir := writeTestDir(t)
+	defer os.RemoveAll(dir)
+
+	store, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &rest.Config{Host: offlineHost, Transport: store}
+	ctx := context.TODO()
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, err := discoveryClient.ServerResourcesForGroupVersion("v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range resources.APIResources {
+		names = append(names, r.Name)
+	}
+	if want := []string{"endpoints", "events", "namespaces", "nodes", "pods", "pods/log"}; !reflect.DeepEqual(want, names) {
+		t.Errorf("unexpected resources: %v", names)
+	}
+	groups, err := discoveryClient.ServerGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var groupNames []string
+	for _, g := range groups.Groups {
+		groupNames = append(groupNames, g.Name)
+	}
+	if want := []string{"", "config.openshift.io", "project.openshift.io"}; !reflect.DeepEqual(want, groupNames) {
+		t.Errorf("unexpected groups: %v", groupNames)
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pods, err := client.CoreV1().Pods("demo").List(ctx, metav1.ListOptions{LabelSelector: "app=web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods.Items) != 1 || pods.Items[0].Name != "web-1" {
+		t.Errorf("unexpected pods: %#v", pods.Items)
+	}
+	events, err := client.CoreV1().Events("").List(ctx, metav1.ListOptions{FieldSelector: "involvedObject.name=db-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events.Items) != 1 || events.Items[0].Reason != "Failed" {
+		t.Errorf("unexpected events: %#v", events.Items)
+	}
+	if _, err := client.CoreV1().Endpoints("demo").Get(ctx, "web", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected endpoints to be served from a list without item kinds: %v", err)
+	}
+	if _, err := client.CoreV1().Pods("other").Get(ctx, "web-1", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if _, err := client.AppsV1().Deployments("demo").List(ctx, metav1.ListOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected not found for a resource that was not gathered, got %v", err)
+	}
+	if err := client.CoreV1().Pods("demo").Delete(ctx, "web-1", metav1.DeleteOptions{}); !apierrors.IsMethodNotSupported(err) {
+		t.Errorf("expected deletes to be rejected, got %v", err)
+	}
+
+	logs, err := client.CoreV1().Pods("demo").GetLogs("web-1", &corev1.PodLogOptions{}).DoRaw(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(logs) != "first\nsecond\n" {
+		t.Errorf("unexpected logs: %q", logs)
+	}
+	tail := int64(1)
+	logs, err = client.CoreV1().Pods("demo").GetLogs("web-1", &corev1.PodLogOptions{Timestamps: true, TailLines: &tail}).DoRaw(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(logs) != "2022-01-01T00:00:02Z second\n" {
+		t.Errorf("unexpected logs: %q", logs)
+	}
+	if _, err := client.CoreV1().Pods("demo").GetLogs("db-1", &corev1.PodLogOptions{}).DoRaw(ctx); !apierrors.IsBadRequest(err) {
+		t.Errorf("expected a container to be required, got %v", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dynamicClient.Resource(schema.GroupVersionResource{Group: "config.openshift.io", Version: "v1", Resource: "clusteroperators"}).Get(ctx, "etcd", metav1.GetOptions{}); err != nil {
+		t.Error(err)
+	}
+	if _, err := dynamicClient.Resource(schema.GroupVersionResource{Group: "project.openshift.io", Version: "v1", Resource: "projects"}).Get(ctx, "demo", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected a project for each namespace: %v", err)
+	}
+
+	table := &metav1.Table{}
+	if err := client.CoreV1().RESTClient().Get().Resource("pods").Namespace("demo").
+		SetHeader("Accept", "application/json;as=Table;v=v1;g=meta.k8s.io").Do(ctx).Into(table); err != nil {
+		t.Fatal(err)
+	}
+	if len(table.ColumnDefinitions) != 5 || len(table.Rows) != 2 || table.Rows[0].Cells[0] != "db-1" {
+		t.Errorf("unexpected table: %#v", table)
+	}
+}
+
+func TestPodLogUnexpectedContainer(t *testing.T) {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web-1", "namespace": "demo"},
+		"spec":     map[string]interface{}{"containers": []interface{}{"web"}},
+	}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/demo/pods/web-1/log", nil)
+	if code, _, _ := (&Store{}).podLog(pod, req); code != http.StatusInternalServerError {
+		t.Errorf("expected an internal error, got %d", code)
+	}
+}
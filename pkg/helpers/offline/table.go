@@ -0,0 +1,290 @@
+package offline
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// column is a column of the table printed by oc get, and how to compute it from an object.
+type column struct {
+	name  string
+	value func(obj *unstructured.Unstructured) interface{}
+}
+
+var (
+	nameColumn = column{name: "Name", value: func(obj *unstructured.Unstructured) interface{} { return obj.GetName() }}
+	ageColumn  = column{name: "Age", value: func(obj *unstructured.Unstructured) interface{} { return age(obj.GetCreationTimestamp()) }}
+)
+
+// columns are the columns of common resources. Other resources are printed with their name and age.
+var columns = map[schema.GroupResource][]column{
+	{Resource: "pods"}: {
+		nameColumn,
+		{name: "Ready", value: podReady},
+		{name: "Status", value: podStatus},
+		{name: "Restarts", value: podRestarts},
+		ageColumn,
+	},
+	{Resource: "nodes"}: {
+		nameColumn,
+		{name: "Status", value: nodeStatus},
+		{name: "Roles", value: nodeRoles},
+		ageColumn,
+		{name: "Version", value: stringField("status", "nodeInfo", "kubeletVersion")},
+	},
+	{Resource: "namespaces"}: {
+		nameColumn,
+		{name: "Status", value: stringField("status", "phase")},
+		ageColumn,
+	},
+	{Resource: "services"}: {
+		nameColumn,
+		{name: "Type", value: stringField("spec", "type")},
+		{name: "Cluster-IP", value: stringField("spec", "clusterIP")},
+		{name: "Ports", value: servicePorts},
+		ageColumn,
+	},
+	{Resource: "events"}: {
+		{name: "Last Seen", value: eventLastSeen},
+		{name: "Type", value: stringField("type")},
+		{name: "Reason", value: stringField("reason")},
+		{name: "Object", value: eventObject},
+		{name: "Message", value: stringField("message")},
+	},
+	{Group: "apps", Resource: "deployments"}: {
+		nameColumn,
+		{name: "Ready", value: func(obj *unstructured.Unstructured) interface{} {
+			return fmt.Sprintf("%d/%d", int64Field(obj, "status", "readyReplicas"), int64Field(obj, "spec", "replicas"))
+		}},
+		{name: "Up-to-date", value: func(obj *unstructured.Unstructured) interface{} { return int64Field(obj, "status", "updatedReplicas") }},
+		{name: "Available", value: func(obj *unstructured.Unstructured) interface{} {
+			return int64Field(obj, "status", "availableReplicas")
+		}},
+		ageColumn,
+	},
+	{Group: "config.openshift.io", Resource: "clusteroperators"}: {
+		nameColumn,
+		{name: "Version", value: operatorVersion},
+		{name: "Available", value: conditionColumn("Available")},
+		{name: "Progressing", value: conditionColumn("Progressing")},
+		{name: "Degraded", value: conditionColumn("Degraded")},
+		{name: "Since", value: func(obj *unstructured.Unstructured) interface{} {
+			if c := condition(obj, "Available"); c != nil {
+				if t, err := time.Parse(time.RFC3339, fmt.Sprint(c["lastTransitionTime"])); err == nil {
+					return age(metav1.NewTime(t))
+				}
+			}
+			return "<unknown>"
+		}},
+		{name: "Message", value: func(obj *unstructured.Unstructured) interface{} {
+			for _, t := range []string{"Degraded", "Progressing", "Available"} {
+				if c := condition(obj, t); c != nil && (c["status"] == "True") == (t != "Available") {
+					if message, ok := c["message"].(string); ok && len(message) > 0 {
+						return strings.SplitN(message, "\n", 2)[0]
+					}
+				}
+			}
+			return ""
+		}},
+	},
+}
+
+// newTable returns the table oc get prints for a list of objects.
+func newTable(t *resourceType, objects []*unstructured.Unstructured) *metav1.Table {
+	cols, ok := columns[schema.GroupResource{Group: t.Group, Resource: t.Resource}]
+	if !ok {
+		cols = []column{nameColumn, ageColumn}
+	}
+	table := &metav1.Table{
+		TypeMeta: metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "Table"},
+		ListMeta: metav1.ListMeta{ResourceVersion: "0"},
+		Rows:     []metav1.TableRow{},
+	}
+	for i, c := range cols {
+		definition := metav1.TableColumnDefinition{Name: c.name, Type: "string"}
+		if i == 0 && c.name == "Name" {
+			definition.Format = "name"
+		}
+		table.ColumnDefinitions = append(table.ColumnDefinitions, definition)
+	}
+	for _, obj := range objects {
+		row := metav1.TableRow{}
+		for _, c := range cols {
+			row.Cells = append(row.Cells, c.value(obj))
+		}
+		raw, err := json.Marshal(obj.Object)
+		if err == nil {
+			row.Object = runtime.RawExtension{Raw: raw}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// age formats the time since a timestamp the way oc get does.
+func age(timestamp metav1.Time) string {
+	if timestamp.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(timestamp.Time))
+}
+
+func stringField(fields ...string) func(obj *unstructured.Unstructured) interface{} {
+	return func(obj *unstructured.Unstructured) interface{} {
+		value, _, _ := unstructured.NestedString(obj.Object, fields...)
+		return value
+	}
+}
+
+func int64Field(obj *unstructured.Unstructured, fields ...string) int64 {
+	value, _, _ := unstructured.NestedInt64(obj.Object, fields...)
+	return value
+}
+
+func podReady(obj *unstructured.Unstructured) interface{} {
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "containers")
+	statuses, _, _ := unstructured.NestedSlice(obj.Object, "status", "containerStatuses")
+	ready := 0
+	for _, status := range statuses {
+		if s, ok := status.(map[string]interface{}); ok && s["ready"] == true {
+			ready++
+		}
+	}
+	return fmt.Sprintf("%d/%d", ready, len(containers))
+}
+
+func podStatus(obj *unstructured.Unstructured) interface{} {
+	if obj.GetDeletionTimestamp() != nil {
+		return "Terminating"
+	}
+	reason, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if r, _, _ := unstructured.NestedString(obj.Object, "status", "reason"); len(r) > 0 {
+		reason = r
+	}
+	statuses, _, _ := unstructured.NestedSlice(obj.Object, "status", "containerStatuses")
+	for _, status := range statuses {
+		s, ok := status.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if r, _, _ := unstructured.NestedString(s, "state", "waiting", "reason"); len(r) > 0 {
+			return r
+		}
+		if r, _, _ := unstructured.NestedString(s, "state", "terminated", "reason"); len(r) > 0 && reason == "Running" {
+			return r
+		}
+	}
+	return reason
+}
+
+func podRestarts(obj *unstructured.Unstructured) interface{} {
+	statuses, _, _ := unstructured.NestedSlice(obj.Object, "status", "containerStatuses")
+	var restarts int64
+	for _, status := range statuses {
+		if s, ok := status.(map[string]interface{}); ok {
+			count, _, _ := unstructured.NestedInt64(s, "restartCount")
+			restarts += count
+		}
+	}
+	return restarts
+}
+
+func nodeStatus(obj *unstructured.Unstructured) interface{} {
+	status := "Unknown"
+	if c := condition(obj, "Ready"); c != nil {
+		status = "NotReady"
+		if c["status"] == "True" {
+			status = "Ready"
+		}
+	}
+	if unschedulable, _, _ := unstructured.NestedBool(obj.Object, "spec", "unschedulable"); unschedulable {
+		status += ",SchedulingDisabled"
+	}
+	return status
+}
+
+func nodeRoles(obj *unstructured.Unstructured) interface{} {
+	var roles []string
+	for label := range obj.GetLabels() {
+		if strings.HasPrefix(label, "node-role.kubernetes.io/") {
+			roles = append(roles, strings.TrimPrefix(label, "node-role.kubernetes.io/"))
+		}
+	}
+	if len(roles) == 0 {
+		return "<none>"
+	}
+	sort.Strings(roles)
+	return strings.Join(roles, ",")
+}
+
+func servicePorts(obj *unstructured.Unstructured) interface{} {
+	ports, _, _ := unstructured.NestedSlice(obj.Object, "spec", "ports")
+	var values []string
+	for _, port := range ports {
+		p, ok := port.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		number, _, _ := unstructured.NestedInt64(p, "port")
+		protocol, _, _ := unstructured.NestedString(p, "protocol")
+		values = append(values, fmt.Sprintf("%d/%s", number, protocol))
+	}
+	if len(values) == 0 {
+		return "<none>"
+	}
+	return strings.Join(values, ",")
+}
+
+func eventLastSeen(obj *unstructured.Unstructured) interface{} {
+	for _, field := range []string{"lastTimestamp", "eventTime", "firstTimestamp"} {
+		value, _, _ := unstructured.NestedString(obj.Object, field)
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return age(metav1.NewTime(t))
+		}
+	}
+	return age(obj.GetCreationTimestamp())
+}
+
+func eventObject(obj *unstructured.Unstructured) interface{} {
+	kind, _, _ := unstructured.NestedString(obj.Object, "involvedObject", "kind")
+	name, _, _ := unstructured.NestedString(obj.Object, "involvedObject", "name")
+	return strings.ToLower(kind) + "/" + name
+}
+
+func operatorVersion(obj *unstructured.Unstructured) interface{} {
+	versions, _, _ := unstructured.NestedSlice(obj.Object, "status", "versions")
+	for _, version := range versions {
+		if v, ok := version.(map[string]interface{}); ok && v["name"] == "operator" {
+			return fmt.Sprint(v["version"])
+		}
+	}
+	return ""
+}
+
+func conditionColumn(conditionType string) func(obj *unstructured.Unstructured) interface{} {
+	return func(obj *unstructured.Unstructured) interface{} {
+		if c := condition(obj, conditionType); c != nil {
+			return fmt.Sprint(c["status"])
+		}
+		return ""
+	}
+}
+
+func condition(obj *unstructured.Unstructured, conditionType string) map[string]interface{} {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		if m, ok := c.(map[string]interface{}); ok && m["type"] == conditionType {
+			return m
+		}
+	}
+	return nil
+}
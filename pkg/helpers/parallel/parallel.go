@@ -25,3 +25,22 @@ func Run(fns ...func() error) []error {
 	}
 	return errs
 }
+
+// RunLimited executes the provided functions with at most limit of them running at any time, and
+// collects any errors they return. A limit lower than one runs the functions serially.
+func RunLimited(limit int, fns ...func() error) []error {
+	if limit < 1 {
+		limit = 1
+	}
+	slots := make(chan struct{}, limit)
+	limited := make([]func() error, len(fns))
+	for i := range fns {
+		fn := fns[i]
+		limited[i] = func() error {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			return fn()
+		}
+	}
+	return Run(limited...)
+}
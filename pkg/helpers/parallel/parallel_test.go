@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestRun(t *testing.T) {
@@ -37,3 +38,31 @@ func TestRun(t *testing.T) {
 		t.Error("unexpected run")
 	}
 }
+
+func TestRunLimited(t *testing.T) {
+	var running, max, count int32
+	fns := []func() error{}
+	for j := 0; j < 10; j++ {
+		fns = append(fns, func() error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&count, 1)
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+	}
+	fns = append(fns, func() error { return fmt.Errorf("an error") })
+	errs := RunLimited(3, fns...)
+	if len(errs) != 1 || count != 10 {
+		t.Errorf("unexpected run: %v, %d", errs, count)
+	}
+	if max > 3 || max < 1 {
+		t.Errorf("expected at most 3 functions to run at once, got %d", max)
+	}
+}
@@ -0,0 +1,238 @@
+package redact
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Action is what a rule does with the values it matches.
+type Action string
+
+const (
+	// ActionRemove removes a field, or deletes the matched text.
+	ActionRemove Action = "remove"
+	// ActionRedact replaces the value with a fixed marker.
+	ActionRedact Action = "redact"
+	// ActionPseudonymize replaces the value with a pseudonym, which is the same wherever the value
+	// appears so that references between files can still be followed.
+	ActionPseudonymize Action = "pseudonymize"
+)
+
+// Rule matches values either with a regular expression, which is applied to every line of every
+// file, or with a field path, which is applied to the objects of the kinds listed.
+type Rule struct {
+	// Name is used as the prefix of pseudonyms.
+	Name string `json:"name"`
+	// Regex matches text. If it has capture groups only the groups are replaced.
+	Regex string `json:"regex,omitempty"`
+	// Exclude leaves the values matched by Regex that also match this expression untouched.
+	Exclude string `json:"exclude,omitempty"`
+	// Path is a field path like spec.host, data["ca.crt"] or status.addresses.*.address.
+	Path string `json:"path,omitempty"`
+	// Kinds restricts Path to objects of these kinds, as Kind or Kind.group.
+	Kinds []string `json:"kinds,omitempty"`
+	// Action defaults to redact.
+	Action Action `json:"action,omitempty"`
+}
+
+// Profile is a set of rules, usually read from a file.
+type Profile struct {
+	// Include lists built-in profiles to apply in addition to the rules of this profile.
+	Include []string `json:"include,omitempty"`
+	Rules   []Rule   `json:"rules,omitempty"`
+}
+
+// hostnameRegex matches the API and ingress hostnames of a cluster, which are not otherwise
+// distinguishable from the many DNS-like names that are safe to share, like API groups.
+const hostnameRegex = `(?i)\b(?:api|api-int|(?:[a-z0-9*-]+\.)+apps)\.[a-z0-9-]+(?:\.[a-z0-9-]+)+\b`
+
+// builtinProfiles can be referred to by name instead of by file.
+var builtinProfiles = map[string]Profile{
+	"ips": {Rules: []Rule{
+		{Name: "ipv4", Action: ActionPseudonymize, Regex: `\b(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\b`},
+		{Name: "ipv6", Action: ActionPseudonymize, Regex: `\b(?:[0-9a-fA-F]{1,4}:){7}[0-9a-fA-F]{1,4}\b|\b(?:[0-9a-fA-F]{1,4}:){1,7}:(?:[0-9a-fA-F]{1,4}(?::[0-9a-fA-F]{1,4}){0,6})?`},
+	}},
+	"hostnames": {Rules: []Rule{
+		{Name: "host", Action: ActionPseudonymize, Regex: hostnameRegex},
+		{Name: "host", Action: ActionPseudonymize, Path: "spec.host", Kinds: []string{"Route.route.openshift.io"}},
+		{Name: "host", Action: ActionPseudonymize, Path: "status.ingress.*.host", Kinds: []string{"Route.route.openshift.io"}},
+		{Name: "host", Action: ActionPseudonymize, Path: "spec.rules.*.host", Kinds: []string{"Ingress.networking.k8s.io"}},
+		{Name: "domain", Action: ActionPseudonymize, Path: "spec.domain", Kinds: []string{"Ingress.config.openshift.io"}},
+		{Name: "domain", Action: ActionPseudonymize, Path: "spec.baseDomain", Kinds: []string{"DNS.config.openshift.io"}},
+		{Name: "node", Action: ActionPseudonymize, Path: "metadata.name", Kinds: []string{"Node"}},
+		{Name: "node", Action: ActionPseudonymize, Path: "spec.nodeName", Kinds: []string{"Pod"}},
+	}},
+	"users": {Rules: []Rule{
+		{Name: "user", Action: ActionPseudonymize, Regex: `"username":"([^"]+)"`, Exclude: `^system:`},
+		{Name: "user", Action: ActionPseudonymize, Path: "metadata.name", Kinds: []string{"User.user.openshift.io"}},
+		{Name: "user", Action: ActionPseudonymize, Path: "fullName", Kinds: []string{"User.user.openshift.io"}},
+		{Name: "user", Action: ActionPseudonymize, Path: "identities.*", Kinds: []string{"User.user.openshift.io"}},
+		{Name: "user", Action: ActionPseudonymize, Path: "users.*", Kinds: []string{"Group.user.openshift.io"}},
+		{Name: "user", Action: ActionPseudonymize, Path: "metadata.name", Kinds: []string{"Identity.user.openshift.io"}},
+		{Name: "user", Action: ActionPseudonymize, Path: "providerUserName", Kinds: []string{"Identity.user.openshift.io"}},
+		{Name: "user", Action: ActionPseudonymize, Path: "user.name", Kinds: []string{"Identity.user.openshift.io"}},
+	}},
+	"tokens": {Rules: []Rule{
+		{Name: "token", Action: ActionRedact, Regex: `(?i)\bbearer\s+([A-Za-z0-9._~+/=-]+)`},
+		{Name: "token", Action: ActionRedact, Regex: `\bsha256~[A-Za-z0-9_-]{43}\b`},
+		{Name: "token", Action: ActionRedact, Regex: `\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`},
+		{Name: "token", Action: ActionRedact, Regex: `(?i)\b(?:password|passwd|secret|token)["']?\s*[:=]\s*["']?([^\s"',]+)`},
+	}},
+	"vendor": {Include: []string{"ips", "hostnames", "users", "tokens"}},
+}
+
+// BuiltinProfiles returns the names of the built-in profiles.
+func BuiltinProfiles() []string {
+	var names []string
+	for name := range builtinProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compiledRule is a rule ready to be applied.
+type compiledRule struct {
+	Rule
+	regex   *regexp.Regexp
+	exclude *regexp.Regexp
+	path    []string
+	kinds   map[string]bool
+}
+
+// loadRules returns the rules of the named built-in profiles or profile files, and of the profiles
+// they include.
+func loadRules(names []string) ([]*compiledRule, error) {
+	var rules []*compiledRule
+	seen := map[string]bool{}
+	var load func(name string) error
+	load = func(name string) error {
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+
+		profile, ok := builtinProfiles[name]
+		if !ok {
+			data, err := ioutil.ReadFile(name)
+			if err != nil {
+				return fmt.Errorf("%q is not a built-in redaction profile (%s) or a readable file: %v", name, strings.Join(BuiltinProfiles(), ", "), err)
+			}
+			profile = Profile{}
+			if err := yaml.UnmarshalStrict(data, &profile); err != nil {
+				return fmt.Errorf("unable to read redaction profile %s: %v", name, err)
+			}
+		}
+		for _, include := range profile.Include {
+			if _, ok := builtinProfiles[include]; !ok {
+				return fmt.Errorf("redaction profile %s includes unknown profile %q", name, include)
+			}
+			if err := load(include); err != nil {
+				return err
+			}
+		}
+		for i, rule := range profile.Rules {
+			compiled, err := compileRule(rule)
+			if err != nil {
+				return fmt.Errorf("redaction profile %s, rule %d: %v", name, i+1, err)
+			}
+			rules = append(rules, compiled)
+		}
+		return nil
+	}
+	for _, name := range names {
+		if err := load(name); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+func compileRule(rule Rule) (*compiledRule, error) {
+	if len(rule.Action) == 0 {
+		rule.Action = ActionRedact
+	}
+	switch rule.Action {
+	case ActionRemove, ActionRedact, ActionPseudonymize:
+	default:
+		return nil, fmt.Errorf("unknown action %q, must be one of %s, %s or %s", rule.Action, ActionRemove, ActionRedact, ActionPseudonymize)
+	}
+	if len(rule.Name) == 0 {
+		rule.Name = "redacted"
+	}
+	if (len(rule.Regex) == 0) == (len(rule.Path) == 0) {
+		return nil, fmt.Errorf("exactly one of regex or path must be set")
+	}
+
+	compiled := &compiledRule{Rule: rule}
+	var err error
+	if len(rule.Regex) > 0 {
+		if compiled.regex, err = regexp.Compile(rule.Regex); err != nil {
+			return nil, err
+		}
+		if len(rule.Kinds) > 0 {
+			return nil, fmt.Errorf("kinds may only be set for path rules")
+		}
+	}
+	if len(rule.Exclude) > 0 {
+		if compiled.exclude, err = regexp.Compile(rule.Exclude); err != nil {
+			return nil, err
+		}
+	}
+	if len(rule.Path) > 0 {
+		if compiled.path, err = parsePath(rule.Path); err != nil {
+			return nil, err
+		}
+		compiled.kinds = map[string]bool{}
+		for _, kind := range rule.Kinds {
+			compiled.kinds[kind] = true
+		}
+	}
+	return compiled, nil
+}
+
+// parsePath splits a field path on dots. Keys that contain dots are written in brackets, like
+// metadata.annotations["openshift.io/description"].
+func parsePath(path string) ([]string, error) {
+	var segments []string
+	for len(path) > 0 {
+		if strings.HasPrefix(path, "[") {
+			end := strings.Index(path, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated [ in path")
+			}
+			segments = append(segments, strings.Trim(path[1:end], `"'`))
+			path = strings.TrimPrefix(path[end+1:], ".")
+			continue
+		}
+		end := strings.IndexAny(path, ".[")
+		if end == -1 {
+			end = len(path)
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("empty path segment")
+		}
+		segments = append(segments, path[:end])
+		path = strings.TrimPrefix(path[end:], ".")
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return segments, nil
+}
+
+// matchesKind returns true if the rule applies to an object of the given kind and API group.
+func (r *compiledRule) matchesKind(kind, group string) bool {
+	if len(r.kinds) == 0 {
+		return true
+	}
+	if len(group) == 0 {
+		return r.kinds[kind]
+	}
+	return r.kinds[kind+"."+group]
+}
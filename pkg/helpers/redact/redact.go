@@ -0,0 +1,446 @@
+// Package redact removes sensitive data from the files written by oc adm inspect and oc adm
+// must-gather, using rules that match text with regular expressions or objects by field path.
+package redact
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// Marker replaces redacted values.
+const Marker = "REDACTED"
+
+// Redactor applies redaction rules. Pseudonyms are shared between all the files a redactor
+// processes, and are safe to use concurrently.
+type Redactor struct {
+	regexRules []*compiledRule
+	pathRules  []*compiledRule
+
+	lock       sync.Mutex
+	pseudonyms map[string]string
+	counts     map[string]int
+}
+
+// New returns a redactor for the named built-in profiles or profile files.
+func New(profiles ...string) (*Redactor, error) {
+	rules, err := loadRules(profiles)
+	if err != nil {
+		return nil, err
+	}
+	r := &Redactor{
+		pseudonyms: map[string]string{},
+		counts:     map[string]int{},
+	}
+	for _, rule := range rules {
+		if rule.regex != nil {
+			r.regexRules = append(r.regexRules, rule)
+		} else {
+			r.pathRules = append(r.pathRules, rule)
+		}
+	}
+	return r, nil
+}
+
+// pseudonym returns the pseudonym of a value, creating it with the given prefix if the value has
+// not been seen before.
+func (r *Redactor) pseudonym(prefix, value string) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if p, ok := r.pseudonyms[value]; ok {
+		return p
+	}
+	r.counts[prefix]++
+	p := fmt.Sprintf("%s-%d", prefix, r.counts[prefix])
+	r.pseudonyms[value] = p
+	return p
+}
+
+// LoadMap reads the pseudonyms written by WriteMap, so that a later run uses the same pseudonyms.
+// A missing file is ignored.
+func (r *Redactor) LoadMap(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	pseudonyms := map[string]string{}
+	if err := json.Unmarshal(data, &pseudonyms); err != nil {
+		return fmt.Errorf("unable to read redaction map %s: %v", path, err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for value, p := range pseudonyms {
+		r.pseudonyms[value] = p
+		// continue numbering after the highest pseudonym of each prefix
+		if i := strings.LastIndex(p, "-"); i != -1 {
+			var n int
+			if _, err := fmt.Sscanf(p[i+1:], "%d", &n); err == nil && n > r.counts[p[:i]] {
+				r.counts[p[:i]] = n
+			}
+		}
+	}
+	return nil
+}
+
+// WriteMap writes the original value of every pseudonym. The file allows the output to be
+// interpreted, and must not be shared with it.
+func (r *Redactor) WriteMap(path string) error {
+	r.lock.Lock()
+	data, err := json.MarshalIndent(r.pseudonyms, "", "  ")
+	r.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// Line redacts a line of text.
+func (r *Redactor) Line(line []byte) []byte {
+	for _, rule := range r.regexRules {
+		line = r.replaceMatches(rule, line)
+	}
+	return line
+}
+
+// replaceMatches replaces the text matched by a rule, or by its capture groups if it has any.
+func (r *Redactor) replaceMatches(rule *compiledRule, line []byte) []byte {
+	matches := rule.regex.FindAllSubmatchIndex(line, -1)
+	if len(matches) == 0 {
+		return line
+	}
+	var out bytes.Buffer
+	last := 0
+	for _, match := range matches {
+		spans := match[:2]
+		if len(match) > 2 {
+			spans = match[2:]
+		}
+		for i := 0; i+1 < len(spans); i += 2 {
+			start, end := spans[i], spans[i+1]
+			if start < last || start == -1 {
+				continue
+			}
+			value := string(line[start:end])
+			if rule.exclude != nil && rule.exclude.MatchString(value) {
+				continue
+			}
+			out.Write(line[last:start])
+			out.WriteString(r.replacement(rule, value))
+			last = end
+		}
+	}
+	out.Write(line[last:])
+	return out.Bytes()
+}
+
+// replacement returns what a value matched by a rule is replaced with.
+func (r *Redactor) replacement(rule *compiledRule, value string) string {
+	switch rule.Action {
+	case ActionRemove:
+		return ""
+	case ActionPseudonymize:
+		return r.pseudonym(rule.Name, value)
+	default:
+		return Marker
+	}
+}
+
+// Copy copies src to dst, redacting every line.
+func (r *Redactor) Copy(dst io.Writer, src io.Reader) error {
+	reader := bufio.NewReader(src)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if _, werr := dst.Write(r.Line(line)); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// CopyFile copies src to dst like Copy, but decompresses and compresses again the content of
+// files named *.gz.
+func (r *Redactor) CopyFile(name string, dst io.Writer, src io.Reader) error {
+	if filepath.Ext(name) != ".gz" {
+		return r.Copy(dst, src)
+	}
+	gzipReader, err := gzip.NewReader(src)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+	gzipWriter := gzip.NewWriter(dst)
+	if err := r.Copy(gzipWriter, gzipReader); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// Document redacts the YAML or JSON documents in data. Path rules are applied to the documents
+// that are Kubernetes objects or lists, and regex rules to the text of all of them. Documents are
+// written again in the same format, but not necessarily in the same layout.
+func (r *Redactor) Document(data []byte) ([]byte, error) {
+	if len(r.pathRules) > 0 {
+		if redacted, ok := r.objects(data); ok {
+			data = redacted
+		}
+	}
+	var out bytes.Buffer
+	if err := r.Copy(&out, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// objects applies the path rules to the objects in data. It returns false if data contains
+// anything other than objects.
+func (r *Redactor) objects(data []byte) ([]byte, bool) {
+	isJSON := bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	var docs [][]byte
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, false
+		}
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+		// keep integers exact
+		var obj map[string]interface{}
+		jsonDecoder := json.NewDecoder(bytes.NewReader(raw))
+		jsonDecoder.UseNumber()
+		if err := jsonDecoder.Decode(&obj); err != nil {
+			return nil, false
+		}
+		if _, ok := obj["kind"].(string); !ok {
+			return nil, false
+		}
+		r.Object(obj)
+
+		var doc []byte
+		var err error
+		if isJSON {
+			doc, err = json.MarshalIndent(obj, "", "    ")
+			doc = append(doc, '\n')
+		} else {
+			doc, err = yaml.Marshal(obj)
+		}
+		if err != nil {
+			return nil, false
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil, false
+	}
+	separator := []byte("---\n")
+	if isJSON {
+		separator = nil
+	}
+	return bytes.Join(docs, separator), true
+}
+
+// Object applies the path rules to an object, or to the items of a list.
+func (r *Redactor) Object(obj map[string]interface{}) {
+	kind, _ := obj["kind"].(string)
+	apiVersion, _ := obj["apiVersion"].(string)
+	if items, ok := obj["items"].([]interface{}); ok && strings.HasSuffix(kind, "List") {
+		for _, item := range items {
+			if itemObj, ok := item.(map[string]interface{}); ok {
+				if _, ok := itemObj["kind"]; !ok {
+					// the items of typed lists don't repeat their kind
+					itemObj["kind"] = strings.TrimSuffix(kind, "List")
+					itemObj["apiVersion"] = apiVersion
+					r.Object(itemObj)
+					delete(itemObj, "kind")
+					delete(itemObj, "apiVersion")
+					continue
+				}
+				r.Object(itemObj)
+			}
+		}
+		return
+	}
+
+	gv, _ := schema.ParseGroupVersion(apiVersion)
+	for _, rule := range r.pathRules {
+		if rule.matchesKind(kind, gv.Group) {
+			r.applyPath(obj, rule.path, rule)
+		}
+	}
+}
+
+// applyPath applies a rule to the fields of value at path. A * segment matches every key of a map
+// or item of a list.
+func (r *Redactor) applyPath(value interface{}, path []string, rule *compiledRule) {
+	segment, rest := path[0], path[1:]
+	switch v := value.(type) {
+	case map[string]interface{}:
+		var keys []string
+		if segment == "*" {
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+		} else if _, ok := v[segment]; ok {
+			keys = []string{segment}
+		}
+		for _, key := range keys {
+			if len(rest) > 0 {
+				r.applyPath(v[key], rest, rule)
+				continue
+			}
+			if rule.Action == ActionRemove {
+				delete(v, key)
+				continue
+			}
+			v[key] = r.fieldReplacement(rule, v[key])
+		}
+	case []interface{}:
+		for i := range v {
+			if segment != "*" && segment != fmt.Sprint(i) {
+				continue
+			}
+			if len(rest) > 0 {
+				r.applyPath(v[i], rest, rule)
+				continue
+			}
+			// items can't be removed without changing the type of the parent
+			v[i] = r.fieldReplacement(rule, v[i])
+		}
+	}
+}
+
+// fieldReplacement returns what a field matched by a rule is replaced with.
+func (r *Redactor) fieldReplacement(rule *compiledRule, value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		if rule.Action == ActionPseudonymize && value != nil {
+			s = fmt.Sprint(value)
+		} else {
+			return Marker
+		}
+	}
+	if rule.exclude != nil && rule.exclude.MatchString(s) {
+		return value
+	}
+	if rule.Action == ActionRemove {
+		return ""
+	}
+	return r.replacement(rule, s)
+}
+
+// File redacts a file in place. Binary files other than compressed logs are left untouched.
+func (r *Redactor) File(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	reader := bufio.NewReader(src)
+	ext := filepath.Ext(path)
+	if ext != ".gz" {
+		head, _ := reader.Peek(8000)
+		if bytes.IndexByte(head, 0) != -1 {
+			return nil
+		}
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".redact")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	switch ext {
+	case ".yaml", ".yml", ".json":
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if data, err = r.Document(data); err != nil {
+			return err
+		}
+		if _, err := tmp.Write(data); err != nil {
+			return err
+		}
+	default:
+		if err := r.CopyFile(path, tmp, reader); err != nil {
+			return fmt.Errorf("unable to redact %s: %v", path, err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Dir redacts every regular file below dir in place.
+func (r *Redactor) Dir(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return r.File(path)
+	})
+}
+
+// ValidateMapPath returns an error if the pseudonym map would be written inside the directory the
+// redacted output is written to.
+func ValidateMapPath(mapPath, destDir string) error {
+	absMap, err := filepath.Abs(mapPath)
+	if err != nil {
+		return err
+	}
+	absDest, err := filepath.Abs(destDir)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(absDest, absMap)
+	if err != nil {
+		return nil
+	}
+	if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("the redaction map %s must not be written inside %s, where it would be shared with the redacted data", mapPath, destDir)
+	}
+	return nil
+}
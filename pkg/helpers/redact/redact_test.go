@@ -0,0 +1,253 @@
+package redact
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{path: "spec.host", want: []string{"spec", "host"}},
+		{path: `data["ca.crt"]`, want: []string{"data", "ca.crt"}},
+		{path: `metadata.annotations['a.io/b'].x`, want: []string{"metadata", "annotations", "a.io/b", "x"}},
+		{path: "status.addresses.*.address", want: []string{"status", "addresses", "*", "address"}},
+		{path: "spec..host", wantErr: true},
+		{path: `data["ca.crt"`, wantErr: true},
+		{path: "", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parsePath(test.path)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%q: unexpected error: %v", test.path, err)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(test.want, got) {
+			t.Errorf("%q: got %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	r, err := New("vendor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{in: "connecting to 10.0.1.2:6443 from 10.0.1.3", want: "connecting to ipv4-1:6443 from ipv4-2"},
+		{in: "retrying 10.0.1.2", want: "retrying ipv4-1"},
+		{in: "dial tcp [fd00::1]:443", want: "dial tcp [ipv6-1]:443"},
+		{in: "GET https://api.mycluster.example.com:6443/apis", want: "GET https://host-1:6443/apis"},
+		{in: "route console-openshift-console.apps.mycluster.example.com admitted", want: "route host-2 admitted"},
+		{in: "group apps.openshift.io and api version 4.10.3", want: "group apps.openshift.io and api version 4.10.3"},
+		{in: `{"user":{"username":"alice","groups":["system:authenticated"]}}`, want: `{"user":{"username":"user-1","groups":["system:authenticated"]}}`},
+		{in: `{"user":{"username":"system:admin"}}`, want: `{"user":{"username":"system:admin"}}`},
+		{in: "Authorization: Bearer abc.def-123", want: "Authorization: Bearer REDACTED"},
+		{in: "token sha256~abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQ expired", want: "token REDACTED expired"},
+		{in: "password=hunter2 user=bob", want: "password=REDACTED user=bob"},
+		{in: "nothing to see here", want: "nothing to see here"},
+	}
+	for _, test := range tests {
+		if got := string(r.Line([]byte(test.in))); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestDocument(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	profile := filepath.Join(dir, "profile.yaml")
+	if err := ioutil.WriteFile(profile, []byte(`
+include: [ips]
+rules:
+- path: data["password"]
+  kinds: [ConfigMap]
+  action: remove
+- path: data.*
+  kinds: [Secret]
+- name: node
+  path: metadata.name
+  kinds: [Node]
+  action: pseudonymize
+- name: node
+  path: spec.nodeName
+  kinds: [Pod]
+  action: pseudonymize
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := New(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := r.Document([]byte(`apiVersion: v1
+kind: ConfigMapList
+items:
+- metadata:
+    name: config
+  data:
+    password: secret
+    url: http://10.0.0.1/
+- metadata:
+    name: other
+  data:
+    key: value
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `apiVersion: v1
+items:
+- data:
+    url: http://ipv4-1/
+  metadata:
+    name: config
+- data:
+    key: value
+  metadata:
+    name: other
+kind: ConfigMapList
+`
+	if string(out) != want {
+		t.Errorf("unexpected list:\n%s", out)
+	}
+
+	out, err = r.Document([]byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "a", "generation": 9007199254740993}, "spec": {"nodeName": "worker-a"}}
+{"apiVersion": "v1", "kind": "Node", "metadata": {"name": "worker-a"}}
+{"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "s"}, "data": {"a": "YQ=="}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`"nodeName": "node-1"`, `"name": "node-1"`, `"a": "REDACTED"`, `"generation": 9007199254740993`} {
+		if !bytes.Contains(out, []byte(expected)) {
+			t.Errorf("expected %s in:\n%s", expected, out)
+		}
+	}
+
+	// text that isn't an object is only redacted by regex rules
+	out, err = r.Document([]byte("data:\n  password: 10.0.0.1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "data:\n  password: ipv4-1\n" {
+		t.Errorf("unexpected text: %q", out)
+	}
+}
+
+func TestFileAndMap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write([]byte("rotated 192.168.0.1\n"))
+	w.Close()
+	files := map[string][]byte{
+		"logs/current.log":    []byte("from 192.168.0.2\nfrom 192.168.0.1\n"),
+		"logs/rotated.log.gz": compressed.Bytes(),
+		"binary":              {0, 1, '1', '0', '.', '0', '.', '0', '.', '1'},
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, "out", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mapFile := filepath.Join(dir, "map.json")
+	if err := ioutil.WriteFile(mapFile, []byte(`{"192.168.0.1": "ipv4-7"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New("ips")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LoadMap(mapFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Dir(filepath.Join(dir, "out")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "out", "logs/current.log"))
+	if string(data) != "from ipv4-8\nfrom ipv4-7\n" {
+		t.Errorf("unexpected log: %q", data)
+	}
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "out", "logs/rotated.log.gz"))
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ = ioutil.ReadAll(reader); string(data) != "rotated ipv4-7\n" {
+		t.Errorf("unexpected rotated log: %q", data)
+	}
+	if data, _ = ioutil.ReadFile(filepath.Join(dir, "out", "binary")); !bytes.Equal(data, files["binary"]) {
+		t.Errorf("binary file was changed: %q", data)
+	}
+
+	if err := r.WriteMap(mapFile); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := New("ips")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.LoadMap(mapFile); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(loaded.Line([]byte("192.168.0.2 192.168.0.3"))); got != "ipv4-8 ipv4-9" {
+		t.Errorf("unexpected pseudonyms after loading the map: %q", got)
+	}
+}
+
+func TestValidateMapPath(t *testing.T) {
+	if err := ValidateMapPath("out/map.json", "out"); err == nil {
+		t.Error("expected an error for a map inside the destination directory")
+	}
+	if err := ValidateMapPath("map.json", "out"); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateMapPath("../out-map.json", "out"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, name := range []string{"does-not-exist"} {
+		if _, err := New(name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	for _, rule := range []Rule{
+		{Regex: "a", Path: "b"},
+		{},
+		{Regex: "(", Action: ActionRedact},
+		{Path: "a", Action: "hide"},
+		{Regex: "a", Kinds: []string{"Pod"}},
+	} {
+		if _, err := compileRule(rule); err == nil {
+			t.Errorf("%#v: expected an error", rule)
+		}
+	}
+}